@@ -0,0 +1,192 @@
+// Package bitbucket receives the Bitbucket Cloud webhooks and notifies the Telegram users linked to
+// the Bitbucket accounts involved in each event
+package bitbucket
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"shared/app/bot/botclient"
+	"shared/app/bot/storage"
+	"shared/app/bot/storage/notification"
+	"shared/shared/telegram"
+
+	"bitbucket.org/truora/scrap-services/logger"
+	"bitbucket.org/truora/scrap-services/shared/apigateway"
+	"bitbucket.org/truora/scrap-services/shared/cache"
+	"github.com/aws/aws-lambda-go/events"
+)
+
+const (
+	eventKeyHeader  = "X-Event-Key"
+	requestIDHeader = "X-Request-UUID"
+
+	// deliveryKey claims each webhook delivery so the retries of Bitbucket are not notified twice
+	deliveryKey    = "BOT-BITBUCKET-DELIVERY:%s"
+	deliveryWindow = time.Hour
+)
+
+var (
+	// ErrInvalidSignature when the webhook signature does not match the body
+	ErrInvalidSignature = errors.New("invalid bitbucket webhook signature")
+	// ErrEmptyWebhookSecret when the webhook secret is not configured
+	ErrEmptyWebhookSecret = errors.New("bitbucket webhook secret is empty")
+	// ErrInvalidPayload when the webhook body is not a valid Bitbucket payload
+	ErrInvalidPayload = errors.New("invalid bitbucket webhook payload")
+
+	defaultLogger = logger.New("bot-bitbucket")
+
+	newTelegramClient = botclient.New
+)
+
+// Init initializes the cache used to discard repeated deliveries, it panics when the cache is invalid
+func Init(ctx context.Context) {
+	defaultLogger.Must(ctx, cache.InitFromEnv(), logger.OneDay)
+}
+
+// Handler is the handler of the Bitbucket webhook Lambda behind API Gateway
+func Handler(ctx context.Context, request *events.APIGatewayProxyRequest) (*apigateway.Response, error) {
+	body := []byte(request.Body)
+
+	if request.IsBase64Encoded {
+		decoded, err := base64.StdEncoding.DecodeString(request.Body)
+		if err != nil {
+			return &apigateway.Response{StatusCode: http.StatusBadRequest}, nil
+		}
+
+		body = decoded
+	}
+
+	err := VerifySignature(ctx, body, apigateway.GetHeader(request, signatureHeader))
+	if err != nil {
+		logger.Get(ctx).Error(ctx, "bitbucket_webhook_rejected", logger.OneMonth, []logger.Object{
+			logger.ErrObject(err),
+			logger.APIGatewayObject(request),
+		})
+
+		return &apigateway.Response{StatusCode: http.StatusUnauthorized}, nil
+	}
+
+	eventKey := apigateway.GetHeader(request, eventKeyHeader)
+
+	notice, err := ParseNotification(eventKey, body)
+	if err != nil {
+		logger.Get(ctx).Error(ctx, "bitbucket_webhook_invalid", logger.OneMonth, []logger.Object{
+			logger.ErrObject(err),
+			logger.MapObject("webhook", map[string]interface{}{"s_event_key": eventKey}),
+		})
+
+		return &apigateway.Response{StatusCode: http.StatusBadRequest}, nil
+	}
+
+	if notice == nil || len(notice.AccountIDs) == 0 {
+		return &apigateway.Response{StatusCode: http.StatusOK}, nil
+	}
+
+	telegramClient, err := newTelegramClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	requestID := apigateway.GetHeader(request, requestIDHeader)
+
+	if !claimDelivery(ctx, requestID) {
+		return &apigateway.Response{StatusCode: http.StatusOK}, nil
+	}
+
+	err = Notify(ctx, telegramClient, notice)
+	if err != nil {
+		logger.Get(ctx).Error(ctx, "bitbucket_notification_failed", logger.OneMonth, []logger.Object{
+			logger.ErrObject(err),
+			logger.MapObject("webhook", map[string]interface{}{"s_event_key": eventKey}),
+		})
+
+		// the retry of Bitbucket notifies the accounts that failed, the users already notified get the
+		// notification again
+		releaseDelivery(ctx, requestID)
+
+		return nil, err
+	}
+
+	return &apigateway.Response{StatusCode: http.StatusOK}, nil
+}
+
+// Notify sends the notification to the verified Telegram users linked to its accounts, the accounts
+// without a linked user and the users that muted the event are skipped
+func Notify(ctx context.Context, telegramClient *telegram.Client, notice *Notification) error {
+	var errs []error
+
+	for _, accountID := range notice.AccountIDs {
+		err := notifyAccount(ctx, telegramClient, notice, accountID)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("notify %s failed: %w", accountID, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func notifyAccount(ctx context.Context, telegramClient *telegram.Client, notice *Notification, accountID string) error {
+	user, err := storage.GetTelegramUserByBitbucketID(ctx, accountID)
+	if errors.Is(err, storage.ErrUserNotFound) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if user.ID == 0 {
+		return nil
+	}
+
+	muted, err := notification.IsMuted(ctx, user.ID, notice.Event)
+	if err != nil {
+		return err
+	}
+
+	if muted {
+		return nil
+	}
+
+	_, err = telegramClient.SendMessage(ctx, &telegram.SendMessageRequest{
+		ChatID:                user.ID,
+		Text:                  notice.Text,
+		ParseMode:             telegram.ParseModeHTML,
+		DisableWebPagePreview: true,
+	})
+
+	return err
+}
+
+// claimDelivery returns false if the delivery was already notified, deliveries without ID are always notified
+func claimDelivery(ctx context.Context, requestID string) bool {
+	if requestID == "" {
+		return true
+	}
+
+	claimed, err := cache.AddOnce(ctx, fmt.Sprintf(deliveryKey, requestID), time.Now().Unix(), deliveryWindow)
+	if err != nil {
+		logger.Get(ctx).Warning(ctx, "claim_bitbucket_delivery_failed", logger.OneMonth, []logger.Object{logger.ErrObject(err)})
+
+		return true
+	}
+
+	return claimed
+}
+
+// releaseDelivery removes the claim of the delivery so it is notified when Bitbucket retries it
+func releaseDelivery(ctx context.Context, requestID string) {
+	if requestID == "" {
+		return
+	}
+
+	err := cache.Del(ctx, fmt.Sprintf(deliveryKey, requestID))
+	if err != nil {
+		logger.Get(ctx).Warning(ctx, "release_bitbucket_delivery_failed", logger.OneMonth, []logger.Object{logger.ErrObject(err)})
+	}
+}
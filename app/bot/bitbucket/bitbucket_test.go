@@ -0,0 +1,167 @@
+package bitbucket
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"shared/app/bot/botclient"
+	"shared/app/bot/models"
+	"shared/app/bot/storage"
+	"shared/app/bot/storage/notification"
+	"shared/shared/aws/secrets"
+	"shared/shared/telegram"
+
+	"bitbucket.org/truora/scrap-services/shared/cache"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/require"
+)
+
+func setUp(c *require.Assertions) (*telegram.Fake, func()) {
+	cache.InitMock()
+	storage.InitDynamoMock()
+	secrets.InitSecretsMock()
+	secrets.SetMockedSecret(webhookSecretName, "webhook-secret")
+
+	ctx := context.Background()
+
+	c.NoError(storage.PutUser(ctx, &models.From{ID: 10, Email: "reviewer@dummy.com", BitbucketID: "reviewer-id", EmailVerified: true}))
+	c.NoError(storage.PutUser(ctx, &models.From{ID: 20, Email: "author@dummy.com", BitbucketID: "author-id", EmailVerified: true}))
+
+	fake := telegram.NewFake("")
+
+	newTelegramClient = func(ctx context.Context) (*telegram.Client, error) {
+		return fake.Client(), nil
+	}
+
+	return fake, func() {
+		newTelegramClient = botclient.New
+		fake.Close()
+		secrets.DeactivateMock()
+	}
+}
+
+func newWebhookRequest(eventKey, requestID, body, signature string) *events.APIGatewayProxyRequest {
+	return &events.APIGatewayProxyRequest{
+		Headers: map[string]string{
+			"X-Event-Key":     eventKey,
+			"X-Request-UUID":  requestID,
+			"X-Hub-Signature": signature,
+		},
+		Body: body,
+	}
+}
+
+func TestHandler(t *testing.T) {
+	c := require.New(t)
+
+	fake, tearDown := setUp(c)
+
+	defer tearDown()
+
+	ctx := context.Background()
+	signature := sign("webhook-secret", []byte(pullRequestPayload))
+
+	response, err := Handler(ctx, newWebhookRequest(EventPullRequestFulfilled, "delivery-1", pullRequestPayload, signature))
+	c.NoError(err)
+	c.Equal(http.StatusOK, response.StatusCode)
+
+	sent := fake.Requests("sendMessage")
+	c.Len(sent, 2)
+	c.Equal(float64(20), sent[0].Params["chat_id"])
+	c.Equal(float64(10), sent[1].Params["chat_id"])
+	c.Equal("HTML", sent[0].Params["parse_mode"])
+
+	// the retries of the same delivery are not notified again
+	response, err = Handler(ctx, newWebhookRequest(EventPullRequestFulfilled, "delivery-1", pullRequestPayload, signature))
+	c.NoError(err)
+	c.Equal(http.StatusOK, response.StatusCode)
+	c.Len(fake.Requests("sendMessage"), 2)
+
+	response, err = Handler(ctx, newWebhookRequest(EventPullRequestFulfilled, "delivery-2", pullRequestPayload, sign("other", []byte(pullRequestPayload))))
+	c.NoError(err)
+	c.Equal(http.StatusUnauthorized, response.StatusCode)
+
+	response, err = Handler(ctx, newWebhookRequest(EventPullRequestFulfilled, "delivery-3", "{", sign("webhook-secret", []byte("{"))))
+	c.NoError(err)
+	c.Equal(http.StatusBadRequest, response.StatusCode)
+}
+
+func TestHandlerMuted(t *testing.T) {
+	c := require.New(t)
+
+	fake, tearDown := setUp(c)
+
+	defer tearDown()
+
+	ctx := context.Background()
+
+	c.NoError(notification.Mute(ctx, 20, models.NotificationPRMerged))
+
+	response, err := Handler(ctx, newWebhookRequest(EventPullRequestFulfilled, "delivery-1", pullRequestPayload, sign("webhook-secret", []byte(pullRequestPayload))))
+	c.NoError(err)
+	c.Equal(http.StatusOK, response.StatusCode)
+
+	sent := fake.Requests("sendMessage")
+	c.Len(sent, 1)
+	c.Equal(float64(10), sent[0].Params["chat_id"])
+
+	// other event types are still notified
+	response, err = Handler(ctx, newWebhookRequest(EventPullRequestApproved, "delivery-2", pullRequestPayload, sign("webhook-secret", []byte(pullRequestPayload))))
+	c.NoError(err)
+	c.Equal(http.StatusOK, response.StatusCode)
+	c.Len(fake.Requests("sendMessage"), 2)
+}
+
+func TestHandlerFailed(t *testing.T) {
+	c := require.New(t)
+
+	fake, tearDown := setUp(c)
+
+	defer tearDown()
+
+	ctx := context.Background()
+	signature := sign("webhook-secret", []byte(pullRequestPayload))
+
+	// the delivery is not claimed when the client can not be built so the retry notifies it
+	newTelegramClient = func(ctx context.Context) (*telegram.Client, error) {
+		return nil, errors.New("read token failed")
+	}
+
+	_, err := Handler(ctx, newWebhookRequest(EventPullRequestFulfilled, "delivery-1", pullRequestPayload, signature))
+	c.Error(err)
+
+	newTelegramClient = func(ctx context.Context) (*telegram.Client, error) {
+		return fake.Client(), nil
+	}
+
+	fake.AddError("sendMessage", http.StatusTooManyRequests, "Too Many Requests: retry after 1")
+
+	// the failed delivery is released
+	_, err = Handler(ctx, newWebhookRequest(EventPullRequestFulfilled, "delivery-1", pullRequestPayload, signature))
+	c.ErrorContains(err, "notify author-id failed")
+
+	response, err := Handler(ctx, newWebhookRequest(EventPullRequestFulfilled, "delivery-1", pullRequestPayload, signature))
+	c.NoError(err)
+	c.Equal(http.StatusOK, response.StatusCode)
+	c.Len(fake.Requests("sendMessage"), 4)
+}
+
+func TestNotifyFailed(t *testing.T) {
+	c := require.New(t)
+
+	fake, tearDown := setUp(c)
+
+	defer tearDown()
+
+	fake.AddError("sendMessage", http.StatusForbidden, "Forbidden: bot was blocked by the user")
+
+	err := Notify(context.Background(), fake.Client(), &Notification{
+		Event:      models.NotificationPROpened,
+		AccountIDs: []string{"reviewer-id", "author-id", "unknown-id"},
+		Text:       "review",
+	})
+	c.ErrorContains(err, "notify reviewer-id failed")
+	c.Len(fake.Requests("sendMessage"), 2)
+}
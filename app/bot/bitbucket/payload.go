@@ -0,0 +1,241 @@
+package bitbucket
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+
+	"shared/app/bot/models"
+)
+
+// Bitbucket Cloud event keys sent in the X-Event-Key header
+const (
+	EventPullRequestCreated   = "pullrequest:created"
+	EventPullRequestApproved  = "pullrequest:approved"
+	EventPullRequestFulfilled = "pullrequest:fulfilled"
+	EventPullRequestComment   = "pullrequest:comment_created"
+	EventCommitStatusUpdated  = "repo:commit_status_updated"
+
+	buildFailedState = "FAILED"
+	// maxQuoteLength is the length of the comment quoted in the mention notifications
+	maxQuoteLength = 200
+)
+
+// mentionPattern matches the mentions in the raw content of the comments, e.g. @{557058:c0b7...}
+var mentionPattern = regexp.MustCompile(`@\{([^}]+)\}`)
+
+// Account is a Bitbucket user
+type Account struct {
+	AccountID   string `json:"account_id"`
+	DisplayName string `json:"display_name"`
+}
+
+// Link is a link of a Bitbucket resource
+type Link struct {
+	Href string `json:"href"`
+}
+
+// Links are the links of a Bitbucket resource
+type Links struct {
+	HTML Link `json:"html"`
+}
+
+// Repository is the repository of the event
+type Repository struct {
+	FullName string `json:"full_name"`
+}
+
+// PullRequest is the pull request of the pull request events
+type PullRequest struct {
+	ID        int64     `json:"id"`
+	Title     string    `json:"title"`
+	Author    Account   `json:"author"`
+	Reviewers []Account `json:"reviewers"`
+	Links     Links     `json:"links"`
+}
+
+// Comment is the comment of the comment events
+type Comment struct {
+	Content struct {
+		Raw string `json:"raw"`
+	} `json:"content"`
+	Links Links `json:"links"`
+}
+
+// Commit is the commit of the commit status events, the author user is only present when the
+// commit author is linked to a Bitbucket account
+type Commit struct {
+	Hash   string `json:"hash"`
+	Author struct {
+		User *Account `json:"user"`
+	} `json:"author"`
+}
+
+// CommitStatus is the build status of the commit status events
+type CommitStatus struct {
+	State   string  `json:"state"`
+	Name    string  `json:"name"`
+	URL     string  `json:"url"`
+	RefName string  `json:"refname"`
+	Commit  *Commit `json:"commit"`
+}
+
+// Payload is the body of the Bitbucket webhooks, only the fields of the event are present
+type Payload struct {
+	Actor        Account       `json:"actor"`
+	Repository   Repository    `json:"repository"`
+	PullRequest  *PullRequest  `json:"pullrequest"`
+	Comment      *Comment      `json:"comment"`
+	CommitStatus *CommitStatus `json:"commit_status"`
+}
+
+// Notification is the message sent to the Telegram users linked to the Bitbucket accounts
+type Notification struct {
+	Event      models.NotificationEvent
+	AccountIDs []string
+	// Text is formatted with HTML
+	Text string
+}
+
+// ParseNotification returns the notification of the webhook, nil is returned when the event is not
+// notified, e.g. a successful build
+func ParseNotification(eventKey string, body []byte) (*Notification, error) {
+	payload := &Payload{}
+
+	err := json.Unmarshal(body, payload)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidPayload, err)
+	}
+
+	notification := payload.notification(eventKey)
+	if notification == nil {
+		return nil, nil
+	}
+
+	notification.AccountIDs = withoutActor(notification.AccountIDs, payload.Actor.AccountID)
+
+	return notification, nil
+}
+
+func (payload *Payload) notification(eventKey string) *Notification {
+	if eventKey == EventCommitStatusUpdated {
+		return payload.buildNotification()
+	}
+
+	if payload.PullRequest == nil {
+		return nil
+	}
+
+	pullRequest := payload.PullRequest
+	actor := html.EscapeString(payload.Actor.DisplayName)
+
+	switch eventKey {
+	case EventPullRequestCreated:
+		return &Notification{
+			Event:      models.NotificationPROpened,
+			AccountIDs: accountIDs(pullRequest.Reviewers),
+			Text:       fmt.Sprintf("<b>%s</b> requested your review on %s", actor, payload.pullRequestLink()),
+		}
+	case EventPullRequestApproved:
+		return &Notification{
+			Event:      models.NotificationPRApproved,
+			AccountIDs: []string{pullRequest.Author.AccountID},
+			Text:       fmt.Sprintf("<b>%s</b> approved %s", actor, payload.pullRequestLink()),
+		}
+	case EventPullRequestFulfilled:
+		return &Notification{
+			Event:      models.NotificationPRMerged,
+			AccountIDs: append([]string{pullRequest.Author.AccountID}, accountIDs(pullRequest.Reviewers)...),
+			Text:       fmt.Sprintf("<b>%s</b> merged %s", actor, payload.pullRequestLink()),
+		}
+	case EventPullRequestComment:
+		return payload.mentionNotification(actor)
+	}
+
+	return nil
+}
+
+func (payload *Payload) buildNotification() *Notification {
+	status := payload.CommitStatus
+	if status == nil || status.State != buildFailedState || status.Commit == nil || status.Commit.Author.User == nil {
+		return nil
+	}
+
+	return &Notification{
+		Event:      models.NotificationBuildFailed,
+		AccountIDs: []string{status.Commit.Author.User.AccountID},
+		Text: fmt.Sprintf("Build <b>%s</b> failed on %s of %s, <a href=\"%s\">see the details</a>",
+			html.EscapeString(status.Name),
+			html.EscapeString(status.RefName),
+			html.EscapeString(payload.Repository.FullName),
+			html.EscapeString(status.URL),
+		),
+	}
+}
+
+func (payload *Payload) mentionNotification(actor string) *Notification {
+	if payload.Comment == nil {
+		return nil
+	}
+
+	mentioned := []string{}
+
+	for _, match := range mentionPattern.FindAllStringSubmatch(payload.Comment.Content.Raw, -1) {
+		mentioned = append(mentioned, match[1])
+	}
+
+	if len(mentioned) == 0 {
+		return nil
+	}
+
+	quote := mentionPattern.ReplaceAllString(payload.Comment.Content.Raw, "@…")
+	if runes := []rune(quote); len(runes) > maxQuoteLength {
+		quote = string(runes[:maxQuoteLength]) + "…"
+	}
+
+	return &Notification{
+		Event:      models.NotificationMention,
+		AccountIDs: mentioned,
+		Text:       fmt.Sprintf("<b>%s</b> mentioned you on %s\n<i>%s</i>", actor, payload.pullRequestLink(), html.EscapeString(quote)),
+	}
+}
+
+// pullRequestLink returns the HTML link of the pull request, e.g. truora/api#12 Add checks
+func (payload *Payload) pullRequestLink() string {
+	return fmt.Sprintf("<a href=\"%s\">%s#%d</a> %s",
+		html.EscapeString(payload.PullRequest.Links.HTML.Href),
+		html.EscapeString(payload.Repository.FullName),
+		payload.PullRequest.ID,
+		html.EscapeString(payload.PullRequest.Title),
+	)
+}
+
+func accountIDs(accounts []Account) []string {
+	ids := make([]string, 0, len(accounts))
+
+	for _, account := range accounts {
+		ids = append(ids, account.AccountID)
+	}
+
+	return ids
+}
+
+// withoutActor removes the empty, repeated and actor accounts, users are not notified of their own actions
+func withoutActor(ids []string, actorID string) []string {
+	seen := map[string]bool{actorID: true, "": true}
+	unique := []string{}
+
+	for _, id := range ids {
+		id = strings.TrimSpace(id)
+		if seen[id] {
+			continue
+		}
+
+		seen[id] = true
+		unique = append(unique, id)
+	}
+
+	return unique
+}
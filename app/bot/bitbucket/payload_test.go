@@ -0,0 +1,82 @@
+package bitbucket
+
+import (
+	"fmt"
+	"testing"
+
+	"shared/app/bot/models"
+
+	"github.com/stretchr/testify/require"
+)
+
+const pullRequestPayload = `{
+	"actor": {"account_id": "actor-id", "display_name": "Ana <Lead>"},
+	"repository": {"full_name": "truora/api"},
+	"pullrequest": {
+		"id": 12,
+		"title": "Add checks",
+		"author": {"account_id": "author-id"},
+		"reviewers": [{"account_id": "reviewer-id"}, {"account_id": "actor-id"}, {"account_id": "reviewer-id"}],
+		"links": {"html": {"href": "https://bitbucket.org/truora/api/pull-requests/12"}}
+	},
+	"comment": {"content": {"raw": "@{reviewer-id} and @{author-id} please check <this>"}}
+}`
+
+func TestParsePullRequestNotifications(t *testing.T) {
+	c := require.New(t)
+
+	notification, err := ParseNotification(EventPullRequestCreated, []byte(pullRequestPayload))
+	c.NoError(err)
+	c.Equal(models.NotificationPROpened, notification.Event)
+	c.Equal([]string{"reviewer-id"}, notification.AccountIDs)
+	c.Equal(`<b>Ana &lt;Lead&gt;</b> requested your review on <a href="https://bitbucket.org/truora/api/pull-requests/12">truora/api#12</a> Add checks`, notification.Text)
+
+	notification, err = ParseNotification(EventPullRequestApproved, []byte(pullRequestPayload))
+	c.NoError(err)
+	c.Equal(models.NotificationPRApproved, notification.Event)
+	c.Equal([]string{"author-id"}, notification.AccountIDs)
+
+	notification, err = ParseNotification(EventPullRequestFulfilled, []byte(pullRequestPayload))
+	c.NoError(err)
+	c.Equal(models.NotificationPRMerged, notification.Event)
+	c.Equal([]string{"author-id", "reviewer-id"}, notification.AccountIDs)
+
+	notification, err = ParseNotification(EventPullRequestComment, []byte(pullRequestPayload))
+	c.NoError(err)
+	c.Equal(models.NotificationMention, notification.Event)
+	c.Equal([]string{"reviewer-id", "author-id"}, notification.AccountIDs)
+	c.Contains(notification.Text, "<i>@… and @… please check &lt;this&gt;</i>")
+
+	notification, err = ParseNotification("pullrequest:rejected", []byte(pullRequestPayload))
+	c.NoError(err)
+	c.Nil(notification)
+
+	_, err = ParseNotification(EventPullRequestCreated, []byte("{"))
+	c.ErrorIs(err, ErrInvalidPayload)
+}
+
+func TestParseBuildNotification(t *testing.T) {
+	c := require.New(t)
+
+	body := `{
+		"actor": {"account_id": "ci-id"},
+		"repository": {"full_name": "truora/api"},
+		"commit_status": {
+			"state": "%s",
+			"name": "tests",
+			"url": "https://ci.example.com/builds/1",
+			"refname": "master",
+			"commit": {"hash": "abc", "author": {"user": {"account_id": "author-id"}}}
+		}
+	}`
+
+	notification, err := ParseNotification(EventCommitStatusUpdated, []byte(fmt.Sprintf(body, "FAILED")))
+	c.NoError(err)
+	c.Equal(models.NotificationBuildFailed, notification.Event)
+	c.Equal([]string{"author-id"}, notification.AccountIDs)
+	c.Equal(`Build <b>tests</b> failed on master of truora/api, <a href="https://ci.example.com/builds/1">see the details</a>`, notification.Text)
+
+	notification, err = ParseNotification(EventCommitStatusUpdated, []byte(fmt.Sprintf(body, "SUCCESSFUL")))
+	c.NoError(err)
+	c.Nil(notification)
+}
@@ -0,0 +1,55 @@
+package bitbucket
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"shared/shared/aws/secrets"
+
+	"bitbucket.org/truora/scrap-services/shared/env"
+)
+
+const (
+	signatureHeader = "X-Hub-Signature"
+	signaturePrefix = "sha256="
+)
+
+var (
+	// webhookSecretName is the secret configured in the Bitbucket webhooks to sign the requests
+	webhookSecretName = env.GetString("BITBUCKET_WEBHOOK_SECRET_NAME", "betty-bitbucket-webhook-secret")
+
+	getSecret = secrets.Get
+)
+
+// VerifySignature checks the X-Hub-Signature of the webhook is the HMAC SHA256 of the body with the secret
+func VerifySignature(ctx context.Context, body []byte, signature string) error {
+	secret, err := getSecret(ctx, webhookSecretName)
+	if err != nil {
+		return err
+	}
+
+	if secret == "" {
+		return ErrEmptyWebhookSecret
+	}
+
+	if !strings.HasPrefix(signature, signaturePrefix) {
+		return ErrInvalidSignature
+	}
+
+	received, err := hex.DecodeString(strings.TrimPrefix(signature, signaturePrefix))
+	if err != nil {
+		return ErrInvalidSignature
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+
+	if !hmac.Equal(received, mac.Sum(nil)) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
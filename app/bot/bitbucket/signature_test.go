@@ -0,0 +1,52 @@
+package bitbucket
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"shared/shared/aws/secrets"
+
+	"github.com/stretchr/testify/require"
+)
+
+func sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifySignature(t *testing.T) {
+	c := require.New(t)
+
+	secrets.InitSecretsMock()
+
+	defer secrets.DeactivateMock()
+
+	ctx := context.Background()
+	body := []byte(`{"actor":{}}`)
+
+	secrets.SetMockedSecret(webhookSecretName, "")
+
+	err := VerifySignature(ctx, body, sign("", body))
+	c.ErrorIs(err, ErrEmptyWebhookSecret)
+
+	secrets.SetMockedSecret(webhookSecretName, "webhook-secret")
+
+	c.NoError(VerifySignature(ctx, body, sign("webhook-secret", body)))
+
+	err = VerifySignature(ctx, body, sign("other-secret", body))
+	c.ErrorIs(err, ErrInvalidSignature)
+
+	err = VerifySignature(ctx, []byte(`{"actor":{"account_id":"x"}}`), sign("webhook-secret", body))
+	c.ErrorIs(err, ErrInvalidSignature)
+
+	err = VerifySignature(ctx, body, "sha256=not-hex")
+	c.ErrorIs(err, ErrInvalidSignature)
+
+	err = VerifySignature(ctx, body, "")
+	c.ErrorIs(err, ErrInvalidSignature)
+}
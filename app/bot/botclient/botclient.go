@@ -0,0 +1,19 @@
+// Package botclient creates the Telegram client of the bot with the token stored in the secrets manager,
+// it is shared by the Lambdas that talk to Telegram without routing updates
+package botclient
+
+import (
+	"context"
+
+	"shared/shared/telegram"
+)
+
+const (
+	// TokenSecretName is the name of the secret with the Telegram bot token
+	TokenSecretName = "betty-bot-token" // #nosec This is not a hardcoded credential
+)
+
+// New creates the Telegram client of the bot
+func New(ctx context.Context) (*telegram.Client, error) {
+	return telegram.NewFromSecret(ctx, TokenSecretName)
+}
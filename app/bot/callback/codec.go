@@ -0,0 +1,239 @@
+// Package callback encodes and decodes the callback_data of the bot inline keyboards, the data is
+// signed and bound to the user and chat the keyboard was sent to so it can not be forged or replayed.
+// Single-use data, e.g. the one of the confirmation buttons, can only be decoded once
+package callback
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"shared/shared/aws/secrets"
+
+	"bitbucket.org/truora/scrap-services/shared/cache"
+	"bitbucket.org/truora/scrap-services/shared/env"
+)
+
+const (
+	// Version of the callback data format
+	Version = "1"
+	// MaxDataLength is the maximum length of the callback_data accepted by Telegram
+	MaxDataLength = 64
+
+	separator = "."
+	fieldsNum = 6
+
+	// inlineKind payloads travel in the callback_data, cachedKind payloads are stored in the cache and
+	// onceKind payloads are stored in the cache and their reference is claimed when they are decoded
+	inlineKind = "i"
+	cachedKind = "c"
+	onceKind   = "o"
+
+	payloadKey      = "BOT-CALLBACK-PAYLOAD:%s"
+	usedKey         = "BOT-CALLBACK-USED:%s"
+	signatureLength = 12
+	referenceLength = 8
+	expirationBase  = 36
+	defaultTTL      = 24 * time.Hour
+)
+
+var (
+	// ErrMalformed when the callback data does not have the expected format
+	ErrMalformed = errors.New("malformed callback data")
+	// ErrUnsupportedVersion when the callback data was encoded with an unknown version
+	ErrUnsupportedVersion = errors.New("unsupported callback data version")
+	// ErrInvalidSignature when the signature does not match the payload, user and chat
+	ErrInvalidSignature = errors.New("invalid callback data signature")
+	// ErrExpired when the callback data expired
+	ErrExpired = errors.New("callback data expired")
+	// ErrPayloadNotFound when the cached payload of the callback data does not exist anymore
+	ErrPayloadNotFound = errors.New("callback payload not found")
+	// ErrAlreadyUsed when the single-use callback data was already decoded
+	ErrAlreadyUsed = errors.New("callback data already used")
+	// ErrInvalidCommand when the command can not be encoded
+	ErrInvalidCommand = errors.New("invalid callback command")
+	// ErrEmptySecret when the signing secret is not configured
+	ErrEmptySecret = errors.New("callback secret is empty")
+
+	// SecretName is the name of the secret used to sign the callback data
+	SecretName = env.GetString("CALLBACK_SECRET_NAME", "betty-bot-callback-secret")
+	ttlMinutes = env.GetInt64("CALLBACK_TTL_MINUTES", int64(defaultTTL/time.Minute))
+
+	now = time.Now
+)
+
+// Payload is the content of the callback data
+type Payload struct {
+	Command string
+	Data    string
+	// UserID and ChatID are the user and chat allowed to use the callback data
+	UserID int64
+	ChatID int64
+	// SingleUse callback data can only be decoded once
+	SingleUse bool
+}
+
+// Codec signs and verifies callback data
+type Codec struct {
+	secret []byte
+	ttl    time.Duration
+}
+
+// NewCodec creates a codec that signs with the secret, callback data expires after the ttl
+func NewCodec(secret []byte, ttl time.Duration) (*Codec, error) {
+	if len(secret) == 0 {
+		return nil, ErrEmptySecret
+	}
+
+	return &Codec{secret: secret, ttl: ttl}, nil
+}
+
+// NewCodecFromSecret creates a codec with the secret stored in the secrets manager
+func NewCodecFromSecret(ctx context.Context) (*Codec, error) {
+	secret, err := secrets.Get(ctx, SecretName)
+	if err != nil {
+		return nil, fmt.Errorf("get callback secret failed: %w", err)
+	}
+
+	return NewCodec([]byte(secret), time.Duration(ttlMinutes)*time.Minute)
+}
+
+// Encode returns the signed callback data of the payload, single-use payloads and the ones that
+// do not fit in the callback data are stored in the cache until they expire
+func (c *Codec) Encode(ctx context.Context, payload Payload) (string, error) {
+	if payload.Command == "" || strings.ContainsAny(payload.Command, separator+" ") {
+		return "", fmt.Errorf("%w: %q", ErrInvalidCommand, payload.Command)
+	}
+
+	expiresAt := now().Add(c.ttl)
+	expiration := strconv.FormatInt(expiresAt.Unix(), expirationBase)
+
+	encoded := c.encode(inlineKind, expiration, payload.Command, payload.Data, payload)
+	if !payload.SingleUse && len(encoded) <= MaxDataLength {
+		return encoded, nil
+	}
+
+	kind := cachedKind
+	if payload.SingleUse {
+		kind = onceKind
+	}
+
+	reference, err := newReference()
+	if err != nil {
+		return "", err
+	}
+
+	err = cache.Add(ctx, fmt.Sprintf(payloadKey, reference), payload.Data, c.ttl)
+	if err != nil {
+		return "", fmt.Errorf("store callback payload failed: %w", err)
+	}
+
+	encoded = c.encode(kind, expiration, payload.Command, reference, payload)
+	if len(encoded) > MaxDataLength {
+		return "", fmt.Errorf("%w: %s is too long", ErrInvalidCommand, payload.Command)
+	}
+
+	return encoded, nil
+}
+
+// Decode verifies the callback data was issued to the user and chat and returns its payload
+func (c *Codec) Decode(ctx context.Context, data string, userID, chatID int64) (*Payload, error) {
+	fields := strings.SplitN(data, separator, fieldsNum)
+	if len(fields) != fieldsNum {
+		return nil, ErrMalformed
+	}
+
+	version, kind, expiration, signature, command, value := fields[0], fields[1], fields[2], fields[3], fields[4], fields[5]
+	if version != Version {
+		return nil, ErrUnsupportedVersion
+	}
+
+	if kind != inlineKind && kind != cachedKind && kind != onceKind {
+		return nil, ErrMalformed
+	}
+
+	payload := &Payload{Command: command, Data: value, UserID: userID, ChatID: chatID, SingleUse: kind == onceKind}
+
+	if !hmac.Equal([]byte(signature), []byte(c.sign(kind, expiration, command, value, *payload))) {
+		return nil, ErrInvalidSignature
+	}
+
+	expiresAt, err := strconv.ParseInt(expiration, expirationBase, 64)
+	if err != nil {
+		return nil, ErrMalformed
+	}
+
+	if now().Unix() > expiresAt {
+		return nil, ErrExpired
+	}
+
+	if kind == inlineKind {
+		return payload, nil
+	}
+
+	payload.Data, err = cache.Get(ctx, fmt.Sprintf(payloadKey, value))
+	if errors.Is(err, cache.ErrKeyNotExists) {
+		return nil, ErrPayloadNotFound
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("read callback payload failed: %w", err)
+	}
+
+	if kind == onceKind {
+		err = claimReference(ctx, value, c.ttl)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return payload, nil
+}
+
+// claimReference marks the reference of a single-use payload as used, the claim lives as long as the
+// callback data so a second press of the button is rejected
+func claimReference(ctx context.Context, reference string, ttl time.Duration) error {
+	claimed, err := cache.AddOnce(ctx, fmt.Sprintf(usedKey, reference), now().Unix(), ttl)
+	if err != nil {
+		return fmt.Errorf("claim callback reference failed: %w", err)
+	}
+
+	if !claimed {
+		return ErrAlreadyUsed
+	}
+
+	return nil
+}
+
+func (c *Codec) encode(kind, expiration, command, value string, payload Payload) string {
+	signature := c.sign(kind, expiration, command, value, payload)
+
+	return strings.Join([]string{Version, kind, expiration, signature, command, value}, separator)
+}
+
+// sign returns the truncated HMAC of the fields, the user and chat are signed but not sent
+// because Telegram adds them to the callback query
+func (c *Codec) sign(kind, expiration, command, value string, payload Payload) string {
+	mac := hmac.New(sha256.New, c.secret)
+	fmt.Fprintf(mac, "%s\n%s\n%s\n%s\n%s\n%d\n%d", Version, kind, expiration, command, value, payload.UserID, payload.ChatID)
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:signatureLength])
+}
+
+func newReference() (string, error) {
+	reference := make([]byte, referenceLength)
+
+	_, err := rand.Read(reference)
+	if err != nil {
+		return "", fmt.Errorf("generate callback reference failed: %w", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(reference), nil
+}
@@ -0,0 +1,154 @@
+package callback
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"shared/shared/aws/secrets"
+
+	"bitbucket.org/truora/scrap-services/shared/cache"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestCodec(c *require.Assertions) *Codec {
+	codec, err := NewCodec([]byte("secret"), time.Hour)
+	c.NoError(err)
+
+	return codec
+}
+
+func TestEncodeDecode(t *testing.T) {
+	c := require.New(t)
+
+	ctx := context.Background()
+	codec := newTestCodec(c)
+
+	data, err := codec.Encode(ctx, Payload{Command: "approve", Data: "a.b", UserID: 1, ChatID: 2})
+	c.NoError(err)
+	c.LessOrEqual(len(data), MaxDataLength)
+	c.True(strings.HasPrefix(data, Version+separator+inlineKind))
+
+	payload, err := codec.Decode(ctx, data, 1, 2)
+	c.NoError(err)
+	c.Equal(&Payload{Command: "approve", Data: "a.b", UserID: 1, ChatID: 2}, payload)
+}
+
+func TestEncodeDecodeCachedPayload(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+
+	ctx := context.Background()
+	codec := newTestCodec(c)
+	largeData := strings.Repeat("x", 200)
+
+	data, err := codec.Encode(ctx, Payload{Command: "deployterraformstaging", Data: largeData, UserID: 1, ChatID: 2})
+	c.NoError(err)
+	c.LessOrEqual(len(data), MaxDataLength)
+	c.True(strings.HasPrefix(data, Version+separator+cachedKind))
+
+	payload, err := codec.Decode(ctx, data, 1, 2)
+	c.NoError(err)
+	c.Equal(largeData, payload.Data)
+
+	_, err = codec.Decode(ctx, data, 3, 2)
+	c.ErrorIs(err, ErrInvalidSignature)
+
+	cache.InitMock()
+
+	_, err = codec.Decode(ctx, data, 1, 2)
+	c.ErrorIs(err, ErrPayloadNotFound)
+}
+
+func TestEncodeDecodeSingleUse(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+
+	ctx := context.Background()
+	codec := newTestCodec(c)
+
+	data, err := codec.Encode(ctx, Payload{Command: "setrole", Data: "answer=yes", UserID: 1, ChatID: 2, SingleUse: true})
+	c.NoError(err)
+	c.True(strings.HasPrefix(data, Version+separator+onceKind))
+
+	_, err = codec.Decode(ctx, data, 3, 2)
+	c.ErrorIs(err, ErrInvalidSignature)
+
+	payload, err := codec.Decode(ctx, data, 1, 2)
+	c.NoError(err)
+	c.Equal(&Payload{Command: "setrole", Data: "answer=yes", UserID: 1, ChatID: 2, SingleUse: true}, payload)
+
+	_, err = codec.Decode(ctx, data, 1, 2)
+	c.ErrorIs(err, ErrAlreadyUsed)
+
+	// the kind is signed so single-use data can not be sent as reusable data
+	_, err = codec.Decode(ctx, strings.Replace(data, separator+onceKind+separator, separator+cachedKind+separator, 1), 1, 2)
+	c.ErrorIs(err, ErrInvalidSignature)
+}
+
+func TestDecodeErrors(t *testing.T) {
+	c := require.New(t)
+
+	ctx := context.Background()
+	codec := newTestCodec(c)
+
+	data, err := codec.Encode(ctx, Payload{Command: "approve", Data: "123", UserID: 1, ChatID: 2})
+	c.NoError(err)
+
+	_, err = codec.Decode(ctx, data, 1, 3)
+	c.ErrorIs(err, ErrInvalidSignature)
+
+	_, err = codec.Decode(ctx, strings.TrimSuffix(data, "123")+"124", 1, 2)
+	c.ErrorIs(err, ErrInvalidSignature)
+
+	_, err = codec.Decode(ctx, "2"+data[1:], 1, 2)
+	c.ErrorIs(err, ErrUnsupportedVersion)
+
+	_, err = codec.Decode(ctx, "approve BOTdata_id", 1, 2)
+	c.ErrorIs(err, ErrMalformed)
+
+	otherCodec, err := NewCodec([]byte("other"), time.Hour)
+	c.NoError(err)
+
+	_, err = otherCodec.Decode(ctx, data, 1, 2)
+	c.ErrorIs(err, ErrInvalidSignature)
+
+	defer func() { now = time.Now }()
+
+	now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+
+	_, err = codec.Decode(ctx, data, 1, 2)
+	c.ErrorIs(err, ErrExpired)
+}
+
+func TestEncodeInvalidCommand(t *testing.T) {
+	c := require.New(t)
+
+	codec := newTestCodec(c)
+
+	_, err := codec.Encode(context.Background(), Payload{Command: "app.rove"})
+	c.ErrorIs(err, ErrInvalidCommand)
+
+	_, err = NewCodec(nil, time.Hour)
+	c.ErrorIs(err, ErrEmptySecret)
+}
+
+func TestNewCodecFromSecret(t *testing.T) {
+	c := require.New(t)
+
+	secrets.InitSecretsMock()
+
+	defer secrets.DeactivateMock()
+
+	_, err := NewCodecFromSecret(context.Background())
+	c.Error(err)
+
+	secrets.SetMockedSecret(SecretName, "secret")
+
+	codec, err := NewCodecFromSecret(context.Background())
+	c.NoError(err)
+	c.Equal(time.Duration(ttlMinutes)*time.Minute, codec.ttl)
+}
@@ -0,0 +1,207 @@
+// Command localbot runs the bot router locally receiving the updates with long polling, the cache,
+// users table and secrets are in memory and the messages routed to SNS are printed
+//
+// Usage:
+//
+//	TELEGRAM_BOT_TOKEN=<token> go run ./app/bot/cmd/localbot -user-id <telegram id> -email <email>
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"time"
+
+	"shared/app/bot/botclient"
+	"shared/app/bot/callback"
+	"shared/app/bot/email"
+	"shared/app/bot/models"
+	"shared/app/bot/router"
+	"shared/app/bot/storage"
+	"shared/shared/aws/secrets"
+	"shared/shared/telegram"
+
+	"bitbucket.org/truora/scrap-services/logger"
+	"bitbucket.org/truora/scrap-services/shared/cache"
+	"bitbucket.org/truora/scrap-services/shared/sns"
+)
+
+const (
+	printSNS = "print"
+	mockSNS  = "mock"
+
+	retryDelay = time.Second
+)
+
+// ErrMissingToken when the bot token is not configured
+var ErrMissingToken = errors.New("missing bot token, set TELEGRAM_BOT_TOKEN or -token")
+
+type options struct {
+	token         string
+	pollTimeout   int
+	snsMode       string
+	deleteWebhook bool
+	userID        int64
+	email         string
+	role          string
+}
+
+func parseOptions(args []string) (*options, error) {
+	opts := &options{}
+
+	flags := flag.NewFlagSet("localbot", flag.ContinueOnError)
+	flags.StringVar(&opts.token, "token", os.Getenv("TELEGRAM_BOT_TOKEN"), "Telegram bot token")
+	flags.IntVar(&opts.pollTimeout, "timeout", 10, "long polling timeout in seconds")
+	flags.StringVar(&opts.snsMode, "sns", printSNS, "print the SNS messages or send them to the SNS mock: print|mock")
+	flags.BoolVar(&opts.deleteWebhook, "delete-webhook", false, "delete the bot webhook, getUpdates does not work while a webhook is set")
+	flags.Int64Var(&opts.userID, "user-id", 0, "Telegram ID of a verified user added to the local users table")
+	flags.StringVar(&opts.email, "email", "local@truora.com", "email of the verified user")
+	flags.StringVar(&opts.role, "role", models.RoleAdmin, "role of the verified user")
+
+	err := flags.Parse(args)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.token == "" {
+		return nil, ErrMissingToken
+	}
+
+	if opts.snsMode != printSNS && opts.snsMode != mockSNS {
+		return nil, fmt.Errorf("unknown sns mode %s", opts.snsMode)
+	}
+
+	return opts, nil
+}
+
+// setup replaces the cache, users table, secrets and SNS with local versions
+func setup(ctx context.Context, opts *options, output io.Writer) error {
+	cache.InitMock()
+	storage.InitDynamoMock()
+	secrets.InitSecretsMock()
+
+	callbackSecret := make([]byte, 32)
+
+	_, err := rand.Read(callbackSecret)
+	if err != nil {
+		return err
+	}
+
+	secrets.SetMockedSecret(botclient.TokenSecretName, opts.token)
+	secrets.SetMockedSecret(callback.SecretName, base64.StdEncoding.EncodeToString(callbackSecret))
+
+	router.SetEmailSender(email.LogSender{})
+
+	if opts.snsMode == mockSNS {
+		sns.InitSNSMock()
+	} else {
+		router.SetPublisher(printPublisher(output))
+	}
+
+	if opts.userID == 0 {
+		return nil
+	}
+
+	return storage.PutUser(ctx, &models.From{
+		ID:            opts.userID,
+		Email:         opts.email,
+		EmailVerified: true,
+		UserRole:      opts.role,
+		CreationDate:  time.Now(),
+	})
+}
+
+func printPublisher(output io.Writer) func(ctx context.Context, topic, attribute string, message *models.CallbackMessage) error {
+	return func(ctx context.Context, topic, attribute string, message *models.CallbackMessage) error {
+		payload, err := json.MarshalIndent(message, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		_, err = fmt.Fprintf(output, "SNS topic=%s attribute=%s\n%s\n", topic, attribute, payload)
+
+		return err
+	}
+}
+
+// poll routes the updates received after the offset and returns the next offset
+func poll(ctx context.Context, telegramClient *telegram.Client, offset int64, timeout int) (int64, error) {
+	updates, err := telegramClient.GetUpdates(ctx, &telegram.GetUpdatesRequest{Offset: offset, Timeout: timeout})
+	if err != nil {
+		return offset, err
+	}
+
+	for _, update := range updates {
+		err = router.ProcessUpdate(ctx, update.Raw)
+		if err != nil {
+			logger.Get(ctx).Warning(ctx, "local_update_failed", logger.OneDay, []logger.Object{
+				logger.ErrObject(err),
+				logger.MapObject("update", map[string]interface{}{"i_update_id": update.UpdateID}),
+			})
+		}
+
+		offset = update.UpdateID + 1
+	}
+
+	return offset, nil
+}
+
+func run(ctx context.Context, opts *options) error {
+	err := setup(ctx, opts, os.Stdout)
+	if err != nil {
+		return err
+	}
+
+	telegramClient, err := telegram.New(opts.token)
+	if err != nil {
+		return err
+	}
+
+	if opts.deleteWebhook {
+		err = telegramClient.DeleteWebhook(ctx, &telegram.DeleteWebhookRequest{})
+		if err != nil {
+			return err
+		}
+	}
+
+	var offset int64
+
+	for ctx.Err() == nil {
+		offset, err = poll(ctx, telegramClient, offset, opts.pollTimeout)
+		if err == nil {
+			continue
+		}
+
+		logger.Get(ctx).Error(ctx, "get_updates_failed", logger.OneDay, []logger.Object{logger.ErrObject(err)})
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(retryDelay):
+		}
+	}
+
+	return nil
+}
+
+func main() {
+	opts, err := parseOptions(os.Args[1:])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	log := logger.New("localbot")
+	ctx = logger.Set(ctx, log)
+
+	log.Must(ctx, run(ctx, opts), logger.OneDay)
+}
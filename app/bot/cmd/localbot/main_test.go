@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"shared/app/bot/router"
+	"shared/shared/aws/secrets"
+	"shared/shared/telegram"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseOptions(t *testing.T) {
+	c := require.New(t)
+
+	t.Setenv("TELEGRAM_BOT_TOKEN", "")
+
+	_, err := parseOptions([]string{})
+	c.ErrorIs(err, ErrMissingToken)
+
+	_, err = parseOptions([]string{"-token", "token", "-sns", "kafka"})
+	c.Error(err)
+
+	opts, err := parseOptions([]string{"-token", "token", "-user-id", "123"})
+	c.NoError(err)
+	c.Equal(int64(123), opts.userID)
+	c.Equal(printSNS, opts.snsMode)
+}
+
+func TestPoll(t *testing.T) {
+	c := require.New(t)
+
+	fake := telegram.NewFake("")
+
+	defer fake.Close()
+	defer secrets.DeactivateMock()
+	defer router.SetPublisher(nil)
+
+	ctx := context.Background()
+	output := bytes.NewBufferString("")
+
+	err := setup(ctx, &options{token: telegram.FakeToken, snsMode: printSNS, userID: 123, email: "local@truora.com", role: "admin"}, output)
+	c.NoError(err)
+
+	fake.AddUpdates(
+		`{"update_id":10,"message":{"message_id":1,"text":"/help","from":{"id":123},"chat":{"id":123,"type":"private"}}}`,
+		`{"update_id":11,"message":{"message_id":2,"text":"/deployTerraformStaging checks/core","from":{"id":123},"chat":{"id":123,"type":"private"}}}`,
+	)
+
+	offset, err := poll(ctx, fake.Client(), 0, 0)
+	c.NoError(err)
+	c.Equal(int64(12), offset)
+	c.Contains(fake.SentTexts()[0], "Available commands")
+	c.Contains(output.String(), "attribute=deployterraformstaging")
+	c.Contains(output.String(), "checks/core")
+
+	offset, err = poll(ctx, fake.Client(), offset, 0)
+	c.NoError(err)
+	c.Equal(int64(12), offset)
+}
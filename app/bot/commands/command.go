@@ -0,0 +1,212 @@
+// Package commands contains the declaration of the commands supported by the bot
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"shared/app/bot/models"
+)
+
+// ChatType is the type of the Telegram chat where a command is sent
+type ChatType string
+
+const (
+	// ChatPrivate private chat with the bot
+	ChatPrivate ChatType = "private"
+	// ChatGroup group chat
+	ChatGroup ChatType = "group"
+	// ChatSupergroup supergroup chat
+	ChatSupergroup ChatType = "supergroup"
+	// ChatChannel channel chat
+	ChatChannel ChatType = "channel"
+
+	commandPrefix = "/"
+)
+
+var (
+	// ErrMissingArgs when required arguments are missing
+	ErrMissingArgs = errors.New("missing required arguments")
+	// ErrTooManyArgs when more arguments than the declared ones are received
+	ErrTooManyArgs = errors.New("too many arguments")
+	// ErrChatNotAllowed when the command can not be used in the chat type
+	ErrChatNotAllowed = errors.New("command not allowed in this chat")
+)
+
+// Argument is a positional argument of a command
+type Argument struct {
+	Name        string
+	Description string
+	// Variadic receives all the remaining arguments, only valid for the last argument
+	Variadic bool
+}
+
+// Command is the declaration of a command supported by the bot
+type Command struct {
+	Name         string
+	Aliases      []string
+	Description  string
+	RequiredArgs []Argument
+	OptionalArgs []Argument
+	Flags        []Flag
+	// ChatTypes where the command is allowed, e.g. only private, only group or both. Empty means every chat type
+	ChatTypes []ChatType
+	// Topic where the command is published, empty means the default bot commands topic
+	Topic string
+	// Public commands can be used by users that are not registered yet
+	Public bool
+	// Roles allowed to run the command, empty means every registered user
+	Roles []string
+	// NestedCommand commands receive another command as their last argument, everything from the first
+	// token starting with / is kept as typed, e.g. /schedule 30m /deploy api -b master
+	NestedCommand bool
+	// Approvals is the number of distinct users that must approve the command before it is published,
+	// the user that sent the command never counts. Zero publishes the command right away
+	Approvals int
+	// ApproverRoles are the roles allowed to approve the command, empty means only the admins
+	ApproverRoles []string
+	// Uploads are the files accepted with the command or as answers of its wizard, nil rejects every file
+	Uploads *UploadLimits
+}
+
+// UploadLimits are the files accepted by a command
+type UploadLimits struct {
+	// Required commands can not be sent without a file
+	Required bool
+	// MaxSize in bytes, zero uses the limit of the router
+	MaxSize int64
+	// MimeTypes accepted, e.g. application/pdf or image/*. Empty uses the types of the router
+	MimeTypes []string
+}
+
+// Usage returns how to use the command, e.g. /deploy <service> [branch]
+func (cmd *Command) Usage() string {
+	usage := []string{commandPrefix + cmd.Name}
+
+	for _, arg := range cmd.RequiredArgs {
+		usage = append(usage, "<"+arg.usageName()+">")
+	}
+
+	for _, arg := range cmd.OptionalArgs {
+		usage = append(usage, "["+arg.usageName()+"]")
+	}
+
+	for _, flag := range cmd.Flags {
+		usage = append(usage, "["+flag.usageName()+"]")
+	}
+
+	return strings.Join(usage, " ")
+}
+
+func (arg Argument) usageName() string {
+	if arg.Variadic {
+		return arg.Name + "..."
+	}
+
+	return arg.Name
+}
+
+func (flag Flag) usageName() string {
+	name := longFlagPrefix + flag.Name
+	if flag.Short != "" {
+		name = "-" + flag.Short + "|" + name
+	}
+
+	if flag.Bool {
+		return name
+	}
+
+	return name + " <" + flag.Name + ">"
+}
+
+// AllowsChat returns true if the command can be used in the given chat type
+func (cmd *Command) AllowsChat(chatType string) bool {
+	if len(cmd.ChatTypes) == 0 {
+		return true
+	}
+
+	for _, allowed := range cmd.ChatTypes {
+		if string(allowed) == chatType {
+			return true
+		}
+
+		// supergroups are groups with more members, a command allowed in groups works in both
+		if allowed == ChatGroup && ChatType(chatType) == ChatSupergroup {
+			return true
+		}
+	}
+
+	return false
+}
+
+// AllowsRole returns true if users with the given role can run the command, admins can run every command
+func (cmd *Command) AllowsRole(role string) bool {
+	if len(cmd.Roles) == 0 || role == models.RoleAdmin {
+		return true
+	}
+
+	for _, allowed := range cmd.Roles {
+		if allowed == role {
+			return true
+		}
+	}
+
+	return false
+}
+
+// AllowsApprover returns true if the role can approve the command, admins can approve every command
+func (cmd *Command) AllowsApprover(role string) bool {
+	if role == models.RoleAdmin {
+		return true
+	}
+
+	for _, allowed := range cmd.ApproverRoles {
+		if allowed == role {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ParseArgs assigns the received arguments to the declared ones by position
+func (cmd *Command) ParseArgs(args []string) (map[string]string, error) {
+	parsed := map[string]string{}
+	declared := cmd.arguments()
+
+	for i, arg := range declared {
+		if i >= len(args) {
+			break
+		}
+
+		if arg.Variadic {
+			parsed[arg.Name] = strings.Join(args[i:], " ")
+
+			return parsed, cmd.checkRequired(parsed)
+		}
+
+		parsed[arg.Name] = args[i]
+	}
+
+	if len(args) > len(declared) {
+		return nil, fmt.Errorf("%w, usage: %s", ErrTooManyArgs, cmd.Usage())
+	}
+
+	return parsed, cmd.checkRequired(parsed)
+}
+
+// arguments returns the required arguments followed by the optional ones
+func (cmd *Command) arguments() []Argument {
+	return append(append([]Argument{}, cmd.RequiredArgs...), cmd.OptionalArgs...)
+}
+
+func (cmd *Command) checkRequired(parsed map[string]string) error {
+	for _, arg := range cmd.RequiredArgs {
+		if _, ok := parsed[arg.Name]; !ok {
+			return fmt.Errorf("%w, usage: %s", ErrMissingArgs, cmd.Usage())
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,109 @@
+package commands
+
+import (
+	"testing"
+
+	"shared/app/bot/models"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUsage(t *testing.T) {
+	c := require.New(t)
+
+	cmd := &Command{
+		Name:         "deploy",
+		RequiredArgs: []Argument{{Name: "service"}},
+		OptionalArgs: []Argument{{Name: "branch"}, {Name: "extra", Variadic: true}},
+	}
+
+	c.Equal("/deploy <service> [branch] [extra...]", cmd.Usage())
+	c.Equal("/help", (&Command{Name: "help"}).Usage())
+
+	cmd.Flags = []Flag{{Name: "env", Short: "e"}, {Name: "force", Bool: true}}
+	c.Equal("/deploy <service> [branch] [extra...] [-e|--env <env>] [--force]", cmd.Usage())
+}
+
+func TestAllowsChat(t *testing.T) {
+	c := require.New(t)
+
+	cmd := &Command{Name: "deploy"}
+	c.True(cmd.AllowsChat(string(ChatPrivate)))
+	c.True(cmd.AllowsChat(string(ChatGroup)))
+
+	cmd.ChatTypes = []ChatType{ChatPrivate}
+	c.True(cmd.AllowsChat(string(ChatPrivate)))
+	c.False(cmd.AllowsChat(string(ChatGroup)))
+
+	cmd.ChatTypes = []ChatType{ChatGroup}
+	c.False(cmd.AllowsChat(string(ChatPrivate)))
+	c.True(cmd.AllowsChat(string(ChatGroup)))
+	c.True(cmd.AllowsChat(string(ChatSupergroup)))
+}
+
+func TestAllowsRole(t *testing.T) {
+	c := require.New(t)
+
+	cmd := &Command{Name: "deploy"}
+	c.True(cmd.AllowsRole(models.RoleDevelopers))
+
+	cmd.Roles = []string{"leads"}
+	c.True(cmd.AllowsRole("leads"))
+	c.True(cmd.AllowsRole(models.RoleAdmin))
+	c.False(cmd.AllowsRole(models.RoleDevelopers))
+	c.False(cmd.AllowsRole(""))
+}
+
+func TestAllowsApprover(t *testing.T) {
+	c := require.New(t)
+
+	cmd := &Command{Name: "deploy", Approvals: 2}
+	c.True(cmd.AllowsApprover(models.RoleAdmin))
+	c.False(cmd.AllowsApprover("leads"))
+
+	cmd.ApproverRoles = []string{"leads"}
+	c.True(cmd.AllowsApprover("leads"))
+	c.True(cmd.AllowsApprover(models.RoleAdmin))
+	c.False(cmd.AllowsApprover(models.RoleDevelopers))
+}
+
+func TestParseArgs(t *testing.T) {
+	c := require.New(t)
+
+	cmd := &Command{
+		Name:         "deploy",
+		RequiredArgs: []Argument{{Name: "service"}},
+		OptionalArgs: []Argument{{Name: "branch"}},
+	}
+
+	args, err := cmd.ParseArgs([]string{"api"})
+	c.NoError(err)
+	c.Equal(map[string]string{"service": "api"}, args)
+
+	args, err = cmd.ParseArgs([]string{"api", "master"})
+	c.NoError(err)
+	c.Equal(map[string]string{"service": "api", "branch": "master"}, args)
+
+	_, err = cmd.ParseArgs([]string{})
+	c.ErrorIs(err, ErrMissingArgs)
+	c.Contains(err.Error(), "/deploy <service> [branch]")
+
+	_, err = cmd.ParseArgs([]string{"api", "master", "other"})
+	c.ErrorIs(err, ErrTooManyArgs)
+}
+
+func TestParseArgsVariadic(t *testing.T) {
+	c := require.New(t)
+
+	cmd := &Command{
+		Name:         "deploy",
+		RequiredArgs: []Argument{{Name: "service"}, {Name: "args", Variadic: true}},
+	}
+
+	args, err := cmd.ParseArgs([]string{"api", "-b", "master"})
+	c.NoError(err)
+	c.Equal(map[string]string{"service": "api", "args": "-b master"}, args)
+
+	_, err = cmd.ParseArgs([]string{"api"})
+	c.ErrorIs(err, ErrMissingArgs)
+}
@@ -0,0 +1,294 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"shared/app/bot/i18n"
+	"shared/app/bot/models"
+)
+
+const (
+	longFlagPrefix = "--"
+	endOfFlags     = "--"
+)
+
+var (
+	// ErrParse when the command text can not be parsed
+	ErrParse = errors.New("invalid command arguments")
+
+	keyValueRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*=`)
+	// negative numbers like -5 are positional arguments, not short flags
+	negativeNumberRegex = regexp.MustCompile(`^-[0-9]`)
+)
+
+// Flag is a named argument of a command, e.g. --branch=master, --branch master or -b master
+type Flag struct {
+	Name        string
+	Short       string
+	Description string
+	// Bool flags do not receive a value, they are set to "true" when present
+	Bool bool
+	// Repeated flags can be received more than once
+	Repeated bool
+}
+
+// ParseError is a parse error that knows where the problem is in the command text, the problem is
+// the catalog key of its message so it is shown in the language of the user
+type ParseError struct {
+	Text   string
+	Offset int
+	Key    string
+	Vars   i18n.Vars
+}
+
+func newParseError(text string, offset int, key string, vars i18n.Vars) *ParseError {
+	return &ParseError{Text: text, Offset: offset, Key: key, Vars: vars}
+}
+
+// Error returns the reason in English and the column of the problem
+func (err *ParseError) Error() string {
+	return fmt.Sprintf("%s at column %d", err.Reason(i18n.English), err.column()+1)
+}
+
+// Reason returns the problem in the language
+func (err *ParseError) Reason(language i18n.Language) string {
+	return i18n.Translate(language, err.Key, err.Vars)
+}
+
+// Unwrap allows to use errors.Is(err, ErrParse)
+func (err *ParseError) Unwrap() error {
+	return ErrParse
+}
+
+// Pointer returns the reason in the language of the context followed by the command text and a caret
+// under the problem
+func (err *ParseError) Pointer(ctx context.Context) string {
+	return fmt.Sprintf("%s:\n%s\n%s^", err.Reason(i18n.FromContext(ctx)), err.Text, strings.Repeat(" ", err.column()))
+}
+
+func (err *ParseError) column() int {
+	return utf8.RuneCountInString(err.Text[:err.Offset])
+}
+
+type token struct {
+	value  string
+	offset int
+	// quoted tokens start with a quote, key="a b" is not quoted so it is still a key=value pair
+	quoted bool
+}
+
+// tokenize splits the text on spaces keeping quoted strings together, quotes can be
+// single, double or the curly ones added by mobile keyboards
+func tokenize(text string) ([]token, error) {
+	tokens := []token{}
+
+	var current *token
+	var quote rune
+	var quoteOffset int
+	var escape bool
+
+	for offset, r := range text {
+		switch {
+		case escape:
+			current.value += string(r)
+			escape = false
+		case quote != 0 && quote != '\'' && r == '\\':
+			escape = true
+		case quote != 0 && closesQuote(quote, r):
+			quote = 0
+		case quote != 0:
+			current.value += string(r)
+		case isQuote(r):
+			if current == nil {
+				current = &token{offset: offset, quoted: true}
+			}
+
+			quote = r
+			quoteOffset = offset
+		case unicode.IsSpace(r):
+			if current != nil {
+				tokens = append(tokens, *current)
+				current = nil
+			}
+		default:
+			if current == nil {
+				current = &token{offset: offset}
+			}
+
+			current.value += string(r)
+		}
+	}
+
+	if quote != 0 {
+		return nil, newParseError(text, quoteOffset, "parse.unterminated_quote", nil)
+	}
+
+	if current != nil {
+		tokens = append(tokens, *current)
+	}
+
+	return tokens, nil
+}
+
+func isQuote(r rune) bool {
+	return r == '"' || r == '\'' || r == '“'
+}
+
+func closesQuote(quote, r rune) bool {
+	if quote == '“' {
+		return r == '”'
+	}
+
+	return quote == r
+}
+
+// Parse parses the command text, the first token is the command name and the rest are
+// flags, key=value pairs and positional arguments
+func (cmd *Command) Parse(text string) (*models.CommandInput, error) {
+	tokens, err := tokenize(text)
+	if err != nil {
+		return nil, err
+	}
+
+	input := &models.CommandInput{
+		Name:      cmd.Name,
+		Flags:     map[string][]string{},
+		KeyValues: map[string]string{},
+	}
+
+	if len(tokens) == 0 {
+		return input, nil
+	}
+
+	tokens = tokens[1:]
+	onlyPositional := false
+
+	nested := ""
+	if cmd.NestedCommand {
+		tokens, nested = splitNestedCommand(text, tokens)
+	}
+
+	for i := 0; i < len(tokens); i++ {
+		tok := tokens[i]
+
+		switch {
+		case onlyPositional || tok.quoted || tok.value == "-" || !strings.HasPrefix(tok.value, "-") || negativeNumberRegex.MatchString(tok.value):
+			if !onlyPositional && !tok.quoted && keyValueRegex.MatchString(tok.value) {
+				key, value, _ := strings.Cut(tok.value, "=")
+				input.KeyValues[key] = value
+
+				continue
+			}
+
+			input.Positional = append(input.Positional, tok.value)
+		case tok.value == endOfFlags:
+			onlyPositional = true
+		default:
+			i, err = cmd.parseFlag(text, tokens, i, input)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if nested != "" {
+		input.Positional = append(input.Positional, nested)
+	}
+
+	return input, nil
+}
+
+// splitNestedCommand returns the tokens before the nested command and the nested command text
+func splitNestedCommand(text string, tokens []token) ([]token, string) {
+	for i, tok := range tokens {
+		if !tok.quoted && strings.HasPrefix(tok.value, commandPrefix) {
+			return tokens[:i], strings.TrimSpace(text[tok.offset:])
+		}
+	}
+
+	return tokens, ""
+}
+
+// parseFlag parses the flag at tokens[i] and returns the index of the last token consumed
+func (cmd *Command) parseFlag(text string, tokens []token, i int, input *models.CommandInput) (int, error) {
+	tok := tokens[i]
+	name, value, hasValue := strings.Cut(strings.TrimLeft(tok.value, "-"), "=")
+
+	if name == "" {
+		return i, newParseError(text, tok.offset, "parse.invalid_flag", nil)
+	}
+
+	flags := []*Flag{}
+
+	if strings.HasPrefix(tok.value, longFlagPrefix) {
+		flag := cmd.flag(name)
+		if flag == nil {
+			return i, newParseError(text, tok.offset, "parse.unknown_flag", i18n.Vars{"flag": longFlagPrefix + name})
+		}
+
+		flags = append(flags, flag)
+	} else {
+		// short flags can be grouped, e.g. -fv, only the last one can receive a value
+		for _, short := range name {
+			flag := cmd.flag(string(short))
+			if flag == nil {
+				return i, newParseError(text, tok.offset, "parse.unknown_flag", i18n.Vars{"flag": "-" + string(short)})
+			}
+
+			flags = append(flags, flag)
+		}
+	}
+
+	for _, flag := range flags[:len(flags)-1] {
+		if !flag.Bool {
+			return i, newParseError(text, tok.offset, "parse.missing_value", i18n.Vars{"flag": "-" + flag.Short})
+		}
+
+		err := addFlag(text, tok, input, flag, "true")
+		if err != nil {
+			return i, err
+		}
+	}
+
+	last := flags[len(flags)-1]
+
+	switch {
+	case last.Bool && hasValue:
+		return i, newParseError(text, tok.offset, "parse.unexpected_value", i18n.Vars{"flag": longFlagPrefix + last.Name})
+	case last.Bool:
+		value = "true"
+	case !hasValue && (i+1 >= len(tokens) || tokens[i+1].value == endOfFlags):
+		return i, newParseError(text, tok.offset, "parse.missing_value", i18n.Vars{"flag": longFlagPrefix + last.Name})
+	case !hasValue:
+		i++
+		value = tokens[i].value
+	}
+
+	return i, addFlag(text, tok, input, last, value)
+}
+
+func addFlag(text string, tok token, input *models.CommandInput, flag *Flag, value string) error {
+	if len(input.Flags[flag.Name]) > 0 && !flag.Repeated {
+		return newParseError(text, tok.offset, "parse.repeated_flag", i18n.Vars{"flag": longFlagPrefix + flag.Name})
+	}
+
+	input.Flags[flag.Name] = append(input.Flags[flag.Name], value)
+
+	return nil
+}
+
+func (cmd *Command) flag(name string) *Flag {
+	for i, flag := range cmd.Flags {
+		if flag.Name == name || (flag.Short != "" && flag.Short == name) {
+			return &cmd.Flags[i]
+		}
+	}
+
+	return nil
+}
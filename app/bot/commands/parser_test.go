@@ -0,0 +1,156 @@
+package commands
+
+import (
+	"context"
+	"testing"
+
+	"shared/app/bot/i18n"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newDeployCommand() *Command {
+	return &Command{
+		Name:         "deploy",
+		RequiredArgs: []Argument{{Name: "service"}},
+		Flags: []Flag{
+			{Name: "env", Short: "e"},
+			{Name: "branch", Short: "b"},
+			{Name: "force", Short: "f", Bool: true},
+			{Name: "verbose", Short: "v", Bool: true},
+			{Name: "tag", Short: "t", Repeated: true},
+		},
+	}
+}
+
+func TestParse(t *testing.T) {
+	c := require.New(t)
+
+	cmd := newDeployCommand()
+
+	input, err := cmd.Parse(`/deploy api --env=prod -b master -fv --tag one -t "two words" replicas=3 "key=value" -- --not-a-flag`)
+	c.NoError(err)
+	c.Equal("deploy", input.Name)
+	c.Equal([]string{"api", "key=value", "--not-a-flag"}, input.Positional)
+	c.Equal(map[string][]string{
+		"env":     {"prod"},
+		"branch":  {"master"},
+		"force":   {"true"},
+		"verbose": {"true"},
+		"tag":     {"one", "two words"},
+	}, input.Flags)
+	c.Equal(map[string]string{"replicas": "3"}, input.KeyValues)
+	c.Equal("master", input.Flag("branch"))
+	c.Equal("two words", input.Flag("tag"))
+	c.Equal("", input.Flag("missing"))
+	c.True(input.HasFlag("force"))
+	c.False(input.HasFlag("missing"))
+}
+
+func TestParseQuotes(t *testing.T) {
+	c := require.New(t)
+
+	cmd := newDeployCommand()
+
+	input, err := cmd.Parse(`/deploy 'single quoted' "escaped \"quote\"" “curly quotes” "" https://truora.com?a=b`)
+	c.NoError(err)
+	c.Equal([]string{"single quoted", `escaped "quote"`, "curly quotes", "", "https://truora.com?a=b"}, input.Positional)
+
+	input, err = cmd.Parse("/deploy")
+	c.NoError(err)
+	c.Empty(input.Positional)
+
+	input, err = cmd.Parse("")
+	c.NoError(err)
+	c.Empty(input.Positional)
+}
+
+func TestParseQuotedKeyValues(t *testing.T) {
+	c := require.New(t)
+
+	cmd := newDeployCommand()
+
+	input, err := cmd.Parse(`/deploy api message="fix the build" --branch="feature one"`)
+	c.NoError(err)
+	c.Equal([]string{"api"}, input.Positional)
+	c.Equal(map[string]string{"message": "fix the build"}, input.KeyValues)
+	c.Equal("feature one", input.Flag("branch"))
+}
+
+func TestParseNegativeNumbers(t *testing.T) {
+	c := require.New(t)
+
+	cmd := newDeployCommand()
+
+	input, err := cmd.Parse("/deploy api -5 -1.5 -e prod")
+	c.NoError(err)
+	c.Equal([]string{"api", "-5", "-1.5"}, input.Positional)
+	c.Equal("prod", input.Flag("env"))
+}
+
+func TestParseNestedCommand(t *testing.T) {
+	c := require.New(t)
+
+	cmd := &Command{
+		Name:          "schedule",
+		RequiredArgs:  []Argument{{Name: "when"}, {Name: "command"}},
+		Flags:         []Flag{{Name: "every", Short: "e"}},
+		NestedCommand: true,
+	}
+
+	input, err := cmd.Parse(`/schedule --every 24h 30m /deploy api -b master "two words"`)
+	c.NoError(err)
+	c.Equal([]string{"30m", `/deploy api -b master "two words"`}, input.Positional)
+	c.Equal("24h", input.Flag("every"))
+
+	input, err = cmd.Parse(`/schedule 30m "/quoted"`)
+	c.NoError(err)
+	c.Equal([]string{"30m", "/quoted"}, input.Positional)
+}
+
+func TestParseErrors(t *testing.T) {
+	c := require.New(t)
+
+	cmd := newDeployCommand()
+
+	testCases := []struct {
+		text    string
+		reason  string
+		pointer string
+	}{
+		{text: `/deploy "api`, reason: "unterminated quote", pointer: "unterminated quote:\n/deploy \"api\n        ^"},
+		{text: `/deploy api --unknown`, reason: "unknown flag --unknown", pointer: "unknown flag --unknown:\n/deploy api --unknown\n            ^"},
+		{text: `/deploy api -x`, reason: "unknown flag -x"},
+		{text: `/deploy api --env`, reason: "flag --env requires a value"},
+		{text: `/deploy api --env -- prod`, reason: "flag --env requires a value"},
+		{text: `/deploy api -ef`, reason: "flag -e requires a value"},
+		{text: `/deploy api --force=true`, reason: "flag --force does not receive a value"},
+		{text: `/deploy api --env prod -e dev`, reason: "flag --env can only be used once"},
+		{text: `/deploy api --=prod`, reason: "invalid flag"},
+	}
+
+	for _, testCase := range testCases {
+		_, err := cmd.Parse(testCase.text)
+		c.ErrorIs(err, ErrParse, testCase.text)
+
+		parseErr, ok := err.(*ParseError)
+		c.True(ok)
+		c.Equal(testCase.reason, parseErr.Reason(i18n.English))
+
+		if testCase.pointer != "" {
+			c.Equal(testCase.pointer, parseErr.Pointer(context.Background()))
+		}
+	}
+}
+
+func TestParseErrorColumn(t *testing.T) {
+	c := require.New(t)
+
+	_, err := newDeployCommand().Parse(`/deploy ñandú --nope`)
+	c.EqualError(err, "unknown flag --nope at column 15")
+	c.Equal("unknown flag --nope:\n/deploy ñandú --nope\n              ^", err.(*ParseError).Pointer(context.Background()))
+
+	// the pointer is shown in the language of the user
+	ctx := i18n.WithLanguage(context.Background(), i18n.Spanish)
+	c.Equal("opción desconocida --nope:\n/deploy ñandú --nope\n              ^", err.(*ParseError).Pointer(ctx))
+}
@@ -0,0 +1,124 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	// ErrUnknownCommand when the command is not registered
+	ErrUnknownCommand = errors.New("unknown command")
+	// ErrDuplicatedCommand when a command name or alias is already registered
+	ErrDuplicatedCommand = errors.New("command already registered")
+	// ErrInvalidCommand when the command declaration is invalid
+	ErrInvalidCommand = errors.New("invalid command declaration")
+)
+
+// Registry contains the commands supported by the bot indexed by name and aliases
+type Registry struct {
+	commands map[string]*Command
+	ordered  []*Command
+}
+
+// NewRegistry creates a registry with the given commands
+func NewRegistry(commands ...*Command) (*Registry, error) {
+	registry := &Registry{commands: map[string]*Command{}}
+
+	for _, cmd := range commands {
+		err := registry.Register(cmd)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return registry, nil
+}
+
+// MustNewRegistry creates a registry with the given commands and panics if any declaration is invalid
+func MustNewRegistry(commands ...*Command) *Registry {
+	registry, err := NewRegistry(commands...)
+	if err != nil {
+		panic(err)
+	}
+
+	return registry
+}
+
+// Register adds the command to the registry
+func (r *Registry) Register(cmd *Command) error {
+	err := validate(cmd)
+	if err != nil {
+		return err
+	}
+
+	names := append([]string{cmd.Name}, cmd.Aliases...)
+
+	for _, name := range names {
+		if _, ok := r.commands[normalize(name)]; ok {
+			return fmt.Errorf("%w: %s", ErrDuplicatedCommand, name)
+		}
+	}
+
+	for _, name := range names {
+		r.commands[normalize(name)] = cmd
+	}
+
+	r.ordered = append(r.ordered, cmd)
+
+	return nil
+}
+
+func validate(cmd *Command) error {
+	if cmd == nil || cmd.Name == "" {
+		return fmt.Errorf("%w: missing name", ErrInvalidCommand)
+	}
+
+	args := cmd.arguments()
+
+	for i, arg := range args {
+		if arg.Variadic && i != len(args)-1 {
+			return fmt.Errorf("%w: %s variadic argument must be the last one", ErrInvalidCommand, cmd.Name)
+		}
+	}
+
+	flags := map[string]bool{}
+
+	for _, flag := range cmd.Flags {
+		if flag.Name == "" || utf8.RuneCountInString(flag.Short) > 1 {
+			return fmt.Errorf("%w: %s has an invalid flag %q", ErrInvalidCommand, cmd.Name, flag.Name)
+		}
+
+		if flags[flag.Name] || (flag.Short != "" && flags[flag.Short]) {
+			return fmt.Errorf("%w: %s flag %q is declared twice", ErrInvalidCommand, cmd.Name, flag.Name)
+		}
+
+		flags[flag.Name] = true
+
+		if flag.Short != "" {
+			flags[flag.Short] = true
+		}
+	}
+
+	return nil
+}
+
+// Get returns the command registered with the given name or alias
+func (r *Registry) Get(name string) (*Command, error) {
+	cmd, ok := r.commands[normalize(name)]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownCommand, name)
+	}
+
+	return cmd, nil
+}
+
+// Commands returns the registered commands in registration order
+func (r *Registry) Commands() []*Command {
+	return r.ordered
+}
+
+func normalize(name string) string {
+	return strings.ToLower(strings.TrimPrefix(name, commandPrefix))
+}
@@ -0,0 +1,62 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry(t *testing.T) {
+	c := require.New(t)
+
+	deploy := &Command{
+		Name:         "deploy",
+		Aliases:      []string{"d"},
+		Description:  "Deploys a service",
+		RequiredArgs: []Argument{{Name: "service"}},
+	}
+	help := &Command{Name: "help", Description: "Shows the available commands"}
+
+	registry, err := NewRegistry(deploy, help)
+	c.NoError(err)
+
+	cmd, err := registry.Get("deploy")
+	c.NoError(err)
+	c.Equal(deploy, cmd)
+
+	cmd, err = registry.Get("/D")
+	c.NoError(err)
+	c.Equal(deploy, cmd)
+
+	_, err = registry.Get("unknown")
+	c.ErrorIs(err, ErrUnknownCommand)
+
+	c.Equal([]*Command{deploy, help}, registry.Commands())
+}
+
+func TestRegistryErrors(t *testing.T) {
+	c := require.New(t)
+
+	_, err := NewRegistry(&Command{})
+	c.ErrorIs(err, ErrInvalidCommand)
+
+	_, err = NewRegistry(&Command{Name: "deploy"}, &Command{Name: "other", Aliases: []string{"Deploy"}})
+	c.ErrorIs(err, ErrDuplicatedCommand)
+
+	_, err = NewRegistry(&Command{
+		Name:         "deploy",
+		RequiredArgs: []Argument{{Name: "args", Variadic: true}},
+		OptionalArgs: []Argument{{Name: "branch"}},
+	})
+	c.ErrorIs(err, ErrInvalidCommand)
+
+	_, err = NewRegistry(&Command{Name: "deploy", Flags: []Flag{{Name: "env", Short: "e"}, {Name: "exclude", Short: "e"}}})
+	c.ErrorIs(err, ErrInvalidCommand)
+
+	_, err = NewRegistry(&Command{Name: "deploy", Flags: []Flag{{Name: "env", Short: "en"}}})
+	c.ErrorIs(err, ErrInvalidCommand)
+
+	c.Panics(func() {
+		MustNewRegistry(&Command{})
+	})
+}
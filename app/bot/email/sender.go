@@ -0,0 +1,70 @@
+// Package email sends the emails of the bot, e.g. the verification codes of the registration
+package email
+
+import (
+	"context"
+	"errors"
+
+	"bitbucket.org/truora/scrap-services/logger"
+	"bitbucket.org/truora/scrap-services/shared/env"
+)
+
+const (
+	// SenderSES sends the emails with Amazon SES
+	SenderSES = "ses"
+	// SenderLog only logs the emails, it is meant to run the bot locally
+	SenderLog = "log"
+)
+
+var (
+	// ErrMissingRecipient when the email does not have a recipient
+	ErrMissingRecipient = errors.New("missing email recipient")
+	// ErrUnknownSender when the configured sender does not exist
+	ErrUnknownSender = errors.New("unknown email sender")
+
+	senderName = env.GetString("EMAIL_SENDER", SenderSES)
+)
+
+// Message is a plain text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Sender sends emails
+type Sender interface {
+	Send(ctx context.Context, message *Message) error
+}
+
+// NewSenderFromEnv returns the sender configured in EMAIL_SENDER
+func NewSenderFromEnv() (Sender, error) {
+	switch senderName {
+	case SenderSES:
+		return NewSESSender(), nil
+	case SenderLog:
+		return LogSender{}, nil
+	}
+
+	return nil, ErrUnknownSender
+}
+
+// LogSender logs the emails instead of sending them, the body is logged so it must not be used in production
+type LogSender struct{}
+
+// Send logs the email
+func (LogSender) Send(ctx context.Context, message *Message) error {
+	if message.To == "" {
+		return ErrMissingRecipient
+	}
+
+	logger.Get(ctx).Info(ctx, "email_logged", logger.OneDay, []logger.Object{
+		logger.MapObject("email", map[string]interface{}{
+			"s_to":      message.To,
+			"s_subject": message.Subject,
+			"s_body":    message.Body,
+		}),
+	})
+
+	return nil
+}
@@ -0,0 +1,51 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"bitbucket.org/truora/scrap-services/logger"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewSenderFromEnv(t *testing.T) {
+	c := require.New(t)
+
+	sender, err := NewSenderFromEnv()
+	c.NoError(err)
+	c.IsType(&SESSender{}, sender)
+
+	senderName = SenderLog
+
+	defer func() {
+		senderName = SenderSES
+	}()
+
+	sender, err = NewSenderFromEnv()
+	c.NoError(err)
+	c.IsType(LogSender{}, sender)
+
+	senderName = "smtp"
+
+	_, err = NewSenderFromEnv()
+	c.ErrorIs(err, ErrUnknownSender)
+}
+
+func TestLogSender(t *testing.T) {
+	c := require.New(t)
+
+	log := logger.New("test")
+	buf := bytes.NewBufferString("")
+	log.Output = buf
+
+	ctx := logger.Set(context.Background(), log)
+
+	err := LogSender{}.Send(ctx, &Message{To: "dummy_email@dummy.com", Subject: "Your code", Body: "123456"})
+	c.NoError(err)
+	c.Contains(buf.String(), "email_logged")
+	c.Contains(buf.String(), "123456")
+
+	err = LogSender{}.Send(ctx, &Message{Subject: "Your code"})
+	c.ErrorIs(err, ErrMissingRecipient)
+}
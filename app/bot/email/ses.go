@@ -0,0 +1,49 @@
+package email
+
+import (
+	"context"
+
+	"bitbucket.org/truora/scrap-services/shared/env"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ses"
+	"github.com/aws/aws-sdk-go/service/ses/sesiface"
+)
+
+const charset = "UTF-8"
+
+var sourceAddress = env.GetString("EMAIL_SOURCE", "betty@truora.com")
+
+// SESSender sends the emails with Amazon SES from the Source address
+type SESSender struct {
+	Client sesiface.SESAPI
+	Source string
+}
+
+// NewSESSender creates a SES sender using the address in EMAIL_SOURCE
+func NewSESSender() *SESSender {
+	return &SESSender{
+		Client: ses.New(session.Must(session.NewSession())),
+		Source: sourceAddress,
+	}
+}
+
+// Send sends the email as plain text
+func (sender *SESSender) Send(ctx context.Context, message *Message) error {
+	if message.To == "" {
+		return ErrMissingRecipient
+	}
+
+	_, err := sender.Client.SendEmailWithContext(ctx, &ses.SendEmailInput{
+		Source:      aws.String(sender.Source),
+		Destination: &ses.Destination{ToAddresses: []*string{aws.String(message.To)}},
+		Message: &ses.Message{
+			Subject: &ses.Content{Charset: aws.String(charset), Data: aws.String(message.Subject)},
+			Body: &ses.Body{
+				Text: &ses.Content{Charset: aws.String(charset), Data: aws.String(message.Body)},
+			},
+		},
+	})
+
+	return err
+}
@@ -0,0 +1,47 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ses"
+	"github.com/aws/aws-sdk-go/service/ses/sesiface"
+	"github.com/stretchr/testify/require"
+)
+
+type mockSESClient struct {
+	sesiface.SESAPI
+	inputs []*ses.SendEmailInput
+	err    error
+}
+
+func (m *mockSESClient) SendEmailWithContext(ctx aws.Context, input *ses.SendEmailInput, opts ...request.Option) (*ses.SendEmailOutput, error) {
+	m.inputs = append(m.inputs, input)
+
+	return &ses.SendEmailOutput{MessageId: aws.String("message-id")}, m.err
+}
+
+func TestSESSender(t *testing.T) {
+	c := require.New(t)
+
+	client := &mockSESClient{}
+	sender := &SESSender{Client: client, Source: "betty@truora.com"}
+
+	err := sender.Send(context.Background(), &Message{To: "dummy_email@dummy.com", Subject: "Your code", Body: "123456"})
+	c.NoError(err)
+	c.Len(client.inputs, 1)
+	c.Equal("betty@truora.com", *client.inputs[0].Source)
+	c.Equal("dummy_email@dummy.com", *client.inputs[0].Destination.ToAddresses[0])
+	c.Equal("123456", *client.inputs[0].Message.Body.Text.Data)
+
+	err = sender.Send(context.Background(), &Message{Subject: "Your code"})
+	c.ErrorIs(err, ErrMissingRecipient)
+
+	client.err = errors.New("throttled")
+
+	err = sender.Send(context.Background(), &Message{To: "dummy_email@dummy.com"})
+	c.Error(err)
+}
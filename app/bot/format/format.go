@@ -0,0 +1,186 @@
+// Package format builds the texts and inline keyboards of the bot messages, the texts are escaped for
+// the Telegram parse mode and split to fit in a message
+package format
+
+import (
+	"strings"
+
+	"shared/shared/telegram"
+)
+
+const (
+	codeFence = "```"
+	newLine   = "\n"
+)
+
+var (
+	// markdownEscaper escapes the characters reserved by MarkdownV2 outside of code entities
+	markdownEscaper = strings.NewReplacer(
+		`\`, `\\`, "_", `\_`, "*", `\*`, "[", `\[`, "]", `\]`, "(", `\(`, ")", `\)`, "~", `\~`, "`", "\\`",
+		">", `\>`, "#", `\#`, "+", `\+`, "-", `\-`, "=", `\=`, "|", `\|`, "{", `\{`, "}", `\}`, ".", `\.`, "!", `\!`,
+	)
+	// markdownCodeEscaper escapes the characters reserved by MarkdownV2 inside code and pre entities
+	markdownCodeEscaper = strings.NewReplacer(`\`, `\\`, "`", "\\`")
+	// markdownURLEscaper escapes the characters reserved by MarkdownV2 inside the URL of a link
+	markdownURLEscaper = strings.NewReplacer(`\`, `\\`, ")", `\)`)
+	// htmlEscaper escapes the characters reserved by HTML, Telegram only supports these named entities
+	htmlEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;")
+)
+
+// EscapeMarkdown escapes the text to be sent as plain text in a MarkdownV2 message
+func EscapeMarkdown(text string) string {
+	return markdownEscaper.Replace(text)
+}
+
+// EscapeHTML escapes the text to be sent as plain text in an HTML message
+func EscapeHTML(text string) string {
+	return htmlEscaper.Replace(text)
+}
+
+// Escape escapes the text to be sent as plain text with the parse mode, other modes return the text as it is
+func Escape(mode telegram.ParseMode, text string) string {
+	switch mode {
+	case telegram.ParseModeMarkdownV2:
+		return EscapeMarkdown(text)
+	case telegram.ParseModeHTML:
+		return EscapeHTML(text)
+	}
+
+	return text
+}
+
+// Builder builds a message text for a parse mode, every text added is escaped so the message can
+// always be parsed by Telegram. The zero value builds plain text
+type Builder struct {
+	mode telegram.ParseMode
+	text strings.Builder
+}
+
+// NewMarkdown creates a builder of MarkdownV2 texts
+func NewMarkdown() *Builder {
+	return &Builder{mode: telegram.ParseModeMarkdownV2}
+}
+
+// NewHTML creates a builder of HTML texts
+func NewHTML() *Builder {
+	return &Builder{mode: telegram.ParseModeHTML}
+}
+
+// ParseMode returns the parse mode of the text
+func (b *Builder) ParseMode() telegram.ParseMode {
+	return b.mode
+}
+
+// String returns the built text
+func (b *Builder) String() string {
+	return b.text.String()
+}
+
+// Chunks returns the built text split in messages that Telegram accepts
+func (b *Builder) Chunks() []string {
+	return Split(b.mode, b.String(), MaxMessageLength)
+}
+
+// Raw adds text that is already formatted for the parse mode, it is not escaped
+func (b *Builder) Raw(formatted string) *Builder {
+	b.text.WriteString(formatted)
+
+	return b
+}
+
+// Text adds plain text
+func (b *Builder) Text(text string) *Builder {
+	return b.Raw(Escape(b.mode, text))
+}
+
+// Line adds plain text followed by a new line
+func (b *Builder) Line(text string) *Builder {
+	return b.Text(text).NewLine()
+}
+
+// NewLine adds a line break
+func (b *Builder) NewLine() *Builder {
+	return b.Raw(newLine)
+}
+
+// Bold adds bold text
+func (b *Builder) Bold(text string) *Builder {
+	return b.wrap(text, "*", "b")
+}
+
+// Italic adds italic text
+func (b *Builder) Italic(text string) *Builder {
+	return b.wrap(text, "_", "i")
+}
+
+// Underline adds underlined text
+func (b *Builder) Underline(text string) *Builder {
+	return b.wrap(text, "__", "u")
+}
+
+// Strikethrough adds strikethrough text
+func (b *Builder) Strikethrough(text string) *Builder {
+	return b.wrap(text, "~", "s")
+}
+
+// Spoiler adds text hidden until the user taps it
+func (b *Builder) Spoiler(text string) *Builder {
+	return b.wrap(text, "||", "tg-spoiler")
+}
+
+// Code adds inline monospaced text
+func (b *Builder) Code(text string) *Builder {
+	if b.mode == telegram.ParseModeMarkdownV2 {
+		return b.Raw("`" + markdownCodeEscaper.Replace(text) + "`")
+	}
+
+	return b.wrap(text, "", "code")
+}
+
+// Link adds the text linked to the URL
+func (b *Builder) Link(text, url string) *Builder {
+	switch b.mode {
+	case telegram.ParseModeMarkdownV2:
+		return b.Raw("[" + EscapeMarkdown(text) + "](" + markdownURLEscaper.Replace(url) + ")")
+	case telegram.ParseModeHTML:
+		return b.Raw(`<a href="` + EscapeHTML(url) + `">` + EscapeHTML(text) + "</a>")
+	}
+
+	return b.Text(text + " (" + url + ")")
+}
+
+// CodeBlock adds a pre-formatted block, the language enables the syntax highlighting, e.g. go or json
+func (b *Builder) CodeBlock(code, language string) *Builder {
+	code = strings.TrimSuffix(code, newLine)
+
+	switch b.mode {
+	case telegram.ParseModeMarkdownV2:
+		return b.Raw(codeFence + language + newLine + markdownCodeEscaper.Replace(code) + newLine + codeFence + newLine)
+	case telegram.ParseModeHTML:
+		if language == "" {
+			return b.Raw("<pre>" + EscapeHTML(code) + "</pre>" + newLine)
+		}
+
+		return b.Raw(`<pre><code class="language-` + EscapeHTML(language) + `">` + EscapeHTML(code) + "</code></pre>" + newLine)
+	}
+
+	return b.Line(code)
+}
+
+// Table adds the rows as a pre-formatted block with the columns aligned, the header is separated from
+// the rows by a line
+func (b *Builder) Table(header []string, rows ...[]string) *Builder {
+	return b.CodeBlock(Table(header, rows...), "")
+}
+
+// wrap adds the text escaped between the MarkdownV2 delimiter or the HTML tag of the entity
+func (b *Builder) wrap(text, delimiter, tag string) *Builder {
+	switch b.mode {
+	case telegram.ParseModeMarkdownV2:
+		return b.Raw(delimiter + EscapeMarkdown(text) + delimiter)
+	case telegram.ParseModeHTML:
+		return b.Raw("<" + tag + ">" + EscapeHTML(text) + "</" + tag + ">")
+	}
+
+	return b.Text(text)
+}
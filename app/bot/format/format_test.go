@@ -0,0 +1,78 @@
+package format
+
+import (
+	"testing"
+
+	"shared/shared/telegram"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEscape(t *testing.T) {
+	c := require.New(t)
+
+	c.Equal("1\\.5 \\* \\(a\\_b\\) \\\\ \\[x\\]\\! \\#tag \\`code\\`", EscapeMarkdown("1.5 * (a_b) \\ [x]! #tag `code`"))
+	c.Equal("a &lt;b&gt; &amp; &quot;c&quot;", EscapeHTML(`a <b> & "c"`))
+	c.Equal("a_b", Escape("", "a_b"))
+	c.Equal(`a\_b`, Escape(telegram.ParseModeMarkdownV2, "a_b"))
+}
+
+func TestMarkdownBuilder(t *testing.T) {
+	c := require.New(t)
+
+	builder := NewMarkdown().
+		Bold("Deploy v1.2").Text(" to ").Italic("staging").NewLine().
+		Code("go test ./...").Text(" ").Link("logs (raw)", "https://example.com/a_(b)").NewLine().
+		Underline("u").Strikethrough("s").Spoiler("p").NewLine().
+		CodeBlock("fmt.Println(`hi`)\n", "go")
+
+	c.Equal(telegram.ParseModeMarkdownV2, builder.ParseMode())
+	c.Equal("*Deploy v1\\.2* to _staging_\n"+
+		"`go test ./...` [logs \\(raw\\)](https://example.com/a_(b\\))\n"+
+		"__u__~s~||p||\n"+
+		"```go\nfmt.Println(\\`hi\\`)\n```\n", builder.String())
+}
+
+func TestHTMLBuilder(t *testing.T) {
+	c := require.New(t)
+
+	builder := NewHTML().
+		Bold("a<b").Text(" & ").Italic("c").NewLine().
+		Code("x > 1").Text(" ").Link("docs", `https://example.com/?a=1&b="2"`).NewLine().
+		Spoiler("p").NewLine().
+		CodeBlock("if a < b {}", "go").
+		CodeBlock("plain", "")
+
+	c.Equal(telegram.ParseModeHTML, builder.ParseMode())
+	c.Equal("<b>a&lt;b</b> &amp; <i>c</i>\n"+
+		`<code>x &gt; 1</code> <a href="https://example.com/?a=1&amp;b=&quot;2&quot;">docs</a>`+"\n"+
+		"<tg-spoiler>p</tg-spoiler>\n"+
+		`<pre><code class="language-go">if a &lt; b {}</code></pre>`+"\n"+
+		"<pre>plain</pre>\n", builder.String())
+}
+
+func TestPlainBuilder(t *testing.T) {
+	c := require.New(t)
+
+	builder := &Builder{}
+	builder.Bold("a_b").Text(" ").Link("docs", "https://example.com").NewLine().Table([]string{"name"}, []string{"api"})
+
+	c.Equal(telegram.ParseMode(""), builder.ParseMode())
+	c.Equal("a_b docs (https://example.com)\nname\n----\napi\n", builder.String())
+}
+
+func TestBuilderChunks(t *testing.T) {
+	c := require.New(t)
+
+	builder := NewMarkdown()
+	for i := 0; i < 1000; i++ {
+		builder.Line("line.")
+	}
+
+	chunks := builder.Chunks()
+	c.Len(chunks, 2)
+
+	for _, chunk := range chunks {
+		c.LessOrEqual(len(chunk), MaxMessageLength)
+	}
+}
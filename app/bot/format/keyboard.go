@@ -0,0 +1,157 @@
+package format
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"shared/app/bot/callback"
+	"shared/app/bot/models"
+	"shared/shared/telegram"
+)
+
+// MaxRowButtons is the maximum number of buttons in a row of an inline keyboard accepted by Telegram
+const MaxRowButtons = 8
+
+var (
+	// ErrInvalidButton when a keyboard button has no text or has neither command nor URL
+	ErrInvalidButton = errors.New("invalid keyboard button")
+	// ErrTooManyButtons when a keyboard row has more buttons than Telegram accepts
+	ErrTooManyButtons = errors.New("too many buttons in a keyboard row")
+)
+
+// Keyboard builds an inline keyboard row by row, the command buttons are signed with the callback codec
+// when the keyboard is built for a user and chat
+type Keyboard struct {
+	rows [][]models.Button
+}
+
+// NewKeyboard creates an empty keyboard
+func NewKeyboard() *Keyboard {
+	return &Keyboard{rows: [][]models.Button{}}
+}
+
+// KeyboardFrom creates a keyboard with the rows of buttons, e.g. the keyboard of a result
+func KeyboardFrom(rows [][]models.Button) *Keyboard {
+	keyboard := NewKeyboard()
+
+	for _, row := range rows {
+		keyboard.Row(row...)
+	}
+
+	return keyboard
+}
+
+// CommandButton sends the command with the data back to the router when it is pressed
+func CommandButton(text, command, data string) models.Button {
+	return models.Button{Text: text, Command: command, Data: data}
+}
+
+// SingleUseButton sends the command with the data back to the router, it is rejected after it was pressed once
+func SingleUseButton(text, command, data string) models.Button {
+	return models.Button{Text: text, Command: command, Data: data, SingleUse: true}
+}
+
+// URLButton opens the URL when it is pressed
+func URLButton(text, url string) models.Button {
+	return models.Button{Text: text, URL: url}
+}
+
+// Row adds a row with the buttons, an empty row is ignored
+func (k *Keyboard) Row(buttons ...models.Button) *Keyboard {
+	if len(buttons) > 0 {
+		k.rows = append(k.rows, append([]models.Button{}, buttons...))
+	}
+
+	return k
+}
+
+// Column adds each button in its own row
+func (k *Keyboard) Column(buttons ...models.Button) *Keyboard {
+	for _, button := range buttons {
+		k.Row(button)
+	}
+
+	return k
+}
+
+// Grid adds the buttons in rows of the given number of columns, the last row can be shorter
+func (k *Keyboard) Grid(columns int, buttons ...models.Button) *Keyboard {
+	if columns <= 0 {
+		columns = 1
+	}
+
+	for start := 0; start < len(buttons); start += columns {
+		end := start + columns
+		if end > len(buttons) {
+			end = len(buttons)
+		}
+
+		k.Row(buttons[start:end]...)
+	}
+
+	return k
+}
+
+// Buttons returns the rows of buttons, e.g. to send them in a result that the responder signs
+func (k *Keyboard) Buttons() [][]models.Button {
+	return k.rows
+}
+
+// Markup signs the command buttons so only the user can press them in the chat and returns the keyboard
+// to send with a message. An empty keyboard returns nil
+func (k *Keyboard) Markup(ctx context.Context, codec *callback.Codec, userID, chatID int64) (*telegram.InlineKeyboardMarkup, error) {
+	if len(k.rows) == 0 {
+		return nil, nil
+	}
+
+	markup := &telegram.InlineKeyboardMarkup{InlineKeyboard: make([][]telegram.InlineKeyboardButton, 0, len(k.rows))}
+
+	for _, row := range k.rows {
+		if len(row) > MaxRowButtons {
+			return nil, fmt.Errorf("%w: %d buttons", ErrTooManyButtons, len(row))
+		}
+
+		buttons := make([]telegram.InlineKeyboardButton, 0, len(row))
+
+		for _, button := range row {
+			keyboardButton, err := markupButton(ctx, codec, button, userID, chatID)
+			if err != nil {
+				return nil, err
+			}
+
+			buttons = append(buttons, keyboardButton)
+		}
+
+		markup.InlineKeyboard = append(markup.InlineKeyboard, buttons)
+	}
+
+	return markup, nil
+}
+
+func markupButton(ctx context.Context, codec *callback.Codec, button models.Button, userID, chatID int64) (telegram.InlineKeyboardButton, error) {
+	if button.Text == "" {
+		return telegram.InlineKeyboardButton{}, fmt.Errorf("%w: missing text", ErrInvalidButton)
+	}
+
+	if button.URL != "" {
+		return telegram.InlineKeyboardButton{Text: button.Text, URL: button.URL}, nil
+	}
+
+	if button.Command == "" {
+		return telegram.InlineKeyboardButton{}, fmt.Errorf("%w: %q", ErrInvalidButton, button.Text)
+	}
+
+	data, err := codec.Encode(ctx, callback.Payload{
+		Command:   button.Command,
+		Data:      button.Data,
+		UserID:    userID,
+		ChatID:    chatID,
+		SingleUse: button.SingleUse,
+	})
+	if err != nil {
+		return telegram.InlineKeyboardButton{}, err
+	}
+
+	return telegram.InlineKeyboardButton{Text: button.Text, CallbackData: data}, nil
+}
@@ -0,0 +1,102 @@
+package format
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"shared/app/bot/callback"
+	"shared/app/bot/models"
+
+	"bitbucket.org/truora/scrap-services/shared/cache"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyboardRows(t *testing.T) {
+	c := require.New(t)
+
+	keyboard := NewKeyboard().
+		Row(CommandButton("Approve", "approval", "a.1"), CommandButton("Reject", "approval", "r.1")).
+		Row().
+		Column(URLButton("Docs", "https://example.com")).
+		Grid(2, CommandButton("1", "page", "1"), CommandButton("2", "page", "2"), CommandButton("3", "page", "3"))
+
+	c.Equal([][]models.Button{
+		{{Text: "Approve", Command: "approval", Data: "a.1"}, {Text: "Reject", Command: "approval", Data: "r.1"}},
+		{{Text: "Docs", URL: "https://example.com"}},
+		{{Text: "1", Command: "page", Data: "1"}, {Text: "2", Command: "page", Data: "2"}},
+		{{Text: "3", Command: "page", Data: "3"}},
+	}, keyboard.Buttons())
+
+	c.Equal(keyboard.Buttons(), KeyboardFrom(keyboard.Buttons()).Buttons())
+}
+
+func TestKeyboardMarkup(t *testing.T) {
+	c := require.New(t)
+
+	ctx := context.Background()
+
+	codec, err := callback.NewCodec([]byte("secret"), time.Hour)
+	c.NoError(err)
+
+	markup, err := NewKeyboard().Row(CommandButton("Next", "users", "page=2"), URLButton("Docs", "https://example.com")).Markup(ctx, codec, 1, 2)
+	c.NoError(err)
+	c.Len(markup.InlineKeyboard, 1)
+	c.Equal("https://example.com", markup.InlineKeyboard[0][1].URL)
+	c.Empty(markup.InlineKeyboard[0][1].CallbackData)
+
+	payload, err := codec.Decode(ctx, markup.InlineKeyboard[0][0].CallbackData, 1, 2)
+	c.NoError(err)
+	c.Equal(&callback.Payload{Command: "users", Data: "page=2", UserID: 1, ChatID: 2}, payload)
+
+	_, err = codec.Decode(ctx, markup.InlineKeyboard[0][0].CallbackData, 3, 2)
+	c.ErrorIs(err, callback.ErrInvalidSignature)
+
+	markup, err = NewKeyboard().Markup(ctx, codec, 1, 2)
+	c.NoError(err)
+	c.Nil(markup)
+}
+
+func TestKeyboardMarkupSingleUse(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+
+	ctx := context.Background()
+
+	codec, err := callback.NewCodec([]byte("secret"), time.Hour)
+	c.NoError(err)
+
+	markup, err := NewKeyboard().Row(SingleUseButton("Yes", "setrole", "answer=yes")).Markup(ctx, codec, 1, 2)
+	c.NoError(err)
+
+	payload, err := codec.Decode(ctx, markup.InlineKeyboard[0][0].CallbackData, 1, 2)
+	c.NoError(err)
+	c.True(payload.SingleUse)
+
+	_, err = codec.Decode(ctx, markup.InlineKeyboard[0][0].CallbackData, 1, 2)
+	c.ErrorIs(err, callback.ErrAlreadyUsed)
+}
+
+func TestKeyboardMarkupInvalid(t *testing.T) {
+	c := require.New(t)
+
+	ctx := context.Background()
+
+	codec, err := callback.NewCodec([]byte("secret"), time.Hour)
+	c.NoError(err)
+
+	_, err = NewKeyboard().Row(models.Button{Text: "Nothing"}).Markup(ctx, codec, 1, 2)
+	c.ErrorIs(err, ErrInvalidButton)
+
+	_, err = NewKeyboard().Row(CommandButton("", "users", "")).Markup(ctx, codec, 1, 2)
+	c.ErrorIs(err, ErrInvalidButton)
+
+	buttons := []models.Button{}
+	for i := 0; i <= MaxRowButtons; i++ {
+		buttons = append(buttons, CommandButton("x", "users", ""))
+	}
+
+	_, err = NewKeyboard().Row(buttons...).Markup(ctx, codec, 1, 2)
+	c.ErrorIs(err, ErrTooManyButtons)
+}
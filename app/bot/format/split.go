@@ -0,0 +1,340 @@
+package format
+
+import (
+	"strings"
+
+	"shared/shared/telegram"
+)
+
+const (
+	// MaxMessageLength is the maximum length of a message text accepted by Telegram
+	MaxMessageLength = 4096
+
+	htmlPreOpen  = "<pre>"
+	htmlPreClose = "</pre>"
+	htmlCodeOpen = "<code"
+	// htmlCodeClose closes the code tag of a block with a language
+	htmlCodeClose = "</code>"
+)
+
+// part is a piece of the text, the blocks have the tags or fences that open and close them so they can be
+// closed and opened again when they are split
+type part struct {
+	open    string
+	content string
+	close   string
+}
+
+// splitter packs the parts of the text in chunks
+type splitter struct {
+	mode    telegram.ParseMode
+	limit   int
+	current string
+	chunks  []string
+}
+
+// Split splits the text in chunks that fit in the limit. The text is split at the last paragraph, line or
+// word that fits and the pre-formatted blocks are split by lines, they are closed and opened again in
+// every chunk so each chunk can be parsed alone. Other entities are not split, they must fit in a chunk.
+// The length is measured like Telegram does, in UTF-16 code units
+func Split(mode telegram.ParseMode, text string, limit int) []string {
+	if length(text) <= limit {
+		return []string{text}
+	}
+
+	s := &splitter{mode: mode, limit: limit, chunks: []string{}}
+
+	for _, part := range parts(mode, text) {
+		if part.open == "" {
+			s.addText(part.content)
+
+			continue
+		}
+
+		s.addBlock(part)
+	}
+
+	s.flush()
+
+	return s.chunks
+}
+
+func (s *splitter) room() int {
+	return s.limit - length(s.current)
+}
+
+func (s *splitter) flush() {
+	chunk := strings.TrimRight(s.current, newLine)
+	if strings.TrimSpace(chunk) != "" {
+		s.chunks = append(s.chunks, chunk)
+	}
+
+	s.current = ""
+}
+
+func (s *splitter) addText(text string) {
+	for text != "" {
+		// the new lines between the previous chunk and this one are dropped
+		if s.current == "" {
+			text = strings.TrimLeft(text, newLine)
+		}
+
+		cut, next, hard := cutText(s.mode, text, s.room(), false)
+		if cut == len(text) {
+			s.current += text
+
+			return
+		}
+
+		// a word is not cut when it fits in the next chunk
+		if (cut == 0 || hard) && s.current != "" {
+			s.flush()
+
+			continue
+		}
+
+		cut, next = atLeastOneRune(text, cut, next)
+
+		s.current += text[:cut]
+		s.flush()
+
+		text = text[next:]
+	}
+}
+
+func (s *splitter) addBlock(block part) {
+	if length(block.open+block.content+block.close) <= s.room() {
+		s.current += block.open + block.content + block.close
+
+		return
+	}
+
+	overhead := length(block.open) + length(block.close)
+	content := block.content
+
+	for content != "" {
+		cut, next, hard := cutText(s.mode, content, s.room()-overhead, true)
+		if cut == len(content) {
+			s.current += block.open + content + block.close
+
+			return
+		}
+
+		if (cut == 0 || hard) && s.current != "" {
+			s.flush()
+
+			continue
+		}
+
+		cut, next = atLeastOneRune(content, cut, next)
+
+		s.current += block.open + content[:cut] + block.close
+		s.flush()
+
+		content = content[next:]
+	}
+}
+
+// cutText returns where the text is cut to fit in the room and where the rest starts, the separator between
+// them is dropped. hard is true when the text is cut in the middle of a word or of a code line
+func cutText(mode telegram.ParseMode, text string, room int, code bool) (int, int, bool) {
+	index := fitIndex(text, room)
+	if index == len(text) {
+		return index, index, false
+	}
+
+	index = safeIndex(mode, text, index)
+
+	separators := []string{"\n\n", newLine, " "}
+	if code {
+		separators = []string{newLine}
+	}
+
+	for _, separator := range separators {
+		// the separator is dropped so it can start right where the text stops fitting
+		end := index + len(separator)
+		if end > len(text) {
+			end = len(text)
+		}
+
+		if i := strings.LastIndex(text[:end], separator); i > 0 {
+			return i, i + len(separator), false
+		}
+	}
+
+	return index, index, true
+}
+
+// atLeastOneRune makes the cut advance when not even a rune fits, it only happens with tiny limits
+func atLeastOneRune(text string, cut, next int) (int, int) {
+	if cut > 0 {
+		return cut, next
+	}
+
+	for i := range text {
+		if i > 0 {
+			return i, i
+		}
+	}
+
+	return len(text), len(text)
+}
+
+// fitIndex returns the largest byte index whose prefix of the text fits in the room
+func fitIndex(text string, room int) int {
+	units := 0
+
+	for i, r := range text {
+		units += runeLength(r)
+		if units > room {
+			return i
+		}
+	}
+
+	return len(text)
+}
+
+// safeIndex moves the index back so the text is not cut in the middle of a MarkdownV2 escape or of an
+// HTML entity or tag
+func safeIndex(mode telegram.ParseMode, text string, index int) int {
+	switch mode {
+	case telegram.ParseModeMarkdownV2:
+		backslashes := 0
+
+		for i := index - 1; i >= 0 && text[i] == '\\'; i-- {
+			backslashes++
+		}
+
+		if backslashes%2 == 1 {
+			index--
+		}
+	case telegram.ParseModeHTML:
+		if amp := strings.LastIndex(text[:index], "&"); amp >= 0 && !strings.Contains(text[amp:index], ";") {
+			index = amp
+		}
+
+		if lt := strings.LastIndex(text[:index], "<"); lt >= 0 && !strings.Contains(text[lt:index], ">") {
+			index = lt
+		}
+	}
+
+	return index
+}
+
+// length returns the length of the text in UTF-16 code units
+func length(text string) int {
+	units := 0
+
+	for _, r := range text {
+		units += runeLength(r)
+	}
+
+	return units
+}
+
+// runeLength returns the UTF-16 code units of the rune, the runes outside the basic plane use two
+func runeLength(r rune) int {
+	if r >= 0x10000 {
+		return 2
+	}
+
+	return 1
+}
+
+// parts splits the text in plain text and pre-formatted blocks
+func parts(mode telegram.ParseMode, text string) []part {
+	switch mode {
+	case telegram.ParseModeMarkdownV2:
+		return markdownParts(text)
+	case telegram.ParseModeHTML:
+		return htmlParts(text)
+	}
+
+	return []part{{content: text}}
+}
+
+// markdownParts finds the blocks fenced by lines starting with ```, a fence without its closing line is
+// kept as plain text
+func markdownParts(text string) []part {
+	result := []part{}
+	plain := ""
+	lines := strings.SplitAfter(text, newLine)
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		if !strings.HasPrefix(line, codeFence) || strings.Count(line, codeFence) > 1 {
+			plain += line
+
+			continue
+		}
+
+		end := i + 1
+		for end < len(lines) && !strings.HasPrefix(lines[end], codeFence) {
+			end++
+		}
+
+		if end == len(lines) {
+			plain += strings.Join(lines[i:], "")
+
+			break
+		}
+
+		if plain != "" {
+			result = append(result, part{content: plain})
+		}
+
+		content := strings.TrimSuffix(strings.Join(lines[i+1:end], ""), newLine)
+		result = append(result, part{open: line, content: content, close: newLine + codeFence})
+
+		plain = strings.TrimPrefix(lines[end], codeFence)
+		i = end
+	}
+
+	if plain != "" {
+		result = append(result, part{content: plain})
+	}
+
+	return result
+}
+
+// htmlParts finds the pre blocks, the code tag with the language of the block is closed and opened with it
+func htmlParts(text string) []part {
+	result := []part{}
+
+	for {
+		start := strings.Index(text, htmlPreOpen)
+		if start < 0 {
+			break
+		}
+
+		end := strings.Index(text[start:], htmlPreClose)
+		if end < 0 {
+			break
+		}
+
+		end += start
+
+		block := part{open: htmlPreOpen, content: text[start+len(htmlPreOpen) : end], close: htmlPreClose}
+
+		if strings.HasPrefix(block.content, htmlCodeOpen) && strings.HasSuffix(block.content, htmlCodeClose) {
+			tagEnd := strings.Index(block.content, ">") + 1
+
+			block.open += block.content[:tagEnd]
+			block.content = strings.TrimSuffix(block.content[tagEnd:], htmlCodeClose)
+			block.close = htmlCodeClose + block.close
+		}
+
+		if start > 0 {
+			result = append(result, part{content: text[:start]})
+		}
+
+		result = append(result, block)
+		text = text[end+len(htmlPreClose):]
+	}
+
+	if text != "" {
+		result = append(result, part{content: text})
+	}
+
+	return result
+}
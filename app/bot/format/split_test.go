@@ -0,0 +1,63 @@
+package format
+
+import (
+	"strings"
+	"testing"
+
+	"shared/shared/telegram"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitShortText(t *testing.T) {
+	c := require.New(t)
+
+	c.Equal([]string{"hello"}, Split(telegram.ParseModeMarkdownV2, "hello", MaxMessageLength))
+	c.Equal([]string{strings.Repeat("a", MaxMessageLength)}, Split("", strings.Repeat("a", MaxMessageLength), MaxMessageLength))
+}
+
+func TestSplitBoundaries(t *testing.T) {
+	c := require.New(t)
+
+	c.Equal([]string{"aaaa", "bbbb cccc"}, Split("", "aaaa\n\nbbbb cccc", 12))
+	c.Equal([]string{"aaaa bbbb", "cccc"}, Split("", "aaaa bbbb\n\ncccc", 10))
+	c.Equal([]string{"aaaa", "bbbb"}, Split("", "aaaa bbbb", 6))
+	c.Equal([]string{"abcd", "efgh", "ij"}, Split("", "abcdefghij", 4))
+	c.Equal([]string{"😀😀", "😀"}, Split("", "😀😀😀", 4))
+}
+
+func TestSplitKeepsEscapes(t *testing.T) {
+	c := require.New(t)
+
+	c.Equal([]string{"ab", `\.c`, "d"}, Split(telegram.ParseModeMarkdownV2, `ab\.cd`, 3))
+	c.Equal([]string{"ab", "&amp;", "cd"}, Split(telegram.ParseModeHTML, "ab&amp;cd", 5))
+}
+
+func TestSplitMarkdownCodeBlock(t *testing.T) {
+	c := require.New(t)
+
+	text := "intro\n```go\nline1\nline2\nline3\n```\nend"
+
+	c.Equal([]string{
+		"intro",
+		"```go\nline1\n```",
+		"```go\nline2\n```",
+		"```go\nline3\n```\nend",
+	}, Split(telegram.ParseModeMarkdownV2, text, 20))
+}
+
+func TestSplitHTMLCodeBlock(t *testing.T) {
+	c := require.New(t)
+
+	open := `<pre><code class="language-go">`
+	text := open + "a\nb</code></pre>"
+
+	c.Equal([]string{open + "a</code></pre>", open + "b</code></pre>"}, Split(telegram.ParseModeHTML, text, 46))
+	c.Equal([]string{"<pre>a</pre>", "<pre>b</pre>"}, Split(telegram.ParseModeHTML, "<pre>a\nb</pre>", 12))
+}
+
+func TestSplitUnclosedFence(t *testing.T) {
+	c := require.New(t)
+
+	c.Equal([]string{"```go", "aaaa"}, Split(telegram.ParseModeMarkdownV2, "```go\naaaa", 6))
+}
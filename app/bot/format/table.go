@@ -0,0 +1,68 @@
+package format
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	columnSeparator = " | "
+	headerSeparator = "-+-"
+	headerUnderline = "-"
+)
+
+// Table returns the rows with their columns aligned to be sent in a pre-formatted block, the header is
+// separated from the rows by a line. Rows can have less cells than the others and new lines in the cells
+// are replaced by spaces
+func Table(header []string, rows ...[]string) string {
+	columns := len(header)
+
+	for _, row := range rows {
+		if len(row) > columns {
+			columns = len(row)
+		}
+	}
+
+	widths := make([]int, columns)
+
+	for _, row := range append([][]string{header}, rows...) {
+		for i, cell := range row {
+			if width := utf8.RuneCountInString(tableCell(cell)); width > widths[i] {
+				widths[i] = width
+			}
+		}
+	}
+
+	lines := make([]string, 0, len(rows)+2)
+
+	if len(header) > 0 {
+		underline := make([]string, columns)
+		for i, width := range widths {
+			underline[i] = strings.Repeat(headerUnderline, width)
+		}
+
+		lines = append(lines, tableRow(header, widths), strings.Join(underline, headerSeparator))
+	}
+
+	for _, row := range rows {
+		lines = append(lines, tableRow(row, widths))
+	}
+
+	return strings.Join(lines, newLine)
+}
+
+// tableRow pads the cells to the width of their column, the missing cells at the end are not added
+func tableRow(cells []string, widths []int) string {
+	padded := make([]string, len(cells))
+
+	for i, cell := range cells {
+		cell = tableCell(cell)
+		padded[i] = cell + strings.Repeat(" ", widths[i]-utf8.RuneCountInString(cell))
+	}
+
+	return strings.TrimRight(strings.Join(padded, columnSeparator), " ")
+}
+
+func tableCell(cell string) string {
+	return strings.ReplaceAll(cell, newLine, " ")
+}
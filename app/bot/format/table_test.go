@@ -0,0 +1,25 @@
+package format
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTable(t *testing.T) {
+	c := require.New(t)
+
+	table := Table([]string{"Command", "Outcome"},
+		[]string{"/deploy", "published"},
+		[]string{"/schedule", "invalid", "extra"},
+		[]string{"/añadir\nx"},
+	)
+
+	c.Equal("Command   | Outcome\n"+
+		"----------+-----------+------\n"+
+		"/deploy   | published\n"+
+		"/schedule | invalid   | extra\n"+
+		"/añadir x", table)
+
+	c.Equal("a | b", Table(nil, []string{"a", "b"}))
+}
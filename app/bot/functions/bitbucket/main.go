@@ -0,0 +1,14 @@
+package main
+
+import (
+	"context"
+
+	"shared/app/bot/bitbucket"
+
+	"github.com/aws/aws-lambda-go/lambda"
+)
+
+func main() {
+	bitbucket.Init(context.Background())
+	lambda.Start(bitbucket.Handler)
+}
@@ -0,0 +1,14 @@
+package main
+
+import (
+	"context"
+
+	"shared/app/bot/replayer"
+
+	"github.com/aws/aws-lambda-go/lambda"
+)
+
+func main() {
+	replayer.Init(context.Background())
+	lambda.Start(replayer.Handler)
+}
@@ -0,0 +1,14 @@
+package main
+
+import (
+	"context"
+
+	"shared/app/bot/responder"
+
+	"github.com/aws/aws-lambda-go/lambda"
+)
+
+func main() {
+	responder.Init(context.Background())
+	lambda.Start(responder.Handler)
+}
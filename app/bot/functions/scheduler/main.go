@@ -0,0 +1,14 @@
+package main
+
+import (
+	"context"
+
+	"shared/app/bot/scheduler"
+
+	"github.com/aws/aws-lambda-go/lambda"
+)
+
+func main() {
+	scheduler.Init(context.Background())
+	lambda.Start(scheduler.Handler)
+}
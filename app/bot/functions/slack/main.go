@@ -0,0 +1,14 @@
+package main
+
+import (
+	"context"
+
+	"shared/app/bot/slack"
+
+	"github.com/aws/aws-lambda-go/lambda"
+)
+
+func main() {
+	slack.Init(context.Background())
+	lambda.Start(slack.Handler)
+}
@@ -0,0 +1,223 @@
+package i18n
+
+// english is the catalog used when a key is missing in the catalog of the user language
+var english = Catalog{
+	"ack.accepted": {Other: "Accepted /{command} (id: {id})"},
+	"ack.rejected": {Other: "Could not run /{command} (id: {id}), please try again"},
+	"ack.delayed":  {Other: "/{command} is delayed, it will be sent to the workers as soon as possible (id: {id})"},
+
+	"admin.no_more_users":          {Other: "There are no more users"},
+	"admin.next":                   {Other: "Next"},
+	"admin.user_details":           {Other: "Email: {email}\nTelegram: {telegram}\nRole: {role}\nEmail verified: {verified}\nBitbucket account: {bitbucket}\nSlack user: {slack}\nCreated: {created}"},
+	"admin.verified":               {Other: "verified"},
+	"admin.not_verified":           {Other: "not verified"},
+	"admin.own_user":               {Other: "You can not run this command on your own user"},
+	"admin.yes":                    {Other: "Yes"},
+	"admin.no":                     {Other: "No"},
+	"admin.confirm":                {Other: "Do you want to {action}?"},
+	"admin.canceled":               {Other: "Canceled, the user was not changed"},
+	"admin.user_exists":            {Other: "{email} is already a user, send /{command} {email} to see it"},
+	"admin.user_added":             {Other: "Added {email} as {role}, they can link their Telegram account with /{command}"},
+	"admin.user_not_found":         {Other: "No user with email {email}"},
+	"admin.action_failed":          {Other: "Could not {action}, please try again"},
+	"admin.action_done":            {Other: "Done, {action}"},
+	"admin.invalid_role":           {Other: "unknown role {role}, the roles are: {roles}"},
+	"admin.action.setrole":         {Other: "change the role of {email} from {from} to {to}"},
+	"admin.action.revokeuser":      {Other: "revoke the verification of {email}"},
+	"admin.action.linkbitbucket":   {Other: "link the Bitbucket account {account} to {email}"},
+	"admin.action.unlinkbitbucket": {Other: "unlink the Bitbucket account {account} from {email}"},
+	"admin.action.linkslack":       {Other: "link the Slack user {slack_id} to {email}"},
+	"admin.action.unlinkslack":     {Other: "unlink the Slack user {slack_id} from {email}"},
+	"admin.action.deleteuser":      {Other: "delete {email}"},
+
+	"approval.not_enough_approvers": {One: "/{command} needs 1 approval but there are not enough approvers", Other: "/{command} needs {count} approvals but there are not enough approvers"},
+	"approval.prompt":               {One: "{user} asks to run {command}, it needs 1 approval (id: {id})", Other: "{user} asks to run {command}, it needs {count} approvals (id: {id})"},
+	"approval.requested":            {One: "/{command} needs 1 approval, I asked the approvers (id: {id})", Other: "/{command} needs {count} approvals, I asked the approvers (id: {id})"},
+	"approval.approve":              {Other: "Approve"},
+	"approval.reject":               {Other: "Reject"},
+	"approval.already_resolved":     {Other: "The request {id} was already resolved"},
+	"approval.not_allowed":          {Other: "You are not allowed to approve /{command}"},
+	"approval.own_request":          {Other: "You can not approve your own request"},
+	"approval.request_expired":      {Other: "The request {id} expired"},
+	"approval.approved_pending":     {One: "You approved /{command} (id: {id}), it needs 1 more approval", Other: "You approved /{command} (id: {id}), it needs {count} more approvals"},
+	"approval.approved":             {Other: "Approved, /{command} was sent (id: {id})"},
+	"approval.rejected_prompt":      {Other: "Rejected by {user}, /{command} was not sent (id: {id})"},
+	"approval.rejected":             {Other: "Your /{command} request was rejected by {user} (id: {id})"},
+	"approval.expired_prompt":       {Other: "Expired, /{command} was not sent (id: {id})"},
+	"approval.expired":              {Other: "Your /{command} request expired without enough approvals (id: {id})"},
+
+	"history.empty":                   {Other: "You have not run any command yet"},
+	"audit.empty":                     {Other: "There are no audit records"},
+	"audit.outcome.published":         {Other: "published"},
+	"audit.outcome.delayed":           {Other: "delayed"},
+	"audit.outcome.awaiting_approval": {Other: "awaiting_approval"},
+	"audit.outcome.handled":           {Other: "handled"},
+	"audit.outcome.invalid":           {Other: "invalid"},
+	"audit.outcome.denied":            {Other: "denied"},
+	"audit.outcome.disabled":          {Other: "disabled"},
+	"audit.outcome.failed":            {Other: "failed"},
+
+	"deadletter.empty":           {Other: "There are no parked messages"},
+	"deadletter.list_item":       {One: "{id} · /{command} · user {user} · 1 attempt · {next}", Other: "{id} · /{command} · user {user} · {count} attempts · {next}"},
+	"deadletter.list_footer":     {Other: "Send /{command} <id> to see a message"},
+	"deadletter.details":         {Other: "ID: {id}\nAttribute: {attribute}\nTopic: {topic}\nText: {text}\nUser: {user} in chat {chat}\nAttempts: {attempts} of {max}\nLast error: {error}\nNext attempt: {next}\nParked: {parked}\nSend /{retry} {id} or /{discard} {id}"},
+	"deadletter.out_of_attempts": {Other: "out of attempts"},
+	"deadletter.claimed":         {Other: "The message {id} is being published, please try again later"},
+	"deadletter.retry_failed":    {Other: "Could not publish {id}, the attempt {attempt} failed: {error}"},
+	"deadletter.blocked":         {Other: "The message {id} stays parked, /{command} is disabled or the bot is in maintenance"},
+	"deadletter.published":       {Other: "Published {id}"},
+	"deadletter.discarded":       {Other: "Discarded {id}"},
+	"deadletter.not_found":       {Other: "No parked message with ID {id}"},
+
+	"events.welcome": {Other: "Hi everyone! Send /{command} to see what I can do"},
+
+	"external.empty":         {Other: "Please send a command"},
+	"external.telegram_only": {Other: "/{command} is only available in Telegram"},
+
+	"flag.maintenance":             {Other: "The bot is in maintenance, the commands are not being run: {message}"},
+	"flag.command_disabled":        {Other: "/{command} is disabled for now, please try again later"},
+	"flag.command_disabled_reason": {Other: "/{command} is disabled for now: {reason}"},
+	"flag.beta_only":               {Other: "/{command} is in beta and only some users can run it for now"},
+	"flag.router_command":          {Other: "/{command} is answered by the bot, only the commands sent to the workers can be changed"},
+	"flag.not_in_beta":             {Other: "/{command} is not in beta, send /{start} {command} first"},
+	"flag.disabled":                {Other: "Disabled /{command}, send /{enable} {command} to enable it again"},
+	"flag.enabled":                 {Other: "Enabled /{command}"},
+	"flag.beta_started":            {Other: "/{command} is in beta, send /{allow} {command} <email> to let a user run it"},
+	"flag.beta_ended":              {Other: "Every user can run /{command} again"},
+	"flag.user_allowed":            {Other: "{email} can run /{command}"},
+	"flag.user_denied":             {Other: "{email} can not run /{command} anymore"},
+	"flag.maintenance_started":     {Other: "Maintenance started, no command is sent to the workers until /{end}"},
+	"flag.maintenance_ended":       {Other: "Maintenance ended, the commands are sent to the workers again"},
+	"flag.list_maintenance":        {Other: "Maintenance: {message}"},
+	"flag.list_maintenance_off":    {Other: "Maintenance: off"},
+	"flag.list_disabled":           {Other: "Disabled /{command}: {reason}"},
+	"flag.list_beta":               {Other: "Beta /{command}: {users}"},
+	"flag.list_no_users":           {Other: "nobody yet"},
+
+	"help.header":  {Other: "Available commands:"},
+	"help.command": {Other: "{usage} - {description}"},
+	"help.aliases": {Other: " (aliases: {aliases})"},
+
+	"command.help":                      {Other: "Shows the available commands"},
+	"command.hi":                        {Other: "Links your Telegram account to your email"},
+	"command.register":                  {Other: "Verifies your email with a code and links it to your Telegram account"},
+	"command.deployterraformstaging":    {Other: "Deploys a terraform project to staging"},
+	"command.deployterraformproduction": {Other: "Deploys a terraform project to production once an admin approves it"},
+	"command.schedule":                  {Other: "Runs a command later, e.g. /schedule 30m /deployterraformstaging checks/core"},
+	"command.schedules":                 {Other: "Lists your scheduled commands"},
+	"command.unschedule":                {Other: "Cancels a scheduled command"},
+	"command.notifications":             {Other: "Shows the Bitbucket notifications you receive"},
+	"command.mute":                      {Other: "Stops a type of Bitbucket notifications, e.g. /mute pr_merged"},
+	"command.unmute":                    {Other: "Receives a type of Bitbucket notifications again"},
+	"command.users":                     {Other: "Lists the bot users"},
+	"command.user":                      {Other: "Shows a bot user"},
+	"command.adduser":                   {Other: "Adds a bot user so it can register with its email"},
+	"command.setrole":                   {Other: "Changes the role of a bot user"},
+	"command.revokeuser":                {Other: "Revokes the email verification of a bot user"},
+	"command.linkbitbucket":             {Other: "Links a Bitbucket account ID to a bot user"},
+	"command.unlinkbitbucket":           {Other: "Unlinks the Bitbucket account of a bot user"},
+	"command.linkslack":                 {Other: "Links a Slack user ID to a bot user"},
+	"command.unlinkslack":               {Other: "Unlinks the Slack user of a bot user"},
+	"command.deleteuser":                {Other: "Deletes a bot user"},
+	"command.language":                  {Other: "Shows or changes the language of the bot answers"},
+	"command.history":                   {Other: "Shows the commands you ran lately"},
+	"command.audit":                     {Other: "Shows the commands the team ran lately"},
+	"command.deadletters":               {Other: "Lists the commands that could not be sent to the workers"},
+	"command.deadletter":                {Other: "Shows a command that could not be sent to the workers"},
+	"command.retrydeadletter":           {Other: "Sends a parked command to the workers now"},
+	"command.discarddeadletter":         {Other: "Discards a parked command, its user is told it will not run"},
+	"command.flags":                     {Other: "Shows the disabled commands, the commands in beta and the maintenance"},
+	"command.disablecommand":            {Other: "Stops sending a command to the workers, its users are told the reason"},
+	"command.enablecommand":             {Other: "Sends a disabled command to the workers again"},
+	"command.startbeta":                 {Other: "Only lets the users allowed with /allowbeta run a command"},
+	"command.endbeta":                   {Other: "Lets every user run a command in beta"},
+	"command.allowbeta":                 {Other: "Lets a bot user run a command in beta"},
+	"command.denybeta":                  {Other: "Stops a bot user from running a command in beta"},
+	"command.maintenance":               {Other: "Stops sending every command to the workers, the users are sent the message"},
+	"command.endmaintenance":            {Other: "Sends the commands to the workers again"},
+
+	"language.current": {Other: "I answer you in {language}, send /{command} <code> to change it. Available languages: {languages}"},
+	"language.changed": {Other: "Done, I will answer you in {language}"},
+	"language.unknown": {Other: "Unknown language {language}, use one of {languages}"},
+	"language.name.en": {Other: "English"},
+	"language.name.es": {Other: "Spanish"},
+
+	"notifications.on":            {Other: "on"},
+	"notifications.muted":         {Other: "muted"},
+	"notifications.footer":        {Other: "Send /{mute} <event> or /{unmute} <event> to change them"},
+	"notifications.muted_event":   {Other: "Muted the {event} notifications"},
+	"notifications.unmuted_event": {Other: "Unmuted the {event} notifications"},
+	"notifications.unknown_event": {Other: "Unknown event {event}, use one of {events}"},
+
+	"parse.unterminated_quote": {Other: "unterminated quote"},
+	"parse.invalid_flag":       {Other: "invalid flag"},
+	"parse.unknown_flag":       {Other: "unknown flag {flag}"},
+	"parse.missing_value":      {Other: "flag {flag} requires a value"},
+	"parse.unexpected_value":   {Other: "flag {flag} does not receive a value"},
+	"parse.repeated_flag":      {Other: "flag {flag} can only be used once"},
+
+	"policy.not_registered": {Other: "You are not registered, please send /{command} to verify your email"},
+	"policy.not_allowed":    {Other: "You are not allowed to run /{command}"},
+
+	"register.already_registered": {Other: "You are already registered as {email}"},
+	"register.ask_email":          {Other: "What is your email? send /cancel to stop"},
+	"register.canceled":           {Other: "Registration canceled"},
+	"register.invalid_code":       {Other: "Invalid code, please try again or send {resend} to get a new one"},
+	"register.code_expired":       {Other: "The code is no longer valid, send {resend} to get a new one"},
+	"register.verified":           {Other: "Your email {email} is verified, send /{command} to see the available commands"},
+	"register.not_added":          {Other: "Your email {email} is verified but it was not added to the bot, please ask an admin to add you"},
+	"register.invalid_email":      {Other: "Please send a valid email, e.g. name@truora.com"},
+	"register.cooldown":           {Other: "Please wait {cooldown} before asking a new code"},
+	"register.too_many_codes":     {Other: "You asked too many codes, please try again in an hour"},
+	"register.email_subject":      {Other: "Your Betty verification code"},
+	"register.email_body":         {Other: "Your code to link your Telegram account is {code}, it can only be used once"},
+	"register.send_failed":        {Other: "Cannot send the code, please try again later"},
+	"register.code_sent":          {Other: "I sent a code to {email}, please send it here. Send {resend} to get a new one or /cancel to stop"},
+
+	"registry.unknown_command":  {Other: "Unknown command /{command}, send /{help} to see the available commands"},
+	"registry.chat_not_allowed": {Other: "/{command} can not be used in this chat"},
+	"registry.missing_args":     {Other: "missing required arguments, usage: {usage}"},
+	"registry.too_many_args":    {Other: "too many arguments, usage: {usage}"},
+
+	"router.button_expired":      {Other: "This button expired, please send the command again"},
+	"router.conversation_failed": {Other: "Cannot continue conversation, please try sending a command"},
+	"router.missing_args":        {Other: "Please add arguments to the command"},
+
+	"schedule.invalid_duration": {Other: "use a duration between {min} and {max}, e.g. 30m or 2h"},
+	"schedule.invalid_delay":    {Other: "Invalid delay {delay}, {reason}"},
+	"schedule.invalid_interval": {Other: "Invalid interval {interval}, {reason}"},
+	"schedule.too_many":         {Other: "You have too many scheduled commands, cancel one with /{command} <id>"},
+	"schedule.scheduled":        {Other: "Scheduled {text} in {delay} (id: {id}), send /{command} {id} to cancel it"},
+	"schedule.scheduled_every":  {Other: "Scheduled {text} in {delay} and every {every} after that (id: {id}), send /{command} {id} to cancel it"},
+	"schedule.missing_slash":    {Other: "The command to schedule must start with /"},
+	"schedule.missing_args":     {Other: "Add the arguments to schedule it, usage: {usage}"},
+	"schedule.not_schedulable":  {Other: "/{command} can not be scheduled"},
+	"schedule.empty":            {Other: "You have no scheduled commands, send /{command} to add one"},
+	"schedule.list_header":      {Other: "Your scheduled commands:"},
+	"schedule.list_item":        {Other: "{id} in {delay}: {text}"},
+	"schedule.list_item_every":  {Other: "{id} in {delay}: {text} (every {every})"},
+	"schedule.not_found":        {Other: "You have no scheduled command with id {id}, send /{command} to see them"},
+	"schedule.canceled":         {Other: "Canceled the scheduled command {id}"},
+
+	"slack.empty":          {Other: "Please send a command, e.g. {root} deployterraformstaging checks/core"},
+	"slack.not_linked":     {Other: "Your Slack user is not linked to a bot user, please ask an admin to link the Slack user {slack_id} to your email"},
+	"slack.user_failed":    {Other: "Could not find your bot user, please try again"},
+	"slack.command_failed": {Other: "Could not run /{command}, please try again"},
+
+	"upload.not_accepted":     {Other: "/{command} does not accept files"},
+	"upload.required":         {Other: "/{command} needs a file, send it with the command as caption"},
+	"upload.too_large":        {Other: "The file is too large, the limit is {limit}"},
+	"upload.type_not_allowed": {Other: "Files of type {type} are not accepted, send one of {types}"},
+	"upload.failed":           {Other: "Could not read the file, please send it again"},
+
+	"wizard.empty_answer":                   {Other: "the answer can not be empty"},
+	"wizard.yes_or_no":                      {Other: "please answer yes or no"},
+	"wizard.deployterraformstaging.project": {Other: "Which terraform project do you want to deploy? e.g. checks/core"},
+	"wizard.deployterraformstaging.branch":  {Other: "Which branch? send {skip} to use the default branch"},
+	"wizard.deployterraformstaging.confirm": {Other: "Do you want to deploy it to staging? yes/no"},
+
+	"conversation.timed_out":           {Other: "The conversation timed out, please send the command again"},
+	"conversation.canceled":            {Other: "Canceled"},
+	"conversation.upload_required":     {Other: "please send a file"},
+	"conversation.upload_not_expected": {Other: "please answer with text, not with a file"},
+}
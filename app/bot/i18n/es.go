@@ -0,0 +1,223 @@
+package i18n
+
+// spanish has the Spanish messages, the keys missing here are answered in English
+var spanish = Catalog{
+	"ack.accepted": {Other: "Recibí /{command} (id: {id})"},
+	"ack.rejected": {Other: "No pude ejecutar /{command} (id: {id}), por favor intenta de nuevo"},
+	"ack.delayed":  {Other: "/{command} está demorado, lo enviaré a los workers tan pronto como sea posible (id: {id})"},
+
+	"admin.no_more_users":          {Other: "No hay más usuarios"},
+	"admin.next":                   {Other: "Siguiente"},
+	"admin.user_details":           {Other: "Email: {email}\nTelegram: {telegram}\nRol: {role}\nEmail verificado: {verified}\nCuenta de Bitbucket: {bitbucket}\nUsuario de Slack: {slack}\nCreado: {created}"},
+	"admin.verified":               {Other: "verificado"},
+	"admin.not_verified":           {Other: "sin verificar"},
+	"admin.own_user":               {Other: "No puedes ejecutar este comando sobre tu propio usuario"},
+	"admin.yes":                    {Other: "Sí"},
+	"admin.no":                     {Other: "No"},
+	"admin.confirm":                {Other: "¿Quieres {action}?"},
+	"admin.canceled":               {Other: "Cancelado, el usuario no cambió"},
+	"admin.user_exists":            {Other: "{email} ya es un usuario, envía /{command} {email} para verlo"},
+	"admin.user_added":             {Other: "Se agregó {email} como {role}, puede vincular su cuenta de Telegram con /{command}"},
+	"admin.user_not_found":         {Other: "No hay un usuario con el email {email}"},
+	"admin.action_failed":          {Other: "No pude {action}, por favor intenta de nuevo"},
+	"admin.action_done":            {Other: "Listo, {action}"},
+	"admin.invalid_role":           {Other: "rol desconocido {role}, los roles son: {roles}"},
+	"admin.action.setrole":         {Other: "cambiar el rol de {email} de {from} a {to}"},
+	"admin.action.revokeuser":      {Other: "revocar la verificación de {email}"},
+	"admin.action.linkbitbucket":   {Other: "vincular la cuenta de Bitbucket {account} a {email}"},
+	"admin.action.unlinkbitbucket": {Other: "desvincular la cuenta de Bitbucket {account} de {email}"},
+	"admin.action.linkslack":       {Other: "vincular el usuario de Slack {slack_id} a {email}"},
+	"admin.action.unlinkslack":     {Other: "desvincular el usuario de Slack {slack_id} de {email}"},
+	"admin.action.deleteuser":      {Other: "eliminar a {email}"},
+
+	"approval.not_enough_approvers": {One: "/{command} necesita 1 aprobación pero no hay suficientes aprobadores", Other: "/{command} necesita {count} aprobaciones pero no hay suficientes aprobadores"},
+	"approval.prompt":               {One: "{user} quiere ejecutar {command}, necesita 1 aprobación (id: {id})", Other: "{user} quiere ejecutar {command}, necesita {count} aprobaciones (id: {id})"},
+	"approval.requested":            {One: "/{command} necesita 1 aprobación, ya se la pedí a los aprobadores (id: {id})", Other: "/{command} necesita {count} aprobaciones, ya se las pedí a los aprobadores (id: {id})"},
+	"approval.approve":              {Other: "Aprobar"},
+	"approval.reject":               {Other: "Rechazar"},
+	"approval.already_resolved":     {Other: "La solicitud {id} ya fue resuelta"},
+	"approval.not_allowed":          {Other: "No tienes permiso para aprobar /{command}"},
+	"approval.own_request":          {Other: "No puedes aprobar tu propia solicitud"},
+	"approval.request_expired":      {Other: "La solicitud {id} expiró"},
+	"approval.approved_pending":     {One: "Aprobaste /{command} (id: {id}), necesita 1 aprobación más", Other: "Aprobaste /{command} (id: {id}), necesita {count} aprobaciones más"},
+	"approval.approved":             {Other: "Aprobada, /{command} fue enviado (id: {id})"},
+	"approval.rejected_prompt":      {Other: "Rechazada por {user}, /{command} no fue enviado (id: {id})"},
+	"approval.rejected":             {Other: "{user} rechazó tu solicitud de /{command} (id: {id})"},
+	"approval.expired_prompt":       {Other: "Expiró, /{command} no fue enviado (id: {id})"},
+	"approval.expired":              {Other: "Tu solicitud de /{command} expiró sin suficientes aprobaciones (id: {id})"},
+
+	"history.empty":                   {Other: "Todavía no has ejecutado ningún comando"},
+	"audit.empty":                     {Other: "No hay registros de auditoría"},
+	"audit.outcome.published":         {Other: "publicado"},
+	"audit.outcome.delayed":           {Other: "demorado"},
+	"audit.outcome.awaiting_approval": {Other: "esperando aprobación"},
+	"audit.outcome.handled":           {Other: "respondido"},
+	"audit.outcome.invalid":           {Other: "inválido"},
+	"audit.outcome.denied":            {Other: "denegado"},
+	"audit.outcome.disabled":          {Other: "deshabilitado"},
+	"audit.outcome.failed":            {Other: "fallido"},
+
+	"deadletter.empty":           {Other: "No hay mensajes pendientes"},
+	"deadletter.list_item":       {One: "{id} · /{command} · usuario {user} · 1 intento · {next}", Other: "{id} · /{command} · usuario {user} · {count} intentos · {next}"},
+	"deadletter.list_footer":     {Other: "Envía /{command} <id> para ver un mensaje"},
+	"deadletter.details":         {Other: "ID: {id}\nAtributo: {attribute}\nTópico: {topic}\nTexto: {text}\nUsuario: {user} en el chat {chat}\nIntentos: {attempts} de {max}\nÚltimo error: {error}\nSiguiente intento: {next}\nPendiente desde: {parked}\nEnvía /{retry} {id} o /{discard} {id}"},
+	"deadletter.out_of_attempts": {Other: "sin intentos"},
+	"deadletter.claimed":         {Other: "El mensaje {id} se está publicando, por favor intenta más tarde"},
+	"deadletter.retry_failed":    {Other: "No pude publicar {id}, el intento {attempt} falló: {error}"},
+	"deadletter.blocked":         {Other: "El mensaje {id} sigue guardado, /{command} está deshabilitado o el bot está en mantenimiento"},
+	"deadletter.published":       {Other: "Publiqué {id}"},
+	"deadletter.discarded":       {Other: "Descarté {id}"},
+	"deadletter.not_found":       {Other: "No hay un mensaje pendiente con el ID {id}"},
+
+	"events.welcome": {Other: "¡Hola a todos! Envíen /{command} para ver lo que puedo hacer"},
+
+	"external.empty":         {Other: "Por favor envía un comando"},
+	"external.telegram_only": {Other: "/{command} solo está disponible en Telegram"},
+
+	"flag.maintenance":             {Other: "El bot está en mantenimiento, los comandos no se están ejecutando: {message}"},
+	"flag.command_disabled":        {Other: "/{command} está deshabilitado por ahora, por favor intenta más tarde"},
+	"flag.command_disabled_reason": {Other: "/{command} está deshabilitado por ahora: {reason}"},
+	"flag.beta_only":               {Other: "/{command} está en beta y por ahora solo algunos usuarios lo pueden ejecutar"},
+	"flag.router_command":          {Other: "/{command} lo responde el bot, solo se pueden cambiar los comandos que se envían a los workers"},
+	"flag.not_in_beta":             {Other: "/{command} no está en beta, envía primero /{start} {command}"},
+	"flag.disabled":                {Other: "Se deshabilitó /{command}, envía /{enable} {command} para habilitarlo de nuevo"},
+	"flag.enabled":                 {Other: "Se habilitó /{command}"},
+	"flag.beta_started":            {Other: "/{command} está en beta, envía /{allow} {command} <email> para que un usuario lo pueda ejecutar"},
+	"flag.beta_ended":              {Other: "Todos los usuarios pueden ejecutar /{command} de nuevo"},
+	"flag.user_allowed":            {Other: "{email} puede ejecutar /{command}"},
+	"flag.user_denied":             {Other: "{email} ya no puede ejecutar /{command}"},
+	"flag.maintenance_started":     {Other: "Comenzó el mantenimiento, no se envían comandos a los workers hasta /{end}"},
+	"flag.maintenance_ended":       {Other: "Terminó el mantenimiento, los comandos se envían de nuevo a los workers"},
+	"flag.list_maintenance":        {Other: "Mantenimiento: {message}"},
+	"flag.list_maintenance_off":    {Other: "Mantenimiento: no"},
+	"flag.list_disabled":           {Other: "Deshabilitado /{command}: {reason}"},
+	"flag.list_beta":               {Other: "Beta /{command}: {users}"},
+	"flag.list_no_users":           {Other: "nadie todavía"},
+
+	"help.header":  {Other: "Comandos disponibles:"},
+	"help.command": {Other: "{usage} - {description}"},
+	"help.aliases": {Other: " (alias: {aliases})"},
+
+	"command.help":                      {Other: "Muestra los comandos disponibles"},
+	"command.hi":                        {Other: "Vincula tu cuenta de Telegram a tu email"},
+	"command.register":                  {Other: "Verifica tu email con un código y lo vincula a tu cuenta de Telegram"},
+	"command.deployterraformstaging":    {Other: "Despliega un proyecto de terraform en staging"},
+	"command.deployterraformproduction": {Other: "Despliega un proyecto de terraform en producción cuando un admin lo aprueba"},
+	"command.schedule":                  {Other: "Ejecuta un comando más tarde, p. ej. /schedule 30m /deployterraformstaging checks/core"},
+	"command.schedules":                 {Other: "Lista tus comandos programados"},
+	"command.unschedule":                {Other: "Cancela un comando programado"},
+	"command.notifications":             {Other: "Muestra las notificaciones de Bitbucket que recibes"},
+	"command.mute":                      {Other: "Silencia un tipo de notificaciones de Bitbucket, p. ej. /mute pr_merged"},
+	"command.unmute":                    {Other: "Vuelve a recibir un tipo de notificaciones de Bitbucket"},
+	"command.users":                     {Other: "Lista los usuarios del bot"},
+	"command.user":                      {Other: "Muestra un usuario del bot"},
+	"command.adduser":                   {Other: "Agrega un usuario del bot para que se pueda registrar con su email"},
+	"command.setrole":                   {Other: "Cambia el rol de un usuario del bot"},
+	"command.revokeuser":                {Other: "Revoca la verificación del email de un usuario del bot"},
+	"command.linkbitbucket":             {Other: "Vincula el ID de una cuenta de Bitbucket a un usuario del bot"},
+	"command.unlinkbitbucket":           {Other: "Desvincula la cuenta de Bitbucket de un usuario del bot"},
+	"command.linkslack":                 {Other: "Vincula el ID de un usuario de Slack a un usuario del bot"},
+	"command.unlinkslack":               {Other: "Desvincula el usuario de Slack de un usuario del bot"},
+	"command.deleteuser":                {Other: "Elimina un usuario del bot"},
+	"command.language":                  {Other: "Muestra o cambia el idioma de las respuestas del bot"},
+	"command.history":                   {Other: "Muestra los comandos que ejecutaste últimamente"},
+	"command.audit":                     {Other: "Muestra los comandos que el equipo ejecutó últimamente"},
+	"command.deadletters":               {Other: "Lista los comandos que no se pudieron enviar a los workers"},
+	"command.deadletter":                {Other: "Muestra un comando que no se pudo enviar a los workers"},
+	"command.retrydeadletter":           {Other: "Envía ahora un comando pendiente a los workers"},
+	"command.discarddeadletter":         {Other: "Descarta un comando pendiente, se le avisa a su usuario que no se ejecutará"},
+	"command.flags":                     {Other: "Muestra los comandos deshabilitados, los comandos en beta y el mantenimiento"},
+	"command.disablecommand":            {Other: "Deja de enviar un comando a los workers, a sus usuarios se les dice la razón"},
+	"command.enablecommand":             {Other: "Envía de nuevo a los workers un comando deshabilitado"},
+	"command.startbeta":                 {Other: "Solo deja ejecutar un comando a los usuarios permitidos con /allowbeta"},
+	"command.endbeta":                   {Other: "Deja que todos los usuarios ejecuten un comando en beta"},
+	"command.allowbeta":                 {Other: "Deja que un usuario del bot ejecute un comando en beta"},
+	"command.denybeta":                  {Other: "Impide que un usuario del bot ejecute un comando en beta"},
+	"command.maintenance":               {Other: "Deja de enviar todos los comandos a los workers, a los usuarios se les envía el mensaje"},
+	"command.endmaintenance":            {Other: "Envía de nuevo los comandos a los workers"},
+
+	"language.current": {Other: "Te respondo en {language}, envía /{command} <código> para cambiarlo. Idiomas disponibles: {languages}"},
+	"language.changed": {Other: "Listo, te responderé en {language}"},
+	"language.unknown": {Other: "Idioma desconocido {language}, usa uno de {languages}"},
+	"language.name.en": {Other: "inglés"},
+	"language.name.es": {Other: "español"},
+
+	"notifications.on":            {Other: "activas"},
+	"notifications.muted":         {Other: "silenciadas"},
+	"notifications.footer":        {Other: "Envía /{mute} <evento> o /{unmute} <evento> para cambiarlas"},
+	"notifications.muted_event":   {Other: "Silencié las notificaciones {event}"},
+	"notifications.unmuted_event": {Other: "Activé las notificaciones {event}"},
+	"notifications.unknown_event": {Other: "Evento desconocido {event}, usa uno de {events}"},
+
+	"parse.unterminated_quote": {Other: "comillas sin cerrar"},
+	"parse.invalid_flag":       {Other: "opción inválida"},
+	"parse.unknown_flag":       {Other: "opción desconocida {flag}"},
+	"parse.missing_value":      {Other: "la opción {flag} requiere un valor"},
+	"parse.unexpected_value":   {Other: "la opción {flag} no recibe un valor"},
+	"parse.repeated_flag":      {Other: "la opción {flag} solo se puede usar una vez"},
+
+	"policy.not_registered": {Other: "No estás registrado, por favor envía /{command} para verificar tu email"},
+	"policy.not_allowed":    {Other: "No tienes permiso para ejecutar /{command}"},
+
+	"register.already_registered": {Other: "Ya estás registrado como {email}"},
+	"register.ask_email":          {Other: "¿Cuál es tu email? envía /cancel para detenerte"},
+	"register.canceled":           {Other: "Registro cancelado"},
+	"register.invalid_code":       {Other: "Código inválido, por favor intenta de nuevo o envía {resend} para recibir uno nuevo"},
+	"register.code_expired":       {Other: "El código ya no es válido, envía {resend} para recibir uno nuevo"},
+	"register.verified":           {Other: "Tu email {email} está verificado, envía /{command} para ver los comandos disponibles"},
+	"register.not_added":          {Other: "Tu email {email} está verificado pero no fue agregado al bot, por favor pídele a un administrador que te agregue"},
+	"register.invalid_email":      {Other: "Por favor envía un email válido, p. ej. nombre@truora.com"},
+	"register.cooldown":           {Other: "Por favor espera {cooldown} antes de pedir un código nuevo"},
+	"register.too_many_codes":     {Other: "Pediste demasiados códigos, por favor intenta de nuevo en una hora"},
+	"register.email_subject":      {Other: "Tu código de verificación de Betty"},
+	"register.email_body":         {Other: "Tu código para vincular tu cuenta de Telegram es {code}, solo se puede usar una vez"},
+	"register.send_failed":        {Other: "No pude enviar el código, por favor intenta más tarde"},
+	"register.code_sent":          {Other: "Envié un código a {email}, por favor envíalo aquí. Envía {resend} para recibir uno nuevo o /cancel para detenerte"},
+
+	"registry.unknown_command":  {Other: "Comando desconocido /{command}, envía /{help} para ver los comandos disponibles"},
+	"registry.chat_not_allowed": {Other: "/{command} no se puede usar en este chat"},
+	"registry.missing_args":     {Other: "faltan argumentos obligatorios, uso: {usage}"},
+	"registry.too_many_args":    {Other: "demasiados argumentos, uso: {usage}"},
+
+	"router.button_expired":      {Other: "Este botón expiró, por favor envía el comando de nuevo"},
+	"router.conversation_failed": {Other: "No pude continuar la conversación, por favor intenta enviar un comando"},
+	"router.missing_args":        {Other: "Por favor agrega los argumentos del comando"},
+
+	"schedule.invalid_duration": {Other: "usa una duración entre {min} y {max}, p. ej. 30m o 2h"},
+	"schedule.invalid_delay":    {Other: "Espera inválida {delay}, {reason}"},
+	"schedule.invalid_interval": {Other: "Intervalo inválido {interval}, {reason}"},
+	"schedule.too_many":         {Other: "Tienes demasiados comandos programados, cancela uno con /{command} <id>"},
+	"schedule.scheduled":        {Other: "Programé {text} en {delay} (id: {id}), envía /{command} {id} para cancelarlo"},
+	"schedule.scheduled_every":  {Other: "Programé {text} en {delay} y luego cada {every} (id: {id}), envía /{command} {id} para cancelarlo"},
+	"schedule.missing_slash":    {Other: "El comando a programar debe empezar con /"},
+	"schedule.missing_args":     {Other: "Agrega los argumentos para programarlo, uso: {usage}"},
+	"schedule.not_schedulable":  {Other: "/{command} no se puede programar"},
+	"schedule.empty":            {Other: "No tienes comandos programados, envía /{command} para agregar uno"},
+	"schedule.list_header":      {Other: "Tus comandos programados:"},
+	"schedule.list_item":        {Other: "{id} en {delay}: {text}"},
+	"schedule.list_item_every":  {Other: "{id} en {delay}: {text} (cada {every})"},
+	"schedule.not_found":        {Other: "No tienes un comando programado con el id {id}, envía /{command} para verlos"},
+	"schedule.canceled":         {Other: "Cancelé el comando programado {id}"},
+
+	"slack.empty":          {Other: "Por favor envía un comando, p. ej. {root} deployterraformstaging checks/core"},
+	"slack.not_linked":     {Other: "Tu usuario de Slack no está vinculado a un usuario del bot, pídele a un administrador que vincule el usuario de Slack {slack_id} a tu correo"},
+	"slack.user_failed":    {Other: "No se pudo encontrar tu usuario del bot, por favor intenta de nuevo"},
+	"slack.command_failed": {Other: "No se pudo ejecutar /{command}, por favor intenta de nuevo"},
+
+	"upload.not_accepted":     {Other: "/{command} no acepta archivos"},
+	"upload.required":         {Other: "/{command} necesita un archivo, envíalo con el comando como descripción"},
+	"upload.too_large":        {Other: "El archivo es demasiado grande, el límite es {limit}"},
+	"upload.type_not_allowed": {Other: "No se aceptan archivos de tipo {type}, envía uno de {types}"},
+	"upload.failed":           {Other: "No pude leer el archivo, por favor envíalo de nuevo"},
+
+	"wizard.empty_answer":                   {Other: "la respuesta no puede estar vacía"},
+	"wizard.yes_or_no":                      {Other: "por favor responde sí o no"},
+	"wizard.deployterraformstaging.project": {Other: "¿Qué proyecto de terraform quieres desplegar? p. ej. checks/core"},
+	"wizard.deployterraformstaging.branch":  {Other: "¿Qué rama? envía {skip} para usar la rama por defecto"},
+	"wizard.deployterraformstaging.confirm": {Other: "¿Quieres desplegarlo en staging? sí/no"},
+
+	"conversation.timed_out":           {Other: "La conversación expiró, por favor envía el comando de nuevo"},
+	"conversation.canceled":            {Other: "Cancelado"},
+	"conversation.upload_required":     {Other: "por favor envía un archivo"},
+	"conversation.upload_not_expected": {Other: "por favor responde con texto, no con un archivo"},
+}
@@ -0,0 +1,228 @@
+// Package i18n translates the replies of the bot to the language of the user. The messages are declared
+// in a catalog by language and key, they can have {name} placeholders and a singular form for a count of one
+package i18n
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"bitbucket.org/truora/scrap-services/shared/env"
+)
+
+// Language is the ISO 639-1 code of a supported language
+type Language string
+
+const (
+	// English is the language of the messages missing in other catalogs
+	English Language = "en"
+	// Spanish language
+	Spanish Language = "es"
+
+	// countVar is the placeholder that selects the singular form of a message
+	countVar = "count"
+)
+
+// DefaultLanguage is used when the user has no preference and Telegram does not send a supported language
+var DefaultLanguage = defaultLanguage(env.GetString("BOT_DEFAULT_LANGUAGE", string(English)))
+
+// Vars are the values of the placeholders of a message
+type Vars map[string]interface{}
+
+// Message is a translated message, One is used when the count placeholder is 1 and it is not empty
+type Message struct {
+	One   string
+	Other string
+}
+
+// Catalog has the messages of a language by key
+type Catalog map[string]Message
+
+var catalogs = map[Language]Catalog{
+	English: english,
+	Spanish: spanish,
+}
+
+// Languages returns the supported languages sorted by code
+func Languages() []Language {
+	languages := make([]Language, 0, len(catalogs))
+
+	for language := range catalogs {
+		languages = append(languages, language)
+	}
+
+	sort.Slice(languages, func(i, j int) bool { return languages[i] < languages[j] })
+
+	return languages
+}
+
+// Catalogs returns the catalog of each supported language
+func Catalogs() map[Language]Catalog {
+	return catalogs
+}
+
+// Parse returns the supported language of an IETF language tag, e.g. es-CO, and false if it is not supported
+func Parse(code string) (Language, bool) {
+	code = strings.ToLower(strings.TrimSpace(code))
+	primary, _, _ := strings.Cut(strings.ReplaceAll(code, "_", "-"), "-")
+
+	language := Language(primary)
+	_, ok := catalogs[language]
+
+	return language, ok
+}
+
+// defaultLanguage returns the configured default language, English is used when it is not supported
+func defaultLanguage(code string) Language {
+	if language, ok := Parse(code); ok {
+		return language
+	}
+
+	return English
+}
+
+// Resolve returns the first supported language of the codes, the default language is returned when none is supported
+func Resolve(codes ...string) Language {
+	for _, code := range codes {
+		if language, ok := Parse(code); ok {
+			return language
+		}
+	}
+
+	return DefaultLanguage
+}
+
+// Translate returns the message of the key in the language with its placeholders replaced, the English
+// message is used when the language does not have the key and the key itself when no catalog has it
+func Translate(language Language, key string, vars Vars) string {
+	message, ok := catalogs[language][key]
+	if !ok {
+		message, ok = english[key]
+	}
+
+	if !ok {
+		return key
+	}
+
+	text := message.Other
+	if message.One != "" && isOne(vars[countVar]) {
+		text = message.One
+	}
+
+	return format(text, vars)
+}
+
+func format(text string, vars Vars) string {
+	if len(vars) == 0 {
+		return text
+	}
+
+	replacements := make([]string, 0, 2*len(vars))
+
+	for name, value := range vars {
+		replacements = append(replacements, "{"+name+"}", fmt.Sprint(value))
+	}
+
+	return strings.NewReplacer(replacements...).Replace(text)
+}
+
+func isOne(count interface{}) bool {
+	switch value := count.(type) {
+	case int:
+		return value == 1
+	case int64:
+		return value == 1
+	case int32:
+		return value == 1
+	case uint:
+		return value == 1
+	case uint64:
+		return value == 1
+	case float64:
+		return value == 1
+	}
+
+	return false
+}
+
+type contextKey struct{}
+
+// lazyLanguage resolves the language the first time a message is translated, so the replies that are
+// never sent do not look up the user preference
+type lazyLanguage struct {
+	once     sync.Once
+	resolve  func() Language
+	language Language
+}
+
+func (lazy *lazyLanguage) get() Language {
+	lazy.once.Do(func() {
+		lazy.language = lazy.resolve()
+	})
+
+	return lazy.language
+}
+
+// WithLanguage returns a context whose messages are translated to the language
+func WithLanguage(ctx context.Context, language Language) context.Context {
+	return WithResolver(ctx, func() Language { return language })
+}
+
+// WithResolver returns a context whose messages are translated to the language returned by resolve,
+// it is only called once when the first message is translated
+func WithResolver(ctx context.Context, resolve func() Language) context.Context {
+	return context.WithValue(ctx, contextKey{}, &lazyLanguage{resolve: resolve})
+}
+
+// FromContext returns the language of the context or the default language when it has none
+func FromContext(ctx context.Context) Language {
+	lazy, ok := ctx.Value(contextKey{}).(*lazyLanguage)
+	if !ok {
+		return Resolve()
+	}
+
+	return lazy.get()
+}
+
+// Text translates the key to the language of the context
+func Text(ctx context.Context, key string, vars Vars) string {
+	return Translate(FromContext(ctx), key, vars)
+}
+
+// Error is an error whose message is in the catalog, Error returns it in English for the logs and
+// ErrorText translates it for the user
+type Error struct {
+	Key  string
+	Vars Vars
+}
+
+// NewError creates an error with the message of the key
+func NewError(key string, vars Vars) *Error {
+	return &Error{Key: key, Vars: vars}
+}
+
+// Error returns the message in English
+func (err *Error) Error() string {
+	return Translate(English, err.Key, err.Vars)
+}
+
+// Is allows to use errors.Is with errors of the same key
+func (err *Error) Is(target error) bool {
+	targetErr, ok := target.(*Error)
+
+	return ok && targetErr.Key == err.Key
+}
+
+// ErrorText translates the error to the language of the context when it is or wraps an Error, the
+// error message is returned otherwise
+func ErrorText(ctx context.Context, err error) string {
+	var translated *Error
+	if errors.As(err, &translated) {
+		return Text(ctx, translated.Key, translated.Vars)
+	}
+
+	return err.Error()
+}
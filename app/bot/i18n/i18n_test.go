@@ -0,0 +1,118 @@
+package i18n
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var placeholderRegex = regexp.MustCompile(`\{[a-z_]+\}`)
+
+func placeholders(text string) []string {
+	found := placeholderRegex.FindAllString(text, -1)
+	sort.Strings(found)
+
+	return found
+}
+
+func TestCatalogsHaveTheSameMessages(t *testing.T) {
+	c := require.New(t)
+
+	for _, language := range Languages() {
+		catalog := Catalogs()[language]
+
+		c.Len(catalog, len(english), "%s catalog has a different number of messages", language)
+
+		for key, message := range english {
+			translated, ok := catalog[key]
+			c.True(ok, "%s catalog is missing %s", language, key)
+			c.NotEmpty(translated.Other, "%s catalog has an empty %s", language, key)
+			c.Equal(placeholders(message.Other), placeholders(translated.Other), "%s catalog has different placeholders in %s", language, key)
+			c.Equal(message.One == "", translated.One == "", "%s catalog has a different singular form in %s", language, key)
+		}
+
+		for _, code := range Languages() {
+			_, ok := catalog["language.name."+string(code)]
+			c.True(ok, "%s catalog is missing the name of %s", language, code)
+		}
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	c := require.New(t)
+
+	c.Equal("Accepted /deploy (id: 1a2b)", Translate(English, "ack.accepted", Vars{"command": "deploy", "id": "1a2b"}))
+	c.Equal("Recibí /deploy (id: 1a2b)", Translate(Spanish, "ack.accepted", Vars{"command": "deploy", "id": "1a2b"}))
+
+	// unsupported languages are answered in English and unknown keys are returned as they are
+	c.Equal("Accepted /deploy (id: 1a2b)", Translate("fr", "ack.accepted", Vars{"command": "deploy", "id": "1a2b"}))
+	c.Equal("Which project?", Translate(Spanish, "Which project?", nil))
+
+	// unknown placeholders are left as they are
+	c.Equal("Accepted /{command} (id: 1a2b)", Translate(English, "ack.accepted", Vars{"id": "1a2b"}))
+}
+
+func TestTranslatePlural(t *testing.T) {
+	c := require.New(t)
+
+	vars := Vars{"command": "deploy", "id": "1a2b", "count": 1}
+	c.Equal("You approved /deploy (id: 1a2b), it needs 1 more approval", Translate(English, "approval.approved_pending", vars))
+	c.Equal("Aprobaste /deploy (id: 1a2b), necesita 1 aprobación más", Translate(Spanish, "approval.approved_pending", vars))
+
+	vars["count"] = int64(2)
+	c.Equal("You approved /deploy (id: 1a2b), it needs 2 more approvals", Translate(English, "approval.approved_pending", vars))
+
+	vars["count"] = 0
+	c.Equal("Aprobaste /deploy (id: 1a2b), necesita 0 aprobaciones más", Translate(Spanish, "approval.approved_pending", vars))
+}
+
+func TestResolve(t *testing.T) {
+	c := require.New(t)
+
+	c.Equal(Spanish, Resolve("es-CO"))
+	c.Equal(English, Resolve("EN_us"))
+	c.Equal(Spanish, Resolve("", "pt-BR", "es"))
+	c.Equal(DefaultLanguage, Resolve("pt-BR"))
+	c.Equal(DefaultLanguage, Resolve())
+
+	_, ok := Parse("fr")
+	c.False(ok)
+}
+
+func TestFromContext(t *testing.T) {
+	c := require.New(t)
+
+	ctx := context.Background()
+	c.Equal(DefaultLanguage, FromContext(ctx))
+
+	resolved := 0
+	ctx = WithResolver(ctx, func() Language {
+		resolved++
+
+		return Spanish
+	})
+
+	c.Equal("Cancelado", Text(ctx, "conversation.canceled", nil))
+	c.Equal(Spanish, FromContext(ctx))
+	c.Equal(1, resolved)
+
+	c.Equal("Canceled", Text(WithLanguage(ctx, English), "conversation.canceled", nil))
+}
+
+func TestErrorText(t *testing.T) {
+	c := require.New(t)
+
+	err := NewError("wizard.yes_or_no", nil)
+	ctx := WithLanguage(context.Background(), Spanish)
+
+	c.Equal("please answer yes or no", err.Error())
+	c.Equal("por favor responde sí o no", ErrorText(ctx, err))
+	c.Equal("por favor responde sí o no", ErrorText(ctx, fmt.Errorf("validate failed: %w", err)))
+	c.ErrorIs(fmt.Errorf("validate failed: %w", err), NewError("wizard.yes_or_no", nil))
+	c.Equal("unknown", ErrorText(ctx, errors.New("unknown")))
+}
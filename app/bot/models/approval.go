@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+)
+
+// ApprovalRequest is a command parked until enough users approve it
+type ApprovalRequest struct {
+	ID string `json:"id"`
+	// Command is the name of the parked command
+	Command string `json:"command"`
+	// Required is the number of distinct approvals needed to publish the command
+	Required int `json:"required"`
+	// Message is published when the command is approved, it keeps the user and chat that sent it
+	Message   CallbackMessage `json:"message"`
+	CreatedAt time.Time       `json:"created_at"`
+	ExpiresAt time.Time       `json:"expires_at"`
+	// Prompts are the messages sent to the approvers, they are edited when the request is resolved
+	Prompts []ApprovalPrompt `json:"prompts"`
+}
+
+// ApprovalPrompt is the message with the approval buttons sent to an approver
+type ApprovalPrompt struct {
+	ChatID    int64 `json:"chat_id"`
+	MessageID int   `json:"message_id"`
+}
+
+// RequesterID returns the Telegram ID of the user that sent the command
+func (request *ApprovalRequest) RequesterID() int64 {
+	return request.Message.From.ID
+}
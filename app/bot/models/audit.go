@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+)
+
+// AuditOutcome is how the router finished routing a command
+type AuditOutcome string
+
+const (
+	// OutcomePublished when the command was sent to the workers
+	OutcomePublished AuditOutcome = "published"
+	// OutcomeDelayed when the command was parked to be sent to the workers later
+	OutcomeDelayed AuditOutcome = "delayed"
+	// OutcomeAwaitingApproval when the command was parked until enough users approve it
+	OutcomeAwaitingApproval AuditOutcome = "awaiting_approval"
+	// OutcomeHandled when the command was answered by the router
+	OutcomeHandled AuditOutcome = "handled"
+	// OutcomeInvalid when the command, its arguments or its chat were rejected
+	OutcomeInvalid AuditOutcome = "invalid"
+	// OutcomeDenied when the user is not allowed to run the command
+	OutcomeDenied AuditOutcome = "denied"
+	// OutcomeDisabled when the command was disabled or the bot was in maintenance
+	OutcomeDisabled AuditOutcome = "disabled"
+	// OutcomeFailed when the routing of the command failed
+	OutcomeFailed AuditOutcome = "failed"
+)
+
+// AuditRecord is the record of a command routed by the bot
+type AuditRecord struct {
+	ID            string            `json:"id"`
+	UserID        int64             `json:"user_id"`
+	Username      string            `json:"username"`
+	Email         string            `json:"email"`
+	ChatID        int64             `json:"chat_id"`
+	Command       string            `json:"command"`
+	Args          map[string]string `json:"args,omitempty"`
+	CorrelationID string            `json:"correlation_id,omitempty"`
+	Outcome       AuditOutcome      `json:"outcome"`
+	Error         string            `json:"error,omitempty"`
+	Latency       time.Duration     `json:"latency"`
+	CreatedAt     time.Time         `json:"created_at"`
+}
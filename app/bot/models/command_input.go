@@ -0,0 +1,24 @@
+package models
+
+// CommandInput is the structured input of a command already parsed by the router
+type CommandInput struct {
+	Name       string              `json:"name"`
+	Positional []string            `json:"positional"`
+	Flags      map[string][]string `json:"flags"`
+	KeyValues  map[string]string   `json:"key_values"`
+}
+
+// Flag returns the last value received for the flag
+func (input *CommandInput) Flag(name string) string {
+	values := input.Flags[name]
+	if len(values) == 0 {
+		return ""
+	}
+
+	return values[len(values)-1]
+}
+
+// HasFlag returns true if the flag was received
+func (input *CommandInput) HasFlag(name string) bool {
+	return len(input.Flags[name]) > 0
+}
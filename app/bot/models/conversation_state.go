@@ -2,7 +2,23 @@ package models
 
 // ConversationState data stored in Betty cache to follow up conversations
 type ConversationState struct {
+	// Version of the schema used to store the state, states with an unknown version are discarded
+	Version        int               `json:"version"`
 	Command        string            `json:"command"`
 	Data           string            `json:"data"`
 	AdditionalData map[string]string `json:"additional_data"`
+	// Wizard is only present when the conversation is a multi-step wizard
+	Wizard *WizardState `json:"wizard,omitempty"`
+}
+
+// WizardState is the progress of a multi-step wizard
+type WizardState struct {
+	Step string `json:"step"`
+	// History are the steps already answered, used to go back
+	History []string          `json:"history"`
+	Answers map[string]string `json:"answers"`
+	// Uploads are the files sent as answers, the answer of their step is the file ID
+	Uploads []*Upload `json:"uploads,omitempty"`
+	// ExpiresAt is the unix time when the current step times out
+	ExpiresAt int64 `json:"expires_at"`
 }
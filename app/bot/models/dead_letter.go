@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+)
+
+// DeadLetter is a routed message that could not be published to SNS, it is published again with backoff
+type DeadLetter struct {
+	ID string `json:"id"`
+	// Topic and Attribute are the SNS topic and the command attribute used to publish the message
+	Topic     string          `json:"topic"`
+	Attribute string          `json:"attribute"`
+	Message   CallbackMessage `json:"message"`
+	// Attempts counts the failed publishes, including the publish of the router
+	Attempts  int       `json:"attempts"`
+	LastError string    `json:"last_error"`
+	CreatedAt time.Time `json:"created_at"`
+	// NextAttemptAt is zero when the message ran out of attempts, it is kept until an admin retries or discards it
+	NextAttemptAt time.Time `json:"next_attempt_at"`
+}
+
+// IsExhausted returns true if the message is no longer published again automatically
+func (letter *DeadLetter) IsExhausted() bool {
+	return letter.NextAttemptAt.IsZero()
+}
@@ -2,7 +2,6 @@ package models
 
 import (
 	"time"
-
 )
 
 // CallbackMessage received from Telegram when a callback is triggered
@@ -13,34 +12,110 @@ type CallbackMessage struct {
 	Data           string
 	Command        string
 	AdditionalData map[string]string
+	// Args are the command arguments named as declared in the command registry
+	Args map[string]string
+	// Input is the structured input of the command with flags and key=value pairs
+	Input *CommandInput
+	// EventType is the type of the Telegram update that originated the message
+	EventType EventType
+	// InlineQuery is only present for inline_query updates
+	InlineQuery *InlineQuery
+	// ChatMember is only present for my_chat_member updates
+	ChatMember *ChatMemberUpdated
+	// CorrelationID identifies the routed command, workers copy it to their results
+	CorrelationID string
+	// AckMessageID is the accepted acknowledgement sent by the router, results edit it in place
+	AckMessageID int
+	// Slack is only present for the commands received from Slack
+	Slack *SlackContext
+	// Uploads are the files sent with the command or as answers of its wizard, already checked by the router
+	Uploads []*Upload
+}
+
+// SlackContext is where a command received from Slack was sent, the results are posted to its response URL
+type SlackContext struct {
+	TeamID    string `json:"team_id"`
+	UserID    string `json:"user_id"`
+	ChannelID string `json:"channel_id"`
+	// ResponseURL accepts the answers of the command for 30 minutes
+	ResponseURL string `json:"response_url"`
+	TriggerID   string `json:"trigger_id"`
 }
 
 // Message is the entire information about a message
 type Message struct {
-	ID     int            `json:"message_id"`
-	Text   string         `json:"text"`
-	Images []*PhotoUpload `json:"photo"`
-	From   From
-	Chat   Chat
+	ID       int            `json:"message_id"`
+	Text     string         `json:"text"`
+	Images   []*PhotoUpload `json:"photo"`
+	Document *Document      `json:"document"`
+	Caption  string         `json:"caption"`
+	From     From
+	Chat     Chat
+	// ReplyToMessage is the original message when this message is a reply
+	ReplyToMessage *Message `json:"reply_to_message"`
+}
+
+// IsGroup returns true if the message was sent in a group or supergroup
+func (message Message) IsGroup() bool {
+	return message.Chat.Type == "group" || message.Chat.Type == "supergroup"
+}
+
+// Upload returns the file sent with the message, the largest size of a photo or the document. Nil when
+// the message has no file
+func (message Message) Upload() *Upload {
+	if message.Document != nil {
+		return &Upload{
+			Type:         UploadDocument,
+			FileID:       message.Document.FileID,
+			FileUniqueID: message.Document.FileUniqueID,
+			FileName:     message.Document.FileName,
+			MimeType:     message.Document.MimeType,
+			FileSize:     message.Document.FileSize,
+		}
+	}
+
+	if len(message.Images) == 0 {
+		return nil
+	}
+
+	// Telegram sends the sizes of a photo from the smallest to the largest
+	photo := message.Images[len(message.Images)-1]
+
+	return &Upload{
+		Type:         UploadPhoto,
+		FileID:       photo.FileID,
+		FileUniqueID: photo.FileUniqueID,
+		MimeType:     PhotoMimeType,
+		FileSize:     photo.FileSize,
+	}
 }
 
 // PhotoUpload is the different sizes for an uploaded image
 type PhotoUpload struct {
-	FileID string `json:"file_id"`
+	FileID       string `json:"file_id"`
+	FileUniqueID string `json:"file_unique_id"`
+	Width        int    `json:"width"`
+	Height       int    `json:"height"`
+	FileSize     int64  `json:"file_size"`
 }
 
 // From is where the message is coming
 type From struct {
-	ID             int64             `json:"id"`
-	IsBot          bool              `json:"is_bot"`
-	Email          string            `json:"email"`
-	FirstName      string            `json:"first_name"`
-	LastName       string            `json:"last_name"`
-	Username       string            `json:"username"`
-	EmailVerified  bool              `json:"email_verified"`
-	ExpirationTime int64             `json:"expiration_time"`
-	CreationDate   time.Time         `json:"creation_date"`
-	PhoneNumber    string            `json:"phone_number"`
+	ID             int64     `json:"id"`
+	IsBot          bool      `json:"is_bot"`
+	Email          string    `json:"email"`
+	FirstName      string    `json:"first_name"`
+	LastName       string    `json:"last_name"`
+	Username       string    `json:"username"`
+	EmailVerified  bool      `json:"email_verified"`
+	ExpirationTime int64     `json:"expiration_time"`
+	CreationDate   time.Time `json:"creation_date"`
+	PhoneNumber    string    `json:"phone_number"`
+	BitbucketID    string    `json:"bitbucket_account_id,omitempty"`
+	SlackID        string    `json:"slack_id,omitempty"`
+	UserRole       string    `json:"user_role"`
+	// LanguageCode is the IETF language tag of the Telegram client of the user, e.g. es or en-US
+	LanguageCode string `json:"language_code"`
 }
 
 // Chat contains information about chat
@@ -50,4 +125,5 @@ type Chat struct {
 	LastName    string `json:"last_name"`
 	PhoneNumber string `json:"phone_number"`
 	Type        string `json:"type"`
+	Title       string `json:"title"`
 }
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessageIsGroup(t *testing.T) {
+	c := require.New(t)
+
+	c.True(Message{Chat: Chat{Type: "group"}}.IsGroup())
+	c.True(Message{Chat: Chat{Type: "supergroup"}}.IsGroup())
+	c.False(Message{Chat: Chat{Type: "private"}}.IsGroup())
+	c.False(Message{Chat: Chat{Type: "channel"}}.IsGroup())
+}
+
+func TestMessageUpload(t *testing.T) {
+	c := require.New(t)
+
+	c.Nil(Message{Text: "/deploy"}.Upload())
+
+	upload := Message{Images: []*PhotoUpload{{FileID: "small", FileSize: 10}, {FileID: "large", FileUniqueID: "unique", FileSize: 100}}}.Upload()
+	c.Equal(&Upload{Type: UploadPhoto, FileID: "large", FileUniqueID: "unique", MimeType: PhotoMimeType, FileSize: 100}, upload)
+
+	upload = Message{Document: &Document{FileID: "file", FileName: "report.pdf", MimeType: "application/pdf", FileSize: 2048}}.Upload()
+	c.Equal(UploadDocument, upload.Type)
+	c.Equal("report.pdf", upload.FileName)
+	c.Equal("application/pdf", upload.MimeType)
+	c.Equal(int64(2048), upload.FileSize)
+}
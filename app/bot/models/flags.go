@@ -0,0 +1,37 @@
+package models
+
+// Flags are the switches of the commands that the admins change at runtime, e.g. during an incident
+type Flags struct {
+	// Maintenance is sent instead of publishing any command, it is empty when the bot is not in maintenance
+	Maintenance string
+	// Disabled has the reason each disabled command is not published, the reason can be empty
+	Disabled map[string]string
+	// Betas has the users allowed to run each command in beta by Telegram ID with their email
+	Betas map[string]map[int64]string
+}
+
+// IsDisabled returns true if the command is disabled and the reason given by the admin
+func (flags *Flags) IsDisabled(command string) (string, bool) {
+	reason, ok := flags.Disabled[command]
+
+	return reason, ok
+}
+
+// IsBeta returns true if only the allowed users can run the command
+func (flags *Flags) IsBeta(command string) bool {
+	_, ok := flags.Betas[command]
+
+	return ok
+}
+
+// AllowsUser returns true if the user can run the command, every user can run the commands not in beta
+func (flags *Flags) AllowsUser(command string, userID int64) bool {
+	users, ok := flags.Betas[command]
+	if !ok {
+		return true
+	}
+
+	_, ok = users[userID]
+
+	return ok
+}
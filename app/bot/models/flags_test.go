@@ -0,0 +1,33 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFlags(t *testing.T) {
+	c := require.New(t)
+
+	flags := &Flags{
+		Disabled: map[string]string{"deploy": "broken pipeline"},
+		Betas:    map[string]map[int64]string{"report": {10: "dummy_email@dummy.com"}},
+	}
+
+	reason, disabled := flags.IsDisabled("deploy")
+	c.True(disabled)
+	c.Equal("broken pipeline", reason)
+
+	_, disabled = flags.IsDisabled("report")
+	c.False(disabled)
+
+	c.True(flags.IsBeta("report"))
+	c.False(flags.IsBeta("deploy"))
+
+	c.True(flags.AllowsUser("report", 10))
+	c.False(flags.AllowsUser("report", 11))
+	c.True(flags.AllowsUser("deploy", 11))
+
+	// the zero value has no flags
+	c.True((&Flags{}).AllowsUser("report", 11))
+}
@@ -0,0 +1,37 @@
+package models
+
+// NotificationEvent is a type of event the bot notifies to the users, the users can mute each type
+type NotificationEvent string
+
+const (
+	// NotificationPROpened when the user is a reviewer of a new pull request
+	NotificationPROpened NotificationEvent = "pr_opened"
+	// NotificationPRApproved when a pull request of the user is approved
+	NotificationPRApproved NotificationEvent = "pr_approved"
+	// NotificationPRMerged when a pull request of the user or reviewed by the user is merged
+	NotificationPRMerged NotificationEvent = "pr_merged"
+	// NotificationBuildFailed when a build of a commit of the user fails
+	NotificationBuildFailed NotificationEvent = "build_failed"
+	// NotificationMention when the user is mentioned in a pull request comment
+	NotificationMention NotificationEvent = "mention"
+)
+
+// NotificationEvents are the event types the users can mute
+var NotificationEvents = []NotificationEvent{
+	NotificationPROpened,
+	NotificationPRApproved,
+	NotificationPRMerged,
+	NotificationBuildFailed,
+	NotificationMention,
+}
+
+// IsNotificationEvent returns true if the name is a known notification event type
+func IsNotificationEvent(name string) bool {
+	for _, event := range NotificationEvents {
+		if string(event) == name {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,16 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsNotificationEvent(t *testing.T) {
+	c := require.New(t)
+
+	c.True(IsNotificationEvent("pr_merged"))
+	c.True(IsNotificationEvent(string(NotificationMention)))
+	c.False(IsNotificationEvent("pr_declined"))
+	c.False(IsNotificationEvent(""))
+}
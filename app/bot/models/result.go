@@ -0,0 +1,66 @@
+package models
+
+// AttachmentType is the type of a file attached to a result
+type AttachmentType string
+
+const (
+	// AttachmentPhoto is sent as a photo
+	AttachmentPhoto AttachmentType = "photo"
+	// AttachmentDocument is sent as a general file
+	AttachmentDocument AttachmentType = "document"
+)
+
+// Result is the envelope published by the workers to the bot results topic to answer a command
+type Result struct {
+	// CorrelationID is the ID of the routed command the result answers
+	CorrelationID string `json:"correlation_id"`
+	ChatID        int64  `json:"chat_id"`
+	// UserID is the user allowed to press the keyboard buttons
+	UserID int64  `json:"user_id"`
+	Text   string `json:"text"`
+	// ParseMode of the text, e.g. MarkdownV2 or HTML. Empty sends plain text
+	ParseMode string `json:"parse_mode,omitempty"`
+	// EditMessageID is the message edited with the result, usually the accepted acknowledgement
+	EditMessageID    int          `json:"edit_message_id,omitempty"`
+	ReplyToMessageID int          `json:"reply_to_message_id,omitempty"`
+	Keyboard         [][]Button   `json:"keyboard,omitempty"`
+	Attachments      []Attachment `json:"attachments,omitempty"`
+	// SlackResponseURL posts the result to Slack instead of Telegram, it is set for the commands received from Slack
+	SlackResponseURL string `json:"slack_response_url,omitempty"`
+}
+
+// Button is an inline keyboard button, it runs the command with the data or opens the URL
+type Button struct {
+	Text    string `json:"text"`
+	Command string `json:"command,omitempty"`
+	Data    string `json:"data,omitempty"`
+	URL     string `json:"url,omitempty"`
+	// SingleUse command buttons are rejected after they were pressed once
+	SingleUse bool `json:"single_use,omitempty"`
+}
+
+// Attachment is a file sent after the result text, by Telegram file ID or URL
+type Attachment struct {
+	Type    AttachmentType `json:"type"`
+	FileID  string         `json:"file_id,omitempty"`
+	URL     string         `json:"url,omitempty"`
+	Caption string         `json:"caption,omitempty"`
+}
+
+// NewResult creates the result of the routed message, it edits the accepted acknowledgement when there is one
+func NewResult(message *CallbackMessage, text string) *Result {
+	result := &Result{
+		CorrelationID:    message.CorrelationID,
+		ChatID:           message.Message.Chat.ID,
+		UserID:           message.From.ID,
+		Text:             text,
+		EditMessageID:    message.AckMessageID,
+		ReplyToMessageID: message.Message.ID,
+	}
+
+	if message.Slack != nil {
+		result.SlackResponseURL = message.Slack.ResponseURL
+	}
+
+	return result
+}
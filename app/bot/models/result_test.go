@@ -0,0 +1,39 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewResult(t *testing.T) {
+	c := require.New(t)
+
+	message := &CallbackMessage{
+		From:          From{ID: 1},
+		Message:       Message{ID: 10, Chat: Chat{ID: 2}},
+		CorrelationID: "correlation-id",
+		AckMessageID:  11,
+	}
+
+	c.Equal(&Result{
+		CorrelationID:    "correlation-id",
+		ChatID:           2,
+		UserID:           1,
+		Text:             "done",
+		EditMessageID:    11,
+		ReplyToMessageID: 10,
+	}, NewResult(message, "done"))
+}
+
+func TestNewResultFromSlack(t *testing.T) {
+	c := require.New(t)
+
+	message := &CallbackMessage{
+		From:    From{ID: 1},
+		Message: Message{Chat: Chat{ID: 1}},
+		Slack:   &SlackContext{ResponseURL: "https://hooks.slack.com/commands/1"},
+	}
+
+	c.Equal("https://hooks.slack.com/commands/1", NewResult(message, "done").SlackResponseURL)
+}
@@ -0,0 +1,11 @@
+package models
+
+import "bitbucket.org/truora/scrap-services/deployments/approval"
+
+// The bot roles are the deployment approval roles, the bot only adds the admin role on top of them
+const (
+	// RoleDevelopers is the role of the new users
+	RoleDevelopers = string(approval.RoleDevelopers)
+	// RoleAdmin users can run every command, including the administration ones
+	RoleAdmin = "admin"
+)
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+)
+
+// ScheduledCommand is a command that the bot runs on behalf of the user at FireAt
+type ScheduledCommand struct {
+	ID string `json:"id"`
+	// Text is the command as typed by the user, e.g. /deploy api prod
+	Text string `json:"text"`
+	// Every is the interval of the recurring commands, zero runs the command once
+	Every     time.Duration `json:"every,omitempty"`
+	FireAt    time.Time     `json:"fire_at"`
+	CreatedAt time.Time     `json:"created_at"`
+	// Message is the message routed when the command fires, it keeps the user and chat that scheduled it
+	Message CallbackMessage `json:"message"`
+}
+
+// IsRecurring returns true if the command runs again after firing
+func (command *ScheduledCommand) IsRecurring() bool {
+	return command.Every > 0
+}
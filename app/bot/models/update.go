@@ -0,0 +1,79 @@
+package models
+
+// EventType is the type of the update received from Telegram
+type EventType string
+
+const (
+	// EventMessage new incoming message
+	EventMessage EventType = "message"
+	// EventEditedMessage new version of a message that was edited
+	EventEditedMessage EventType = "edited_message"
+	// EventChannelPost new incoming channel post
+	EventChannelPost EventType = "channel_post"
+	// EventCallbackQuery new incoming callback query from an inline keyboard
+	EventCallbackQuery EventType = "callback_query"
+	// EventInlineQuery new incoming inline query
+	EventInlineQuery EventType = "inline_query"
+	// EventMyChatMember the bot member status was updated in a chat
+	EventMyChatMember EventType = "my_chat_member"
+	// EventDocument new incoming message with a document
+	EventDocument EventType = "document"
+)
+
+// Chat member statuses sent by Telegram
+const (
+	ChatMemberCreator       = "creator"
+	ChatMemberAdministrator = "administrator"
+	ChatMemberMember        = "member"
+	ChatMemberRestricted    = "restricted"
+	ChatMemberLeft          = "left"
+	ChatMemberKicked        = "kicked"
+)
+
+// Document is a general file sent in a message
+type Document struct {
+	FileID       string `json:"file_id"`
+	FileUniqueID string `json:"file_unique_id"`
+	FileName     string `json:"file_name"`
+	MimeType     string `json:"mime_type"`
+	FileSize     int64  `json:"file_size"`
+}
+
+// InlineQuery is a query typed after the bot username in any chat
+type InlineQuery struct {
+	ID       string `json:"id"`
+	From     From   `json:"from"`
+	Query    string `json:"query"`
+	Offset   string `json:"offset"`
+	ChatType string `json:"chat_type"`
+}
+
+// ChatMember is the status of a user in a chat
+type ChatMember struct {
+	User   From   `json:"user"`
+	Status string `json:"status"`
+}
+
+// ChatMemberUpdated is the change of the member status of a user in a chat
+type ChatMemberUpdated struct {
+	Chat          Chat       `json:"chat"`
+	From          From       `json:"from"`
+	Date          int64      `json:"date"`
+	OldChatMember ChatMember `json:"old_chat_member"`
+	NewChatMember ChatMember `json:"new_chat_member"`
+}
+
+// IsMember returns true if the status means the user is part of the chat
+func (member ChatMember) IsMember() bool {
+	return member.Status != ChatMemberLeft && member.Status != ChatMemberKicked && member.Status != ""
+}
+
+// Added returns true if the user joined the chat with this update
+func (update *ChatMemberUpdated) Added() bool {
+	return !update.OldChatMember.IsMember() && update.NewChatMember.IsMember()
+}
+
+// Removed returns true if the user left or was removed from the chat with this update
+func (update *ChatMemberUpdated) Removed() bool {
+	return update.OldChatMember.IsMember() && !update.NewChatMember.IsMember()
+}
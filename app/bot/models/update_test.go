@@ -0,0 +1,32 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestChatMemberUpdated(t *testing.T) {
+	c := require.New(t)
+
+	update := &ChatMemberUpdated{
+		OldChatMember: ChatMember{Status: ChatMemberLeft},
+		NewChatMember: ChatMember{Status: ChatMemberMember},
+	}
+	c.True(update.Added())
+	c.False(update.Removed())
+
+	update = &ChatMemberUpdated{
+		OldChatMember: ChatMember{Status: ChatMemberAdministrator},
+		NewChatMember: ChatMember{Status: ChatMemberKicked},
+	}
+	c.False(update.Added())
+	c.True(update.Removed())
+
+	update = &ChatMemberUpdated{
+		OldChatMember: ChatMember{Status: ChatMemberMember},
+		NewChatMember: ChatMember{Status: ChatMemberAdministrator},
+	}
+	c.False(update.Added())
+	c.False(update.Removed())
+}
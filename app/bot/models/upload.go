@@ -0,0 +1,29 @@
+package models
+
+// UploadType is the kind of file sent by the user
+type UploadType string
+
+const (
+	// UploadPhoto is a compressed image, Telegram always sends it as JPEG
+	UploadPhoto UploadType = "photo"
+	// UploadDocument is a general file sent as it is
+	UploadDocument UploadType = "document"
+
+	// PhotoMimeType is the type of every photo sent by Telegram
+	PhotoMimeType = "image/jpeg"
+)
+
+// Upload is a stable reference to a file sent by the user, workers download it with the FileID
+type Upload struct {
+	Type UploadType `json:"type"`
+	// FileID downloads the file with getFile, it only works with the bot that received it
+	FileID string `json:"file_id"`
+	// FileUniqueID is the same for the file over time and across bots, it can not download the file
+	FileUniqueID string `json:"file_unique_id"`
+	FileName     string `json:"file_name,omitempty"`
+	MimeType     string `json:"mime_type"`
+	FileSize     int64  `json:"file_size"`
+	// FilePath is returned by getFile and is valid for at least one hour, call getFile with the FileID
+	// to get a new one after that
+	FilePath string `json:"file_path"`
+}
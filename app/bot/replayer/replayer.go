@@ -0,0 +1,83 @@
+// Package replayer publishes again the routed messages parked in the dead-letter queue, it runs on every
+// tick of a scheduled Lambda and attempts the due messages with backoff
+package replayer
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"shared/app/bot/models"
+	"shared/app/bot/router"
+	"shared/app/bot/storage/deadletter"
+	"shared/app/bot/storage/queue"
+
+	"bitbucket.org/truora/scrap-services/logger"
+	"bitbucket.org/truora/scrap-services/shared/env"
+	"github.com/aws/aws-lambda-go/events"
+)
+
+var (
+	// maxPerTick limits the messages attempted by a tick so it finishes before the next one starts
+	maxPerTick = int(env.GetInt64("REPLAYER_MAX_PER_TICK", 50))
+
+	replay = router.ReplayDeadLetter
+	now    = time.Now
+)
+
+// Init initializes the cache and the topics used by the router
+func Init(ctx context.Context) {
+	router.Init(ctx)
+}
+
+// Handler is the handler of the replayer Lambda triggered every minute by an EventBridge rule
+func Handler(ctx context.Context, event events.CloudWatchEvent) error {
+	return Tick(ctx)
+}
+
+// Tick attempts the messages due now, it stops when there are no more due messages or another tick is
+// attempting them
+func Tick(ctx context.Context) error {
+	for attempted := 0; attempted < maxPerTick; attempted++ {
+		tickTime := now()
+
+		letter, err := deadletter.ClaimDue(ctx, tickTime)
+		if errors.Is(err, deadletter.ErrNoDueDeadLetter) || errors.Is(err, deadletter.ErrDeadLetterClaimed) {
+			return nil
+		}
+
+		if errors.Is(err, deadletter.ErrDeadLetterNotFound) {
+			continue
+		}
+
+		if errors.Is(err, queue.ErrDropped) {
+			logger.Get(ctx).Error(ctx, "dead_letter_dropped", logger.OneMonth, []logger.Object{
+				logger.ErrObject(err),
+			})
+
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+
+		attempt(ctx, letter, tickTime)
+	}
+
+	return nil
+}
+
+// attempt publishes the message, a failed attempt was already recorded by the router to be attempted later
+func attempt(ctx context.Context, letter *models.DeadLetter, tickTime time.Time) {
+	err := replay(ctx, letter, tickTime)
+	if err != nil {
+		logger.Get(ctx).Warning(ctx, "dead_letter_replay_failed", logger.OneMonth, []logger.Object{
+			logger.ErrObject(err),
+			logger.MapObject("dead_letter", map[string]interface{}{
+				"s_id":       letter.ID,
+				"i_attempts": letter.Attempts,
+			}),
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package replayer
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"shared/app/bot/models"
+	"shared/app/bot/router"
+	"shared/app/bot/storage/deadletter"
+	"shared/app/bot/storage/queue"
+
+	"bitbucket.org/truora/scrap-services/shared/cache"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/require"
+)
+
+func parkTestLetter(c *require.Assertions, parkedAt time.Time) *models.DeadLetter {
+	message := &models.CallbackMessage{From: models.From{ID: 10}, Message: models.Message{Text: "/deployterraformstaging checks/core"}}
+
+	letter, err := deadletter.Park(context.Background(), "commands-topic", "deployterraformstaging", message, errors.New("sns unavailable"), parkedAt)
+	c.NoError(err)
+
+	return letter
+}
+
+func mockReplay(err error) (*[]string, func()) {
+	replayed := []string{}
+
+	replay = func(ctx context.Context, letter *models.DeadLetter, tickTime time.Time) error {
+		replayed = append(replayed, letter.ID)
+
+		if err != nil {
+			return deadletter.Fail(ctx, letter, err, tickTime)
+		}
+
+		return deadletter.Delete(ctx, letter.ID)
+	}
+
+	return &replayed, func() { replay = router.ReplayDeadLetter }
+}
+
+func TestTick(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+
+	replayed, restore := mockReplay(nil)
+
+	defer restore()
+
+	ctx := context.Background()
+
+	due := parkTestLetter(c, time.Now().Add(-time.Hour))
+	parkTestLetter(c, time.Now())
+
+	err := Handler(ctx, events.CloudWatchEvent{})
+	c.NoError(err)
+	c.Equal([]string{due.ID}, *replayed)
+
+	_, err = deadletter.Get(ctx, due.ID)
+	c.ErrorIs(err, deadletter.ErrDeadLetterNotFound)
+}
+
+func TestTickBackoff(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+
+	replayed, restore := mockReplay(errors.New("sns unavailable"))
+
+	defer restore()
+
+	ctx := context.Background()
+	letter := parkTestLetter(c, time.Now().Add(-time.Hour))
+
+	c.NoError(Tick(ctx))
+	c.Len(*replayed, 1)
+
+	// the failed message waits for its backoff
+	c.NoError(Tick(ctx))
+	c.Len(*replayed, 1)
+
+	stored, err := deadletter.Get(ctx, letter.ID)
+	c.NoError(err)
+	c.Equal(2, stored.Attempts)
+
+	now = func() time.Time { return stored.NextAttemptAt }
+
+	defer func() {
+		now = time.Now
+	}()
+
+	c.NoError(Tick(ctx))
+	c.Len(*replayed, 2)
+}
+
+func TestTickDropsUnreadable(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+
+	replayed, restore := mockReplay(nil)
+
+	defer restore()
+
+	oldMaxReads := queue.MaxReads
+	queue.MaxReads = 1
+
+	defer func() {
+		queue.MaxReads = oldMaxReads
+	}()
+
+	ctx := context.Background()
+
+	unreadable := parkTestLetter(c, time.Now().Add(-2*time.Hour))
+	due := parkTestLetter(c, time.Now().Add(-time.Hour))
+
+	c.NoError(cache.Add(ctx, "BOT-DEAD-LETTER:"+unreadable.ID, "{", time.Hour))
+
+	// the unreadable message does not block the next one
+	c.NoError(Tick(ctx))
+	c.Equal([]string{due.ID}, *replayed)
+}
@@ -0,0 +1,242 @@
+// Package responder delivers to Telegram or Slack the results published by the workers to the bot results topic
+package responder
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"shared/app/bot/botclient"
+	"shared/app/bot/callback"
+	"shared/app/bot/format"
+	"shared/app/bot/models"
+	"shared/shared/aws/sns"
+	"shared/shared/telegram"
+
+	"bitbucket.org/truora/scrap-services/logger"
+	"bitbucket.org/truora/scrap-services/shared/cache"
+	"bitbucket.org/truora/scrap-services/shared/env"
+	"github.com/aws/aws-lambda-go/events"
+)
+
+var (
+	// ErrMissingChat when the result does not have the chat where it is delivered
+	ErrMissingChat = errors.New("result chat is missing")
+	// ErrInvalidButton when a keyboard button has no text or has neither command nor URL
+	ErrInvalidButton = format.ErrInvalidButton
+	// ErrInvalidAttachment when an attachment has an unknown type or no file
+	ErrInvalidAttachment = errors.New("invalid result attachment")
+	// ErrPartialDelivery when the delivery failed after part of the result was sent
+	ErrPartialDelivery = errors.New("result partially delivered")
+
+	defaultLogger = logger.New("bot-responder")
+
+	// ResultsTopic is the topic where the workers publish their results
+	ResultsTopic = env.GetString("BOT_RESULTS_TOPIC", "arn:aws:sns:us-east-1:031975712270:bot-results-topic")
+
+	newTelegramClient = botclient.New
+	getCallbackCodec  = callback.NewCodecFromSecret
+)
+
+// Init initializes the cache where the long callback data is stored, it panics when the cache is invalid
+func Init(ctx context.Context) {
+	defaultLogger.Must(ctx, cache.InitFromEnv(), logger.OneDay)
+}
+
+// Handler is the handler of the responder Lambda subscribed to the results topic
+func Handler(ctx context.Context, snsEvent events.SNSEvent) error {
+	telegramClient, err := newTelegramClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	codec, err := getCallbackCodec(ctx)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+
+	for _, record := range snsEvent.Records {
+		result := &models.Result{}
+
+		err = json.Unmarshal([]byte(record.SNS.Message), result)
+		if err != nil {
+			// a malformed result will never be delivered, retrying it only delays the other results
+			logger.Get(ctx).Error(ctx, "unmarshal_result_failed", logger.OneMonth, []logger.Object{logger.ErrObject(err)})
+
+			continue
+		}
+
+		err = Deliver(ctx, telegramClient, codec, result)
+		if errors.Is(err, ErrPartialDelivery) {
+			// retrying the result would send again the messages that were already delivered
+			logger.Get(ctx).Warning(ctx, "result_partially_delivered", logger.OneMonth, []logger.Object{
+				logger.ErrObject(err),
+				logger.MapObject("result", map[string]interface{}{"s_correlation_id": result.CorrelationID, "i_chat_id": result.ChatID}),
+			})
+
+			continue
+		}
+
+		if err != nil {
+			logger.Get(ctx).Error(ctx, "deliver_result_failed", logger.OneMonth, []logger.Object{
+				logger.ErrObject(err),
+				logger.MapObject("result", map[string]interface{}{"s_correlation_id": result.CorrelationID, "i_chat_id": result.ChatID}),
+			})
+
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// PublishResult publishes the result of a worker to the results topic
+func PublishResult(ctx context.Context, result *models.Result) error {
+	if result.ChatID == 0 && result.SlackResponseURL == "" {
+		return ErrMissingChat
+	}
+
+	return sns.PublishJSON(ctx, ResultsTopic, result)
+}
+
+// Deliver sends the result to its chat, the message in EditMessageID is edited when possible, a text
+// longer than a message is sent in several messages and the attachments are sent after the text.
+// ErrPartialDelivery is returned when part of the result was sent. The results of the commands received
+// from Slack are posted to Slack
+func Deliver(ctx context.Context, telegramClient *telegram.Client, codec *callback.Codec, result *models.Result) error {
+	if result.SlackResponseURL != "" {
+		return deliverSlack(ctx, result)
+	}
+
+	if result.ChatID == 0 {
+		return ErrMissingChat
+	}
+
+	keyboard, err := buildKeyboard(ctx, codec, result)
+	if err != nil {
+		return err
+	}
+
+	sent := false
+
+	if result.Text != "" {
+		err = deliverText(ctx, telegramClient, result, keyboard)
+		if err != nil {
+			return err
+		}
+
+		sent = true
+	}
+
+	for _, attachment := range result.Attachments {
+		err = sendAttachment(ctx, telegramClient, result.ChatID, attachment)
+		if err != nil {
+			return deliveryError(sent, err)
+		}
+
+		sent = true
+	}
+
+	return nil
+}
+
+// deliveryError marks the error as ErrPartialDelivery when part of the result was sent
+func deliveryError(sent bool, err error) error {
+	if sent {
+		return fmt.Errorf("%w: %w", ErrPartialDelivery, err)
+	}
+
+	return err
+}
+
+// deliverText sends the text split in the messages that Telegram accepts, the first one edits the
+// acknowledgement and the keyboard is sent with the last one
+func deliverText(ctx context.Context, telegramClient *telegram.Client, result *models.Result, keyboard *telegram.InlineKeyboardMarkup) error {
+	parseMode := telegram.ParseMode(result.ParseMode)
+	chunks := format.Split(parseMode, result.Text, format.MaxMessageLength)
+
+	for i, chunk := range chunks {
+		var chunkKeyboard *telegram.InlineKeyboardMarkup
+		if i == len(chunks)-1 {
+			chunkKeyboard = keyboard
+		}
+
+		if i == 0 && result.EditMessageID != 0 {
+			if editText(ctx, telegramClient, result, chunk, chunkKeyboard) {
+				continue
+			}
+		}
+
+		request := &telegram.SendMessageRequest{
+			ChatID:      result.ChatID,
+			Text:        chunk,
+			ParseMode:   parseMode,
+			ReplyMarkup: chunkKeyboard,
+		}
+
+		if i == 0 {
+			request.ReplyToMessageID = result.ReplyToMessageID
+		}
+
+		_, err := telegramClient.SendMessage(ctx, request)
+		if err != nil {
+			return deliveryError(i > 0, err)
+		}
+	}
+
+	return nil
+}
+
+// editText edits the acknowledgement with the text, it returns false when the message could not be edited
+func editText(ctx context.Context, telegramClient *telegram.Client, result *models.Result, text string, keyboard *telegram.InlineKeyboardMarkup) bool {
+	_, err := telegramClient.EditMessageText(ctx, &telegram.EditMessageTextRequest{
+		ChatID:      result.ChatID,
+		MessageID:   result.EditMessageID,
+		Text:        text,
+		ParseMode:   telegram.ParseMode(result.ParseMode),
+		ReplyMarkup: keyboard,
+	})
+	if err == nil {
+		return true
+	}
+
+	// the acknowledgement can be deleted or too old to be edited, the result is sent as a new message
+	logger.Get(ctx).Warning(ctx, "edit_result_message_failed", logger.OneMonth, []logger.Object{
+		logger.ErrObject(err),
+		logger.MapObject("result", map[string]interface{}{"s_correlation_id": result.CorrelationID, "i_message_id": result.EditMessageID}),
+	})
+
+	return false
+}
+
+// buildKeyboard signs the command buttons for the user and chat of the result
+func buildKeyboard(ctx context.Context, codec *callback.Codec, result *models.Result) (*telegram.InlineKeyboardMarkup, error) {
+	return format.KeyboardFrom(result.Keyboard).Markup(ctx, codec, result.UserID, result.ChatID)
+}
+
+func sendAttachment(ctx context.Context, telegramClient *telegram.Client, chatID int64, attachment models.Attachment) error {
+	file := attachment.FileID
+	if file == "" {
+		file = attachment.URL
+	}
+
+	if file == "" {
+		return fmt.Errorf("%w: missing file", ErrInvalidAttachment)
+	}
+
+	var err error
+
+	switch attachment.Type {
+	case models.AttachmentPhoto:
+		_, err = telegramClient.SendPhoto(ctx, &telegram.SendPhotoRequest{ChatID: chatID, Photo: file, Caption: attachment.Caption})
+	case models.AttachmentDocument:
+		_, err = telegramClient.SendDocument(ctx, &telegram.SendDocumentRequest{ChatID: chatID, Document: file, Caption: attachment.Caption})
+	default:
+		err = fmt.Errorf("%w: unknown type %q", ErrInvalidAttachment, attachment.Type)
+	}
+
+	return err
+}
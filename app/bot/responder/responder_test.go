@@ -0,0 +1,253 @@
+package responder
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"shared/app/bot/botclient"
+	"shared/app/bot/callback"
+	"shared/app/bot/format"
+	"shared/app/bot/models"
+	"shared/shared/aws/sns"
+	"shared/shared/telegram"
+
+	"bitbucket.org/truora/scrap-services/logger"
+	"bitbucket.org/truora/scrap-services/shared/cache"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestCodec(c *require.Assertions) *callback.Codec {
+	codec, err := callback.NewCodec([]byte("callback-secret"), time.Hour)
+	c.NoError(err)
+
+	return codec
+}
+
+func TestDeliverEditsAcknowledgement(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+
+	fake := telegram.NewFake("")
+
+	defer fake.Close()
+
+	ctx := context.Background()
+	codec := newTestCodec(c)
+
+	result := &models.Result{
+		CorrelationID: "abc",
+		ChatID:        123,
+		UserID:        456,
+		Text:          "Deployed checks/core",
+		EditMessageID: 9,
+		Keyboard: [][]models.Button{{
+			{Text: "Rollback", Command: "rollback", Data: "checks/core"},
+			{Text: "Logs", URL: "https://example.com/logs"},
+		}},
+		Attachments: []models.Attachment{
+			{Type: models.AttachmentPhoto, FileID: "photo-id"},
+			{Type: models.AttachmentDocument, URL: "https://example.com/report.pdf", Caption: "report"},
+		},
+	}
+
+	err := Deliver(ctx, fake.Client(), codec, result)
+	c.NoError(err)
+	c.Empty(fake.SentTexts())
+
+	edits := fake.Requests("editMessageText")
+	c.Len(edits, 1)
+	c.EqualValues(9, edits[0].Params["message_id"])
+	c.Equal("Deployed checks/core", edits[0].Params["text"])
+
+	keyboard := edits[0].Params["reply_markup"].(map[string]interface{})["inline_keyboard"].([]interface{})[0].([]interface{})
+	c.Equal("https://example.com/logs", keyboard[1].(map[string]interface{})["url"])
+
+	payload, err := codec.Decode(ctx, keyboard[0].(map[string]interface{})["callback_data"].(string), 456, 123)
+	c.NoError(err)
+	c.Equal("rollback", payload.Command)
+	c.Equal("checks/core", payload.Data)
+
+	c.Equal("photo-id", fake.Requests("sendPhoto")[0].Params["photo"])
+	c.Equal("https://example.com/report.pdf", fake.Requests("sendDocument")[0].Params["document"])
+}
+
+func TestDeliverSendsWhenEditFails(t *testing.T) {
+	c := require.New(t)
+
+	fake := telegram.NewFake("")
+
+	defer fake.Close()
+
+	fake.AddError("editMessageText", 400, "Bad Request: message to edit not found")
+
+	err := Deliver(context.Background(), fake.Client(), newTestCodec(c), &models.Result{ChatID: 123, Text: "done", EditMessageID: 9, ReplyToMessageID: 3})
+	c.NoError(err)
+	c.Equal([]string{"done"}, fake.SentTexts())
+	c.EqualValues(3, fake.Requests("sendMessage")[0].Params["reply_to_message_id"])
+}
+
+func TestDeliverSplitsLongText(t *testing.T) {
+	c := require.New(t)
+
+	fake := telegram.NewFake("")
+
+	defer fake.Close()
+
+	ctx := context.Background()
+	codec := newTestCodec(c)
+
+	first := strings.Repeat("a", format.MaxMessageLength-10)
+	second := strings.Repeat("b", 20)
+
+	err := Deliver(ctx, fake.Client(), codec, &models.Result{
+		ChatID:        123,
+		UserID:        456,
+		Text:          first + "\n" + second,
+		EditMessageID: 9,
+		Keyboard:      [][]models.Button{{{Text: "Logs", URL: "https://example.com/logs"}}},
+	})
+	c.NoError(err)
+
+	edits := fake.Requests("editMessageText")
+	c.Len(edits, 1)
+	c.Equal(first, edits[0].Params["text"])
+	c.Nil(edits[0].Params["reply_markup"])
+
+	c.Equal([]string{second}, fake.SentTexts())
+	c.NotNil(fake.Requests("sendMessage")[0].Params["reply_markup"])
+}
+
+func TestDeliverErrors(t *testing.T) {
+	c := require.New(t)
+
+	fake := telegram.NewFake("")
+
+	defer fake.Close()
+
+	ctx := context.Background()
+	codec := newTestCodec(c)
+
+	err := Deliver(ctx, fake.Client(), codec, &models.Result{Text: "done"})
+	c.ErrorIs(err, ErrMissingChat)
+
+	err = Deliver(ctx, fake.Client(), codec, &models.Result{ChatID: 123, Text: "done", Keyboard: [][]models.Button{{{Text: "Nothing"}}}})
+	c.ErrorIs(err, ErrInvalidButton)
+
+	err = Deliver(ctx, fake.Client(), codec, &models.Result{ChatID: 123, Attachments: []models.Attachment{{Type: models.AttachmentPhoto}}})
+	c.ErrorIs(err, ErrInvalidAttachment)
+
+	err = Deliver(ctx, fake.Client(), codec, &models.Result{ChatID: 123, Attachments: []models.Attachment{{Type: "video", FileID: "video-id"}}})
+	c.ErrorIs(err, ErrInvalidAttachment)
+
+	c.Empty(fake.SentTexts())
+}
+
+func TestHandler(t *testing.T) {
+	c := require.New(t)
+
+	fake := telegram.NewFake("")
+
+	defer fake.Close()
+
+	newTelegramClient = func(ctx context.Context) (*telegram.Client, error) {
+		return fake.Client(), nil
+	}
+
+	getCallbackCodec = func(ctx context.Context) (*callback.Codec, error) {
+		return newTestCodec(c), nil
+	}
+
+	defer func() {
+		newTelegramClient = botclient.New
+		getCallbackCodec = callback.NewCodecFromSecret
+	}()
+
+	result, err := json.Marshal(&models.Result{ChatID: 123, Text: "done"})
+	c.NoError(err)
+
+	snsEvent := events.SNSEvent{Records: []events.SNSEventRecord{
+		{SNS: events.SNSEntity{Message: `{`}},
+		{SNS: events.SNSEntity{Message: string(result)}},
+	}}
+
+	err = Handler(context.Background(), snsEvent)
+	c.NoError(err)
+	c.Equal([]string{"done"}, fake.SentTexts())
+
+	fake.AddError("sendMessage", 400, "Bad Request: chat not found")
+
+	err = Handler(context.Background(), snsEvent)
+	c.ErrorIs(err, telegram.ErrAPI)
+}
+
+func TestHandlerPartialDelivery(t *testing.T) {
+	c := require.New(t)
+
+	fake := telegram.NewFake("")
+
+	defer fake.Close()
+
+	newTelegramClient = func(ctx context.Context) (*telegram.Client, error) {
+		return fake.Client(), nil
+	}
+
+	getCallbackCodec = func(ctx context.Context) (*callback.Codec, error) {
+		return newTestCodec(c), nil
+	}
+
+	defer func() {
+		newTelegramClient = botclient.New
+		getCallbackCodec = callback.NewCodecFromSecret
+	}()
+
+	ctx := context.Background()
+	log := logger.New("test")
+	buf := bytes.NewBufferString("")
+	log.Output = buf
+
+	ctx = logger.Set(ctx, log)
+
+	result := &models.Result{ChatID: 123, Text: "done", Attachments: []models.Attachment{{Type: models.AttachmentPhoto, FileID: "photo-id"}}}
+
+	fake.AddError("sendPhoto", 400, "Bad Request: wrong file identifier")
+
+	err := Deliver(ctx, fake.Client(), newTestCodec(c), result)
+	c.ErrorIs(err, ErrPartialDelivery)
+	c.ErrorIs(err, telegram.ErrAPI)
+
+	encoded, err := json.Marshal(result)
+	c.NoError(err)
+
+	fake.AddError("sendPhoto", 400, "Bad Request: wrong file identifier")
+
+	// the text was delivered so the result is not retried
+	err = Handler(ctx, events.SNSEvent{Records: []events.SNSEventRecord{{SNS: events.SNSEntity{Message: string(encoded)}}}})
+	c.NoError(err)
+	c.Equal([]string{"done", "done"}, fake.SentTexts())
+	c.Contains(buf.String(), "result_partially_delivered")
+}
+
+func TestPublishResult(t *testing.T) {
+	c := require.New(t)
+
+	sns.InitSNSMock()
+
+	defer sns.InitSNSMock()
+
+	err := PublishResult(context.Background(), &models.Result{ChatID: 123, Text: "done"})
+	c.NoError(err)
+
+	err = PublishResult(context.Background(), &models.Result{Text: "done"})
+	c.ErrorIs(err, ErrMissingChat)
+
+	sns.ForceMockFail = true
+
+	err = PublishResult(context.Background(), &models.Result{ChatID: 123, Text: "done"})
+	c.Error(err)
+}
@@ -0,0 +1,77 @@
+package responder
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"shared/app/bot/models"
+	"shared/shared/client"
+)
+
+const (
+	// slackResponseURLPrefix is the prefix of the response URLs sent by Slack, the results are not posted
+	// to other hosts so a forged response URL can not receive them
+	slackResponseURLPrefix = "https://hooks.slack.com/"
+)
+
+var (
+	// ErrSlackResponse when Slack does not accept the result posted to the response URL
+	ErrSlackResponse = errors.New("slack response failed")
+	// ErrInvalidSlackResponseURL when the response URL of the result is not a Slack URL
+	ErrInvalidSlackResponseURL = errors.New("invalid slack response URL")
+)
+
+// slackMessage is the message posted to the response URL of a Slack command
+type slackMessage struct {
+	ResponseType string `json:"response_type"`
+	Text         string `json:"text"`
+}
+
+// deliverSlack posts the result to the response URL of the Slack command. The command buttons and the
+// Telegram files can not be used in Slack so only the links of the result are added to the text
+func deliverSlack(ctx context.Context, result *models.Result) error {
+	if !strings.HasPrefix(result.SlackResponseURL, slackResponseURLPrefix) {
+		return fmt.Errorf("%w: %s", ErrInvalidSlackResponseURL, result.SlackResponseURL)
+	}
+
+	lines := []string{result.Text}
+
+	for _, row := range result.Keyboard {
+		for _, button := range row {
+			if button.URL != "" {
+				lines = append(lines, fmt.Sprintf("<%s|%s>", button.URL, button.Text))
+			}
+		}
+	}
+
+	for _, attachment := range result.Attachments {
+		if attachment.URL != "" {
+			lines = append(lines, attachment.URL)
+		}
+	}
+
+	body, err := json.Marshal(slackMessage{ResponseType: "ephemeral", Text: strings.TrimSpace(strings.Join(lines, "\n"))})
+	if err != nil {
+		return err
+	}
+
+	headers := http.Header{}
+	headers.Set("Content-Type", "application/json")
+
+	response, err := client.Default.SendRequest(ctx, http.MethodPost, result.SlackResponseURL, headers, string(body))
+	if response == nil {
+		return fmt.Errorf("%w: %w", ErrSlackResponse, err)
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: status %d", ErrSlackResponse, response.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,76 @@
+package responder
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"shared/app/bot/models"
+	"shared/shared/telegram"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/require"
+)
+
+const testResponseURL = "https://hooks.slack.com/commands/1"
+
+func TestDeliverSlack(t *testing.T) {
+	c := require.New(t)
+
+	fake := telegram.NewFake("")
+
+	defer fake.Close()
+
+	received := []slackMessage{}
+	status := http.StatusOK
+
+	httpmock.RegisterResponder(http.MethodPost, testResponseURL, func(request *http.Request) (*http.Response, error) {
+		message := slackMessage{}
+		c.NoError(json.NewDecoder(request.Body).Decode(&message))
+
+		received = append(received, message)
+
+		return httpmock.NewStringResponse(status, ""), nil
+	})
+
+	ctx := context.Background()
+
+	result := &models.Result{
+		Text:             "Deployed checks/core",
+		SlackResponseURL: testResponseURL,
+		Keyboard: [][]models.Button{{
+			{Text: "Rollback", Command: "rollback", Data: "checks/core"},
+			{Text: "Logs", URL: "https://example.com/logs"},
+		}},
+		Attachments: []models.Attachment{
+			{Type: models.AttachmentPhoto, FileID: "photo-id"},
+			{Type: models.AttachmentDocument, URL: "https://example.com/report.pdf"},
+		},
+	}
+
+	c.NoError(Deliver(ctx, fake.Client(), newTestCodec(c), result))
+	c.Len(received, 1)
+	c.Equal("ephemeral", received[0].ResponseType)
+	c.Equal("Deployed checks/core\n<https://example.com/logs|Logs>\nhttps://example.com/report.pdf", received[0].Text)
+	c.Empty(fake.SentTexts())
+
+	status = http.StatusNotFound
+
+	err := Deliver(ctx, fake.Client(), newTestCodec(c), result)
+	c.ErrorIs(err, ErrSlackResponse)
+
+	httpmock.RegisterResponder(http.MethodPost, testResponseURL, httpmock.NewErrorResponder(errors.New("connection reset")))
+
+	err = Deliver(ctx, fake.Client(), newTestCodec(c), result)
+	c.ErrorIs(err, ErrSlackResponse)
+	c.ErrorContains(err, "connection reset")
+
+	// the results are only posted to Slack
+	result.SlackResponseURL = "https://example.com/commands/1"
+
+	err = Deliver(ctx, fake.Client(), newTestCodec(c), result)
+	c.ErrorIs(err, ErrInvalidSlackResponseURL)
+	c.Len(received, 2)
+}
@@ -0,0 +1,109 @@
+package router
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+
+	"shared/app/bot/i18n"
+	"shared/app/bot/models"
+	"shared/shared/telegram"
+
+	"bitbucket.org/truora/scrap-services/logger"
+	"bitbucket.org/truora/scrap-services/shared/env"
+)
+
+const (
+	correlationIDLength = 8
+	// shortCorrelationIDLength is the part of the correlation ID shown to the user
+	shortCorrelationIDLength = 8
+)
+
+// sendAcknowledgement sends the accepted acknowledgement before publishing the commands, the results edit it in place
+var sendAcknowledgement = env.GetBool("SEND_ACCEPTED_ACK", true)
+
+func newCorrelationID() (string, error) {
+	id := make([]byte, correlationIDLength)
+
+	_, err := rand.Read(id)
+	if err != nil {
+		return "", fmt.Errorf("generate correlation ID failed: %w", err)
+	}
+
+	return hex.EncodeToString(id), nil
+}
+
+func shortCorrelationID(id string) string {
+	if len(id) <= shortCorrelationIDLength {
+		return id
+	}
+
+	return id[:shortCorrelationIDLength]
+}
+
+// acknowledge sets the correlation ID of the command and tells the user it was accepted. A failed
+// acknowledgement does not stop the command, the result is sent as a new message instead
+func acknowledge(ctx context.Context, telegramClient *telegram.Client, message *models.CallbackMessage, command string) {
+	correlationID, err := newCorrelationID()
+	if err != nil {
+		logger.Get(ctx).Warning(ctx, "create_correlation_id_failed", logger.OneMonth, []logger.Object{logger.ErrObject(err)})
+
+		return
+	}
+
+	message.CorrelationID = correlationID
+
+	if !sendAcknowledgement {
+		return
+	}
+
+	ack, err := telegramClient.SendMessage(ctx, &telegram.SendMessageRequest{
+		ChatID:           replyChatID(message),
+		Text:             translate(ctx, "ack.accepted", i18n.Vars{"command": command, "id": shortCorrelationID(correlationID)}),
+		ReplyToMessageID: message.Message.ID,
+	})
+	if err != nil {
+		logger.Get(ctx).Warning(ctx, "send_accepted_ack_failed", logger.OneMonth, []logger.Object{
+			logger.ErrObject(err),
+			logger.MapObject("ack", map[string]interface{}{"s_correlation_id": correlationID, "s_command": command}),
+		})
+
+		return
+	}
+
+	message.AckMessageID = ack.MessageID
+}
+
+// rejectAcknowledgement tells the user the accepted command could not be sent to the workers
+func rejectAcknowledgement(ctx context.Context, telegramClient *telegram.Client, message *models.CallbackMessage, command string) {
+	updateAcknowledgement(ctx, telegramClient, message, translate(ctx, "ack.rejected", i18n.Vars{"command": command, "id": shortCorrelationID(message.CorrelationID)}))
+}
+
+// delayAcknowledgement tells the user the accepted command is parked until the workers can receive it
+func delayAcknowledgement(ctx context.Context, telegramClient *telegram.Client, message *models.CallbackMessage, command string) {
+	updateAcknowledgement(ctx, telegramClient, message, translate(ctx, "ack.delayed", i18n.Vars{"command": command, "id": shortCorrelationID(message.CorrelationID)}))
+}
+
+// resumeAcknowledgement tells the user the delayed command was finally sent to the workers
+func resumeAcknowledgement(ctx context.Context, telegramClient *telegram.Client, message *models.CallbackMessage, command string) {
+	updateAcknowledgement(ctx, telegramClient, message, translate(ctx, "ack.accepted", i18n.Vars{"command": command, "id": shortCorrelationID(message.CorrelationID)}))
+}
+
+// updateAcknowledgement edits the accepted acknowledgement, the text is sent as a new message when there is none
+func updateAcknowledgement(ctx context.Context, telegramClient *telegram.Client, message *models.CallbackMessage, text string) {
+	if message.AckMessageID == 0 {
+		telegramClient.SendText(ctx, replyChatID(message), text)
+
+		return
+	}
+
+	_, err := telegramClient.EditMessageText(ctx, &telegram.EditMessageTextRequest{
+		ChatID:    replyChatID(message),
+		MessageID: message.AckMessageID,
+		Text:      text,
+	})
+	if err != nil {
+		logger.Get(ctx).Warning(ctx, "edit_accepted_ack_failed", logger.OneMonth, []logger.Object{logger.ErrObject(err)})
+	}
+}
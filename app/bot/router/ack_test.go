@@ -0,0 +1,141 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"shared/app/bot/models"
+	"shared/app/bot/storage/deadletter"
+	"shared/shared/telegram"
+
+	"bitbucket.org/truora/scrap-services/shared/cache"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAcknowledge(t *testing.T) {
+	c := require.New(t)
+
+	fake := telegram.NewFake("")
+
+	defer fake.Close()
+
+	ctx := context.Background()
+	message := &models.CallbackMessage{Message: models.Message{ID: 7, Chat: models.Chat{ID: 123}}}
+
+	acknowledge(ctx, fake.Client(), message, deployTerraformStagingCommand)
+	c.Len(message.CorrelationID, correlationIDLength*2)
+	c.Equal(1, message.AckMessageID)
+
+	requests := fake.Requests("sendMessage")
+	c.Len(requests, 1)
+	c.Equal("Accepted /deployterraformstaging (id: "+message.CorrelationID[:shortCorrelationIDLength]+")", requests[0].Params["text"])
+	c.EqualValues(7, requests[0].Params["reply_to_message_id"])
+
+	rejectAcknowledgement(ctx, fake.Client(), message, deployTerraformStagingCommand)
+
+	edits := fake.Requests("editMessageText")
+	c.Len(edits, 1)
+	c.EqualValues(1, edits[0].Params["message_id"])
+	c.Contains(edits[0].Params["text"], "Could not run /deployterraformstaging")
+}
+
+func TestAcknowledgeFailed(t *testing.T) {
+	c := require.New(t)
+
+	fake := telegram.NewFake("")
+
+	defer fake.Close()
+
+	ctx := context.Background()
+	message := &models.CallbackMessage{Message: models.Message{Chat: models.Chat{ID: 123}}}
+
+	fake.AddError("sendMessage", 400, "Bad Request: chat not found")
+
+	acknowledge(ctx, fake.Client(), message, deployTerraformStagingCommand)
+	c.NotEmpty(message.CorrelationID)
+	c.Zero(message.AckMessageID)
+
+	rejectAcknowledgement(ctx, fake.Client(), message, deployTerraformStagingCommand)
+	c.Empty(fake.Requests("editMessageText"))
+	c.Contains(fake.SentTexts()[1], "Could not run /deployterraformstaging")
+
+	sendAcknowledgement = false
+
+	defer func() {
+		sendAcknowledgement = true
+	}()
+
+	message = &models.CallbackMessage{Message: models.Message{Chat: models.Chat{ID: 123}}}
+
+	acknowledge(ctx, fake.Client(), message, deployTerraformStagingCommand)
+	c.NotEmpty(message.CorrelationID)
+	c.Len(fake.SentTexts(), 2)
+}
+
+func TestProcessDelaysAcknowledgement(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+
+	fake, restore := setFakeTelegram()
+
+	defer restore()
+
+	published := []*models.CallbackMessage{}
+
+	SetPublisher(func(ctx context.Context, topic, attribute string, message *models.CallbackMessage) error {
+		published = append(published, message)
+
+		return errors.New("publish failed")
+	})
+
+	defer SetPublisher(nil)
+
+	err := ProcessUpdate(context.Background(), []byte(`{"update_id":2,"message":{"message_id":3,"text":"/hi dummy_email@dummy.com","chat":{"id":1,"type":"private"}}}`))
+	c.NoError(err)
+	c.Len(published, 1)
+	c.NotEmpty(published[0].CorrelationID)
+	c.Equal(1, published[0].AckMessageID)
+
+	edits := fake.Requests("editMessageText")
+	c.Len(edits, 1)
+	c.Contains(edits[0].Params["text"], "/"+registerCommand+" is delayed")
+
+	letters, err := deadletter.List(context.Background())
+	c.NoError(err)
+	c.Len(letters, 1)
+	c.Equal(published[0].CorrelationID, letters[0].Message.CorrelationID)
+}
+
+func TestProcessRejectsAcknowledgement(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+
+	fake, restore := setFakeTelegram()
+
+	defer restore()
+
+	SetPublisher(func(ctx context.Context, topic, attribute string, message *models.CallbackMessage) error {
+		return errors.New("publish failed")
+	})
+
+	defer SetPublisher(nil)
+
+	parkDeadLetter = func(ctx context.Context, topic, attribute string, message *models.CallbackMessage, publishErr error, now time.Time) (*models.DeadLetter, error) {
+		return nil, errors.New("cache unavailable")
+	}
+
+	defer func() {
+		parkDeadLetter = deadletter.Park
+	}()
+
+	err := ProcessUpdate(context.Background(), []byte(`{"update_id":2,"message":{"message_id":3,"text":"/hi dummy_email@dummy.com","chat":{"id":1,"type":"private"}}}`))
+	c.Error(err)
+
+	edits := fake.Requests("editMessageText")
+	c.Len(edits, 1)
+	c.Contains(edits[0].Params["text"], "Could not run /"+registerCommand)
+}
@@ -0,0 +1,509 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"shared/app/bot/format"
+	"shared/app/bot/i18n"
+	"shared/app/bot/models"
+	"shared/app/bot/storage"
+	"shared/shared/telegram"
+
+	"bitbucket.org/truora/scrap-services/logger"
+	"bitbucket.org/truora/scrap-services/shared/env"
+)
+
+const (
+	usersCommand           = "users"
+	userCommand            = "user"
+	addUserCommand         = "adduser"
+	setRoleCommand         = "setrole"
+	revokeUserCommand      = "revokeuser"
+	linkBitbucketCommand   = "linkbitbucket"
+	unlinkBitbucketCommand = "unlinkbitbucket"
+	linkSlackCommand       = "linkslack"
+	unlinkSlackCommand     = "unlinkslack"
+	deleteUserCommand      = "deleteuser"
+
+	emailArg       = "email"
+	roleArg        = "role"
+	bitbucketIDArg = "bitbucket_id"
+	slackIDArg     = "slack_id"
+
+	// answerKey is added to the data of the confirmation buttons with confirmAnswer or cancelAnswer
+	answerKey     = "answer"
+	confirmAnswer = "yes"
+	cancelAnswer  = "no"
+)
+
+var (
+	// errInvalidRole when the new role of a user is not one of the roles declared by the bot
+	errInvalidRole = i18n.NewError("admin.invalid_role", nil)
+
+	// adminRoles are the roles allowed to manage the bot users
+	adminRoles    = []string{models.RoleAdmin}
+	usersPageSize = env.GetInt64("BOT_USERS_PAGE_SIZE", 10)
+
+	// userActions are the admin commands that change a user, the confirmed ones are applied after the
+	// admin presses the confirmation button
+	userActions = map[string]*userAction{
+		setRoleCommand: {
+			confirm: true,
+			validate: func(args map[string]string) error {
+				return validateRole(args[roleArg])
+			},
+			describe: func(ctx context.Context, user *models.From, args map[string]string) string {
+				return translate(ctx, "admin.action.setrole", i18n.Vars{"email": user.Email, "from": user.UserRole, "to": args[roleArg]})
+			},
+			apply: func(ctx context.Context, user *models.From, args map[string]string) error {
+				return storage.UpdateUserRole(ctx, user.Email, args[roleArg])
+			},
+		},
+		revokeUserCommand: {
+			confirm: true,
+			describe: func(ctx context.Context, user *models.From, args map[string]string) string {
+				return translate(ctx, "admin.action.revokeuser", i18n.Vars{"email": user.Email})
+			},
+			apply: func(ctx context.Context, user *models.From, args map[string]string) error {
+				return storage.RevokeUserVerification(ctx, user.Email)
+			},
+		},
+		linkBitbucketCommand: {
+			describe: func(ctx context.Context, user *models.From, args map[string]string) string {
+				return translate(ctx, "admin.action.linkbitbucket", i18n.Vars{"account": args[bitbucketIDArg], "email": user.Email})
+			},
+			apply: func(ctx context.Context, user *models.From, args map[string]string) error {
+				return storage.SetBitbucketID(ctx, user.Email, args[bitbucketIDArg])
+			},
+		},
+		unlinkBitbucketCommand: {
+			confirm: true,
+			describe: func(ctx context.Context, user *models.From, args map[string]string) string {
+				return translate(ctx, "admin.action.unlinkbitbucket", i18n.Vars{"account": user.BitbucketID, "email": user.Email})
+			},
+			apply: func(ctx context.Context, user *models.From, args map[string]string) error {
+				return storage.SetBitbucketID(ctx, user.Email, "")
+			},
+		},
+		linkSlackCommand: {
+			describe: func(ctx context.Context, user *models.From, args map[string]string) string {
+				return translate(ctx, "admin.action.linkslack", i18n.Vars{"slack_id": args[slackIDArg], "email": user.Email})
+			},
+			apply: func(ctx context.Context, user *models.From, args map[string]string) error {
+				return storage.SetSlackID(ctx, user.Email, args[slackIDArg])
+			},
+		},
+		unlinkSlackCommand: {
+			confirm: true,
+			describe: func(ctx context.Context, user *models.From, args map[string]string) string {
+				return translate(ctx, "admin.action.unlinkslack", i18n.Vars{"slack_id": user.SlackID, "email": user.Email})
+			},
+			apply: func(ctx context.Context, user *models.From, args map[string]string) error {
+				return storage.SetSlackID(ctx, user.Email, "")
+			},
+		},
+		deleteUserCommand: {
+			confirm: true,
+			describe: func(ctx context.Context, user *models.From, args map[string]string) string {
+				return translate(ctx, "admin.action.deleteuser", i18n.Vars{"email": user.Email})
+			},
+			apply: func(ctx context.Context, user *models.From, args map[string]string) error {
+				return storage.DeleteUser(ctx, user.Email)
+			},
+		},
+	}
+)
+
+type userAction struct {
+	// confirm actions can not be undone easily so the admin has to confirm them
+	confirm  bool
+	validate func(args map[string]string) error
+	describe func(ctx context.Context, user *models.From, args map[string]string) string
+	apply    func(ctx context.Context, user *models.From, args map[string]string) error
+}
+
+// listUsers sends a page of users, the next page is sent when the admin presses the next button
+func listUsers(ctx context.Context, telegramClient *telegram.Client, message *models.CallbackMessage) error {
+	cursor := ""
+
+	if message.EventType == models.EventCallbackQuery {
+		answerCallback(ctx, telegramClient, message)
+
+		cursor = message.Data
+	}
+
+	users, next, err := storage.ListUsers(ctx, usersPageSize, cursor)
+	if err != nil {
+		return fmt.Errorf("list users failed: %w", err)
+	}
+
+	if len(users) == 0 {
+		replyAdmin(ctx, telegramClient, message, translate(ctx, "admin.no_more_users", nil), nil)
+
+		return nil
+	}
+
+	lines := make([]string, 0, len(users))
+
+	for _, user := range users {
+		lines = append(lines, fmt.Sprintf("%s · %s · %s", user.Email, user.UserRole, verifiedText(ctx, user)))
+	}
+
+	keyboard := format.NewKeyboard()
+	if next != "" {
+		keyboard.Row(format.CommandButton(translate(ctx, "admin.next", nil), usersCommand, next))
+	}
+
+	markup, err := signKeyboard(ctx, keyboard, message.From.ID, replyChatID(message))
+	if err != nil {
+		return err
+	}
+
+	replyAdmin(ctx, telegramClient, message, strings.Join(lines, "\n"), markup)
+
+	return nil
+}
+
+// showUser sends the stored details of a user
+func showUser(ctx context.Context, telegramClient *telegram.Client, message *models.CallbackMessage) error {
+	user, err := getAdminTarget(ctx, telegramClient, message, message.Args[emailArg])
+	if user == nil {
+		return err
+	}
+
+	bitbucketID := user.BitbucketID
+	if bitbucketID == "" {
+		bitbucketID = "-"
+	}
+
+	slackID := user.SlackID
+	if slackID == "" {
+		slackID = "-"
+	}
+
+	telegramClient.SendText(ctx, replyChatID(message), translate(ctx, "admin.user_details", i18n.Vars{
+		"email":     user.Email,
+		"telegram":  fmt.Sprintf("%d @%s %s %s", user.ID, user.Username, user.FirstName, user.LastName),
+		"role":      user.UserRole,
+		"verified":  verifiedText(ctx, user),
+		"bitbucket": bitbucketID,
+		"slack":     slackID,
+		"created":   user.CreationDate.Format("2006-01-02 15:04"),
+	}))
+
+	return nil
+}
+
+// addUser adds the email with the role so its owner can register, the developers role is used when the
+// admin does not send one
+func addUser(ctx context.Context, telegramClient *telegram.Client, message *models.CallbackMessage) error {
+	address, err := parseEmail(message.Args[emailArg])
+	if err != nil {
+		telegramClient.SendText(ctx, replyChatID(message), translate(ctx, "register.invalid_email", nil))
+
+		return nil
+	}
+
+	role := message.Args[roleArg]
+	if role == "" {
+		role = models.RoleDevelopers
+	}
+
+	err = validateRole(role)
+	if err != nil {
+		telegramClient.SendText(ctx, replyChatID(message), i18n.ErrorText(ctx, err))
+
+		return nil
+	}
+
+	user, err := storage.AddUser(ctx, address, role)
+	if errors.Is(err, storage.ErrUserExists) {
+		telegramClient.SendText(ctx, replyChatID(message), translate(ctx, "admin.user_exists", i18n.Vars{"email": address, "command": userCommand}))
+
+		return nil
+	}
+
+	logUserAction(ctx, message, addUserCommand, &models.From{Email: address}, map[string]string{roleArg: role}, err)
+
+	if err != nil {
+		return fmt.Errorf("add user failed: %w", err)
+	}
+
+	telegramClient.SendText(ctx, replyChatID(message), translate(ctx, "admin.user_added", i18n.Vars{"email": user.Email, "role": user.UserRole, "command": registrationCommand}))
+
+	return nil
+}
+
+// validateRole returns errInvalidRole with the declared roles when the role is not one of them
+func validateRole(role string) error {
+	roles := declaredRoles()
+
+	for _, declared := range roles {
+		if role == declared {
+			return nil
+		}
+	}
+
+	return i18n.NewError(errInvalidRole.Key, i18n.Vars{"role": role, "roles": strings.Join(roles, ", ")})
+}
+
+// declaredRoles returns the admin and developers roles and the roles allowed to run or approve the bot
+// commands sorted by name, a user with any other role could not run any command
+func declaredRoles() []string {
+	unique := map[string]bool{models.RoleAdmin: true, models.RoleDevelopers: true}
+
+	for _, cmd := range botCommands.Commands() {
+		for _, role := range cmd.Roles {
+			unique[role] = true
+		}
+
+		for _, role := range cmd.ApproverRoles {
+			unique[role] = true
+		}
+	}
+
+	roles := make([]string, 0, len(unique))
+
+	for role := range unique {
+		roles = append(roles, role)
+	}
+
+	sort.Strings(roles)
+
+	return roles
+}
+
+// handleUserAction returns the handler of the admin command that changes a user, the confirmed actions
+// send an inline keyboard and they are applied when the confirmation button comes back
+func handleUserAction(name string) func(ctx context.Context, telegramClient *telegram.Client, message *models.CallbackMessage) error {
+	return func(ctx context.Context, telegramClient *telegram.Client, message *models.CallbackMessage) error {
+		action := userActions[name]
+
+		if message.EventType == models.EventCallbackQuery {
+			return confirmUserAction(ctx, telegramClient, message, name, action)
+		}
+
+		if action.validate != nil {
+			err := action.validate(message.Args)
+			if err != nil {
+				telegramClient.SendText(ctx, replyChatID(message), i18n.ErrorText(ctx, err))
+
+				return nil
+			}
+		}
+
+		user, err := getAdminTarget(ctx, telegramClient, message, message.Args[emailArg])
+		if user == nil {
+			return err
+		}
+
+		if !action.confirm {
+			return applyUserAction(ctx, telegramClient, message, name, action, user, message.Args)
+		}
+
+		if user.ID == message.From.ID {
+			telegramClient.SendText(ctx, replyChatID(message), translate(ctx, "admin.own_user", nil))
+
+			return nil
+		}
+
+		return askConfirmation(ctx, telegramClient, message, name, action.describe(ctx, user, message.Args), message.Args)
+	}
+}
+
+func askConfirmation(ctx context.Context, telegramClient *telegram.Client, message *models.CallbackMessage, name, description string, args map[string]string) error {
+	keyboard := format.NewKeyboard()
+	buttons := make([]models.Button, 0, 2)
+
+	for _, answer := range []struct{ value, text string }{{confirmAnswer, translate(ctx, "admin.yes", nil)}, {cancelAnswer, translate(ctx, "admin.no", nil)}} {
+		values := url.Values{answerKey: {answer.value}}
+
+		for key, value := range args {
+			values.Set(key, value)
+		}
+
+		// the confirmations are single use so a repeated press does not apply the action twice
+		buttons = append(buttons, format.SingleUseButton(answer.text, name, values.Encode()))
+	}
+
+	markup, err := signKeyboard(ctx, keyboard.Row(buttons...), message.From.ID, replyChatID(message))
+	if err != nil {
+		return err
+	}
+
+	_, err = telegramClient.SendMessage(ctx, &telegram.SendMessageRequest{
+		ChatID:      replyChatID(message),
+		Text:        translate(ctx, "admin.confirm", i18n.Vars{"action": description}),
+		ReplyMarkup: markup,
+	})
+
+	return err
+}
+
+// confirmUserAction applies the action when the admin pressed the confirmation button, the user is
+// read again because it could change while the confirmation was waiting
+func confirmUserAction(ctx context.Context, telegramClient *telegram.Client, message *models.CallbackMessage, name string, action *userAction) error {
+	answerCallback(ctx, telegramClient, message)
+
+	values, err := url.ParseQuery(message.Data)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidCallback, err)
+	}
+
+	if values.Get(answerKey) != confirmAnswer {
+		replyAdmin(ctx, telegramClient, message, translate(ctx, "admin.canceled", nil), nil)
+
+		return nil
+	}
+
+	args := map[string]string{}
+
+	for key := range values {
+		if key != answerKey {
+			args[key] = values.Get(key)
+		}
+	}
+
+	user, err := getAdminTarget(ctx, telegramClient, message, args[emailArg])
+	if user == nil {
+		return err
+	}
+
+	return applyUserAction(ctx, telegramClient, message, name, action, user, args)
+}
+
+func applyUserAction(ctx context.Context, telegramClient *telegram.Client, message *models.CallbackMessage, name string, action *userAction, user *models.From, args map[string]string) error {
+	description := action.describe(ctx, user, args)
+
+	err := action.apply(ctx, user, args)
+	logUserAction(ctx, message, name, user, args, err)
+
+	if errors.Is(err, storage.ErrUserNotFound) {
+		replyAdmin(ctx, telegramClient, message, translate(ctx, "admin.user_not_found", i18n.Vars{"email": user.Email}), nil)
+
+		return nil
+	}
+
+	if err != nil {
+		replyAdmin(ctx, telegramClient, message, translate(ctx, "admin.action_failed", i18n.Vars{"action": description}), nil)
+
+		return fmt.Errorf("%s failed: %w", name, err)
+	}
+
+	replyAdmin(ctx, telegramClient, message, translate(ctx, "admin.action_done", i18n.Vars{"action": description}), nil)
+
+	return nil
+}
+
+// getAdminTarget returns the user managed by the admin, the admin is told when it does not exist
+func getAdminTarget(ctx context.Context, telegramClient *telegram.Client, message *models.CallbackMessage, email string) (*models.From, error) {
+	user, err := storage.GetUser(ctx, strings.ToLower(email))
+	if errors.Is(err, storage.ErrUserNotFound) {
+		replyAdmin(ctx, telegramClient, message, translate(ctx, "admin.user_not_found", i18n.Vars{"email": email}), nil)
+
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("get user failed: %w", err)
+	}
+
+	return user, nil
+}
+
+// logUserAction writes the audit entry of a change made by an admin
+func logUserAction(ctx context.Context, message *models.CallbackMessage, name string, user *models.From, args map[string]string, err error) {
+	audit := map[string]interface{}{
+		"i_telegram_id":           message.From.ID,
+		"s_username":              message.From.Username,
+		"s_admin_email":           message.From.Email,
+		"s_action":                name,
+		"s_email":                 user.Email,
+		"i_user_id":               user.ID,
+		"s_previous_role":         user.UserRole,
+		"s_previous_bitbucket_id": user.BitbucketID,
+		"s_previous_slack_id":     user.SlackID,
+		"s_previous_verification": verifiedStatus(user),
+	}
+
+	for key, value := range args {
+		audit["s_arg_"+key] = value
+	}
+
+	objects := []logger.Object{logger.MapObject("audit", audit)}
+
+	if err != nil {
+		logger.Get(ctx).Error(ctx, "user_admin_action_failed", logger.ThreeMonths, append(objects, logger.ErrObject(err)))
+
+		return
+	}
+
+	logger.Get(ctx).Info(ctx, "user_admin_action", logger.ThreeMonths, objects)
+}
+
+// replyAdmin edits the message with the buttons pressed by the admin, typed commands get a new message
+func replyAdmin(ctx context.Context, telegramClient *telegram.Client, message *models.CallbackMessage, text string, keyboard *telegram.InlineKeyboardMarkup) {
+	if message.EventType == models.EventCallbackQuery && message.Message.ID != 0 {
+		_, err := telegramClient.EditMessageText(ctx, &telegram.EditMessageTextRequest{
+			ChatID:      replyChatID(message),
+			MessageID:   message.Message.ID,
+			Text:        text,
+			ReplyMarkup: keyboard,
+		})
+		if err == nil {
+			return
+		}
+	}
+
+	telegramClient.SendMessage(ctx, &telegram.SendMessageRequest{
+		ChatID:      replyChatID(message),
+		Text:        text,
+		ReplyMarkup: keyboard,
+	})
+}
+
+// answerCallback stops the loading indicator of the pressed button
+func answerCallback(ctx context.Context, telegramClient *telegram.Client, message *models.CallbackMessage) {
+	err := telegramClient.AnswerCallbackQuery(ctx, &telegram.AnswerCallbackQueryRequest{CallbackQueryID: message.ID})
+	if err != nil {
+		logger.Get(ctx).Warning(ctx, "answer_callback_failed", logger.OneMonth, []logger.Object{logger.ErrObject(err)})
+	}
+}
+
+// signKeyboard signs the command buttons of the keyboard so only the user can press them in the chat,
+// an empty keyboard returns nil
+func signKeyboard(ctx context.Context, keyboard *format.Keyboard, userID, chatID int64) (*telegram.InlineKeyboardMarkup, error) {
+	if len(keyboard.Buttons()) == 0 {
+		return nil, nil
+	}
+
+	codec, err := getCallbackCodec(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return keyboard.Markup(ctx, codec, userID, chatID)
+}
+
+// verifiedText returns the verification status of the user in the language of the context
+func verifiedText(ctx context.Context, user *models.From) string {
+	if user.EmailVerified {
+		return translate(ctx, "admin.verified", nil)
+	}
+
+	return translate(ctx, "admin.not_verified", nil)
+}
+
+// verifiedStatus returns the verification status of the user for the logs
+func verifiedStatus(user *models.From) string {
+	if user.EmailVerified {
+		return "verified"
+	}
+
+	return "not verified"
+}
@@ -0,0 +1,267 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"shared/app/bot/models"
+	"shared/app/bot/storage"
+	"shared/shared/telegram"
+
+	"bitbucket.org/truora/scrap-services/shared/cache"
+	"github.com/stretchr/testify/require"
+)
+
+func newAdminCallback(updateID int64, messageID int, data string) []byte {
+	return []byte(fmt.Sprintf(`{"update_id":%d,"callback_query":{"id":"cb-%d","from":{"id":10},"message":{"message_id":%d,"chat":{"id":10,"type":"private"}},"data":%q}}`, updateID, updateID, messageID, data))
+}
+
+func saveAdmin(c *require.Assertions) {
+	storage.InitDynamoMock()
+
+	err := storage.PutUser(context.Background(), &models.From{ID: 10, Email: "admin@dummy.com", EmailVerified: true, UserRole: models.RoleAdmin, CreationDate: time.Now()})
+	c.NoError(err)
+}
+
+// keyboardButton returns the callback data of the button with the text in the request
+func keyboardButton(c *require.Assertions, request telegram.FakeRequest, text string) string {
+	markup, ok := request.Params["reply_markup"].(map[string]interface{})
+	c.True(ok)
+
+	for _, row := range markup["inline_keyboard"].([]interface{}) {
+		for _, button := range row.([]interface{}) {
+			if button.(map[string]interface{})["text"] == text {
+				return button.(map[string]interface{})["callback_data"].(string)
+			}
+		}
+	}
+
+	c.Failf("button not found", "no %q button", text)
+
+	return ""
+}
+
+func TestListUsersPages(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+	saveAdmin(c)
+	setMockedCallbackCodec(c)
+
+	defer resetCallbackCodec()
+
+	fake, restore := setFakeTelegram()
+
+	defer restore()
+
+	usersPageSize = 2
+
+	defer func() {
+		usersPageSize = 10
+	}()
+
+	ctx := context.Background()
+
+	for _, email := range []string{"a@dummy.com", "b@dummy.com"} {
+		c.NoError(storage.PutUser(ctx, &models.From{Email: email, UserRole: models.RoleDevelopers}))
+	}
+
+	c.NoError(ProcessUpdate(ctx, newScheduleUpdate(1, "/users")))
+
+	sent := fake.Requests("sendMessage")
+	c.Len(sent, 1)
+
+	next := keyboardButton(c, sent[0], "Next")
+
+	c.NoError(ProcessUpdate(ctx, newAdminCallback(2, 1, next)))
+
+	edits := fake.Requests("editMessageText")
+	c.Len(edits, 1)
+	c.Contains(edits[0].Params["text"], "@dummy.com")
+	c.Len(fake.Requests("answerCallbackQuery"), 1)
+}
+
+func TestShowUser(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+	saveAdmin(c)
+
+	fake, restore := setFakeTelegram()
+
+	defer restore()
+
+	ctx := context.Background()
+
+	c.NoError(ProcessUpdate(ctx, newScheduleUpdate(1, "/user admin@dummy.com")))
+	c.Contains(fake.SentTexts()[0], "Email: admin@dummy.com")
+	c.Contains(fake.SentTexts()[0], "Role: "+models.RoleAdmin)
+
+	c.NoError(ProcessUpdate(ctx, newScheduleUpdate(2, "/user missing@dummy.com")))
+	c.Contains(fake.SentTexts()[1], "No user with email missing@dummy.com")
+}
+
+func TestUserActionConfirmed(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+	saveAdmin(c)
+	setMockedCallbackCodec(c)
+
+	defer resetCallbackCodec()
+
+	fake, restore := setFakeTelegram()
+
+	defer restore()
+
+	ctx := context.Background()
+
+	c.NoError(storage.PutUser(ctx, &models.From{ID: 20, Email: "dummy_email@dummy.com", EmailVerified: true, UserRole: models.RoleDevelopers}))
+
+	c.NoError(ProcessUpdate(ctx, newScheduleUpdate(1, "/setrole dummy_email@dummy.com leads")))
+	c.Equal("unknown role leads, the roles are: admin, developers", fake.SentTexts()[0])
+
+	c.NoError(ProcessUpdate(ctx, newScheduleUpdate(2, "/setrole dummy_email@dummy.com admin")))
+	c.Contains(fake.SentTexts()[1], "Do you want to change the role of dummy_email@dummy.com from developers to admin?")
+
+	// the role does not change until the admin confirms it
+	user, err := storage.GetUser(ctx, "dummy_email@dummy.com")
+	c.NoError(err)
+	c.Equal(models.RoleDevelopers, user.UserRole)
+
+	confirmation := fake.Requests("sendMessage")[1]
+
+	c.NoError(ProcessUpdate(ctx, newAdminCallback(3, 2, keyboardButton(c, confirmation, "Yes"))))
+
+	edits := fake.Requests("editMessageText")
+	c.Len(edits, 1)
+	c.Equal("Done, change the role of dummy_email@dummy.com from developers to admin", edits[0].Params["text"])
+
+	user, err = storage.GetUser(ctx, "dummy_email@dummy.com")
+	c.NoError(err)
+	c.Equal(models.RoleAdmin, user.UserRole)
+
+	// the confirmation can not be applied twice
+	c.NoError(ProcessUpdate(ctx, newAdminCallback(31, 2, keyboardButton(c, confirmation, "Yes"))))
+	c.Contains(fake.SentTexts()[2], "This button expired")
+	c.Len(fake.Requests("editMessageText"), 1)
+
+	c.NoError(ProcessUpdate(ctx, newScheduleUpdate(4, "/deleteuser dummy_email@dummy.com")))
+
+	confirmation = fake.Requests("sendMessage")[3]
+
+	c.NoError(ProcessUpdate(ctx, newAdminCallback(5, 3, keyboardButton(c, confirmation, "No"))))
+	c.Equal("Canceled, the user was not changed", fake.Requests("editMessageText")[1].Params["text"])
+
+	_, err = storage.GetUser(ctx, "dummy_email@dummy.com")
+	c.NoError(err)
+
+	c.NoError(ProcessUpdate(ctx, newAdminCallback(6, 3, keyboardButton(c, confirmation, "Yes"))))
+	c.Equal("Done, delete dummy_email@dummy.com", fake.Requests("editMessageText")[2].Params["text"])
+
+	_, err = storage.GetUser(ctx, "dummy_email@dummy.com")
+	c.ErrorIs(err, storage.ErrUserNotFound)
+
+	// admins can not lock themselves out
+	c.NoError(ProcessUpdate(ctx, newScheduleUpdate(7, "/revokeuser admin@dummy.com")))
+	c.Contains(fake.SentTexts()[4], "You can not run this command on your own user")
+}
+
+func TestLinkBitbucket(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+	saveAdmin(c)
+
+	fake, restore := setFakeTelegram()
+
+	defer restore()
+
+	ctx := context.Background()
+
+	c.NoError(storage.PutUser(ctx, &models.From{ID: 20, Email: "dummy_email@dummy.com", EmailVerified: true}))
+
+	c.NoError(ProcessUpdate(ctx, newScheduleUpdate(1, "/linkbitbucket dummy_email@dummy.com {abc-123}")))
+	c.Contains(fake.SentTexts()[0], "Done, link the Bitbucket account {abc-123} to dummy_email@dummy.com")
+
+	user, err := storage.GetTelegramUserByBitbucketID(ctx, "{abc-123}")
+	c.NoError(err)
+	c.Equal("dummy_email@dummy.com", user.Email)
+}
+
+func TestLinkSlack(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+	saveAdmin(c)
+
+	fake, restore := setFakeTelegram()
+
+	defer restore()
+
+	ctx := context.Background()
+
+	c.NoError(storage.PutUser(ctx, &models.From{ID: 20, Email: "dummy_email@dummy.com", EmailVerified: true}))
+
+	c.NoError(ProcessUpdate(ctx, newScheduleUpdate(1, "/linkslack dummy_email@dummy.com U024BE7LH")))
+	c.Contains(fake.SentTexts()[0], "Done, link the Slack user U024BE7LH to dummy_email@dummy.com")
+
+	user, err := storage.GetVerifiedUserBySlackID(ctx, "U024BE7LH")
+	c.NoError(err)
+	c.Equal("dummy_email@dummy.com", user.Email)
+
+	c.NoError(ProcessUpdate(ctx, newScheduleUpdate(2, "/user dummy_email@dummy.com")))
+	c.Contains(fake.SentTexts()[1], "Slack user: U024BE7LH")
+}
+
+func TestUserActionNotAdmin(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+	saveVerifiedUser(c, 10, "dummy_email@dummy.com")
+
+	fake, restore := setFakeTelegram()
+
+	defer restore()
+
+	c.NoError(ProcessUpdate(context.Background(), newScheduleUpdate(1, "/deleteuser other@dummy.com")))
+	c.Contains(fake.SentTexts()[0], "You are not allowed to run /deleteuser")
+}
+
+func TestAddUser(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+	saveAdmin(c)
+
+	fake, restore := setFakeTelegram()
+
+	defer restore()
+
+	ctx := context.Background()
+
+	c.NoError(ProcessUpdate(ctx, newScheduleUpdate(1, "/adduser Dev@Dummy.com")))
+	c.Equal("Added dev@dummy.com as developers, they can link their Telegram account with /register", fake.SentTexts()[0])
+
+	user, err := storage.GetUser(ctx, "dev@dummy.com")
+	c.NoError(err)
+	c.Equal(models.RoleDevelopers, user.UserRole)
+	c.False(user.EmailVerified)
+
+	c.NoError(ProcessUpdate(ctx, newScheduleUpdate(2, "/adduser dev@dummy.com admin")))
+	c.Equal("dev@dummy.com is already a user, send /user dev@dummy.com to see it", fake.SentTexts()[1])
+
+	c.NoError(ProcessUpdate(ctx, newScheduleUpdate(3, "/adduser ops@dummy.com ops")))
+	c.Equal("unknown role ops, the roles are: admin, developers", fake.SentTexts()[2])
+
+	c.NoError(ProcessUpdate(ctx, newScheduleUpdate(4, "/adduser not-an-email")))
+	c.Contains(fake.SentTexts()[3], "Please send a valid email")
+
+	c.NoError(ProcessUpdate(ctx, newScheduleUpdate(5, "/adduser ops@dummy.com admin")))
+
+	user, err = storage.GetUser(ctx, "ops@dummy.com")
+	c.NoError(err)
+	c.Equal(models.RoleAdmin, user.UserRole)
+}
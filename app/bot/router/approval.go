@@ -0,0 +1,380 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"shared/app/bot/commands"
+	"shared/app/bot/format"
+	"shared/app/bot/i18n"
+	"shared/app/bot/models"
+	"shared/app/bot/storage"
+	"shared/app/bot/storage/gate"
+	"shared/app/bot/storage/queue"
+	"shared/shared/telegram"
+
+	"bitbucket.org/truora/scrap-services/logger"
+	"bitbucket.org/truora/scrap-services/shared/env"
+)
+
+const (
+	// approvalCommand is only sent by the buttons of the approval requests, it is not in the registry
+	approvalCommand = "approval"
+	approveAction   = "approve"
+	rejectAction    = "reject"
+	actionSeparator = ":"
+)
+
+// commandApprovals overrides the approvals required by each command, e.g. COMMAND_APPROVALS={"deployterraformstaging":2}
+var commandApprovals, errCommandApprovals = parseCommandApprovals(env.GetString("COMMAND_APPROVALS", ""))
+
+func parseCommandApprovals(config string) (map[string]int, error) {
+	approvals := map[string]int{}
+
+	if config == "" {
+		return approvals, nil
+	}
+
+	err := json.Unmarshal([]byte(config), &approvals)
+	if err != nil {
+		return map[string]int{}, fmt.Errorf("invalid COMMAND_APPROVALS: %w", err)
+	}
+
+	return approvals, nil
+}
+
+func requiredApprovals(command *commands.Command) int {
+	if approvals, ok := commandApprovals[command.Name]; ok {
+		return approvals
+	}
+
+	return command.Approvals
+}
+
+// submitCommand publishes the command, the commands that need approvals are parked until they are approved
+// and the commands stopped by the flags are not published
+func submitCommand(ctx context.Context, telegramClient *telegram.Client, botCommand *commands.Command, message *models.CallbackMessage) error {
+	if blockedByFlags(ctx, telegramClient, message, botCommand) {
+		return nil
+	}
+
+	if requiredApprovals(botCommand) > 0 {
+		return requestApproval(ctx, telegramClient, botCommand, message)
+	}
+
+	return publishCommand(ctx, telegramClient, botCommand, message)
+}
+
+// requestApproval parks the command and sends the approval buttons to the users allowed to approve it,
+// the requester is never asked
+func requestApproval(ctx context.Context, telegramClient *telegram.Client, botCommand *commands.Command, message *models.CallbackMessage) error {
+	required := requiredApprovals(botCommand)
+
+	approvers, err := eligibleApprovers(ctx, botCommand, message.From.ID)
+	if err != nil {
+		return err
+	}
+
+	if len(approvers) < required {
+		setOutcome(ctx, models.OutcomeDenied)
+		telegramClient.SendText(ctx, replyChatID(message), translate(ctx, "approval.not_enough_approvers", i18n.Vars{"command": botCommand.Name, "count": required}))
+
+		return nil
+	}
+
+	id, err := gate.NewID()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	request := &models.ApprovalRequest{
+		ID:        id,
+		Command:   botCommand.Name,
+		Required:  required,
+		Message:   *message,
+		CreatedAt: now,
+		ExpiresAt: now.Add(gate.TTL),
+	}
+
+	err = gate.Park(ctx, request)
+	if err != nil {
+		return err
+	}
+
+	vars := i18n.Vars{"user": userName(message.From), "command": commandText(message, botCommand), "count": required, "id": id}
+
+	for _, approver := range approvers {
+		prompt, err := sendApprovalPrompt(ctx, telegramClient, approver, id, vars)
+		if err != nil {
+			// e.g. the approver never started a chat with the bot
+			logger.Get(ctx).Warning(ctx, "send_approval_prompt_failed", logger.OneMonth, []logger.Object{
+				logger.ErrObject(err),
+				logger.MapObject("approval", map[string]interface{}{"s_id": id, "i_approver_id": approver.ID}),
+			})
+
+			continue
+		}
+
+		request.Prompts = append(request.Prompts, *prompt)
+	}
+
+	err = gate.Save(ctx, request)
+	if err != nil {
+		return err
+	}
+
+	setOutcome(ctx, models.OutcomeAwaitingApproval)
+	logApproval(ctx, "approval_requested", request, message.From)
+	telegramClient.SendText(ctx, replyChatID(message), translate(ctx, "approval.requested", i18n.Vars{"command": botCommand.Name, "count": required, "id": id}))
+
+	return nil
+}
+
+// eligibleApprovers returns the verified users allowed to approve the command except the requester
+func eligibleApprovers(ctx context.Context, botCommand *commands.Command, requesterID int64) ([]*models.From, error) {
+	users, err := storage.ListVerifiedUsers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list approvers failed: %w", err)
+	}
+
+	approvers := []*models.From{}
+
+	for _, user := range users {
+		if user.ID != requesterID && botCommand.AllowsApprover(user.UserRole) {
+			approvers = append(approvers, user)
+		}
+	}
+
+	return approvers, nil
+}
+
+// sendApprovalPrompt sends the request with the approval buttons to the approver in its language
+func sendApprovalPrompt(ctx context.Context, telegramClient *telegram.Client, approver *models.From, id string, vars i18n.Vars) (*models.ApprovalPrompt, error) {
+	approverID := approver.ID
+	keyboard := format.NewKeyboard().Row(
+		format.CommandButton(translateFor(ctx, *approver, "approval.approve", nil), approvalCommand, approveAction+actionSeparator+id),
+		format.CommandButton(translateFor(ctx, *approver, "approval.reject", nil), approvalCommand, rejectAction+actionSeparator+id),
+	)
+
+	markup, err := signKeyboard(ctx, keyboard, approverID, approverID)
+	if err != nil {
+		return nil, err
+	}
+
+	sent, err := telegramClient.SendMessage(ctx, &telegram.SendMessageRequest{
+		ChatID:      approverID,
+		Text:        translateFor(ctx, *approver, "approval.prompt", vars),
+		ReplyMarkup: markup,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.ApprovalPrompt{ChatID: approverID, MessageID: sent.MessageID}, nil
+}
+
+// handleApproval applies the button pressed by an approver, the role of the approver is checked again
+// because it could change since the request was sent
+func handleApproval(ctx context.Context, telegramClient *telegram.Client, message *models.CallbackMessage) error {
+	if message.EventType != models.EventCallbackQuery {
+		return nil
+	}
+
+	answerCallback(ctx, telegramClient, message)
+
+	action, id, _ := strings.Cut(message.Data, actionSeparator)
+
+	request, err := gate.Get(ctx, id)
+	if errors.Is(err, gate.ErrRequestNotFound) {
+		replyAdmin(ctx, telegramClient, message, translate(ctx, "approval.already_resolved", i18n.Vars{"id": id}), nil)
+
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	botCommand, err := botCommands.Get(request.Command)
+	if err != nil {
+		return err
+	}
+
+	if !botCommand.AllowsApprover(message.From.UserRole) {
+		replyAdmin(ctx, telegramClient, message, translate(ctx, "approval.not_allowed", i18n.Vars{"command": request.Command}), nil)
+
+		return nil
+	}
+
+	if action == rejectAction {
+		return rejectRequest(ctx, telegramClient, message, request)
+	}
+
+	return approveRequest(ctx, telegramClient, message, botCommand, request)
+}
+
+func approveRequest(ctx context.Context, telegramClient *telegram.Client, message *models.CallbackMessage, botCommand *commands.Command, request *models.ApprovalRequest) error {
+	approvals, err := gate.Approve(ctx, request, message.From.ID, time.Now())
+	if errors.Is(err, gate.ErrSelfApproval) {
+		replyAdmin(ctx, telegramClient, message, translate(ctx, "approval.own_request", nil), nil)
+
+		return nil
+	}
+
+	if errors.Is(err, gate.ErrRequestExpired) {
+		replyAdmin(ctx, telegramClient, message, translate(ctx, "approval.request_expired", i18n.Vars{"id": request.ID}), nil)
+
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	logApproval(ctx, "approval_granted", request, message.From)
+
+	if approvals < request.Required {
+		replyAdmin(ctx, telegramClient, message, translate(ctx, "approval.approved_pending", i18n.Vars{"command": request.Command, "id": request.ID, "count": request.Required - approvals}), nil)
+
+		return nil
+	}
+
+	err = gate.Resolve(ctx, request)
+	if errors.Is(err, gate.ErrRequestResolved) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	resolvePrompts(ctx, telegramClient, request, "approval.approved", i18n.Vars{"command": request.Command, "id": request.ID})
+	logApproval(ctx, "approval_completed", request, message.From)
+
+	parked := request.Message
+	// the acknowledgement is sent to the requester in its language
+	requesterCtx := withUserLanguage(ctx, &parked.From)
+
+	// the command could be disabled while it was waiting for the approvals
+	if blockedByFlags(requesterCtx, telegramClient, &parked, botCommand) {
+		return nil
+	}
+
+	return publishCommand(requesterCtx, telegramClient, botCommand, &parked)
+}
+
+func rejectRequest(ctx context.Context, telegramClient *telegram.Client, message *models.CallbackMessage, request *models.ApprovalRequest) error {
+	err := gate.Resolve(ctx, request)
+	if errors.Is(err, gate.ErrRequestResolved) {
+		replyAdmin(ctx, telegramClient, message, translate(ctx, "approval.already_resolved", i18n.Vars{"id": request.ID}), nil)
+
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	vars := i18n.Vars{"user": userName(message.From), "command": request.Command, "id": request.ID}
+
+	resolvePrompts(ctx, telegramClient, request, "approval.rejected_prompt", vars)
+	telegramClient.SendText(ctx, replyChatID(&request.Message), translateFor(ctx, request.Message.From, "approval.rejected", vars))
+	logApproval(ctx, "approval_rejected", request, message.From)
+
+	return nil
+}
+
+// ExpireApprovals tells the requesters and approvers that the requests expired at now were not approved in time
+func ExpireApprovals(ctx context.Context, now time.Time) error {
+	var telegramClient *telegram.Client
+
+	for {
+		request, err := gate.ClaimExpired(ctx, now)
+		if errors.Is(err, gate.ErrNoExpiredRequest) {
+			return nil
+		}
+
+		// the request is already out of the queue, resolved by someone else or lost
+		if errors.Is(err, gate.ErrRequestResolved) || errors.Is(err, gate.ErrRequestNotFound) {
+			continue
+		}
+
+		if errors.Is(err, queue.ErrDropped) {
+			logger.Get(ctx).Error(ctx, "approval_request_dropped", logger.OneMonth, []logger.Object{logger.ErrObject(err)})
+
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if telegramClient == nil {
+			telegramClient, err = newTelegramClient(ctx)
+			if err != nil {
+				return err
+			}
+		}
+
+		vars := i18n.Vars{"command": request.Command, "id": request.ID}
+
+		resolvePrompts(ctx, telegramClient, request, "approval.expired_prompt", vars)
+		telegramClient.SendText(ctx, replyChatID(&request.Message), translateFor(ctx, request.Message.From, "approval.expired", vars))
+		logApproval(ctx, "approval_expired", request, models.From{})
+	}
+}
+
+// resolvePrompts replaces the approval buttons sent to every approver with the result of the request in
+// the language of the approver
+func resolvePrompts(ctx context.Context, telegramClient *telegram.Client, request *models.ApprovalRequest, key string, vars i18n.Vars) {
+	for _, prompt := range request.Prompts {
+		_, err := telegramClient.EditMessageText(ctx, &telegram.EditMessageTextRequest{
+			ChatID:    prompt.ChatID,
+			MessageID: prompt.MessageID,
+			Text:      translateFor(ctx, models.From{ID: prompt.ChatID}, key, vars),
+		})
+		if err != nil {
+			logger.Get(ctx).Warning(ctx, "edit_approval_prompt_failed", logger.OneMonth, []logger.Object{logger.ErrObject(err)})
+		}
+	}
+}
+
+func logApproval(ctx context.Context, event string, request *models.ApprovalRequest, actor models.From) {
+	logger.Get(ctx).Info(ctx, event, logger.ThreeMonths, []logger.Object{
+		logger.MapObject("audit", map[string]interface{}{
+			"s_id":           request.ID,
+			"s_command":      request.Command,
+			"i_required":     request.Required,
+			"i_requester_id": request.RequesterID(),
+			"i_chat_id":      request.Message.Message.Chat.ID,
+			"i_actor_id":     actor.ID,
+			"s_actor_email":  actor.Email,
+		}),
+	})
+}
+
+// commandText returns the command as typed by the user, the commands sent by buttons only have their name
+func commandText(message *models.CallbackMessage, botCommand *commands.Command) string {
+	if strings.HasPrefix(message.Message.Text, commandPrefix) {
+		return message.Message.Text
+	}
+
+	return commandPrefix + botCommand.Name
+}
+
+func userName(user models.From) string {
+	if user.Username != "" {
+		return "@" + user.Username
+	}
+
+	if user.Email != "" {
+		return user.Email
+	}
+
+	return fmt.Sprint(user.ID)
+}
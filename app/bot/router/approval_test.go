@@ -0,0 +1,256 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+
+	"shared/app/bot/models"
+	"shared/app/bot/storage"
+	"shared/app/bot/storage/gate"
+	"shared/shared/telegram"
+
+	"bitbucket.org/truora/scrap-services/shared/cache"
+	"github.com/stretchr/testify/require"
+)
+
+var requestID = regexp.MustCompile(`id: ([0-9a-f]+)`)
+
+func newApprovalCallback(updateID, approverID int64, data string) []byte {
+	return []byte(fmt.Sprintf(`{"update_id":%d,"callback_query":{"id":"cb-%d","from":{"id":%d},"message":{"message_id":1,"chat":{"id":%d,"type":"private"}},"data":%q}}`, updateID, updateID, approverID, approverID, data))
+}
+
+// saveApprovers stores the requester admin 10, the admins 20 and 30 and the developer 40
+func saveApprovers(c *require.Assertions) {
+	saveAdmin(c)
+
+	ctx := context.Background()
+
+	for id, role := range map[int64]string{20: models.RoleAdmin, 30: models.RoleAdmin, 40: models.RoleDevelopers} {
+		c.NoError(storage.PutUser(ctx, &models.From{ID: id, Email: fmt.Sprintf("user%d@dummy.com", id), EmailVerified: true, UserRole: role}))
+	}
+}
+
+func setCommandApprovals(approvals int) func() {
+	commandApprovals = map[string]int{deployTerraformStagingCommand: approvals}
+
+	return func() {
+		commandApprovals = map[string]int{}
+	}
+}
+
+// promptFor returns the approval prompt sent to the chat
+func promptFor(c *require.Assertions, fake *telegram.Fake, chatID int64) telegram.FakeRequest {
+	for _, request := range fake.Requests("sendMessage") {
+		if request.Params["chat_id"] == float64(chatID) {
+			return request
+		}
+	}
+
+	c.Failf("prompt not found", "no prompt sent to %d", chatID)
+
+	return telegram.FakeRequest{}
+}
+
+func lastEdit(fake *telegram.Fake) interface{} {
+	edits := fake.Requests("editMessageText")
+
+	return edits[len(edits)-1].Params["text"]
+}
+
+func TestApprovalGranted(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+	saveApprovers(c)
+	setMockedCallbackCodec(c)
+
+	defer resetCallbackCodec()
+	defer setCommandApprovals(2)()
+
+	fake, restore := setFakeTelegram()
+
+	defer restore()
+
+	published := []*models.CallbackMessage{}
+
+	SetPublisher(func(ctx context.Context, topic, attribute string, message *models.CallbackMessage) error {
+		published = append(published, message)
+
+		return nil
+	})
+
+	defer SetPublisher(nil)
+
+	ctx := context.Background()
+
+	c.NoError(ProcessUpdate(ctx, newScheduleUpdate(1, "/deployterraformstaging checks/core")))
+	c.Empty(published)
+
+	texts := fake.SentTexts()
+	c.Len(texts, 3)
+	c.Contains(texts[2], "/deployterraformstaging needs 2 approvals, I asked the approvers")
+
+	id := requestID.FindStringSubmatch(texts[2])[1]
+
+	// neither the requester nor the developers are asked
+	c.Contains(promptFor(c, fake, 20).Params["text"], "admin@dummy.com asks to run /deployterraformstaging checks/core")
+	approve := keyboardButton(c, promptFor(c, fake, 20), "Approve")
+	secondApprove := keyboardButton(c, promptFor(c, fake, 30), "Approve")
+
+	c.NoError(ProcessUpdate(ctx, newApprovalCallback(2, 20, approve)))
+	c.Contains(lastEdit(fake), "it needs 1 more approval")
+
+	// the approvals of the same user are counted once
+	c.NoError(ProcessUpdate(ctx, newApprovalCallback(3, 20, approve)))
+	c.Contains(lastEdit(fake), "it needs 1 more approval")
+	c.Empty(published)
+
+	// the requester can not approve its own request
+	c.NoError(ProcessUpdate(ctx, newAdminCallback(4, 1, encodeTestCallback(c, approvalCommand, approveAction+actionSeparator+id, 10, 10))))
+	c.Equal("You can not approve your own request", lastEdit(fake))
+
+	c.NoError(ProcessUpdate(ctx, newApprovalCallback(5, 30, secondApprove)))
+	c.Len(published, 1)
+	c.Equal(int64(10), published[0].From.ID)
+	c.Equal("/deployterraformstaging checks/core", published[0].Message.Text)
+	c.Contains(lastEdit(fake), "Approved, /deployterraformstaging was sent")
+
+	c.NoError(ProcessUpdate(ctx, newApprovalCallback(6, 30, secondApprove)))
+	c.Contains(lastEdit(fake), "was already resolved")
+	c.Len(published, 1)
+}
+
+func TestApprovalRejected(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+	saveApprovers(c)
+	setMockedCallbackCodec(c)
+
+	defer resetCallbackCodec()
+	defer setCommandApprovals(1)()
+
+	fake, restore := setFakeTelegram()
+
+	defer restore()
+
+	published := 0
+
+	SetPublisher(func(ctx context.Context, topic, attribute string, message *models.CallbackMessage) error {
+		published++
+
+		return nil
+	})
+
+	defer SetPublisher(nil)
+
+	ctx := context.Background()
+
+	c.NoError(ProcessUpdate(ctx, newScheduleUpdate(1, "/deployterraformstaging checks/core")))
+
+	id := requestID.FindStringSubmatch(fake.SentTexts()[2])[1]
+
+	// the developers are not allowed to approve
+	c.NoError(ProcessUpdate(ctx, newApprovalCallback(2, 40, encodeTestCallback(c, approvalCommand, approveAction+actionSeparator+id, 40, 40))))
+	c.Equal("You are not allowed to approve /deployterraformstaging", lastEdit(fake))
+
+	c.NoError(ProcessUpdate(ctx, newApprovalCallback(3, 20, keyboardButton(c, promptFor(c, fake, 20), "Reject"))))
+	c.Contains(lastEdit(fake), "Rejected by user20@dummy.com, /deployterraformstaging was not sent")
+	c.Contains(fake.SentTexts()[3], "Your /deployterraformstaging request was rejected by user20@dummy.com")
+
+	c.NoError(ProcessUpdate(ctx, newApprovalCallback(4, 30, keyboardButton(c, promptFor(c, fake, 30), "Approve"))))
+	c.Contains(lastEdit(fake), "was already resolved")
+	c.Zero(published)
+}
+
+func TestApprovalWithoutApprovers(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+	saveApprovers(c)
+
+	defer setCommandApprovals(3)()
+
+	fake, restore := setFakeTelegram()
+
+	defer restore()
+
+	c.NoError(ProcessUpdate(context.Background(), newScheduleUpdate(1, "/deployterraformstaging checks/core")))
+	c.Equal([]string{"/deployterraformstaging needs 3 approvals but there are not enough approvers"}, fake.SentTexts())
+}
+
+func TestApprovalDeclaredByCommand(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+	saveApprovers(c)
+	setMockedCallbackCodec(c)
+
+	defer resetCallbackCodec()
+
+	fake, restore := setFakeTelegram()
+
+	defer restore()
+
+	published := 0
+
+	SetPublisher(func(ctx context.Context, topic, attribute string, message *models.CallbackMessage) error {
+		published++
+
+		return nil
+	})
+
+	defer SetPublisher(nil)
+
+	c.NoError(ProcessUpdate(context.Background(), newScheduleUpdate(1, "/deployterraformproduction checks/core")))
+	c.Zero(published)
+	c.Contains(fake.SentTexts()[2], "/deployterraformproduction needs 1 approval, I asked the approvers")
+}
+
+func TestExpireApprovals(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+	saveApprovers(c)
+	setMockedCallbackCodec(c)
+
+	defer resetCallbackCodec()
+	defer setCommandApprovals(1)()
+
+	fake, restore := setFakeTelegram()
+
+	defer restore()
+
+	ctx := context.Background()
+
+	c.NoError(ProcessUpdate(ctx, newScheduleUpdate(1, "/deployterraformstaging checks/core")))
+
+	c.NoError(ExpireApprovals(ctx, time.Now()))
+	c.Len(fake.SentTexts(), 3)
+
+	c.NoError(ExpireApprovals(ctx, time.Now().Add(gate.TTL+time.Minute)))
+	c.Contains(fake.SentTexts()[3], "Your /deployterraformstaging request expired without enough approvals")
+	c.Len(fake.Requests("editMessageText"), 2)
+	c.Contains(lastEdit(fake), "Expired, /deployterraformstaging was not sent")
+
+	c.NoError(ProcessUpdate(ctx, newApprovalCallback(2, 20, keyboardButton(c, promptFor(c, fake, 20), "Approve"))))
+	c.Contains(lastEdit(fake), "was already resolved")
+}
+
+func TestParseCommandApprovals(t *testing.T) {
+	c := require.New(t)
+
+	approvals, err := parseCommandApprovals(`{"deployterraformstaging":2}`)
+	c.NoError(err)
+	c.Equal(map[string]int{"deployterraformstaging": 2}, approvals)
+
+	approvals, err = parseCommandApprovals("")
+	c.NoError(err)
+	c.Empty(approvals)
+
+	_, err = parseCommandApprovals("{")
+	c.Error(err)
+}
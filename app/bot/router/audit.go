@@ -0,0 +1,253 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"shared/app/bot/i18n"
+	"shared/app/bot/models"
+	"shared/app/bot/storage"
+	"shared/app/bot/storage/audit"
+	"shared/shared/telegram"
+
+	"bitbucket.org/truora/scrap-services/logger"
+	"bitbucket.org/truora/scrap-services/shared/env"
+)
+
+const (
+	historyCommand  = "history"
+	auditLogCommand = "audit"
+
+	commandArg  = "command"
+	userFlag    = "user"
+	commandFlag = "command"
+
+	// unknownCommand is the audit bucket of the names that are not commands of the bot, the typed names
+	// are not indexed so the audit indexes can not grow without limit
+	unknownCommand = "unknown"
+)
+
+var (
+	historyLimit = int(env.GetInt64("BOT_HISTORY_LIMIT", 10))
+	auditLimit   = int(env.GetInt64("BOT_AUDIT_LIMIT", 20))
+)
+
+type auditContextKey struct{}
+
+// auditEntry is filled while a command is routed and it is stored when the routing finishes, the
+// message is read at the end because the routing adds the user email, args and correlation ID to it
+type auditEntry struct {
+	record  models.AuditRecord
+	message *models.CallbackMessage
+}
+
+// startAudit returns a context carrying a new audit entry, only the updates that route a command are stored
+func startAudit(ctx context.Context) (context.Context, *auditEntry) {
+	entry := &auditEntry{record: models.AuditRecord{CreatedAt: time.Now()}}
+
+	return context.WithValue(ctx, auditContextKey{}, entry), entry
+}
+
+func getAuditEntry(ctx context.Context) *auditEntry {
+	entry, _ := ctx.Value(auditContextKey{}).(*auditEntry)
+
+	return entry
+}
+
+// trackCommand sets the command routed by the update
+func trackCommand(ctx context.Context, message *models.CallbackMessage, command string) {
+	entry := getAuditEntry(ctx)
+	if entry == nil {
+		return
+	}
+
+	entry.message = message
+	entry.record.Command = auditCommand(command)
+}
+
+// auditCommand returns the name the command is recorded with, the aliases are recorded with the name of
+// their command and the names that are not commands of the bot share the unknown bucket
+func auditCommand(name string) string {
+	command, err := botCommands.Get(name)
+	if err == nil {
+		return command.Name
+	}
+
+	if _, ok := routerCommands[name]; ok {
+		return name
+	}
+
+	return unknownCommand
+}
+
+// setOutcome sets how the routing of the command finished, a routing that fails after the command was
+// accepted is recorded as failed
+func setOutcome(ctx context.Context, outcome models.AuditOutcome) {
+	entry := getAuditEntry(ctx)
+	if entry == nil {
+		return
+	}
+
+	entry.record.Outcome = outcome
+}
+
+// finishAudit stores the audit record of the routed command, a failed store does not fail the update
+func finishAudit(ctx context.Context, entry *auditEntry, err error) {
+	if entry == nil || entry.message == nil {
+		return
+	}
+
+	record := entry.record
+	record.UserID = entry.message.From.ID
+	record.Username = entry.message.From.Username
+	record.Email = entry.message.From.Email
+	record.ChatID = entry.message.Message.Chat.ID
+	record.Args = entry.message.Args
+	record.CorrelationID = entry.message.CorrelationID
+	record.Latency = time.Since(record.CreatedAt)
+
+	if err != nil {
+		record.Error = err.Error()
+
+		if record.Outcome != models.OutcomeDenied && record.Outcome != models.OutcomeInvalid {
+			record.Outcome = models.OutcomeFailed
+		}
+	}
+
+	if record.Outcome == "" {
+		record.Outcome = models.OutcomeHandled
+	}
+
+	err = audit.Save(ctx, &record)
+	if err != nil {
+		logger.Get(ctx).Warning(ctx, "save_audit_record_failed", logger.OneMonth, []logger.Object{
+			logger.ErrObject(err),
+			logger.MapObject("audit", map[string]interface{}{
+				"i_telegram_id": record.UserID,
+				"s_command":     record.Command,
+				"s_outcome":     string(record.Outcome),
+			}),
+		})
+	}
+}
+
+// sendHistory sends the latest commands of the user, they can be filtered by command
+func sendHistory(ctx context.Context, telegramClient *telegram.Client, message *models.CallbackMessage) error {
+	command := strings.TrimPrefix(strings.ToLower(message.Args[commandArg]), commandPrefix)
+
+	records, err := listAuditRecords(ctx, message.From.ID, command, historyLimit)
+	if err != nil {
+		return err
+	}
+
+	if len(records) == 0 {
+		telegramClient.SendText(ctx, replyChatID(message), translate(ctx, "history.empty", nil))
+
+		return nil
+	}
+
+	lines := make([]string, 0, len(records))
+
+	for _, record := range records {
+		lines = append(lines, formatAuditRecord(ctx, record, false))
+	}
+
+	telegramClient.SendText(ctx, replyChatID(message), strings.Join(lines, "\n"))
+
+	return nil
+}
+
+// sendAuditLog sends the latest commands of the team to the admin, they can be filtered by user email and command
+func sendAuditLog(ctx context.Context, telegramClient *telegram.Client, message *models.CallbackMessage) error {
+	userID := int64(0)
+	command := ""
+
+	if message.Input != nil {
+		command = strings.TrimPrefix(strings.ToLower(message.Input.Flag(commandFlag)), commandPrefix)
+
+		if message.Input.HasFlag(userFlag) {
+			email := message.Input.Flag(userFlag)
+
+			user, err := storage.GetUser(ctx, strings.ToLower(email))
+			if errors.Is(err, storage.ErrUserNotFound) {
+				telegramClient.SendText(ctx, replyChatID(message), translate(ctx, "admin.user_not_found", i18n.Vars{"email": email}))
+
+				return nil
+			}
+
+			if err != nil {
+				return fmt.Errorf("get user failed: %w", err)
+			}
+
+			userID = user.ID
+		}
+	}
+
+	records, err := listAuditRecords(ctx, userID, command, auditLimit)
+	if err != nil {
+		return err
+	}
+
+	if len(records) == 0 {
+		telegramClient.SendText(ctx, replyChatID(message), translate(ctx, "audit.empty", nil))
+
+		return nil
+	}
+
+	lines := make([]string, 0, len(records))
+
+	for _, record := range records {
+		lines = append(lines, formatAuditRecord(ctx, record, true))
+	}
+
+	telegramClient.SendText(ctx, replyChatID(message), strings.Join(lines, "\n"))
+
+	return nil
+}
+
+// listAuditRecords returns the latest records of the user and the command, zero and empty do not filter
+func listAuditRecords(ctx context.Context, userID int64, command string, limit int) ([]*models.AuditRecord, error) {
+	switch {
+	case userID == 0 && command == "":
+		return audit.List(ctx, limit)
+	case userID == 0:
+		return audit.ListByCommand(ctx, command, limit)
+	case command == "":
+		return audit.ListByUser(ctx, userID, limit)
+	}
+
+	return audit.ListByUserCommand(ctx, userID, command, limit)
+}
+
+func formatAuditRecord(ctx context.Context, record *models.AuditRecord, withUser bool) string {
+	fields := []string{record.CreatedAt.Format("2006-01-02 15:04")}
+
+	if withUser {
+		user := record.Email
+		if user == "" {
+			user = fmt.Sprint(record.UserID)
+		}
+
+		fields = append(fields, user)
+	}
+
+	args := make([]string, 0, len(record.Args))
+
+	for name, value := range record.Args {
+		args = append(args, name+"="+value)
+	}
+
+	sort.Strings(args)
+
+	fields = append(fields,
+		strings.TrimSpace(commandPrefix+record.Command+" "+strings.Join(args, " ")),
+		translate(ctx, "audit.outcome."+string(record.Outcome), nil),
+		record.Latency.Round(time.Millisecond).String(),
+	)
+
+	return strings.Join(fields, " · ")
+}
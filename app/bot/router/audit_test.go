@@ -0,0 +1,114 @@
+package router
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"shared/app/bot/i18n"
+	"shared/app/bot/models"
+	"shared/app/bot/storage/audit"
+
+	"bitbucket.org/truora/scrap-services/shared/cache"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditRecords(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+	saveAdmin(c)
+
+	fake, restore := setFakeTelegram()
+
+	defer restore()
+
+	SetPublisher(func(ctx context.Context, topic, attribute string, message *models.CallbackMessage) error {
+		return nil
+	})
+
+	defer SetPublisher(nil)
+
+	ctx := context.Background()
+
+	c.NoError(ProcessUpdate(ctx, newScheduleUpdate(1, "/deployterraformstaging checks/core")))
+	c.NoError(ProcessUpdate(ctx, []byte(`{"update_id":2,"message":{"message_id":1,"text":"/deployterraformstaging checks/core","from":{"id":30},"chat":{"id":30,"type":"private"}}}`)))
+
+	records, err := audit.ListByCommand(ctx, deployTerraformStagingCommand, 10)
+	c.NoError(err)
+	c.Len(records, 2)
+	c.Equal(models.OutcomeDenied, records[0].Outcome)
+	c.Equal(int64(30), records[0].UserID)
+	c.Equal(models.OutcomePublished, records[1].Outcome)
+	c.Equal("admin@dummy.com", records[1].Email)
+	c.Equal(map[string]string{"project": "checks/core"}, records[1].Args)
+	c.NotEmpty(records[1].CorrelationID)
+
+	c.NoError(ProcessUpdate(ctx, newScheduleUpdate(3, "/history")))
+	c.Contains(fake.SentTexts()[2], "/deployterraformstaging project=checks/core · published")
+
+	c.NoError(ProcessUpdate(ctx, newScheduleUpdate(4, "/history unschedule")))
+	c.Equal("You have not run any command yet", fake.SentTexts()[3])
+
+	c.NoError(ProcessUpdate(ctx, newScheduleUpdate(5, "/audit -u admin@dummy.com -c deployterraformstaging")))
+	c.Contains(fake.SentTexts()[4], "admin@dummy.com · /deployterraformstaging project=checks/core · published")
+	c.NotContains(fake.SentTexts()[4], "denied")
+
+	c.NoError(ProcessUpdate(ctx, newScheduleUpdate(6, "/audit --command deployterraformstaging")))
+	c.Contains(fake.SentTexts()[5], "30 · /deployterraformstaging project=checks/core · denied")
+
+	c.NoError(ProcessUpdate(ctx, newScheduleUpdate(7, "/audit -u missing@dummy.com")))
+	c.Equal("No user with email missing@dummy.com", fake.SentTexts()[6])
+}
+
+func TestAuditUnknownCommands(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+	saveAdmin(c)
+
+	_, restore := setFakeTelegram()
+
+	defer restore()
+
+	ctx := context.Background()
+
+	c.NoError(ProcessUpdate(ctx, newScheduleUpdate(1, "/deploy1 checks/core")))
+	c.NoError(ProcessUpdate(ctx, newScheduleUpdate(2, "/deploy2 checks/core")))
+	c.NoError(ProcessUpdate(ctx, newScheduleUpdate(3, "/start")))
+
+	// the typed names are not indexed
+	records, err := audit.ListByCommand(ctx, "deploy1", 10)
+	c.NoError(err)
+	c.Empty(records)
+
+	records, err = audit.ListByCommand(ctx, unknownCommand, 10)
+	c.NoError(err)
+	c.Len(records, 2)
+	c.Equal(models.OutcomeInvalid, records[0].Outcome)
+
+	// the aliases are recorded with the name of their command
+	records, err = audit.ListByCommand(ctx, helpCommand, 10)
+	c.NoError(err)
+	c.Len(records, 1)
+}
+
+func TestFormatAuditRecord(t *testing.T) {
+	c := require.New(t)
+
+	ctx := context.Background()
+	record := &models.AuditRecord{
+		UserID:    10,
+		Command:   "deployterraformstaging",
+		Args:      map[string]string{"project": "checks/core", "branch": "main"},
+		Outcome:   models.OutcomeDelayed,
+		Latency:   1234567 * time.Microsecond,
+		CreatedAt: time.Date(2024, 5, 1, 10, 30, 0, 0, time.UTC),
+	}
+
+	c.Equal("2024-05-01 10:30 · /deployterraformstaging branch=main project=checks/core · delayed · 1.235s", formatAuditRecord(ctx, record, false))
+	c.Equal("2024-05-01 10:30 · 10 · /deployterraformstaging branch=main project=checks/core · delayed · 1.235s", formatAuditRecord(ctx, record, true))
+
+	ctx = i18n.WithLanguage(ctx, i18n.Spanish)
+	c.Equal("2024-05-01 10:30 · /deployterraformstaging branch=main project=checks/core · demorado · 1.235s", formatAuditRecord(ctx, record, false))
+}
@@ -0,0 +1,49 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"shared/app/bot/callback"
+	"shared/app/bot/models"
+
+	"bitbucket.org/truora/scrap-services/logger"
+)
+
+var getCallbackCodec = callback.NewCodecFromSecret
+
+// decodeCallback verifies the callback data was issued by the bot to the user and chat that pressed
+// the button and sets the command and data of the payload, rejected callbacks are logged
+func decodeCallback(ctx context.Context, message *models.CallbackMessage) error {
+	codec, err := getCallbackCodec(ctx)
+	if err != nil {
+		return err
+	}
+
+	payload, err := codec.Decode(ctx, message.Data, message.From.ID, message.Message.Chat.ID)
+	if err != nil {
+		logger.Get(ctx).Warning(ctx, "callback_rejected", logger.ThreeMonths, []logger.Object{
+			logger.ErrObject(err),
+			logger.MapObject("callback", map[string]interface{}{
+				"i_telegram_id": message.From.ID,
+				"s_username":    message.From.Username,
+				"i_chat_id":     message.Message.Chat.ID,
+				"s_data":        message.Data,
+			}),
+		})
+
+		return fmt.Errorf("%w: %w", ErrInvalidCallback, err)
+	}
+
+	message.Command = payload.Command
+	message.Data = payload.Data
+
+	return nil
+}
+
+// isExpiredCallback returns true if the callback was rejected because it is old or was already used,
+// the user can send the command again
+func isExpiredCallback(err error) bool {
+	return errors.Is(err, callback.ErrExpired) || errors.Is(err, callback.ErrPayloadNotFound) || errors.Is(err, callback.ErrAlreadyUsed)
+}
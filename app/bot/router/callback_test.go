@@ -0,0 +1,126 @@
+package router
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"shared/app/bot/callback"
+	"shared/app/bot/models"
+
+	"bitbucket.org/truora/scrap-services/logger"
+	"bitbucket.org/truora/scrap-services/shared/cache"
+	"bitbucket.org/truora/scrap-services/shared/sns"
+	"github.com/stretchr/testify/require"
+)
+
+var testCallbackCodec *callback.Codec
+
+func setMockedCallbackCodec(c *require.Assertions) {
+	var err error
+
+	testCallbackCodec, err = callback.NewCodec([]byte("callback-secret"), time.Hour)
+	c.NoError(err)
+
+	getCallbackCodec = func(ctx context.Context) (*callback.Codec, error) {
+		return testCallbackCodec, nil
+	}
+}
+
+func resetCallbackCodec() {
+	getCallbackCodec = callback.NewCodecFromSecret
+}
+
+func encodeTestCallback(c *require.Assertions, command, data string, userID, chatID int64) string {
+	encoded, err := testCallbackCodec.Encode(context.Background(), callback.Payload{Command: command, Data: data, UserID: userID, ChatID: chatID})
+	c.NoError(err)
+
+	return encoded
+}
+
+func newTestCallback(data string, userID, chatID int64) *models.CallbackMessage {
+	return &models.CallbackMessage{
+		Data:    data,
+		From:    models.From{ID: userID},
+		Message: models.Message{Chat: models.Chat{ID: chatID, Type: "private"}},
+	}
+}
+
+func TestDecodeCallbackRejected(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+	setMockedCallbackCodec(c)
+
+	defer resetCallbackCodec()
+
+	ctx := context.Background()
+	log := logger.New("test")
+	buf := bytes.NewBufferString("")
+	log.Output = buf
+
+	ctx = logger.Set(ctx, log)
+
+	data := encodeTestCallback(c, "approve", "123", 1, 2)
+
+	// another user replays the callback data
+	err := decodeCallback(ctx, newTestCallback(data, 3, 2))
+	c.ErrorIs(err, ErrInvalidCallback)
+	c.ErrorIs(err, callback.ErrInvalidSignature)
+	c.Contains(buf.String(), "callback_rejected")
+	c.False(isExpiredCallback(err))
+
+	// single-use callback data is rejected the second time
+	singleUse, err := testCallbackCodec.Encode(ctx, callback.Payload{Command: "approve", Data: "123", UserID: 1, ChatID: 2, SingleUse: true})
+	c.NoError(err)
+	c.NoError(decodeCallback(ctx, newTestCallback(singleUse, 1, 2)))
+
+	err = decodeCallback(ctx, newTestCallback(singleUse, 1, 2))
+	c.ErrorIs(err, callback.ErrAlreadyUsed)
+	c.True(isExpiredCallback(err))
+
+	getCallbackCodec = func(ctx context.Context) (*callback.Codec, error) {
+		return nil, callback.ErrEmptySecret
+	}
+
+	err = decodeCallback(ctx, newTestCallback(data, 1, 2))
+	c.ErrorIs(err, callback.ErrEmptySecret)
+}
+
+func TestApiGatewayHandlerCallback(t *testing.T) {
+	c := require.New(t)
+
+	setMockedClient()
+
+	defer deactivateMockedClient()
+
+	cache.InitMock()
+	sns.InitSNSMock()
+	setMockedCallbackCodec(c)
+
+	defer resetCallbackCodec()
+
+	ctx := context.Background()
+	log := logger.New("test")
+	buf := bytes.NewBufferString("")
+	log.Output = buf
+
+	ctx = logger.Set(ctx, log)
+
+	data := encodeTestCallback(c, helpCommand, "", 1, 2)
+	body := `{"update_id":%d,"callback_query":{"id":"1","from":{"id":%d},"message":{"chat":{"id":2,"type":"private"}},"data":%q}}`
+
+	response, err := apiGatewayHandler(ctx, newTestRequest(fmt.Sprintf(body, 1, 1, data)))
+	c.NoError(err)
+	c.Equal(http.StatusOK, response.StatusCode)
+	c.NotContains(buf.String(), "callback_rejected")
+
+	response, err = apiGatewayHandler(ctx, newTestRequest(fmt.Sprintf(body, 2, 3, data)))
+	c.NoError(err)
+	c.Equal(http.StatusOK, response.StatusCode)
+	c.Contains(buf.String(), "callback_rejected")
+	c.NotContains(buf.String(), "process_router_request_failed")
+}
@@ -0,0 +1,306 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"shared/app/bot/format"
+	"shared/app/bot/i18n"
+	"shared/app/bot/models"
+	"shared/app/bot/storage/deadletter"
+	"shared/shared/telegram"
+
+	"bitbucket.org/truora/scrap-services/logger"
+)
+
+const (
+	deadLettersCommand       = "deadletters"
+	deadLetterCommand        = "deadletter"
+	retryDeadLetterCommand   = "retrydeadletter"
+	discardDeadLetterCommand = "discarddeadletter"
+
+	deadLetterIDArg = "id"
+)
+
+var (
+	// errPublishDelayed when the message could not be published and it was parked to be published later
+	errPublishDelayed = errors.New("publish delayed")
+	// errDeadLetterBlocked when the flags stop the command of the parked message, it stays parked
+	errDeadLetterBlocked = errors.New("dead letter blocked by the flags")
+
+	parkDeadLetter = deadletter.Park
+)
+
+// parkMessage stores the message that failed to publish in the dead-letter queue
+func parkMessage(ctx context.Context, topic, attribute string, message *models.CallbackMessage, publishErr error) error {
+	letter, err := parkDeadLetter(ctx, topic, attribute, message, publishErr, time.Now())
+	if err != nil {
+		logger.Get(ctx).Error(ctx, "park_dead_letter_failed", logger.OneMonth, []logger.Object{
+			logger.ErrObject(errors.Join(publishErr, err)),
+			logger.MapObject("dead_letter", map[string]interface{}{"s_topic": topic, "s_attribute": attribute}),
+		})
+
+		return err
+	}
+
+	logDeadLetter(ctx, "dead_letter_parked", letter, models.From{}, publishErr)
+
+	return nil
+}
+
+// ReplayDeadLetter publishes a parked message again, it is deleted once published and attempted again after
+// a backoff when it fails. The user is told when the delayed command is sent or when it runs out of attempts
+func ReplayDeadLetter(ctx context.Context, letter *models.DeadLetter, now time.Time) error {
+	telegramClient, err := newTelegramClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	return replayDeadLetter(ctx, telegramClient, letter, now)
+}
+
+func replayDeadLetter(ctx context.Context, telegramClient *telegram.Client, letter *models.DeadLetter, now time.Time) error {
+	// the command could be disabled or the bot put in maintenance since the message was parked
+	flagErr := deadLetterFlags(ctx, letter)
+	if flagErr != nil {
+		err := deadletter.Postpone(ctx, letter, now)
+		if err != nil {
+			return errors.Join(flagErr, err)
+		}
+
+		logDeadLetter(ctx, "dead_letter_blocked", letter, models.From{}, flagErr)
+
+		return fmt.Errorf("%w: %w", errDeadLetterBlocked, flagErr)
+	}
+
+	publishErr := publishMessage(ctx, letter.Topic, letter.Attribute, &letter.Message)
+	if publishErr != nil {
+		err := deadletter.Fail(ctx, letter, publishErr, now)
+		if err != nil {
+			return errors.Join(publishErr, err)
+		}
+
+		logDeadLetter(ctx, "dead_letter_failed", letter, models.From{}, publishErr)
+
+		if letter.IsExhausted() {
+			notifyDeadLetter(ctx, telegramClient, letter, rejectAcknowledgement)
+		}
+
+		return publishErr
+	}
+
+	err := deadletter.Delete(ctx, letter.ID)
+	if err != nil {
+		return err
+	}
+
+	logDeadLetter(ctx, "dead_letter_replayed", letter, models.From{}, nil)
+	notifyDeadLetter(ctx, telegramClient, letter, resumeAcknowledgement)
+
+	return nil
+}
+
+// deadLetterFlags returns the flag that stops the command of the parked message, the parked events are
+// not stopped by the flags
+func deadLetterFlags(ctx context.Context, letter *models.DeadLetter) error {
+	botCommand, err := botCommands.Get(letter.Attribute)
+	if err != nil {
+		return nil
+	}
+
+	_, err = checkFlags(ctx, &letter.Message, botCommand)
+
+	return err
+}
+
+// notifyDeadLetter updates the acknowledgement of the parked command, the parked events were never
+// acknowledged so their chats are not told, neither are the commands received from Slack
+func notifyDeadLetter(ctx context.Context, telegramClient *telegram.Client, letter *models.DeadLetter, notify func(ctx context.Context, telegramClient *telegram.Client, message *models.CallbackMessage, command string)) {
+	if letter.Message.CorrelationID == "" || letter.Message.Slack != nil {
+		return
+	}
+
+	notify(withUserLanguage(ctx, &letter.Message.From), telegramClient, &letter.Message, letter.Attribute)
+}
+
+// listDeadLetters sends the parked messages to the admin
+func listDeadLetters(ctx context.Context, telegramClient *telegram.Client, message *models.CallbackMessage) error {
+	letters, err := deadletter.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	if len(letters) == 0 {
+		telegramClient.SendText(ctx, replyChatID(message), translate(ctx, "deadletter.empty", nil))
+
+		return nil
+	}
+
+	lines := make([]string, 0, len(letters))
+
+	for _, letter := range letters {
+		lines = append(lines, translate(ctx, "deadletter.list_item", i18n.Vars{
+			"id":      letter.ID,
+			"command": letter.Attribute,
+			"user":    letter.Message.From.ID,
+			"count":   letter.Attempts,
+			"next":    nextAttempt(ctx, letter),
+		}))
+	}
+
+	lines = append(lines, translate(ctx, "deadletter.list_footer", i18n.Vars{"command": deadLetterCommand}))
+
+	// the list is split in the messages that Telegram accepts, a long queue does not fit in one
+	for _, chunk := range format.Split("", strings.Join(lines, "\n"), format.MaxMessageLength) {
+		_, err = telegramClient.SendText(ctx, replyChatID(message), chunk)
+		if err != nil {
+			return fmt.Errorf("send dead letters failed: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// showDeadLetter sends the details of a parked message
+func showDeadLetter(ctx context.Context, telegramClient *telegram.Client, message *models.CallbackMessage) error {
+	letter, err := getDeadLetter(ctx, telegramClient, message)
+	if letter == nil {
+		return err
+	}
+
+	telegramClient.SendText(ctx, replyChatID(message), translate(ctx, "deadletter.details", i18n.Vars{
+		"id":        letter.ID,
+		"attribute": letter.Attribute,
+		"topic":     letter.Topic,
+		"text":      letter.Message.Message.Text,
+		"user":      letter.Message.From.ID,
+		"chat":      letter.Message.Message.Chat.ID,
+		"attempts":  letter.Attempts,
+		"max":       deadletter.MaxAttempts,
+		"error":     letter.LastError,
+		"next":      nextAttempt(ctx, letter),
+		"parked":    letter.CreatedAt.Format("2006-01-02 15:04"),
+		"retry":     retryDeadLetterCommand,
+		"discard":   discardDeadLetterCommand,
+	}))
+
+	return nil
+}
+
+// retryDeadLetter publishes a parked message now, the exhausted messages can only be published this way
+func retryDeadLetter(ctx context.Context, telegramClient *telegram.Client, message *models.CallbackMessage) error {
+	letter, err := getDeadLetter(ctx, telegramClient, message)
+	if letter == nil {
+		return err
+	}
+
+	// the blocked messages are not claimed so they can be retried as soon as the flags let them
+	if deadLetterFlags(ctx, letter) != nil {
+		telegramClient.SendText(ctx, replyChatID(message), translate(ctx, "deadletter.blocked", i18n.Vars{"id": letter.ID, "command": letter.Attribute}))
+
+		return nil
+	}
+
+	now := time.Now()
+
+	err = deadletter.Claim(ctx, letter, now)
+	if errors.Is(err, deadletter.ErrDeadLetterClaimed) {
+		telegramClient.SendText(ctx, replyChatID(message), translate(ctx, "deadletter.claimed", i18n.Vars{"id": letter.ID}))
+
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	logDeadLetter(ctx, "dead_letter_retried", letter, message.From, nil)
+
+	err = replayDeadLetter(ctx, telegramClient, letter, now)
+	if errors.Is(err, errDeadLetterBlocked) {
+		telegramClient.SendText(ctx, replyChatID(message), translate(ctx, "deadletter.blocked", i18n.Vars{"id": letter.ID, "command": letter.Attribute}))
+
+		return nil
+	}
+
+	if err != nil {
+		telegramClient.SendText(ctx, replyChatID(message), translate(ctx, "deadletter.retry_failed", i18n.Vars{"id": letter.ID, "attempt": letter.Attempts, "error": err}))
+
+		return nil
+	}
+
+	telegramClient.SendText(ctx, replyChatID(message), translate(ctx, "deadletter.published", i18n.Vars{"id": letter.ID}))
+
+	return nil
+}
+
+// discardDeadLetter deletes a parked message, the user is told the command will not run
+func discardDeadLetter(ctx context.Context, telegramClient *telegram.Client, message *models.CallbackMessage) error {
+	letter, err := getDeadLetter(ctx, telegramClient, message)
+	if letter == nil {
+		return err
+	}
+
+	err = deadletter.Delete(ctx, letter.ID)
+	if err != nil {
+		return err
+	}
+
+	logDeadLetter(ctx, "dead_letter_discarded", letter, message.From, nil)
+	notifyDeadLetter(ctx, telegramClient, letter, rejectAcknowledgement)
+	telegramClient.SendText(ctx, replyChatID(message), translate(ctx, "deadletter.discarded", i18n.Vars{"id": letter.ID}))
+
+	return nil
+}
+
+// getDeadLetter returns the parked message of the command arguments, the admin is told when it does not exist
+func getDeadLetter(ctx context.Context, telegramClient *telegram.Client, message *models.CallbackMessage) (*models.DeadLetter, error) {
+	id := strings.ToLower(message.Args[deadLetterIDArg])
+
+	letter, err := deadletter.Get(ctx, id)
+	if errors.Is(err, deadletter.ErrDeadLetterNotFound) {
+		telegramClient.SendText(ctx, replyChatID(message), translate(ctx, "deadletter.not_found", i18n.Vars{"id": id}))
+
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return letter, nil
+}
+
+func nextAttempt(ctx context.Context, letter *models.DeadLetter) string {
+	if letter.IsExhausted() {
+		return translate(ctx, "deadletter.out_of_attempts", nil)
+	}
+
+	return letter.NextAttemptAt.Format("2006-01-02 15:04")
+}
+
+func logDeadLetter(ctx context.Context, event string, letter *models.DeadLetter, actor models.From, err error) {
+	objects := []logger.Object{
+		logger.MapObject("dead_letter", map[string]interface{}{
+			"s_id":             letter.ID,
+			"s_topic":          letter.Topic,
+			"s_attribute":      letter.Attribute,
+			"s_correlation_id": letter.Message.CorrelationID,
+			"i_telegram_id":    letter.Message.From.ID,
+			"i_attempts":       letter.Attempts,
+			"i_actor_id":       actor.ID,
+			"s_actor_email":    actor.Email,
+		}),
+	}
+
+	if err != nil {
+		logger.Get(ctx).Warning(ctx, event, logger.ThreeMonths, append(objects, logger.ErrObject(err)))
+
+		return
+	}
+
+	logger.Get(ctx).Info(ctx, event, logger.ThreeMonths, objects)
+}
@@ -0,0 +1,209 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"shared/app/bot/format"
+	"shared/app/bot/models"
+	"shared/app/bot/storage/deadletter"
+	"shared/app/bot/storage/featureflag"
+
+	"bitbucket.org/truora/scrap-services/shared/cache"
+	"github.com/stretchr/testify/require"
+)
+
+// setFailingPublisher makes the publishes fail while *failing is true
+func setFailingPublisher() (*bool, func()) {
+	failing := true
+
+	SetPublisher(func(ctx context.Context, topic, attribute string, message *models.CallbackMessage) error {
+		if failing {
+			return errors.New("sns unavailable")
+		}
+
+		return nil
+	})
+
+	return &failing, func() { SetPublisher(nil) }
+}
+
+func parkedLetter(c *require.Assertions) *models.DeadLetter {
+	letters, err := deadletter.List(context.Background())
+	c.NoError(err)
+	c.NotEmpty(letters)
+
+	return letters[len(letters)-1]
+}
+
+func TestDeadLetterCommands(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+	saveAdmin(c)
+
+	fake, restore := setFakeTelegram()
+
+	defer restore()
+
+	failing, restorePublisher := setFailingPublisher()
+
+	defer restorePublisher()
+
+	ctx := context.Background()
+
+	c.NoError(ProcessUpdate(ctx, newScheduleUpdate(1, "/deployterraformstaging checks/core")))
+	c.Contains(fake.Requests("editMessageText")[0].Params["text"], "/deployterraformstaging is delayed")
+
+	letter := parkedLetter(c)
+
+	c.NoError(ProcessUpdate(ctx, newScheduleUpdate(2, "/deadletters")))
+	c.Contains(fake.SentTexts()[1], letter.ID+" · /deployterraformstaging · user 10 · 1 attempt · ")
+
+	c.NoError(ProcessUpdate(ctx, newScheduleUpdate(3, "/deadletter "+letter.ID)))
+	c.Contains(fake.SentTexts()[2], "Last error: sns unavailable")
+	c.Contains(fake.SentTexts()[2], "Text: /deployterraformstaging checks/core")
+
+	*failing = false
+
+	c.NoError(ProcessUpdate(ctx, newScheduleUpdate(4, "/retrydeadletter "+letter.ID)))
+	c.Equal("Published "+letter.ID, fake.SentTexts()[3])
+	c.Contains(fake.Requests("editMessageText")[1].Params["text"], "Accepted /deployterraformstaging")
+
+	c.NoError(ProcessUpdate(ctx, newScheduleUpdate(5, "/deadletters")))
+	c.Equal("There are no parked messages", fake.SentTexts()[4])
+
+	*failing = true
+
+	c.NoError(ProcessUpdate(ctx, newScheduleUpdate(6, "/deployterraformstaging checks/core")))
+
+	letter = parkedLetter(c)
+
+	c.NoError(ProcessUpdate(ctx, newScheduleUpdate(7, "/discarddeadletter "+letter.ID)))
+	c.Equal("Discarded "+letter.ID, fake.SentTexts()[6])
+	c.Contains(lastEdit(fake), "Could not run /deployterraformstaging")
+
+	c.NoError(ProcessUpdate(ctx, newScheduleUpdate(8, "/deadletter "+letter.ID)))
+	c.Equal("No parked message with ID "+letter.ID, fake.SentTexts()[7])
+}
+
+func TestListDeadLettersSplit(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+	saveAdmin(c)
+
+	fake, restore := setFakeTelegram()
+
+	defer restore()
+
+	ctx := context.Background()
+	message := &models.CallbackMessage{From: models.From{ID: 10}}
+
+	for i := 0; i < 80; i++ {
+		_, err := deadletter.Park(ctx, botCommandsTopic, deployTerraformStagingCommand, message, errors.New("sns unavailable"), time.Now())
+		c.NoError(err)
+	}
+
+	c.NoError(ProcessUpdate(ctx, newScheduleUpdate(1, "/deadletters")))
+
+	texts := fake.SentTexts()
+	c.Len(texts, 2)
+
+	for _, text := range texts {
+		c.LessOrEqual(utf8.RuneCountInString(text), format.MaxMessageLength)
+	}
+
+	c.Contains(texts[1], "Send /deadletter <id> to see a message")
+
+	fake.AddError("sendMessage", http.StatusBadRequest, "Bad Request: message is too long")
+
+	c.ErrorContains(ProcessUpdate(ctx, newScheduleUpdate(2, "/deadletters")), "send dead letters failed")
+}
+
+func TestReplayDeadLetter(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+	saveAdmin(c)
+
+	fake, restore := setFakeTelegram()
+
+	defer restore()
+
+	_, restorePublisher := setFailingPublisher()
+
+	defer restorePublisher()
+
+	oldMaxAttempts := deadletter.MaxAttempts
+	deadletter.MaxAttempts = 2
+
+	defer func() {
+		deadletter.MaxAttempts = oldMaxAttempts
+	}()
+
+	ctx := context.Background()
+
+	c.NoError(ProcessUpdate(ctx, newScheduleUpdate(1, "/deployterraformstaging checks/core")))
+
+	letter := parkedLetter(c)
+
+	err := ReplayDeadLetter(ctx, letter, time.Now())
+	c.Error(err)
+	c.True(letter.IsExhausted())
+	c.Contains(lastEdit(fake), "Could not run /deployterraformstaging")
+
+	// the exhausted messages are kept for the admins
+	stored, err := deadletter.Get(ctx, letter.ID)
+	c.NoError(err)
+	c.Equal(2, stored.Attempts)
+}
+
+func TestReplayDeadLetterBlocked(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+	saveAdmin(c)
+
+	fake, restore := setFakeTelegram()
+
+	defer restore()
+
+	failing, restorePublisher := setFailingPublisher()
+
+	defer restorePublisher()
+
+	ctx := context.Background()
+
+	c.NoError(ProcessUpdate(ctx, newScheduleUpdate(1, "/deployterraformstaging checks/core")))
+
+	letter := parkedLetter(c)
+	*failing = false
+
+	c.NoError(featureflag.SetMaintenance(ctx, "deploying the bot"))
+
+	now := time.Now()
+
+	err := ReplayDeadLetter(ctx, letter, now)
+	c.ErrorIs(err, errDeadLetterBlocked)
+	c.ErrorIs(err, errMaintenance)
+
+	// the message stays parked without counting an attempt
+	claimed, err := deadletter.ClaimDue(ctx, now.Add(deadletter.Backoff(1)))
+	c.NoError(err)
+	c.Equal(1, claimed.Attempts)
+
+	c.NoError(ProcessUpdate(ctx, newScheduleUpdate(2, "/retrydeadletter "+letter.ID)))
+	c.Equal("The message "+letter.ID+" stays parked, /deployterraformstaging is disabled or the bot is in maintenance", fake.SentTexts()[1])
+
+	c.NoError(featureflag.SetMaintenance(ctx, ""))
+
+	c.NoError(ReplayDeadLetter(ctx, claimed, now))
+
+	_, err = deadletter.Get(ctx, letter.ID)
+	c.ErrorIs(err, deadletter.ErrDeadLetterNotFound)
+}
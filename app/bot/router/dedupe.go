@@ -0,0 +1,76 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"bitbucket.org/truora/scrap-services/logger"
+	"bitbucket.org/truora/scrap-services/shared/cache"
+	"bitbucket.org/truora/scrap-services/shared/env"
+)
+
+const routedUpdateKey = "BOT-ROUTED-UPDATE:%d"
+
+var (
+	// ErrDuplicatedUpdate when the Telegram update was already routed
+	ErrDuplicatedUpdate = errors.New("telegram update already routed")
+
+	dedupeMinutes = env.GetInt64("UPDATE_DEDUPE_MINUTES", 60)
+	dedupeWindow  = time.Duration(dedupeMinutes) * time.Minute
+)
+
+// claimUpdate marks the update as routed, Telegram resends the same update_id when
+// the webhook is slow or fails so only the first delivery inside the window is routed.
+// A cache failure does not block the update, it is logged and routed anyway
+func claimUpdate(ctx context.Context, updateID int64) error {
+	if updateID == 0 {
+		return nil
+	}
+
+	claimed, err := cache.AddOnce(ctx, fmt.Sprintf(routedUpdateKey, updateID), time.Now().Unix(), dedupeWindow)
+	if err != nil {
+		logger.Get(ctx).Error(ctx, "claim_update_failed", logger.OneMonth, []logger.Object{
+			logger.ErrObject(err),
+			updateObject(updateID),
+		})
+
+		return nil
+	}
+
+	if !claimed {
+		logger.Get(ctx).Warning(ctx, "duplicated_update_skipped", logger.OneMonth, []logger.Object{
+			updateObject(updateID),
+			logger.MapObject("metric", map[string]interface{}{
+				"s_name":  "duplicated_updates",
+				"i_value": 1,
+			}),
+		})
+
+		return ErrDuplicatedUpdate
+	}
+
+	return nil
+}
+
+// releaseUpdate removes the routed mark so a new delivery of the update can be routed
+func releaseUpdate(ctx context.Context, updateID int64) {
+	if updateID == 0 {
+		return
+	}
+
+	err := cache.Del(ctx, fmt.Sprintf(routedUpdateKey, updateID))
+	if err != nil {
+		logger.Get(ctx).Error(ctx, "release_update_failed", logger.OneMonth, []logger.Object{
+			logger.ErrObject(err),
+			updateObject(updateID),
+		})
+	}
+}
+
+func updateObject(updateID int64) logger.Object {
+	return logger.MapObject("update", map[string]interface{}{
+		"i_update_id": updateID,
+	})
+}
@@ -0,0 +1,169 @@
+package router
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"shared/app/bot/models"
+	"shared/app/bot/storage/deadletter"
+
+	"bitbucket.org/truora/scrap-services/logger"
+	"bitbucket.org/truora/scrap-services/shared/cache"
+	"bitbucket.org/truora/scrap-services/shared/sns"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClaimUpdate(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+
+	ctx := context.Background()
+
+	c.NoError(claimUpdate(ctx, 0))
+	c.NoError(claimUpdate(ctx, 0))
+
+	c.NoError(claimUpdate(ctx, 123))
+	c.ErrorIs(claimUpdate(ctx, 123), ErrDuplicatedUpdate)
+
+	releaseUpdate(ctx, 123)
+
+	c.NoError(claimUpdate(ctx, 123))
+}
+
+func TestClaimUpdateCacheFailure(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMockWithoutServer()
+
+	defer cache.InitMock()
+
+	ctx := context.Background()
+	log := logger.New("test")
+	buf := bytes.NewBufferString("")
+	log.Output = buf
+
+	ctx = logger.Set(ctx, log)
+
+	c.NoError(claimUpdate(ctx, 123))
+	c.Contains(buf.String(), "claim_update_failed")
+}
+
+func TestApiGatewayHandlerSkipsDuplicatedUpdates(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+	sns.InitSNSMock()
+	setMockedClient()
+
+	defer deactivateMockedClient()
+
+	ctx := context.Background()
+	log := logger.New("test")
+	buf := bytes.NewBufferString("")
+	log.Output = buf
+
+	ctx = logger.Set(ctx, log)
+
+	body := `{"update_id":456,"message":{"text":"/hi dummy_email@dummy.com","chat":{"id":1,"type":"private"}}}`
+
+	response, err := apiGatewayHandler(ctx, newTestRequest(body))
+	c.NoError(err)
+	c.Equal(http.StatusOK, response.StatusCode)
+	c.NotContains(buf.String(), "duplicated_update_skipped")
+
+	response, err = apiGatewayHandler(ctx, newTestRequest(body))
+	c.NoError(err)
+	c.Equal(http.StatusOK, response.StatusCode)
+	c.Contains(buf.String(), "duplicated_update_skipped")
+}
+
+func TestApiGatewayHandlerReleasesUpdateOnPublishFailure(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+	sns.InitSNSMock()
+	setMockedClient()
+
+	defer deactivateMockedClient()
+
+	sns.ForceMockFail = true
+
+	defer func() {
+		sns.ForceMockFail = false
+	}()
+
+	parkDeadLetter = func(ctx context.Context, topic, attribute string, message *models.CallbackMessage, publishErr error, now time.Time) (*models.DeadLetter, error) {
+		return nil, errors.New("cache unavailable")
+	}
+
+	defer func() {
+		parkDeadLetter = deadletter.Park
+	}()
+
+	body := `{"update_id":789,"message":{"text":"/hi dummy_email@dummy.com","chat":{"id":1,"type":"private"}}}`
+
+	response, err := apiGatewayHandler(context.Background(), newTestRequest(body))
+	c.NoError(err)
+	c.Equal(http.StatusOK, response.StatusCode)
+
+	exists, err := cache.Exists(context.Background(), fmt.Sprintf(routedUpdateKey, 789))
+	c.NoError(err)
+	c.False(exists)
+}
+
+func TestApiGatewayHandlerReleasesUpdateOnRoutingFailure(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+	sns.InitSNSMock()
+	setMockedClient()
+
+	defer deactivateMockedClient()
+
+	body := `{"update_id":790,"callback_query":{"data":"invalid"}}`
+
+	response, err := apiGatewayHandler(context.Background(), newTestRequest(body))
+	c.NoError(err)
+	c.Equal(http.StatusOK, response.StatusCode)
+
+	exists, err := cache.Exists(context.Background(), fmt.Sprintf(routedUpdateKey, 790))
+	c.NoError(err)
+	c.False(exists)
+}
+
+func TestApiGatewayHandlerKeepsParkedUpdate(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+	sns.InitSNSMock()
+	setMockedClient()
+
+	defer deactivateMockedClient()
+
+	sns.ForceMockFail = true
+
+	defer func() {
+		sns.ForceMockFail = false
+	}()
+
+	body := `{"update_id":791,"message":{"text":"/hi dummy_email@dummy.com","chat":{"id":1,"type":"private"}}}`
+
+	response, err := apiGatewayHandler(context.Background(), newTestRequest(body))
+	c.NoError(err)
+	c.Equal(http.StatusOK, response.StatusCode)
+
+	// the parked message is replayed so a new delivery of the update must not route it again
+	exists, err := cache.Exists(context.Background(), fmt.Sprintf(routedUpdateKey, 791))
+	c.NoError(err)
+	c.True(exists)
+
+	letters, err := deadletter.List(context.Background())
+	c.NoError(err)
+	c.Len(letters, 1)
+}
@@ -0,0 +1,82 @@
+package router
+
+import (
+	"context"
+	"errors"
+
+	"shared/app/bot/i18n"
+	"shared/app/bot/models"
+	"shared/shared/telegram"
+)
+
+// eventType returns the type of the Telegram update, empty when it is not supported
+func (e *event) eventType() models.EventType {
+	switch {
+	case e.Message != nil && e.Message.Document != nil:
+		return models.EventDocument
+	case e.Message != nil:
+		return models.EventMessage
+	case e.CallbackMessage != nil:
+		return models.EventCallbackQuery
+	case e.EditedMessage != nil:
+		return models.EventEditedMessage
+	case e.ChannelPost != nil:
+		return models.EventChannelPost
+	case e.InlineQuery != nil:
+		return models.EventInlineQuery
+	case e.MyChatMember != nil:
+		return models.EventMyChatMember
+	}
+
+	return ""
+}
+
+// isCommandEvent returns true for the updates that can contain bot commands
+func isCommandEvent(eventType models.EventType) bool {
+	return eventType == models.EventMessage || eventType == models.EventCallbackQuery
+}
+
+// eventMessage wraps the updates that are not commands so workers receive the same payload for every update
+func (e *event) eventMessage(eventType models.EventType) *models.CallbackMessage {
+	message := &models.CallbackMessage{EventType: eventType}
+
+	switch eventType {
+	case models.EventDocument:
+		message.Message = *e.Message
+	case models.EventEditedMessage:
+		message.Message = *e.EditedMessage
+	case models.EventChannelPost:
+		message.Message = *e.ChannelPost
+	case models.EventInlineQuery:
+		message.InlineQuery = e.InlineQuery
+		message.From = e.InlineQuery.From
+
+		return message
+	case models.EventMyChatMember:
+		message.ChatMember = e.MyChatMember
+		message.From = e.MyChatMember.From
+		message.Message.Chat = e.MyChatMember.Chat
+
+		return message
+	}
+
+	message.From = message.Message.From
+
+	return message
+}
+
+// routeEvent publishes the updates that are not commands using the event type as SNS attribute
+func routeEvent(ctx context.Context, telegramClient *telegram.Client, e *event, eventType models.EventType) error {
+	message := e.eventMessage(eventType)
+
+	if eventType == models.EventMyChatMember && message.ChatMember.Added() {
+		telegramClient.SendText(ctx, message.ChatMember.Chat.ID, translateFor(ctx, message.From, "events.welcome", i18n.Vars{"command": helpCommand + bettyBotUserName}))
+	}
+
+	err := publish(ctx, eventTopic(eventType), string(eventType), message)
+	if errors.Is(err, errPublishDelayed) {
+		return nil
+	}
+
+	return err
+}
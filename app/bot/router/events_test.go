@@ -0,0 +1,112 @@
+package router
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"shared/app/bot/models"
+
+	"bitbucket.org/truora/scrap-services/logger"
+	"bitbucket.org/truora/scrap-services/shared/cache"
+	"bitbucket.org/truora/scrap-services/shared/sns"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventType(t *testing.T) {
+	c := require.New(t)
+
+	testCases := map[string]models.EventType{
+		`{"message":{"text":"/help"}}`:                         models.EventMessage,
+		`{"message":{"document":{"file_id":"file"}}}`:          models.EventDocument,
+		`{"callback_query":{"data":"command data"}}`:           models.EventCallbackQuery,
+		`{"edited_message":{"text":"/help"}}`:                  models.EventEditedMessage,
+		`{"channel_post":{"text":"hello"}}`:                    models.EventChannelPost,
+		`{"inline_query":{"id":"1","query":"deploy"}}`:         models.EventInlineQuery,
+		`{"my_chat_member":{"chat":{"id":-1,"type":"group"}}}`: models.EventMyChatMember,
+		`{"poll":{"id":"1"}}`:                                  "",
+		`{"update_id":1}`:                                      "",
+	}
+
+	for body, expected := range testCases {
+		e := &event{}
+		c.NoError(json.Unmarshal([]byte(body), e))
+		c.Equal(expected, e.eventType(), body)
+	}
+}
+
+func TestEventMessage(t *testing.T) {
+	c := require.New(t)
+
+	e := &event{}
+	c.NoError(json.Unmarshal([]byte(`{"edited_message":{"text":"edited","from":{"id":1},"chat":{"id":2}}}`), e))
+
+	message := e.eventMessage(models.EventEditedMessage)
+	c.Equal(models.EventEditedMessage, message.EventType)
+	c.Equal("edited", message.Message.Text)
+	c.Equal(int64(1), message.From.ID)
+
+	e = &event{}
+	c.NoError(json.Unmarshal([]byte(`{"inline_query":{"id":"1","query":"deploy","from":{"id":3}}}`), e))
+
+	message = e.eventMessage(models.EventInlineQuery)
+	c.Equal("deploy", message.InlineQuery.Query)
+	c.Equal(int64(3), message.From.ID)
+
+	e = &event{}
+	c.NoError(json.Unmarshal([]byte(`{"my_chat_member":{"chat":{"id":-10,"type":"group","title":"devops"},"from":{"id":4}}}`), e))
+
+	message = e.eventMessage(models.EventMyChatMember)
+	c.Equal(int64(-10), message.Message.Chat.ID)
+	c.Equal("devops", message.ChatMember.Chat.Title)
+	c.Equal(int64(4), message.From.ID)
+}
+
+func TestApiGatewayHandlerRoutesEvents(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+	sns.InitSNSMock()
+	setMockedClient()
+
+	defer deactivateMockedClient()
+
+	ctx := context.Background()
+	log := logger.New("test")
+	buf := bytes.NewBufferString("")
+	log.Output = buf
+
+	ctx = logger.Set(ctx, log)
+
+	bodies := []string{
+		`{"update_id":1,"edited_message":{"text":"/help","from":{"id":1},"chat":{"id":1,"type":"private"}}}`,
+		`{"update_id":2,"channel_post":{"text":"hello","chat":{"id":-2,"type":"channel"}}}`,
+		`{"update_id":3,"inline_query":{"id":"1","query":"deploy","from":{"id":1}}}`,
+		`{"update_id":4,"message":{"document":{"file_id":"file","file_name":"report.pdf"},"from":{"id":1},"chat":{"id":1,"type":"private"}}}`,
+		`{"update_id":5,"my_chat_member":{"chat":{"id":-3,"type":"group"},"from":{"id":1},"old_chat_member":{"status":"left"},"new_chat_member":{"status":"member"}}}`,
+		`{"update_id":6,"my_chat_member":{"chat":{"id":-3,"type":"group"},"from":{"id":1},"old_chat_member":{"status":"member"},"new_chat_member":{"status":"kicked"}}}`,
+	}
+
+	for _, body := range bodies {
+		response, err := apiGatewayHandler(ctx, newTestRequest(body))
+		c.NoError(err)
+		c.Equal(http.StatusOK, response.StatusCode)
+	}
+
+	c.NotContains(buf.String(), "process_router_request_failed")
+
+	sns.ForceMockFail = true
+
+	defer func() {
+		sns.ForceMockFail = false
+	}()
+
+	// the failed events are parked to be published later
+	response, err := apiGatewayHandler(ctx, newTestRequest(`{"update_id":7,"channel_post":{"text":"hello","chat":{"id":-2,"type":"channel"}}}`))
+	c.NoError(err)
+	c.Equal(http.StatusOK, response.StatusCode)
+	c.Contains(buf.String(), "dead_letter_parked")
+	c.NotContains(buf.String(), "process_router_request_failed")
+}
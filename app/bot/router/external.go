@@ -0,0 +1,91 @@
+package router
+
+import (
+	"context"
+	"errors"
+
+	"shared/app/bot/i18n"
+	"shared/app/bot/models"
+
+	"bitbucket.org/truora/scrap-services/logger"
+)
+
+// RouteExternal routes a command received from another platform, e.g. Slack, on behalf of the stored user
+// in message.From. The command is validated and authorized like the typed commands and it is published to
+// the same topics, the returned text answers the user in the other platform. The commands answered by the
+// router and the commands that need approvals are only available in Telegram
+func RouteExternal(ctx context.Context, message *models.CallbackMessage) (string, error) {
+	ctx, entry := startAudit(ctx)
+	ctx = withUserLanguage(ctx, &message.From)
+
+	reply, err := routeExternal(ctx, message)
+	finishAudit(ctx, entry, err)
+
+	return reply, err
+}
+
+func routeExternal(ctx context.Context, message *models.CallbackMessage) (string, error) {
+	name, err := getCommand(message)
+	if err != nil {
+		return translate(ctx, "external.empty", nil), nil
+	}
+
+	trackCommand(ctx, message, name)
+
+	botCommand, reply, err := validateCommand(ctx, message, name)
+	if err != nil {
+		setOutcome(ctx, models.OutcomeInvalid)
+		logger.Get(ctx).Warning(ctx, "command_rejected", logger.OneMonth, []logger.Object{logger.ErrObject(err)})
+
+		if reply == "" {
+			reply = err.Error()
+		}
+
+		return reply, nil
+	}
+
+	trackCommand(ctx, message, botCommand.Name)
+
+	if _, ok := routerCommands[botCommand.Name]; ok || requiredApprovals(botCommand) > 0 {
+		setOutcome(ctx, models.OutcomeInvalid)
+
+		return translate(ctx, "external.telegram_only", i18n.Vars{"command": botCommand.Name}), nil
+	}
+
+	if !botCommand.Public && (!message.From.EmailVerified || !botCommand.AllowsRole(message.From.UserRole)) {
+		setOutcome(ctx, models.OutcomeDenied)
+		logDenial(ctx, message, botCommand, ErrRoleNotAllowed)
+
+		return translate(ctx, "policy.not_allowed", i18n.Vars{"command": botCommand.Name}), nil
+	}
+
+	reply, err = checkFlags(ctx, message, botCommand)
+	if err != nil {
+		setOutcome(ctx, flagOutcome(err))
+		logDenial(ctx, message, botCommand, err)
+
+		return reply, nil
+	}
+
+	message.CorrelationID, err = newCorrelationID()
+	if err != nil {
+		return "", err
+	}
+
+	id := shortCorrelationID(message.CorrelationID)
+
+	err = publish(ctx, commandTopic(botCommand), botCommand.Name, message)
+	if errors.Is(err, errPublishDelayed) {
+		setOutcome(ctx, models.OutcomeDelayed)
+
+		return translate(ctx, "ack.delayed", i18n.Vars{"command": botCommand.Name, "id": id}), nil
+	}
+
+	if err != nil {
+		return translate(ctx, "ack.rejected", i18n.Vars{"command": botCommand.Name, "id": id}), err
+	}
+
+	setOutcome(ctx, models.OutcomePublished)
+
+	return translate(ctx, "ack.accepted", i18n.Vars{"command": botCommand.Name, "id": id}), nil
+}
@@ -0,0 +1,96 @@
+package router
+
+import (
+	"context"
+	"testing"
+
+	"shared/app/bot/models"
+	"shared/app/bot/storage/audit"
+
+	"bitbucket.org/truora/scrap-services/shared/cache"
+	"github.com/stretchr/testify/require"
+)
+
+func newExternalMessage(text string) *models.CallbackMessage {
+	return &models.CallbackMessage{
+		From:      models.From{ID: 10, Email: "dummy_email@dummy.com", EmailVerified: true, UserRole: models.RoleDevelopers},
+		Message:   models.Message{Text: text, Chat: models.Chat{ID: 10, Type: "private"}},
+		EventType: models.EventMessage,
+		Slack:     &models.SlackContext{UserID: "U024BE7LH", ResponseURL: "https://hooks.slack.com/commands/1"},
+	}
+}
+
+func TestRouteExternal(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+
+	published := []*models.CallbackMessage{}
+
+	SetPublisher(func(ctx context.Context, topic, attribute string, message *models.CallbackMessage) error {
+		published = append(published, message)
+
+		return nil
+	})
+
+	defer SetPublisher(nil)
+
+	ctx := context.Background()
+
+	reply, err := RouteExternal(ctx, newExternalMessage("/deployterraformstaging checks/core -b main"))
+	c.NoError(err)
+	c.Contains(reply, "Accepted /deployterraformstaging (id: ")
+	c.Len(published, 1)
+	c.Equal("checks/core", published[0].Args["project"])
+	c.Equal("main", published[0].Input.Flag("branch"))
+	c.NotEmpty(published[0].CorrelationID)
+	c.Equal("U024BE7LH", published[0].Slack.UserID)
+
+	records, err := audit.List(ctx, 1)
+	c.NoError(err)
+	c.Equal(models.OutcomePublished, records[0].Outcome)
+	c.Equal("dummy_email@dummy.com", records[0].Email)
+
+	reply, err = RouteExternal(ctx, newExternalMessage("/unknown"))
+	c.NoError(err)
+	c.Contains(reply, "Unknown command /unknown")
+
+	reply, err = RouteExternal(ctx, newExternalMessage("/deployterraformstaging"))
+	c.NoError(err)
+	c.Contains(reply, "missing required arguments")
+
+	reply, err = RouteExternal(ctx, newExternalMessage("/users"))
+	c.NoError(err)
+	c.Equal("/users is only available in Telegram", reply)
+
+	message := newExternalMessage("/deployterraformstaging checks/core")
+	message.From.EmailVerified = false
+
+	reply, err = RouteExternal(ctx, message)
+	c.NoError(err)
+	c.Equal("You are not allowed to run /deployterraformstaging", reply)
+	c.Len(published, 1)
+
+	records, err = audit.List(ctx, 1)
+	c.NoError(err)
+	c.Equal(models.OutcomeDenied, records[0].Outcome)
+}
+
+func TestRouteExternalDelayed(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+
+	_, restorePublisher := setFailingPublisher()
+
+	defer restorePublisher()
+
+	ctx := context.Background()
+
+	reply, err := RouteExternal(ctx, newExternalMessage("/deployterraformstaging checks/core"))
+	c.NoError(err)
+	c.Contains(reply, "/deployterraformstaging is delayed")
+
+	letter := parkedLetter(c)
+	c.NotNil(letter.Message.Slack)
+}
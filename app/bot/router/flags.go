@@ -0,0 +1,352 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+
+	"shared/app/bot/commands"
+	"shared/app/bot/i18n"
+	"shared/app/bot/models"
+	"shared/app/bot/storage/featureflag"
+	"shared/shared/telegram"
+
+	"bitbucket.org/truora/scrap-services/logger"
+)
+
+const (
+	flagsCommand          = "flags"
+	disableCommand        = "disablecommand"
+	enableCommand         = "enablecommand"
+	startBetaCommand      = "startbeta"
+	endBetaCommand        = "endbeta"
+	allowBetaCommand      = "allowbeta"
+	denyBetaCommand       = "denybeta"
+	maintenanceCommand    = "maintenance"
+	endMaintenanceCommand = "endmaintenance"
+
+	reasonArg             = "reason"
+	maintenanceMessageArg = "message"
+)
+
+var (
+	// errMaintenance when the bot is in maintenance and no command is published
+	errMaintenance = errors.New("bot in maintenance")
+	// errCommandDisabled when the command was disabled by an admin
+	errCommandDisabled = errors.New("command disabled")
+	// errBetaOnly when the command is in beta and the user is not allowed to run it
+	errBetaOnly = errors.New("command in beta")
+
+	getFlags = featureflag.Get
+)
+
+// checkFlags returns the reply telling the user why the command can not be published now, it is empty when
+// the command can be published. The flags are not enforced when they can not be read so a Redis failure
+// does not stop every command
+func checkFlags(ctx context.Context, message *models.CallbackMessage, botCommand *commands.Command) (string, error) {
+	flags, err := getFlags(ctx)
+	if err != nil {
+		logger.Get(ctx).Warning(ctx, "get_flags_failed", logger.OneMonth, []logger.Object{logger.ErrObject(err)})
+
+		return "", nil
+	}
+
+	if flags.Maintenance != "" {
+		return translate(ctx, "flag.maintenance", i18n.Vars{"message": flags.Maintenance}), errMaintenance
+	}
+
+	if reason, disabled := flags.IsDisabled(botCommand.Name); disabled {
+		if reason == "" {
+			return translate(ctx, "flag.command_disabled", i18n.Vars{"command": botCommand.Name}), errCommandDisabled
+		}
+
+		return translate(ctx, "flag.command_disabled_reason", i18n.Vars{"command": botCommand.Name, "reason": reason}), errCommandDisabled
+	}
+
+	if !flags.AllowsUser(botCommand.Name, message.From.ID) {
+		return translate(ctx, "flag.beta_only", i18n.Vars{"command": botCommand.Name}), errBetaOnly
+	}
+
+	return "", nil
+}
+
+// blockedByFlags tells the user when the flags do not let the command be published
+func blockedByFlags(ctx context.Context, telegramClient *telegram.Client, message *models.CallbackMessage, botCommand *commands.Command) bool {
+	reply, err := checkFlags(ctx, message, botCommand)
+	if err == nil {
+		return false
+	}
+
+	setOutcome(ctx, flagOutcome(err))
+	logDenial(ctx, message, botCommand, err)
+	telegramClient.SendText(ctx, replyChatID(message), reply)
+
+	return true
+}
+
+// flagOutcome returns the audit outcome of a command stopped by the flags, the users left out of a beta
+// are denied while the disabled commands are not run for anyone
+func flagOutcome(err error) models.AuditOutcome {
+	if errors.Is(err, errBetaOnly) {
+		return models.OutcomeDenied
+	}
+
+	return models.OutcomeDisabled
+}
+
+// listFlags sends the flags to the admin
+func listFlags(ctx context.Context, telegramClient *telegram.Client, message *models.CallbackMessage) error {
+	flags, err := getFlags(ctx)
+	if err != nil {
+		return err
+	}
+
+	lines := []string{translate(ctx, "flag.list_maintenance_off", nil)}
+	if flags.Maintenance != "" {
+		lines = []string{translate(ctx, "flag.list_maintenance", i18n.Vars{"message": flags.Maintenance})}
+	}
+
+	disabled := make([]string, 0, len(flags.Disabled))
+	for command := range flags.Disabled {
+		disabled = append(disabled, command)
+	}
+
+	sort.Strings(disabled)
+
+	for _, command := range disabled {
+		reason := flags.Disabled[command]
+		if reason == "" {
+			reason = "-"
+		}
+
+		lines = append(lines, translate(ctx, "flag.list_disabled", i18n.Vars{"command": command, "reason": reason}))
+	}
+
+	betas := make([]string, 0, len(flags.Betas))
+	for command := range flags.Betas {
+		betas = append(betas, command)
+	}
+
+	sort.Strings(betas)
+
+	for _, command := range betas {
+		emails := make([]string, 0, len(flags.Betas[command]))
+		for _, email := range flags.Betas[command] {
+			emails = append(emails, email)
+		}
+
+		sort.Strings(emails)
+
+		users := strings.Join(emails, ", ")
+		if users == "" {
+			users = translate(ctx, "flag.list_no_users", nil)
+		}
+
+		lines = append(lines, translate(ctx, "flag.list_beta", i18n.Vars{"command": command, "users": users}))
+	}
+
+	telegramClient.SendText(ctx, replyChatID(message), strings.Join(lines, "\n"))
+
+	return nil
+}
+
+// disableFlagCommand stops publishing a command until it is enabled again, the reason is sent to its users
+func disableFlagCommand(ctx context.Context, telegramClient *telegram.Client, message *models.CallbackMessage) error {
+	command := getFlagCommand(ctx, telegramClient, message)
+	if command == nil {
+		return nil
+	}
+
+	reason := message.Args[reasonArg]
+
+	err := featureflag.DisableCommand(ctx, command.Name, reason)
+	if err != nil {
+		return err
+	}
+
+	logFlag(ctx, "flag_command_disabled", message, command.Name, reason)
+	telegramClient.SendText(ctx, replyChatID(message), translate(ctx, "flag.disabled", i18n.Vars{"command": command.Name, "enable": enableCommand}))
+
+	return nil
+}
+
+func enableFlagCommand(ctx context.Context, telegramClient *telegram.Client, message *models.CallbackMessage) error {
+	command := getFlagCommand(ctx, telegramClient, message)
+	if command == nil {
+		return nil
+	}
+
+	err := featureflag.EnableCommand(ctx, command.Name)
+	if err != nil {
+		return err
+	}
+
+	logFlag(ctx, "flag_command_enabled", message, command.Name, "")
+	telegramClient.SendText(ctx, replyChatID(message), translate(ctx, "flag.enabled", i18n.Vars{"command": command.Name}))
+
+	return nil
+}
+
+// startBeta limits a command to the users allowed with /allowbeta, nobody can run it until one is allowed
+func startBeta(ctx context.Context, telegramClient *telegram.Client, message *models.CallbackMessage) error {
+	command := getFlagCommand(ctx, telegramClient, message)
+	if command == nil {
+		return nil
+	}
+
+	err := featureflag.StartBeta(ctx, command.Name)
+	if err != nil {
+		return err
+	}
+
+	logFlag(ctx, "flag_beta_started", message, command.Name, "")
+	telegramClient.SendText(ctx, replyChatID(message), translate(ctx, "flag.beta_started", i18n.Vars{"command": command.Name, "allow": allowBetaCommand}))
+
+	return nil
+}
+
+func endBeta(ctx context.Context, telegramClient *telegram.Client, message *models.CallbackMessage) error {
+	command, err := getBetaCommand(ctx, telegramClient, message)
+	if command == nil {
+		return err
+	}
+
+	err = featureflag.EndBeta(ctx, command.Name)
+	if err != nil {
+		return err
+	}
+
+	logFlag(ctx, "flag_beta_ended", message, command.Name, "")
+	telegramClient.SendText(ctx, replyChatID(message), translate(ctx, "flag.beta_ended", i18n.Vars{"command": command.Name}))
+
+	return nil
+}
+
+func allowBetaUser(ctx context.Context, telegramClient *telegram.Client, message *models.CallbackMessage) error {
+	command, err := getBetaCommand(ctx, telegramClient, message)
+	if command == nil {
+		return err
+	}
+
+	user, err := getAdminTarget(ctx, telegramClient, message, message.Args[emailArg])
+	if user == nil {
+		return err
+	}
+
+	err = featureflag.AllowBetaUser(ctx, command.Name, user)
+	if err != nil {
+		return err
+	}
+
+	logFlag(ctx, "flag_beta_user_allowed", message, command.Name, user.Email)
+	telegramClient.SendText(ctx, replyChatID(message), translate(ctx, "flag.user_allowed", i18n.Vars{"command": command.Name, "email": user.Email}))
+
+	return nil
+}
+
+func denyBetaUser(ctx context.Context, telegramClient *telegram.Client, message *models.CallbackMessage) error {
+	command, err := getBetaCommand(ctx, telegramClient, message)
+	if command == nil {
+		return err
+	}
+
+	user, err := getAdminTarget(ctx, telegramClient, message, message.Args[emailArg])
+	if user == nil {
+		return err
+	}
+
+	err = featureflag.DenyBetaUser(ctx, command.Name, user.ID)
+	if err != nil {
+		return err
+	}
+
+	logFlag(ctx, "flag_beta_user_denied", message, command.Name, user.Email)
+	telegramClient.SendText(ctx, replyChatID(message), translate(ctx, "flag.user_denied", i18n.Vars{"command": command.Name, "email": user.Email}))
+
+	return nil
+}
+
+// startMaintenance stops publishing every command, the message is sent to the users as it is written.
+// The commands answered by the router keep working so the admins can end the maintenance
+func startMaintenance(ctx context.Context, telegramClient *telegram.Client, message *models.CallbackMessage) error {
+	text := message.Args[maintenanceMessageArg]
+
+	err := featureflag.SetMaintenance(ctx, text)
+	if err != nil {
+		return err
+	}
+
+	logFlag(ctx, "flag_maintenance_started", message, "", text)
+	telegramClient.SendText(ctx, replyChatID(message), translate(ctx, "flag.maintenance_started", i18n.Vars{"end": endMaintenanceCommand}))
+
+	return nil
+}
+
+func endMaintenance(ctx context.Context, telegramClient *telegram.Client, message *models.CallbackMessage) error {
+	err := featureflag.SetMaintenance(ctx, "")
+	if err != nil {
+		return err
+	}
+
+	logFlag(ctx, "flag_maintenance_ended", message, "", "")
+	telegramClient.SendText(ctx, replyChatID(message), translate(ctx, "flag.maintenance_ended", nil))
+
+	return nil
+}
+
+// getFlagCommand returns the command of the arguments, the admin is told when it does not exist or when it is
+// answered by the router because the flags only stop the published commands
+func getFlagCommand(ctx context.Context, telegramClient *telegram.Client, message *models.CallbackMessage) *commands.Command {
+	name := strings.TrimPrefix(strings.ToLower(message.Args[commandArg]), commandPrefix)
+
+	command, err := botCommands.Get(name)
+	if err != nil {
+		telegramClient.SendText(ctx, replyChatID(message), translate(ctx, "registry.unknown_command", i18n.Vars{"command": name, "help": helpCommand}))
+
+		return nil
+	}
+
+	if _, ok := routerCommands[command.Name]; ok {
+		telegramClient.SendText(ctx, replyChatID(message), translate(ctx, "flag.router_command", i18n.Vars{"command": command.Name}))
+
+		return nil
+	}
+
+	return command
+}
+
+// getBetaCommand returns the command of the arguments when it is in beta, the admin is told when it is not
+func getBetaCommand(ctx context.Context, telegramClient *telegram.Client, message *models.CallbackMessage) (*commands.Command, error) {
+	command := getFlagCommand(ctx, telegramClient, message)
+	if command == nil {
+		return nil, nil
+	}
+
+	flags, err := getFlags(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if !flags.IsBeta(command.Name) {
+		telegramClient.SendText(ctx, replyChatID(message), translate(ctx, "flag.not_in_beta", i18n.Vars{"command": command.Name, "start": startBetaCommand}))
+
+		return nil, nil
+	}
+
+	return command, nil
+}
+
+// logFlag writes the audit entry of a flag changed by an admin, the value is the reason, email or message
+// set by the change
+func logFlag(ctx context.Context, event string, message *models.CallbackMessage, command, value string) {
+	logger.Get(ctx).Info(ctx, event, logger.ThreeMonths, []logger.Object{
+		logger.MapObject("flag", map[string]interface{}{
+			"i_telegram_id": message.From.ID,
+			"s_username":    message.From.Username,
+			"s_admin_email": message.From.Email,
+			"s_command":     command,
+			"s_value":       value,
+		}),
+	})
+}
@@ -0,0 +1,176 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"shared/app/bot/commands"
+	"shared/app/bot/models"
+	"shared/app/bot/storage/audit"
+	"shared/app/bot/storage/featureflag"
+
+	"bitbucket.org/truora/scrap-services/shared/cache"
+	"github.com/stretchr/testify/require"
+)
+
+// resetFlags drops the flags kept in memory by the router so the next tests read them from their cache mock
+func resetFlags(c *require.Assertions) {
+	c.NoError(featureflag.SetMaintenance(context.Background(), ""))
+}
+
+func TestDisableCommand(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+	saveAdmin(c)
+
+	fake, restore := setFakeTelegram()
+
+	defer restore()
+	defer resetFlags(c)
+
+	published, restorePublisher := capturePublished()
+
+	defer restorePublisher()
+
+	ctx := context.Background()
+
+	c.NoError(ProcessUpdate(ctx, newScheduleUpdate(1, "/disablecommand deployterraformstaging broken pipeline")))
+	c.Contains(fake.SentTexts(), "Disabled /deployterraformstaging, send /enablecommand deployterraformstaging to enable it again")
+
+	c.NoError(ProcessUpdate(ctx, newScheduleUpdate(2, "/deployterraformstaging checks/core")))
+	c.Contains(fake.SentTexts(), "/deployterraformstaging is disabled for now: broken pipeline")
+	c.Empty(*published)
+
+	records, err := audit.List(ctx, 1)
+	c.NoError(err)
+	c.Equal(models.OutcomeDisabled, records[0].Outcome)
+
+	c.NoError(ProcessUpdate(ctx, newScheduleUpdate(3, "/flags")))
+	c.Contains(fake.SentTexts(), "Maintenance: off\nDisabled /deployterraformstaging: broken pipeline")
+
+	c.NoError(ProcessUpdate(ctx, newScheduleUpdate(4, "/enablecommand deployterraformstaging")))
+	c.Contains(fake.SentTexts(), "Enabled /deployterraformstaging")
+
+	c.NoError(ProcessUpdate(ctx, newScheduleUpdate(5, "/deployterraformstaging checks/core")))
+	c.Len(*published, 1)
+
+	// only the published commands can be disabled
+	c.NoError(ProcessUpdate(ctx, newScheduleUpdate(6, "/disablecommand /help")))
+	c.Contains(fake.SentTexts(), "/help is answered by the bot, only the commands sent to the workers can be changed")
+
+	c.NoError(ProcessUpdate(ctx, newScheduleUpdate(7, "/disablecommand nothing")))
+	c.Contains(fake.SentTexts(), "Unknown command /nothing, send /help to see the available commands")
+}
+
+func TestMaintenance(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+	saveAdmin(c)
+
+	fake, restore := setFakeTelegram()
+
+	defer restore()
+	defer resetFlags(c)
+
+	published, restorePublisher := capturePublished()
+
+	defer restorePublisher()
+
+	ctx := context.Background()
+
+	c.NoError(ProcessUpdate(ctx, newScheduleUpdate(1, "/maintenance Upgrading the cluster, back at 18:00")))
+	c.Contains(fake.SentTexts(), "Maintenance started, no command is sent to the workers until /endmaintenance")
+
+	c.NoError(ProcessUpdate(ctx, newScheduleUpdate(2, "/deployterraformstaging checks/core")))
+	c.Contains(fake.SentTexts(), "The bot is in maintenance, the commands are not being run: Upgrading the cluster, back at 18:00")
+	c.Empty(*published)
+
+	// the commands answered by the router keep working
+	c.NoError(ProcessUpdate(ctx, newScheduleUpdate(3, "/flags")))
+	c.Contains(fake.SentTexts(), "Maintenance: Upgrading the cluster, back at 18:00")
+
+	reply, err := RouteExternal(ctx, newExternalMessage("/deployterraformstaging checks/core"))
+	c.NoError(err)
+	c.Equal("The bot is in maintenance, the commands are not being run: Upgrading the cluster, back at 18:00", reply)
+	c.Empty(*published)
+
+	c.NoError(ProcessUpdate(ctx, newScheduleUpdate(4, "/endmaintenance")))
+	c.Contains(fake.SentTexts(), "Maintenance ended, the commands are sent to the workers again")
+
+	c.NoError(ProcessUpdate(ctx, newScheduleUpdate(5, "/deployterraformstaging checks/core")))
+	c.Len(*published, 1)
+}
+
+func TestBeta(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+	saveAdmin(c)
+
+	fake, restore := setFakeTelegram()
+
+	defer restore()
+	defer resetFlags(c)
+
+	published, restorePublisher := capturePublished()
+
+	defer restorePublisher()
+
+	ctx := context.Background()
+
+	c.NoError(ProcessUpdate(ctx, newScheduleUpdate(1, "/allowbeta deployterraformstaging admin@dummy.com")))
+	c.Contains(fake.SentTexts(), "/deployterraformstaging is not in beta, send /startbeta deployterraformstaging first")
+
+	c.NoError(ProcessUpdate(ctx, newScheduleUpdate(2, "/startbeta deployterraformstaging")))
+	c.Contains(fake.SentTexts(), "/deployterraformstaging is in beta, send /allowbeta deployterraformstaging <email> to let a user run it")
+
+	c.NoError(ProcessUpdate(ctx, newScheduleUpdate(3, "/deployterraformstaging checks/core")))
+	c.Contains(fake.SentTexts(), "/deployterraformstaging is in beta and only some users can run it for now")
+	c.Empty(*published)
+
+	records, err := audit.List(ctx, 1)
+	c.NoError(err)
+	c.Equal(models.OutcomeDenied, records[0].Outcome)
+
+	c.NoError(ProcessUpdate(ctx, newScheduleUpdate(4, "/allowbeta deployterraformstaging admin@dummy.com")))
+	c.Contains(fake.SentTexts(), "admin@dummy.com can run /deployterraformstaging")
+
+	c.NoError(ProcessUpdate(ctx, newScheduleUpdate(5, "/deployterraformstaging checks/core")))
+	c.Len(*published, 1)
+
+	c.NoError(ProcessUpdate(ctx, newScheduleUpdate(6, "/flags")))
+	c.Contains(fake.SentTexts(), "Maintenance: off\nBeta /deployterraformstaging: admin@dummy.com")
+
+	c.NoError(ProcessUpdate(ctx, newScheduleUpdate(7, "/denybeta deployterraformstaging admin@dummy.com")))
+	c.Contains(fake.SentTexts(), "admin@dummy.com can not run /deployterraformstaging anymore")
+
+	c.NoError(ProcessUpdate(ctx, newScheduleUpdate(8, "/deployterraformstaging checks/core")))
+	c.Len(*published, 1)
+
+	c.NoError(ProcessUpdate(ctx, newScheduleUpdate(9, "/endbeta deployterraformstaging")))
+	c.Contains(fake.SentTexts(), "Every user can run /deployterraformstaging again")
+
+	c.NoError(ProcessUpdate(ctx, newScheduleUpdate(10, "/deployterraformstaging checks/core")))
+	c.Len(*published, 2)
+}
+
+func TestCheckFlagsFailsOpen(t *testing.T) {
+	c := require.New(t)
+
+	getFlags = func(ctx context.Context) (*models.Flags, error) {
+		return nil, errors.New("redis is down")
+	}
+
+	defer func() { getFlags = featureflag.Get }()
+
+	reply, err := checkFlags(context.Background(), &models.CallbackMessage{}, &commands.Command{Name: deployTerraformStagingCommand})
+	c.NoError(err)
+	c.Empty(reply)
+
+	c.Equal(models.OutcomeDenied, flagOutcome(errBetaOnly))
+	c.Equal(models.OutcomeDisabled, flagOutcome(errMaintenance))
+	c.Equal(models.OutcomeDisabled, flagOutcome(errCommandDisabled))
+}
@@ -0,0 +1,49 @@
+package router
+
+import (
+	"regexp"
+	"strings"
+
+	"shared/app/bot/models"
+)
+
+var mentionRegex = regexp.MustCompile(`(?i)(^|\s)` + regexp.QuoteMeta(bettyBotUserName) + `\b`)
+
+// isAddressedToBot returns true for messages that are not sent in groups and for group
+// messages that mention the bot or reply to a bot message, other group messages are ignored
+func isAddressedToBot(message models.Message) bool {
+	if !message.IsGroup() {
+		return true
+	}
+
+	reply := message.ReplyToMessage
+	if reply != nil && reply.From.IsBot && strings.EqualFold("@"+reply.From.Username, bettyBotUserName) {
+		return true
+	}
+
+	if mentionRegex.MatchString(message.Text) {
+		return true
+	}
+
+	fields := strings.Fields(message.Text)
+
+	return len(fields) > 0 && strings.HasSuffix(strings.ToLower(fields[0]), bettyBotUserName)
+}
+
+// stripMention removes the standalone bot mention so "@bettyabot /deploy api" is parsed as "/deploy api"
+func stripMention(text string) string {
+	if !mentionRegex.MatchString(text) {
+		return text
+	}
+
+	return strings.TrimSpace(mentionRegex.ReplaceAllString(text, "${1}"))
+}
+
+// replyChatID returns the chat where the message was sent so replies are posted in the same chat
+func replyChatID(message *models.CallbackMessage) int64 {
+	if message.Message.Chat.ID != 0 {
+		return message.Message.Chat.ID
+	}
+
+	return message.From.ID
+}
@@ -0,0 +1,49 @@
+package router
+
+import (
+	"testing"
+
+	"shared/app/bot/models"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsAddressedToBot(t *testing.T) {
+	c := require.New(t)
+
+	group := models.Chat{ID: -100, Type: "supergroup"}
+
+	c.True(isAddressedToBot(models.Message{Text: "/deploy api", Chat: models.Chat{ID: 1, Type: "private"}}))
+	c.False(isAddressedToBot(models.Message{Text: "/deploy api", Chat: group}))
+	c.True(isAddressedToBot(models.Message{Text: "/deploy@BettyaBot api", Chat: group}))
+	c.True(isAddressedToBot(models.Message{Text: "@bettyabot /deploy api", Chat: group}))
+	c.False(isAddressedToBot(models.Message{Text: "@bettyabotfan /deploy api", Chat: group}))
+	c.True(isAddressedToBot(models.Message{
+		Text:           "api",
+		Chat:           group,
+		ReplyToMessage: &models.Message{From: models.From{IsBot: true, Username: "bettyabot"}},
+	}))
+	c.False(isAddressedToBot(models.Message{
+		Text:           "api",
+		Chat:           group,
+		ReplyToMessage: &models.Message{From: models.From{IsBot: false, Username: "someone"}},
+	}))
+}
+
+func TestStripMention(t *testing.T) {
+	c := require.New(t)
+
+	c.Equal("/deploy api", stripMention("@bettyabot /deploy api"))
+	c.Equal("/deploy api", stripMention("/deploy api @BettyaBot"))
+	c.Equal("/deploy@bettyabot api", stripMention("/deploy@bettyabot api"))
+	c.Equal("/deploy api", stripMention("/deploy api"))
+	c.Equal("@bettyabotfan hello", stripMention("@bettyabotfan hello"))
+	c.Equal("  ", stripMention("  "))
+}
+
+func TestReplyChatID(t *testing.T) {
+	c := require.New(t)
+
+	c.Equal(int64(-100), replyChatID(&models.CallbackMessage{From: models.From{ID: 1}, Message: models.Message{Chat: models.Chat{ID: -100}}}))
+	c.Equal(int64(1), replyChatID(&models.CallbackMessage{From: models.From{ID: 1}}))
+}
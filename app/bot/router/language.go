@@ -0,0 +1,108 @@
+package router
+
+import (
+	"context"
+	"strings"
+
+	"shared/app/bot/i18n"
+	"shared/app/bot/models"
+	"shared/app/bot/storage/preference"
+	"shared/shared/telegram"
+
+	"bitbucket.org/truora/scrap-services/logger"
+)
+
+const (
+	languageCommand = "language"
+	languageArg     = "language"
+)
+
+var (
+	getLanguagePreference = preference.Language
+	setLanguagePreference = preference.SetLanguage
+)
+
+// withUserLanguage returns a context whose replies are translated to the language of the user, the
+// preference is only looked up when the first reply is translated
+func withUserLanguage(ctx context.Context, user *models.From) context.Context {
+	return i18n.WithResolver(ctx, func() i18n.Language {
+		return userLanguage(ctx, *user)
+	})
+}
+
+// userLanguage returns the language chosen by the user with /language, the language of the Telegram
+// client is used when the user did not choose one
+func userLanguage(ctx context.Context, user models.From) i18n.Language {
+	if user.ID == 0 {
+		return i18n.Resolve(user.LanguageCode)
+	}
+
+	chosen, err := getLanguagePreference(ctx, user.ID)
+	if err != nil {
+		logger.Get(ctx).Warning(ctx, "get_language_preference_failed", logger.OneMonth, []logger.Object{
+			logger.ErrObject(err),
+			logger.MapObject("language", map[string]interface{}{"i_telegram_id": user.ID}),
+		})
+	}
+
+	return i18n.Resolve(chosen, user.LanguageCode)
+}
+
+// translate returns the message of the key in the language of the user that sent the routed message
+func translate(ctx context.Context, key string, vars i18n.Vars) string {
+	return i18n.Text(ctx, key, vars)
+}
+
+// translateFor returns the message of the key in the language of another user, e.g. an approver
+func translateFor(ctx context.Context, user models.From, key string, vars i18n.Vars) string {
+	return i18n.Translate(userLanguage(ctx, user), key, vars)
+}
+
+// languageNames returns the supported languages with their names in the language of the context
+func languageNames(ctx context.Context) string {
+	names := []string{}
+
+	for _, language := range i18n.Languages() {
+		names = append(names, string(language)+" ("+translate(ctx, "language.name."+string(language), nil)+")")
+	}
+
+	return strings.Join(names, ", ")
+}
+
+// changeLanguage shows the language of the user or stores the one sent as argument
+func changeLanguage(ctx context.Context, telegramClient *telegram.Client, message *models.CallbackMessage) error {
+	code := message.Args[languageArg]
+	if code == "" {
+		language := i18n.FromContext(ctx)
+
+		telegramClient.SendText(ctx, replyChatID(message), translate(ctx, "language.current", i18n.Vars{
+			"language":  translate(ctx, "language.name."+string(language), nil),
+			"command":   languageCommand,
+			"languages": languageNames(ctx),
+		}))
+
+		return nil
+	}
+
+	language, ok := i18n.Parse(code)
+	if !ok {
+		telegramClient.SendText(ctx, replyChatID(message), translate(ctx, "language.unknown", i18n.Vars{"language": code, "languages": languageNames(ctx)}))
+
+		return nil
+	}
+
+	err := setLanguagePreference(ctx, message.From.ID, string(language))
+	if err != nil {
+		return err
+	}
+
+	logger.Get(ctx).Info(ctx, "language_changed", logger.OneMonth, []logger.Object{
+		logger.MapObject("language", map[string]interface{}{"i_telegram_id": message.From.ID, "s_language": string(language)}),
+	})
+
+	telegramClient.SendText(ctx, replyChatID(message), i18n.Translate(language, "language.changed", i18n.Vars{
+		"language": i18n.Translate(language, "language.name."+string(language), nil),
+	}))
+
+	return nil
+}
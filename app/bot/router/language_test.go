@@ -0,0 +1,215 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"shared/app/bot/i18n"
+	"shared/app/bot/models"
+	"shared/app/bot/storage/preference"
+
+	"bitbucket.org/truora/scrap-services/shared/cache"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	// catalogKey matches the string literals that look like a catalog key, e.g. schedule.not_found
+	catalogKey = regexp.MustCompile(`^[a-z]+(\.[a-z0-9_]+)+$`)
+	// translators are the functions that receive a catalog key with the position of the key argument
+	translators = map[string]int{
+		"translate":      1,
+		"translateFor":   2,
+		"newParseError":  2,
+		"i18n.Text":      1,
+		"i18n.Translate": 1,
+		"i18n.NewError":  0,
+	}
+)
+
+func newLanguageUpdate(updateID int64, text string, languageCode string) []byte {
+	return []byte(fmt.Sprintf(`{"update_id":%d,"message":{"message_id":1,"text":%q,"from":{"id":10,"language_code":%q},"chat":{"id":10,"type":"private"}}}`, updateID, text, languageCode))
+}
+
+// sourceKeys returns the string literals of the package files in the directory that look like a key of the
+// namespaces of the English catalog and the literal keys passed to the translators
+func sourceKeys(c *require.Assertions, dir string) []string {
+	namespaces := map[string]bool{}
+	for key := range i18n.Catalogs()[i18n.English] {
+		namespaces[strings.Split(key, ".")[0]] = true
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	c.NoError(err)
+
+	keys := []string{}
+
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+
+		parsed, err := parser.ParseFile(token.NewFileSet(), file, nil, 0)
+		c.NoError(err)
+
+		ast.Inspect(parsed, func(node ast.Node) bool {
+			call, ok := node.(*ast.CallExpr)
+			if ok {
+				keys = append(keys, translatorKey(call)...)
+			}
+
+			literal, ok := node.(*ast.BasicLit)
+			if !ok || literal.Kind != token.STRING {
+				return true
+			}
+
+			value, err := strconv.Unquote(literal.Value)
+			if err == nil && catalogKey.MatchString(value) && namespaces[strings.Split(value, ".")[0]] {
+				keys = append(keys, value)
+			}
+
+			return true
+		})
+	}
+
+	return keys
+}
+
+// translatorKey returns the key of the call when it is a translator call with a literal key
+func translatorKey(call *ast.CallExpr) []string {
+	name := ""
+
+	switch fun := call.Fun.(type) {
+	case *ast.Ident:
+		name = fun.Name
+	case *ast.SelectorExpr:
+		pkg, ok := fun.X.(*ast.Ident)
+		if ok {
+			name = pkg.Name + "." + fun.Sel.Name
+		}
+	}
+
+	position, ok := translators[name]
+	if !ok || position >= len(call.Args) {
+		return nil
+	}
+
+	literal, ok := call.Args[position].(*ast.BasicLit)
+	if !ok || literal.Kind != token.STRING {
+		return nil
+	}
+
+	key, err := strconv.Unquote(literal.Value)
+	if err != nil {
+		return nil
+	}
+
+	return []string{key}
+}
+
+func TestCatalogsHaveTheRouterKeys(t *testing.T) {
+	c := require.New(t)
+
+	keys := []string{}
+
+	for _, dir := range []string{".", filepath.Join("..", "storage", "conversation"), filepath.Join("..", "commands"), filepath.Join("..", "slack")} {
+		dirKeys := sourceKeys(c, dir)
+		c.NotEmpty(dirKeys, dir)
+
+		keys = append(keys, dirKeys...)
+	}
+
+	c.Contains(keys, "parse.unknown_flag")
+	c.Contains(keys, "slack.not_linked")
+
+	for _, command := range botCommands.Commands() {
+		keys = append(keys, "command."+command.Name)
+	}
+
+	for _, outcome := range []models.AuditOutcome{
+		models.OutcomePublished, models.OutcomeDelayed, models.OutcomeAwaitingApproval, models.OutcomeHandled,
+		models.OutcomeInvalid, models.OutcomeDenied, models.OutcomeFailed,
+	} {
+		keys = append(keys, "audit.outcome."+string(outcome))
+	}
+
+	for language, catalog := range i18n.Catalogs() {
+		for _, key := range keys {
+			_, ok := catalog[key]
+			c.True(ok, "%s is missing in the %s catalog", key, language)
+		}
+	}
+}
+
+func TestChangeLanguage(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+
+	fake, restore := setFakeTelegram()
+
+	defer restore()
+
+	ctx := context.Background()
+
+	err := ProcessUpdate(ctx, newLanguageUpdate(1, "/language", ""))
+	c.NoError(err)
+	c.Equal("I answer you in English, send /language <code> to change it. Available languages: en (English), es (Spanish)", fake.SentTexts()[0])
+
+	err = ProcessUpdate(ctx, newLanguageUpdate(2, "/language fr", ""))
+	c.NoError(err)
+	c.Equal("Unknown language fr, use one of en (English), es (Spanish)", fake.SentTexts()[1])
+
+	err = ProcessUpdate(ctx, newLanguageUpdate(3, "/language ES", ""))
+	c.NoError(err)
+	c.Equal("Listo, te responderé en español", fake.SentTexts()[2])
+
+	language, err := preference.Language(ctx, 10)
+	c.NoError(err)
+	c.Equal("es", language)
+
+	err = ProcessUpdate(ctx, newLanguageUpdate(4, "/unknown", "en-US"))
+	c.NoError(err)
+	c.Equal("Comando desconocido /unknown, envía /help para ver los comandos disponibles", fake.SentTexts()[3])
+}
+
+func TestLanguageFromTelegramClient(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+
+	fake, restore := setFakeTelegram()
+
+	defer restore()
+
+	ctx := context.Background()
+
+	err := ProcessUpdate(ctx, newLanguageUpdate(1, "/unknown", "es-CO"))
+	c.NoError(err)
+	c.Equal("Comando desconocido /unknown, envía /help para ver los comandos disponibles", fake.SentTexts()[0])
+
+	err = ProcessUpdate(ctx, newLanguageUpdate(2, "/unknown", "pt-BR"))
+	c.NoError(err)
+	c.Equal("Unknown command /unknown, send /help to see the available commands", fake.SentTexts()[1])
+}
+
+func TestParseErrorInUserLanguage(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+
+	fake, restore := setFakeTelegram()
+
+	defer restore()
+
+	err := ProcessUpdate(context.Background(), newLanguageUpdate(1, "/deployterraformstaging checks/core --nope", "es"))
+	c.NoError(err)
+	c.Equal("opción desconocida --nope:\n/deployterraformstaging checks/core --nope\n                                    ^", fake.SentTexts()[0])
+}
@@ -0,0 +1,96 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"shared/app/bot/i18n"
+	"shared/app/bot/models"
+	"shared/app/bot/storage/notification"
+	"shared/shared/telegram"
+)
+
+const (
+	muteCommand          = "mute"
+	unmuteCommand        = "unmute"
+	notificationsCommand = "notifications"
+
+	eventArg = "event"
+)
+
+// listNotifications sends the notification events and whether the user muted them
+func listNotifications(ctx context.Context, telegramClient *telegram.Client, message *models.CallbackMessage) error {
+	muted, err := notification.Muted(ctx, message.From.ID)
+	if err != nil {
+		return err
+	}
+
+	lines := make([]string, 0, len(models.NotificationEvents))
+
+	for _, event := range models.NotificationEvents {
+		status := translate(ctx, "notifications.on", nil)
+		if muted[event] {
+			status = translate(ctx, "notifications.muted", nil)
+		}
+
+		lines = append(lines, fmt.Sprintf("%s: %s", event, status))
+	}
+
+	lines = append(lines, translate(ctx, "notifications.footer", i18n.Vars{"mute": muteCommand, "unmute": unmuteCommand}))
+
+	telegramClient.SendText(ctx, replyChatID(message), strings.Join(lines, "\n"))
+
+	return nil
+}
+
+func muteNotification(ctx context.Context, telegramClient *telegram.Client, message *models.CallbackMessage) error {
+	event, ok := notificationEvent(ctx, telegramClient, message)
+	if !ok {
+		return nil
+	}
+
+	err := notification.Mute(ctx, message.From.ID, event)
+	if err != nil {
+		return err
+	}
+
+	telegramClient.SendText(ctx, replyChatID(message), translate(ctx, "notifications.muted_event", i18n.Vars{"event": event}))
+
+	return nil
+}
+
+func unmuteNotification(ctx context.Context, telegramClient *telegram.Client, message *models.CallbackMessage) error {
+	event, ok := notificationEvent(ctx, telegramClient, message)
+	if !ok {
+		return nil
+	}
+
+	err := notification.Unmute(ctx, message.From.ID, event)
+	if err != nil {
+		return err
+	}
+
+	telegramClient.SendText(ctx, replyChatID(message), translate(ctx, "notifications.unmuted_event", i18n.Vars{"event": event}))
+
+	return nil
+}
+
+// notificationEvent returns the event of the command arguments, the user is told the valid events when it is unknown
+func notificationEvent(ctx context.Context, telegramClient *telegram.Client, message *models.CallbackMessage) (models.NotificationEvent, bool) {
+	name := strings.ToLower(message.Args[eventArg])
+	if models.IsNotificationEvent(name) {
+		return models.NotificationEvent(name), true
+	}
+
+	names := make([]string, 0, len(models.NotificationEvents))
+
+	for _, event := range models.NotificationEvents {
+		names = append(names, string(event))
+	}
+
+	telegramClient.SendText(ctx, replyChatID(message), translate(ctx, "notifications.unknown_event", i18n.Vars{"event": strconv.Quote(name), "events": strings.Join(names, ", ")}))
+
+	return "", false
+}
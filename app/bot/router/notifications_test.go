@@ -0,0 +1,46 @@
+package router
+
+import (
+	"context"
+	"testing"
+
+	"shared/app/bot/models"
+	"shared/app/bot/storage/notification"
+
+	"bitbucket.org/truora/scrap-services/shared/cache"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMuteNotifications(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+	saveVerifiedUser(c, 10, "dummy_email@dummy.com")
+
+	fake, restore := setFakeTelegram()
+
+	defer restore()
+
+	ctx := context.Background()
+
+	c.NoError(ProcessUpdate(ctx, newScheduleUpdate(1, "/mute PR_MERGED")))
+	c.Equal("Muted the pr_merged notifications", fake.SentTexts()[0])
+
+	muted, err := notification.IsMuted(ctx, 10, models.NotificationPRMerged)
+	c.NoError(err)
+	c.True(muted)
+
+	c.NoError(ProcessUpdate(ctx, newScheduleUpdate(2, "/notifications")))
+	c.Contains(fake.SentTexts()[1], "pr_merged: muted")
+	c.Contains(fake.SentTexts()[1], "mention: on")
+
+	c.NoError(ProcessUpdate(ctx, newScheduleUpdate(3, "/unmute pr_merged")))
+	c.Equal("Unmuted the pr_merged notifications", fake.SentTexts()[2])
+
+	c.NoError(ProcessUpdate(ctx, newScheduleUpdate(4, "/mute pr_declined")))
+	c.Contains(fake.SentTexts()[3], `Unknown event "pr_declined"`)
+
+	muted, err = notification.IsMuted(ctx, 10, models.NotificationPRMerged)
+	c.NoError(err)
+	c.False(muted)
+}
@@ -0,0 +1,71 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"shared/app/bot/commands"
+	"shared/app/bot/i18n"
+	"shared/app/bot/models"
+	"shared/app/bot/storage"
+	"shared/shared/telegram"
+
+	"bitbucket.org/truora/scrap-services/logger"
+)
+
+const registerCommand = "hi"
+
+var (
+	// ErrUserNotRegistered when the sender is not a verified bot user
+	ErrUserNotRegistered = errors.New("user not registered")
+	// ErrRoleNotAllowed when the sender role can not run the command
+	ErrRoleNotAllowed = errors.New("role not allowed to run the command")
+
+	getTelegramUser = storage.GetTelegramUser
+)
+
+// authorize checks the sender is a verified user with a role allowed to run the command,
+// the stored role and email are added to the message so workers do not need to look them up
+func authorize(ctx context.Context, telegramClient *telegram.Client, message *models.CallbackMessage, command *commands.Command) error {
+	if command.Public {
+		return nil
+	}
+
+	user, err := getTelegramUser(ctx, message.From.ID)
+	if errors.Is(err, storage.ErrUserNotFound) {
+		logDenial(ctx, message, command, ErrUserNotRegistered)
+		telegramClient.SendText(ctx, replyChatID(message), translate(ctx, "policy.not_registered", i18n.Vars{"command": registrationCommand}))
+
+		return ErrUserNotRegistered
+	}
+
+	if err != nil {
+		return fmt.Errorf("get telegram user failed: %w", err)
+	}
+
+	if !command.AllowsRole(user.UserRole) {
+		logDenial(ctx, message, command, ErrRoleNotAllowed)
+		telegramClient.SendText(ctx, replyChatID(message), translate(ctx, "policy.not_allowed", i18n.Vars{"command": command.Name}))
+
+		return ErrRoleNotAllowed
+	}
+
+	message.From.Email = user.Email
+	message.From.EmailVerified = user.EmailVerified
+	message.From.UserRole = user.UserRole
+
+	return nil
+}
+
+func logDenial(ctx context.Context, message *models.CallbackMessage, command *commands.Command, reason error) {
+	logger.Get(ctx).Warning(ctx, "command_denied", logger.ThreeMonths, []logger.Object{
+		logger.ErrObject(reason),
+		logger.MapObject("audit", map[string]interface{}{
+			"i_telegram_id": message.From.ID,
+			"s_username":    message.From.Username,
+			"i_chat_id":     message.Message.Chat.ID,
+			"s_command":     command.Name,
+		}),
+	})
+}
@@ -0,0 +1,85 @@
+package router
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"shared/app/bot/botclient"
+	"shared/app/bot/commands"
+	"shared/app/bot/models"
+	"shared/app/bot/storage"
+
+	"bitbucket.org/truora/scrap-services/logger"
+	"github.com/stretchr/testify/require"
+	"github.com/truora/minidyn"
+)
+
+func TestAuthorize(t *testing.T) {
+	c := require.New(t)
+
+	setMockedClient()
+
+	defer deactivateMockedClient()
+
+	storage.InitDynamoMock()
+
+	ctx := context.Background()
+	log := logger.New("test")
+	buf := bytes.NewBufferString("")
+	log.Output = buf
+
+	ctx = logger.Set(ctx, log)
+
+	telegramClient, err := botclient.New(ctx)
+	c.NoError(err)
+
+	deploy := &commands.Command{Name: "deploy", Roles: []string{"leads"}}
+	status := &commands.Command{Name: "status"}
+	message := &models.CallbackMessage{From: models.From{ID: 123}}
+
+	err = authorize(ctx, telegramClient, message, &commands.Command{Name: "help", Public: true})
+	c.NoError(err)
+
+	err = authorize(ctx, telegramClient, message, status)
+	c.ErrorIs(err, ErrUserNotRegistered)
+	c.Contains(buf.String(), "command_denied")
+
+	err = storage.SaveUser(ctx, models.From{Email: "dummy_email@dummy.com", ID: 123})
+	c.NoError(err)
+
+	err = authorize(ctx, telegramClient, message, status)
+	c.ErrorIs(err, ErrUserNotRegistered)
+
+	err = storage.VerifyUserEmail(ctx, "dummy_email@dummy.com")
+	c.NoError(err)
+
+	err = authorize(ctx, telegramClient, message, status)
+	c.NoError(err)
+	c.Equal("dummy_email@dummy.com", message.From.Email)
+
+	buf.Reset()
+
+	err = authorize(ctx, telegramClient, message, deploy)
+	c.ErrorIs(err, ErrRoleNotAllowed)
+	c.Contains(buf.String(), "command_denied")
+
+	user, err := storage.GetUser(ctx, "dummy_email@dummy.com")
+	c.NoError(err)
+
+	user.UserRole = models.RoleAdmin
+
+	err = storage.PutUser(ctx, user)
+	c.NoError(err)
+
+	err = authorize(ctx, telegramClient, message, deploy)
+	c.NoError(err)
+	c.Equal(models.RoleAdmin, message.From.UserRole)
+
+	storage.ActiveForceFailure()
+
+	defer storage.DeactiveForceFailure()
+
+	err = authorize(ctx, telegramClient, message, deploy)
+	c.ErrorIs(err, minidyn.ErrForcedFailure)
+}
@@ -0,0 +1,220 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+
+	"shared/app/bot/email"
+	"shared/app/bot/i18n"
+	"shared/app/bot/models"
+	"shared/app/bot/storage"
+	"shared/app/bot/storage/conversation"
+	"shared/app/bot/storage/verification"
+	"shared/shared/telegram"
+
+	"bitbucket.org/truora/scrap-services/logger"
+)
+
+const (
+	registrationCommand = "register"
+	// resendAnswer asks a new code while the registration waits for the code
+	resendAnswer = "resend"
+
+	registrationEmailStep = "email"
+	registrationCodeStep  = "code"
+	registrationEmailKey  = "email"
+)
+
+var (
+	// errInvalidEmail when the email of the registration is not a valid address
+	errInvalidEmail = errors.New("invalid email")
+
+	emailSender email.Sender
+)
+
+// register links the Telegram user to an email after the user sends the code emailed to it. The email
+// can be sent with the command or answered when the bot asks it
+func register(ctx context.Context, telegramClient *telegram.Client, message *models.CallbackMessage) error {
+	if message.ID == defaultConversationID {
+		return continueRegistration(ctx, telegramClient, message)
+	}
+
+	user, err := getTelegramUser(ctx, message.From.ID)
+	if err == nil && user.EmailVerified {
+		telegramClient.SendText(ctx, replyChatID(message), translate(ctx, "register.already_registered", i18n.Vars{"email": user.Email}))
+
+		return nil
+	}
+
+	if err != nil && !errors.Is(err, storage.ErrUserNotFound) {
+		return fmt.Errorf("get telegram user failed: %w", err)
+	}
+
+	address := message.Args[registrationEmailKey]
+	if address == "" {
+		return storeRegistration(ctx, telegramClient, message, registrationEmailStep, "", translate(ctx, "register.ask_email", nil))
+	}
+
+	return sendVerificationCode(ctx, telegramClient, message, address)
+}
+
+func continueRegistration(ctx context.Context, telegramClient *telegram.Client, message *models.CallbackMessage) error {
+	answer := strings.TrimSpace(message.Message.Text)
+
+	if answer == conversation.CancelAnswer {
+		telegramClient.SendText(ctx, replyChatID(message), translate(ctx, "register.canceled", nil))
+
+		return conversation.DeleteConversationState(ctx, message.Message)
+	}
+
+	if message.Data == registrationEmailStep {
+		return sendVerificationCode(ctx, telegramClient, message, answer)
+	}
+
+	address := message.AdditionalData[registrationEmailKey]
+
+	if strings.EqualFold(answer, resendAnswer) {
+		return sendVerificationCode(ctx, telegramClient, message, address)
+	}
+
+	verifiedEmail, err := verification.Verify(ctx, message.From.ID, answer)
+	if errors.Is(err, verification.ErrInvalidCode) {
+		telegramClient.SendText(ctx, replyChatID(message), translate(ctx, "register.invalid_code", i18n.Vars{"resend": resendAnswer}))
+
+		return nil
+	}
+
+	if errors.Is(err, verification.ErrTooManyAttempts) || errors.Is(err, verification.ErrCodeNotFound) {
+		telegramClient.SendText(ctx, replyChatID(message), translate(ctx, "register.code_expired", i18n.Vars{"resend": resendAnswer}))
+
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	user, err := storage.LinkTelegramUser(ctx, verifiedEmail, message.From)
+	if errors.Is(err, storage.ErrUserNotFound) {
+		logger.Get(ctx).Warning(ctx, "user_not_added", logger.OneMonth, []logger.Object{
+			logger.MapObject("audit", map[string]interface{}{
+				"i_telegram_id": message.From.ID,
+				"s_email":       verifiedEmail,
+			}),
+		})
+		telegramClient.SendText(ctx, replyChatID(message), translate(ctx, "register.not_added", i18n.Vars{"email": verifiedEmail}))
+
+		return conversation.DeleteConversationState(ctx, message.Message)
+	}
+
+	if err != nil {
+		return fmt.Errorf("link telegram user failed: %w", err)
+	}
+
+	logger.Get(ctx).Info(ctx, "user_registered", logger.ThreeMonths, []logger.Object{
+		logger.MapObject("audit", map[string]interface{}{
+			"i_telegram_id": user.ID,
+			"s_email":       user.Email,
+			"s_user_role":   user.UserRole,
+		}),
+	})
+
+	telegramClient.SendText(ctx, replyChatID(message), translate(ctx, "register.verified", i18n.Vars{"email": user.Email, "command": helpCommand}))
+
+	return conversation.DeleteConversationState(ctx, message.Message)
+}
+
+// sendVerificationCode emails a new code to the address and waits for the user to send it
+func sendVerificationCode(ctx context.Context, telegramClient *telegram.Client, message *models.CallbackMessage, address string) error {
+	address, err := parseEmail(address)
+	if err != nil {
+		telegramClient.SendText(ctx, replyChatID(message), translate(ctx, "register.invalid_email", nil))
+
+		return nil
+	}
+
+	code, err := verification.Issue(ctx, message.From.ID, address)
+	if errors.Is(err, verification.ErrResendTooSoon) {
+		telegramClient.SendText(ctx, replyChatID(message), translate(ctx, "register.cooldown", i18n.Vars{"cooldown": verification.Cooldown}))
+
+		return nil
+	}
+
+	if errors.Is(err, verification.ErrTooManyCodes) {
+		telegramClient.SendText(ctx, replyChatID(message), translate(ctx, "register.too_many_codes", nil))
+
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	sender, err := getEmailSender()
+	if err != nil {
+		return err
+	}
+
+	err = sender.Send(ctx, &email.Message{
+		To:      address,
+		Subject: translate(ctx, "register.email_subject", nil),
+		Body:    translate(ctx, "register.email_body", i18n.Vars{"code": code}),
+	})
+	if err != nil {
+		telegramClient.SendText(ctx, replyChatID(message), translate(ctx, "register.send_failed", nil))
+
+		return fmt.Errorf("send verification email failed: %w", err)
+	}
+
+	reply := translate(ctx, "register.code_sent", i18n.Vars{"email": address, "resend": resendAnswer})
+
+	return storeRegistration(ctx, telegramClient, message, registrationCodeStep, address, reply)
+}
+
+func storeRegistration(ctx context.Context, telegramClient *telegram.Client, message *models.CallbackMessage, step, address, reply string) error {
+	err := conversation.StoreConversationState(ctx, message.Message, &models.ConversationState{
+		Command:        registrationCommand,
+		Data:           step,
+		AdditionalData: map[string]string{registrationEmailKey: address},
+	})
+	if err != nil {
+		return fmt.Errorf("store registration failed: %w", err)
+	}
+
+	telegramClient.SendText(ctx, replyChatID(message), reply)
+
+	return nil
+}
+
+func parseEmail(address string) (string, error) {
+	parsed, err := mail.ParseAddress(strings.TrimSpace(address))
+	if err != nil || parsed.Name != "" {
+		return "", errInvalidEmail
+	}
+
+	return strings.ToLower(parsed.Address), nil
+}
+
+func getEmailSender() (email.Sender, error) {
+	if emailSender != nil {
+		return emailSender, nil
+	}
+
+	sender, err := email.NewSenderFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	emailSender = sender
+
+	return emailSender, nil
+}
+
+// SetEmailSender replaces how the verification codes are emailed, e.g. to log them instead of using SES.
+// A nil sender uses the sender configured in EMAIL_SENDER again
+func SetEmailSender(sender email.Sender) {
+	emailSender = sender
+}
@@ -0,0 +1,189 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"testing"
+
+	"shared/app/bot/email"
+	"shared/app/bot/models"
+	"shared/app/bot/storage"
+	"shared/app/bot/storage/verification"
+
+	"bitbucket.org/truora/scrap-services/shared/cache"
+	"github.com/stretchr/testify/require"
+)
+
+var sentCode = regexp.MustCompile(`\d{6}`)
+
+type fakeEmailSender struct {
+	messages []*email.Message
+	err      error
+}
+
+func (sender *fakeEmailSender) Send(ctx context.Context, message *email.Message) error {
+	sender.messages = append(sender.messages, message)
+
+	return sender.err
+}
+
+func (sender *fakeEmailSender) lastCode() string {
+	return sentCode.FindString(sender.messages[len(sender.messages)-1].Body)
+}
+
+func TestRegister(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+	storage.InitDynamoMock()
+
+	fake, restore := setFakeTelegram()
+
+	defer restore()
+
+	sender := &fakeEmailSender{}
+	SetEmailSender(sender)
+
+	defer SetEmailSender(nil)
+
+	ctx := context.Background()
+
+	c.NoError(storage.PutUser(ctx, &models.From{Email: "dummy_email@dummy.com", UserRole: models.RoleDevelopers}))
+
+	c.NoError(ProcessUpdate(ctx, newScheduleUpdate(1, "/register")))
+	c.Contains(fake.SentTexts()[0], "What is your email?")
+
+	c.NoError(ProcessUpdate(ctx, newScheduleUpdate(2, "not an email")))
+	c.Contains(fake.SentTexts()[1], "Please send a valid email")
+
+	c.NoError(ProcessUpdate(ctx, newScheduleUpdate(3, "Dummy_Email@dummy.com")))
+	c.Contains(fake.SentTexts()[2], "I sent a code to dummy_email@dummy.com")
+	c.Len(sender.messages, 1)
+	c.Equal("dummy_email@dummy.com", sender.messages[0].To)
+
+	code := sender.lastCode()
+	c.Len(code, 6)
+
+	wrong := "000000"
+	if code == wrong {
+		wrong = "111111"
+	}
+
+	c.NoError(ProcessUpdate(ctx, newScheduleUpdate(4, wrong)))
+	c.Contains(fake.SentTexts()[3], "Invalid code")
+
+	// the resend is rate limited
+	c.NoError(ProcessUpdate(ctx, newScheduleUpdate(5, "resend")))
+	c.Contains(fake.SentTexts()[4], "Please wait")
+	c.Len(sender.messages, 1)
+
+	c.NoError(ProcessUpdate(ctx, newScheduleUpdate(6, code)))
+	c.Contains(fake.SentTexts()[5], "Your email dummy_email@dummy.com is verified")
+
+	user, err := storage.GetTelegramUser(ctx, 10)
+	c.NoError(err)
+	c.True(user.EmailVerified)
+	c.Equal("dummy_email@dummy.com", user.Email)
+
+	c.NoError(ProcessUpdate(ctx, newScheduleUpdate(7, "/register other@dummy.com")))
+	c.Contains(fake.SentTexts()[6], "You are already registered as dummy_email@dummy.com")
+}
+
+func TestRegisterNotAdded(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+	storage.InitDynamoMock()
+
+	fake, restore := setFakeTelegram()
+
+	defer restore()
+
+	sender := &fakeEmailSender{}
+	SetEmailSender(sender)
+
+	defer SetEmailSender(nil)
+
+	ctx := context.Background()
+
+	c.NoError(ProcessUpdate(ctx, newScheduleUpdate(1, "/register unknown@dummy.com")))
+	c.Contains(fake.SentTexts()[0], "I sent a code to unknown@dummy.com")
+
+	c.NoError(ProcessUpdate(ctx, newScheduleUpdate(2, sender.lastCode())))
+	c.Equal("Your email unknown@dummy.com is verified but it was not added to the bot, please ask an admin to add you", fake.SentTexts()[1])
+
+	_, err := storage.GetTelegramUser(ctx, 10)
+	c.ErrorIs(err, storage.ErrUserNotFound)
+
+	_, err = storage.GetUser(ctx, "unknown@dummy.com")
+	c.ErrorIs(err, storage.ErrUserNotFound)
+
+	// the registration is over, the next message is not read as a code
+	c.NoError(ProcessUpdate(ctx, newScheduleUpdate(3, "/register")))
+	c.Contains(fake.SentTexts()[2], "What is your email?")
+}
+
+func TestRegisterResendAndCancel(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+	storage.InitDynamoMock()
+
+	fake, restore := setFakeTelegram()
+
+	defer restore()
+
+	sender := &fakeEmailSender{}
+	SetEmailSender(sender)
+
+	defer SetEmailSender(nil)
+
+	ctx := context.Background()
+
+	c.NoError(ProcessUpdate(ctx, newScheduleUpdate(1, "/register dummy_email@dummy.com")))
+	c.Len(sender.messages, 1)
+
+	firstCode := sender.lastCode()
+
+	c.NoError(cache.Del(ctx, fmt.Sprintf("BOT-VERIFICATION-COOLDOWN:%d", 10)))
+
+	c.NoError(ProcessUpdate(ctx, newScheduleUpdate(2, "resend")))
+	c.Len(sender.messages, 2)
+	c.Contains(fake.SentTexts()[1], "I sent a code to dummy_email@dummy.com")
+
+	// the new code replaces the previous one
+	if firstCode != sender.lastCode() {
+		_, err := verification.Verify(ctx, 10, firstCode)
+		c.ErrorIs(err, verification.ErrInvalidCode)
+	}
+
+	c.NoError(ProcessUpdate(ctx, newScheduleUpdate(3, "/cancel")))
+	c.Contains(fake.SentTexts()[2], "Registration canceled")
+
+	c.NoError(ProcessUpdate(ctx, newScheduleUpdate(4, sender.lastCode())))
+	c.Len(fake.SentTexts(), 3)
+
+	_, err := storage.GetTelegramUser(ctx, 10)
+	c.ErrorIs(err, storage.ErrUserNotFound)
+}
+
+func TestRegisterSendFailed(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+	storage.InitDynamoMock()
+
+	fake, restore := setFakeTelegram()
+
+	defer restore()
+
+	SetEmailSender(&fakeEmailSender{err: errors.New("throttled")})
+
+	defer SetEmailSender(nil)
+
+	err := ProcessUpdate(context.Background(), newScheduleUpdate(1, "/register dummy_email@dummy.com"))
+	c.Error(err)
+	c.Contains(fake.SentTexts()[0], "Cannot send the code")
+}
@@ -0,0 +1,389 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"shared/app/bot/commands"
+	"shared/app/bot/i18n"
+	"shared/app/bot/models"
+	"shared/shared/telegram"
+)
+
+const (
+	helpCommand = "help"
+	// deployTerraformProductionCommand is parked until an admin approves it
+	deployTerraformProductionCommand = "deployterraformproduction"
+)
+
+// deployUploads are the files accepted with the deploys, e.g. a plan or a screenshot of the change, they use
+// the size and types of the router
+var deployUploads = &commands.UploadLimits{}
+
+var botCommands = commands.MustNewRegistry(
+	&commands.Command{
+		Name:        helpCommand,
+		Aliases:     []string{"start"},
+		Description: "Shows the available commands",
+		Public:      true,
+	},
+	&commands.Command{
+		Name:         registerCommand,
+		Description:  "Links your Telegram account to your email",
+		RequiredArgs: []commands.Argument{{Name: "email"}},
+		ChatTypes:    []commands.ChatType{commands.ChatPrivate},
+		Public:       true,
+	},
+	&commands.Command{
+		Name:         registrationCommand,
+		Description:  "Verifies your email with a code and links it to your Telegram account",
+		OptionalArgs: []commands.Argument{{Name: "email"}},
+		ChatTypes:    []commands.ChatType{commands.ChatPrivate},
+		Public:       true,
+	},
+	&commands.Command{
+		Name:         deployTerraformStagingCommand,
+		Description:  "Deploys a terraform project to staging",
+		RequiredArgs: []commands.Argument{{Name: "project", Description: "Path of the terraform project, e.g. checks/core"}},
+		Flags:        []commands.Flag{{Name: "branch", Short: "b", Description: "Branch to deploy"}},
+		Roles:        []string{models.RoleDevelopers},
+		Uploads:      deployUploads,
+	},
+	&commands.Command{
+		Name:         deployTerraformProductionCommand,
+		Description:  "Deploys a terraform project to production once an admin approves it",
+		RequiredArgs: []commands.Argument{{Name: "project", Description: "Path of the terraform project, e.g. checks/core"}},
+		Flags:        []commands.Flag{{Name: "branch", Short: "b", Description: "Branch to deploy"}},
+		Roles:        []string{models.RoleDevelopers},
+		Approvals:    1,
+		Uploads:      deployUploads,
+	},
+	&commands.Command{
+		Name:        scheduleCommand,
+		Description: "Runs a command later, e.g. /schedule 30m /deployterraformstaging checks/core",
+		RequiredArgs: []commands.Argument{
+			{Name: "delay", Description: "Time to wait before running the command, e.g. 30m or 2h"},
+			{Name: "command", Description: "Command to run with its arguments"},
+		},
+		Flags:         []commands.Flag{{Name: "every", Short: "e", Description: "Runs the command again at this interval, e.g. 24h"}},
+		NestedCommand: true,
+	},
+	&commands.Command{
+		Name:        schedulesCommand,
+		Description: "Lists your scheduled commands",
+	},
+	&commands.Command{
+		Name:         unscheduleCommand,
+		Description:  "Cancels a scheduled command",
+		RequiredArgs: []commands.Argument{{Name: "id", Description: "ID shown by /" + schedulesCommand}},
+	},
+	&commands.Command{
+		Name:        notificationsCommand,
+		Description: "Shows the Bitbucket notifications you receive",
+	},
+	&commands.Command{
+		Name:         muteCommand,
+		Description:  "Stops a type of Bitbucket notifications, e.g. /mute pr_merged",
+		RequiredArgs: []commands.Argument{{Name: eventArg}},
+	},
+	&commands.Command{
+		Name:         unmuteCommand,
+		Description:  "Receives a type of Bitbucket notifications again",
+		RequiredArgs: []commands.Argument{{Name: eventArg}},
+	},
+	&commands.Command{
+		Name:        usersCommand,
+		Description: "Lists the bot users",
+		Roles:       adminRoles,
+	},
+	&commands.Command{
+		Name:         userCommand,
+		Description:  "Shows a bot user",
+		RequiredArgs: []commands.Argument{{Name: emailArg}},
+		Roles:        adminRoles,
+	},
+	&commands.Command{
+		Name:         addUserCommand,
+		Description:  "Adds a bot user so it can register with its email",
+		RequiredArgs: []commands.Argument{{Name: emailArg}},
+		OptionalArgs: []commands.Argument{{Name: roleArg, Description: "Role of the user, developers by default"}},
+		Roles:        adminRoles,
+	},
+	&commands.Command{
+		Name:         setRoleCommand,
+		Description:  "Changes the role of a bot user",
+		RequiredArgs: []commands.Argument{{Name: emailArg}, {Name: roleArg}},
+		Roles:        adminRoles,
+	},
+	&commands.Command{
+		Name:         revokeUserCommand,
+		Description:  "Revokes the email verification of a bot user",
+		RequiredArgs: []commands.Argument{{Name: emailArg}},
+		Roles:        adminRoles,
+	},
+	&commands.Command{
+		Name:         linkBitbucketCommand,
+		Description:  "Links a Bitbucket account ID to a bot user",
+		RequiredArgs: []commands.Argument{{Name: emailArg}, {Name: bitbucketIDArg}},
+		Roles:        adminRoles,
+	},
+	&commands.Command{
+		Name:         unlinkBitbucketCommand,
+		Description:  "Unlinks the Bitbucket account of a bot user",
+		RequiredArgs: []commands.Argument{{Name: emailArg}},
+		Roles:        adminRoles,
+	},
+	&commands.Command{
+		Name:         linkSlackCommand,
+		Description:  "Links a Slack user ID to a bot user",
+		RequiredArgs: []commands.Argument{{Name: emailArg}, {Name: slackIDArg, Description: "Member ID shown in the Slack profile, e.g. U024BE7LH"}},
+		Roles:        adminRoles,
+	},
+	&commands.Command{
+		Name:         unlinkSlackCommand,
+		Description:  "Unlinks the Slack user of a bot user",
+		RequiredArgs: []commands.Argument{{Name: emailArg}},
+		Roles:        adminRoles,
+	},
+	&commands.Command{
+		Name:         deleteUserCommand,
+		Description:  "Deletes a bot user",
+		RequiredArgs: []commands.Argument{{Name: emailArg}},
+		Roles:        adminRoles,
+	},
+	&commands.Command{
+		Name:         languageCommand,
+		Description:  "Shows or changes the language of the bot answers",
+		Public:       true,
+		OptionalArgs: []commands.Argument{{Name: languageArg, Description: "Code of the language, e.g. es or en"}},
+	},
+	&commands.Command{
+		Name:         historyCommand,
+		Description:  "Shows the commands you ran lately",
+		OptionalArgs: []commands.Argument{{Name: commandArg, Description: "Only shows this command, e.g. deployterraformstaging"}},
+	},
+	&commands.Command{
+		Name:        auditLogCommand,
+		Description: "Shows the commands the team ran lately",
+		Flags: []commands.Flag{
+			{Name: userFlag, Short: "u", Description: "Only shows the commands of the user with this email"},
+			{Name: commandFlag, Short: "c", Description: "Only shows this command"},
+		},
+		Roles: adminRoles,
+	},
+	&commands.Command{
+		Name:        deadLettersCommand,
+		Description: "Lists the commands that could not be sent to the workers",
+		Roles:       adminRoles,
+	},
+	&commands.Command{
+		Name:         deadLetterCommand,
+		Description:  "Shows a command that could not be sent to the workers",
+		RequiredArgs: []commands.Argument{{Name: deadLetterIDArg, Description: "ID shown by /" + deadLettersCommand}},
+		Roles:        adminRoles,
+	},
+	&commands.Command{
+		Name:         retryDeadLetterCommand,
+		Description:  "Sends a parked command to the workers now",
+		RequiredArgs: []commands.Argument{{Name: deadLetterIDArg, Description: "ID shown by /" + deadLettersCommand}},
+		Roles:        adminRoles,
+	},
+	&commands.Command{
+		Name:         discardDeadLetterCommand,
+		Description:  "Discards a parked command, its user is told it will not run",
+		RequiredArgs: []commands.Argument{{Name: deadLetterIDArg, Description: "ID shown by /" + deadLettersCommand}},
+		Roles:        adminRoles,
+	},
+	&commands.Command{
+		Name:        flagsCommand,
+		Description: "Shows the disabled commands, the commands in beta and the maintenance",
+		Roles:       adminRoles,
+	},
+	&commands.Command{
+		Name:         disableCommand,
+		Description:  "Stops sending a command to the workers, its users are told the reason",
+		RequiredArgs: []commands.Argument{{Name: commandArg, Description: "Command to disable, e.g. deployterraformstaging"}},
+		OptionalArgs: []commands.Argument{{Name: reasonArg, Variadic: true}},
+		Roles:        adminRoles,
+	},
+	&commands.Command{
+		Name:         enableCommand,
+		Description:  "Sends a disabled command to the workers again",
+		RequiredArgs: []commands.Argument{{Name: commandArg}},
+		Roles:        adminRoles,
+	},
+	&commands.Command{
+		Name:         startBetaCommand,
+		Description:  "Only lets the users allowed with /" + allowBetaCommand + " run a command",
+		RequiredArgs: []commands.Argument{{Name: commandArg}},
+		Roles:        adminRoles,
+	},
+	&commands.Command{
+		Name:         endBetaCommand,
+		Description:  "Lets every user run a command in beta",
+		RequiredArgs: []commands.Argument{{Name: commandArg}},
+		Roles:        adminRoles,
+	},
+	&commands.Command{
+		Name:         allowBetaCommand,
+		Description:  "Lets a bot user run a command in beta",
+		RequiredArgs: []commands.Argument{{Name: commandArg}, {Name: emailArg}},
+		Roles:        adminRoles,
+	},
+	&commands.Command{
+		Name:         denyBetaCommand,
+		Description:  "Stops a bot user from running a command in beta",
+		RequiredArgs: []commands.Argument{{Name: commandArg}, {Name: emailArg}},
+		Roles:        adminRoles,
+	},
+	&commands.Command{
+		Name:         maintenanceCommand,
+		Description:  "Stops sending every command to the workers, the users are sent the message",
+		RequiredArgs: []commands.Argument{{Name: maintenanceMessageArg, Variadic: true}},
+		Roles:        adminRoles,
+	},
+	&commands.Command{
+		Name:        endMaintenanceCommand,
+		Description: "Sends the commands to the workers again",
+		Roles:       adminRoles,
+	},
+)
+
+// routerCommands are answered by the router instead of being published to the workers
+var routerCommands map[string]func(ctx context.Context, telegramClient *telegram.Client, message *models.CallbackMessage) error
+
+func init() {
+	routerCommands = map[string]func(ctx context.Context, telegramClient *telegram.Client, message *models.CallbackMessage) error{
+		helpCommand:              sendHelp,
+		registrationCommand:      register,
+		scheduleCommand:          scheduleMessage,
+		schedulesCommand:         listSchedules,
+		unscheduleCommand:        unschedule,
+		notificationsCommand:     listNotifications,
+		muteCommand:              muteNotification,
+		unmuteCommand:            unmuteNotification,
+		usersCommand:             listUsers,
+		userCommand:              showUser,
+		addUserCommand:           addUser,
+		approvalCommand:          handleApproval,
+		deadLettersCommand:       listDeadLetters,
+		deadLetterCommand:        showDeadLetter,
+		retryDeadLetterCommand:   retryDeadLetter,
+		discardDeadLetterCommand: discardDeadLetter,
+		historyCommand:           sendHistory,
+		auditLogCommand:          sendAuditLog,
+		languageCommand:          changeLanguage,
+		flagsCommand:             listFlags,
+		disableCommand:           disableFlagCommand,
+		enableCommand:            enableFlagCommand,
+		startBetaCommand:         startBeta,
+		endBetaCommand:           endBeta,
+		allowBetaCommand:         allowBetaUser,
+		denyBetaCommand:          denyBetaUser,
+		maintenanceCommand:       startMaintenance,
+		endMaintenanceCommand:    endMaintenance,
+	}
+
+	for name := range userActions {
+		routerCommands[name] = handleUserAction(name)
+	}
+}
+
+// sendHelp sends the usage of the registered commands with their descriptions in the language of the user
+func sendHelp(ctx context.Context, telegramClient *telegram.Client, message *models.CallbackMessage) error {
+	lines := []string{translate(ctx, "help.header", nil)}
+
+	for _, command := range botCommands.Commands() {
+		line := translate(ctx, "help.command", i18n.Vars{"usage": command.Usage(), "description": commandDescription(ctx, command)})
+
+		if len(command.Aliases) > 0 {
+			line += translate(ctx, "help.aliases", i18n.Vars{"aliases": commandPrefix + strings.Join(command.Aliases, ", "+commandPrefix)})
+		}
+
+		lines = append(lines, line)
+	}
+
+	telegramClient.SendText(ctx, replyChatID(message), strings.Join(lines, "\n"))
+
+	return nil
+}
+
+// commandDescription returns the translated description of the command, the registered one is used when
+// the catalogs do not have it
+func commandDescription(ctx context.Context, command *commands.Command) string {
+	key := "command." + command.Name
+	if description := translate(ctx, key, nil); description != key {
+		return description
+	}
+
+	return command.Description
+}
+
+// resolveCommand returns the registered command, commands typed by the user are validated against
+// the registry and the user is told what is wrong with them. errWizardRequired is returned with the
+// command when it is sent without arguments and its arguments can be asked by a wizard. Commands coming from callbacks or
+// conversations were issued by the bot so they are published to the default topic when not registered
+func resolveCommand(ctx context.Context, telegramClient *telegram.Client, message *models.CallbackMessage, name string) (*commands.Command, error) {
+	command, reply, err := validateCommand(ctx, message, name)
+	if err != nil && !errors.Is(err, errWizardRequired) && reply != "" {
+		telegramClient.SendText(ctx, replyChatID(message), reply)
+	}
+
+	return command, err
+}
+
+// validateCommand does the checks of resolveCommand without answering the user, the returned reply
+// tells the user what is wrong with the command and it is empty when there is nothing to tell
+func validateCommand(ctx context.Context, message *models.CallbackMessage, name string) (*commands.Command, string, error) {
+	command, err := botCommands.Get(name)
+
+	if message.Command != "" {
+		if err != nil {
+			return &commands.Command{Name: name}, "", nil
+		}
+
+		return command, "", nil
+	}
+
+	if errors.Is(err, commands.ErrUnknownCommand) {
+		return nil, translate(ctx, "registry.unknown_command", i18n.Vars{"command": name, "help": helpCommand}), err
+	}
+
+	if !command.AllowsChat(message.Message.Chat.Type) {
+		return nil, translate(ctx, "registry.chat_not_allowed", i18n.Vars{"command": name}), commands.ErrChatNotAllowed
+	}
+
+	message.Input, err = command.Parse(message.Message.Text)
+	if err != nil {
+		var parseErr *commands.ParseError
+		if errors.As(err, &parseErr) {
+			return nil, parseErr.Pointer(ctx), err
+		}
+
+		return nil, "", err
+	}
+
+	message.Args, err = command.ParseArgs(message.Input.Positional)
+	if errors.Is(err, commands.ErrMissingArgs) && needsWizard(command, message.Input) {
+		return command, argsErrorText(ctx, command, err), errWizardRequired
+	}
+
+	if err != nil {
+		return nil, argsErrorText(ctx, command, err), err
+	}
+
+	return command, "", nil
+}
+
+// argsErrorText tells the user what is wrong with the arguments of the command
+func argsErrorText(ctx context.Context, command *commands.Command, err error) string {
+	switch {
+	case errors.Is(err, commands.ErrMissingArgs):
+		return translate(ctx, "registry.missing_args", i18n.Vars{"usage": command.Usage()})
+	case errors.Is(err, commands.ErrTooManyArgs):
+		return translate(ctx, "registry.too_many_args", i18n.Vars{"usage": command.Usage()})
+	}
+
+	return err.Error()
+}
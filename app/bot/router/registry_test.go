@@ -0,0 +1,109 @@
+package router
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"testing"
+
+	"shared/app/bot/botclient"
+	"shared/app/bot/commands"
+	"shared/app/bot/models"
+
+	"bitbucket.org/truora/scrap-services/logger"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveCommand(t *testing.T) {
+	c := require.New(t)
+
+	setMockedClient()
+
+	defer deactivateMockedClient()
+
+	ctx := context.Background()
+
+	telegramClient, err := botclient.New(ctx)
+	c.NoError(err)
+
+	message := &models.CallbackMessage{Message: models.Message{Text: "/hi dummy_email@dummy.com", Chat: models.Chat{Type: "private"}}}
+
+	command, err := resolveCommand(ctx, telegramClient, message, "hi")
+	c.NoError(err)
+	c.Equal("hi", command.Name)
+	c.Equal(map[string]string{"email": "dummy_email@dummy.com"}, message.Args)
+	c.Equal(botCommandsTopic, commandTopic(command))
+
+	message = &models.CallbackMessage{Message: models.Message{Text: "/hi", Chat: models.Chat{Type: "private"}}}
+
+	_, err = resolveCommand(ctx, telegramClient, message, "hi")
+	c.ErrorIs(err, commands.ErrMissingArgs)
+
+	message = &models.CallbackMessage{Message: models.Message{Text: "/hi dummy_email@dummy.com", Chat: models.Chat{Type: "group"}}}
+
+	_, err = resolveCommand(ctx, telegramClient, message, "hi")
+	c.ErrorIs(err, commands.ErrChatNotAllowed)
+
+	message = &models.CallbackMessage{Message: models.Message{Text: "/deployTerraformStaging -b master checks/core"}}
+
+	command, err = resolveCommand(ctx, telegramClient, message, "deployterraformstaging")
+	c.NoError(err)
+	c.Equal("deployterraformstaging", command.Name)
+	c.False(command.AllowsRole(""))
+	c.Equal(map[string]string{"project": "checks/core"}, message.Args)
+	c.Equal("master", message.Input.Flag("branch"))
+
+	message = &models.CallbackMessage{Message: models.Message{Text: "/deployTerraformStaging --env prod checks/core"}}
+
+	_, err = resolveCommand(ctx, telegramClient, message, "deployterraformstaging")
+	c.ErrorIs(err, commands.ErrParse)
+
+	message = &models.CallbackMessage{Message: models.Message{Text: "/unknown"}}
+
+	_, err = resolveCommand(ctx, telegramClient, message, "unknown")
+	c.ErrorIs(err, commands.ErrUnknownCommand)
+}
+
+func TestResolveCommandFromCallback(t *testing.T) {
+	c := require.New(t)
+
+	setMockedClient()
+
+	defer deactivateMockedClient()
+
+	ctx := context.Background()
+
+	telegramClient, err := botclient.New(ctx)
+	c.NoError(err)
+
+	command, err := resolveCommand(ctx, telegramClient, &models.CallbackMessage{Command: "approve"}, "approve")
+	c.NoError(err)
+	c.Equal("approve", command.Name)
+	c.Equal(botCommandsTopic, commandTopic(command))
+
+	command, err = resolveCommand(ctx, telegramClient, &models.CallbackMessage{Command: "start"}, "start")
+	c.NoError(err)
+	c.Equal(helpCommand, command.Name)
+}
+
+func TestApiGatewayHandlerUnknownCommand(t *testing.T) {
+	c := require.New(t)
+
+	setMockedClient()
+
+	defer deactivateMockedClient()
+
+	ctx := context.Background()
+	log := logger.New("test")
+	buf := bytes.NewBufferString("")
+	log.Output = buf
+
+	ctx = logger.Set(ctx, log)
+
+	response, err := apiGatewayHandler(ctx, newTestRequest(`{"message":{"text":"/unknown"}}`))
+	c.NoError(err)
+	c.Equal(http.StatusOK, response.StatusCode)
+
+	output := buf.String()
+	c.NotContains(output, "process_router_request_failed")
+}
@@ -0,0 +1,493 @@
+// Package router routes the Telegram updates received by the bot to the SNS topics of the workers
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"shared/app/bot/botclient"
+	"shared/app/bot/commands"
+	"shared/app/bot/models"
+	"shared/app/bot/storage/conversation"
+	"shared/shared/telegram"
+
+	"bitbucket.org/truora/scrap-services/logger"
+	"bitbucket.org/truora/scrap-services/shared/apigateway"
+	"bitbucket.org/truora/scrap-services/shared/cache"
+	"bitbucket.org/truora/scrap-services/shared/env"
+	"bitbucket.org/truora/scrap-services/shared/sns"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	awssns "github.com/aws/aws-sdk-go/service/sns"
+)
+
+const (
+	bettyBotUserName      = "@bettyabot"
+	commandPrefix         = "/"
+	defaultConversationID = "conversation"
+)
+
+var (
+	// ErrMessageEmpty when message and command is empty
+	ErrMessageEmpty = errors.New("message and command is empty")
+	// ErrMissingArgs when arguments are requiered but they are missing
+	ErrMissingArgs = errors.New("missing arguments to execute command")
+	// ErrInvalidCommand invalid command received
+	ErrInvalidCommand = errors.New("invalid command received")
+	// ErrInvalidCallback when the callback message data is invalid
+	ErrInvalidCallback = errors.New("invalid callback message")
+	// ErrUnknownTelegramEvent when the event received from Telegram is not supported
+	ErrUnknownTelegramEvent = errors.New("unknown telegram event")
+
+	defaultLogger = logger.New("bot")
+
+	assignNewWebhook = env.GetBool("ASSIGN_WEBHOOK", false)
+	botCommandsTopic = env.GetString("BOT_COMMANDS_TOPIC", "arn:aws:sns:us-east-1:031975712270:bot-commands-topic")
+
+	getConversationState = conversation.GetConversationState
+	newTelegramClient    = botclient.New
+	publishMessage       = sendSNS
+)
+
+type event struct {
+	UpdateID        int64                     `json:"update_id"`
+	Message         *models.Message           `json:"message"`
+	CallbackMessage *models.CallbackMessage   `json:"callback_query"`
+	EditedMessage   *models.Message           `json:"edited_message"`
+	ChannelPost     *models.Message           `json:"channel_post"`
+	InlineQuery     *models.InlineQuery       `json:"inline_query"`
+	MyChatMember    *models.ChatMemberUpdated `json:"my_chat_member"`
+}
+
+type request struct {
+	*events.APIGatewayProxyRequest
+	logger       *logger.Logger
+	startingTime time.Time
+	err          error
+	audit        *auditEntry
+}
+
+func (req *request) init(ctx context.Context) {
+	req.startingTime = time.Now()
+	req.logger = logger.Get(ctx)
+}
+
+func (req *request) finish(ctx context.Context) {
+	finishAudit(ctx, req.audit, req.err)
+	req.logger.LogLambdaTime(ctx, logger.APIGatewayObject(req.APIGatewayProxyRequest), req.startingTime, req.err, recover())
+}
+
+func (req *request) process(ctx context.Context) (err error) {
+	ctx, req.audit = startAudit(ctx)
+
+	event, telegramClient, err := req.createEventAndClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = claimUpdate(ctx, event.UpdateID)
+	if errors.Is(err, ErrDuplicatedUpdate) {
+		return nil
+	}
+
+	// the update was not routed, a new delivery of it has to be routed again
+	defer func() {
+		if err != nil {
+			releaseUpdate(ctx, event.UpdateID)
+		}
+	}()
+
+	eventType := event.eventType()
+	if eventType == models.EventDocument && isUploadCommand(ctx, event.Message) {
+		eventType = models.EventMessage
+	}
+
+	if eventType != "" && !isCommandEvent(eventType) {
+		return routeEvent(ctx, telegramClient, event, eventType)
+	}
+
+	message, err := getMessage(ctx, event)
+	ctx = withUserLanguage(ctx, &message.From)
+
+	if isExpiredCallback(err) {
+		telegramClient.SendText(ctx, replyChatID(message), translate(ctx, "router.button_expired", nil))
+
+		return nil
+	}
+
+	if errors.Is(err, ErrInvalidCallback) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if message.EventType == models.EventMessage {
+		// the command sent with a file is its caption
+		if message.Message.Text == "" && message.Message.Upload() != nil {
+			message.Message.Text = message.Message.Caption
+		}
+
+		if !isAddressedToBot(message.Message) {
+			return nil
+		}
+
+		message.Message.Text = stripMention(message.Message.Text)
+	}
+
+	command, err := getCommand(message)
+	if errors.Is(err, ErrMessageEmpty) || errors.Is(err, ErrInvalidCommand) || isWizardControl(command) {
+		if message.Data != "" {
+			return nil
+		}
+
+		command, err = setCacheCallbackData(ctx, telegramClient, message)
+		if err != nil {
+			logger.Get(ctx).Error(ctx, "set_conversation_data_failed", logger.OneMonth, []logger.Object{logger.ErrObject(err)})
+			telegramClient.SendText(ctx, replyChatID(message), translate(ctx, "router.conversation_failed", nil))
+
+			return err
+		}
+	}
+
+	if err != nil {
+		logger.Get(ctx).Error(ctx, "getting_command_failed", logger.OneMonth, []logger.Object{logger.ErrObject(err)})
+		telegramClient.SendText(ctx, replyChatID(message), translate(ctx, "router.missing_args", nil))
+
+		return err
+	}
+
+	if command == "" {
+		return nil
+	}
+
+	botCommand, err := resolveCommand(ctx, telegramClient, message, command)
+	startsWizard := errors.Is(err, errWizardRequired)
+
+	trackCommand(ctx, message, command)
+
+	if err != nil && !startsWizard {
+		setOutcome(ctx, models.OutcomeInvalid)
+		logger.Get(ctx).Warning(ctx, "command_rejected", logger.OneMonth, []logger.Object{logger.ErrObject(err)})
+
+		return nil
+	}
+
+	trackCommand(ctx, message, botCommand.Name)
+
+	err = authorize(ctx, telegramClient, message, botCommand)
+	if errors.Is(err, ErrUserNotRegistered) || errors.Is(err, ErrRoleNotAllowed) {
+		setOutcome(ctx, models.OutcomeDenied)
+
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if startsWizard {
+		setOutcome(ctx, models.OutcomeHandled)
+
+		return startWizard(ctx, telegramClient, message, botCommand)
+	}
+
+	err = attachUpload(ctx, telegramClient, message, botCommand)
+	if isUploadRejected(err) {
+		setOutcome(ctx, models.OutcomeInvalid)
+
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if handle, ok := routerCommands[botCommand.Name]; ok {
+		setOutcome(ctx, models.OutcomeHandled)
+
+		return handle(ctx, telegramClient, message)
+	}
+
+	return submitCommand(ctx, telegramClient, botCommand, message)
+}
+
+// publishCommand acknowledges the command and publishes it to its topic, the user is told when it fails
+func publishCommand(ctx context.Context, telegramClient *telegram.Client, botCommand *commands.Command, message *models.CallbackMessage) error {
+	acknowledge(ctx, telegramClient, message, botCommand.Name)
+
+	err := publish(ctx, commandTopic(botCommand), botCommand.Name, message)
+	if errors.Is(err, errPublishDelayed) {
+		setOutcome(ctx, models.OutcomeDelayed)
+		delayAcknowledgement(ctx, telegramClient, message, botCommand.Name)
+
+		return nil
+	}
+
+	if err != nil {
+		rejectAcknowledgement(ctx, telegramClient, message, botCommand.Name)
+
+		return err
+	}
+
+	setOutcome(ctx, models.OutcomePublished)
+
+	return nil
+}
+
+// publish sends the message to SNS. A failed message is parked in the dead-letter queue and errPublishDelayed
+// is returned, the error of a message that can not be parked releases the update so Telegram retries can
+// route it
+func publish(ctx context.Context, topic, attribute string, message *models.CallbackMessage) error {
+	publishErr := publishMessage(ctx, topic, attribute, message)
+	if publishErr == nil {
+		return nil
+	}
+
+	err := parkMessage(ctx, topic, attribute, message, publishErr)
+	if err == nil {
+		return errPublishDelayed
+	}
+
+	return fmt.Errorf("error sending SNS message %w", errors.Join(publishErr, err))
+}
+
+func (req *request) createEventAndClient(ctx context.Context) (*event, *telegram.Client, error) {
+	event := &event{}
+
+	err := json.Unmarshal([]byte(req.APIGatewayProxyRequest.Body), event)
+	if err != nil {
+		defaultLogger.Error(ctx, "unmarshal_failed", logger.OneMonth, []logger.Object{logger.ErrObject(err)})
+
+		return nil, nil, err
+	}
+
+	telegramClient, err := createTelegramClient(ctx, req.getWebhookURL())
+	if err != nil {
+		defaultLogger.Error(ctx, "create_telegram_client_failed", logger.OneMonth, []logger.Object{logger.ErrObject(err)})
+
+		return nil, nil, err
+	}
+
+	return event, telegramClient, nil
+}
+
+func createTelegramClient(ctx context.Context, endpoint string) (*telegram.Client, error) {
+	telegramClient, err := newTelegramClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if assignNewWebhook {
+		err = registerWebhook(ctx, telegramClient, endpoint)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return telegramClient, nil
+}
+
+func getMessage(ctx context.Context, event *event) (*models.CallbackMessage, error) {
+	if event.Message != nil {
+		return &models.CallbackMessage{Message: *event.Message, From: event.Message.From, EventType: models.EventMessage}, nil
+	}
+
+	if event.CallbackMessage == nil {
+		return &models.CallbackMessage{}, ErrUnknownTelegramEvent
+	}
+
+	event.CallbackMessage.EventType = models.EventCallbackQuery
+
+	err := decodeCallback(ctx, event.CallbackMessage)
+
+	return event.CallbackMessage, err
+}
+
+func getCommand(message *models.CallbackMessage) (string, error) {
+	if message.Command != "" {
+		return message.Command, nil
+	}
+
+	text := message.Message.Text
+	if text == "" {
+		return "", ErrMessageEmpty
+	}
+
+	commandAndArgs := strings.Split(text, " ")
+	if len(commandAndArgs) == 0 || commandAndArgs[0] == "" {
+		return "", ErrMissingArgs
+	}
+
+	command := commandAndArgs[0]
+	if !strings.HasPrefix(command, commandPrefix) {
+		return "", ErrInvalidCommand
+	}
+
+	cmdTrimmed := strings.TrimSuffix(strings.ToLower(command), bettyBotUserName)
+	cmd := strings.TrimPrefix(strings.ToLower(cmdTrimmed), commandPrefix)
+
+	return cmd, nil
+}
+
+func setCacheCallbackData(ctx context.Context, telegramClient *telegram.Client, message *models.CallbackMessage) (string, error) {
+	conversationState, err := getConversationState(ctx, message.Message)
+	if errors.Is(err, conversation.ErrConversationNotFound) {
+		return "", nil
+	}
+
+	if err != nil {
+		defaultLogger.Error(ctx, "get_conversation_state_failed", logger.OneMonth, []logger.Object{logger.ErrObject(err)})
+
+		return "", err
+	}
+
+	if conversationState.Wizard != nil {
+		return handleWizard(ctx, telegramClient, message, conversationState)
+	}
+
+	message.ID = defaultConversationID
+	message.Command = conversationState.Command
+	message.Data = conversationState.Data
+	message.AdditionalData = conversationState.AdditionalData
+
+	return conversationState.Command, nil
+}
+
+func sendSNS(ctx context.Context, topic, name string, message *models.CallbackMessage) error {
+	attributes := make(map[string]*awssns.MessageAttributeValue)
+
+	for key, value := range messageAttributes(name, message) {
+		attributes[key] = &awssns.MessageAttributeValue{
+			DataType:    aws.String("String"),
+			StringValue: aws.String(value),
+		}
+	}
+
+	input := awssns.PublishInput{
+		TopicArn:          aws.String(topic),
+		MessageAttributes: attributes,
+	}
+
+	return sns.PublishJSONWithInput(ctx, input, message, false)
+}
+
+func apiGatewayHandler(ctx context.Context, req *request) (*apigateway.Response, error) {
+	req.init(ctx)
+
+	defer req.finish(ctx)
+
+	err := req.verifySecretToken(ctx)
+	if err != nil {
+		req.err = err
+
+		req.logger.Error(ctx, "webhook_request_rejected", logger.OneMonth, []logger.Object{
+			logger.ErrObject(err),
+			logger.APIGatewayObject(req.APIGatewayProxyRequest),
+		})
+
+		return &apigateway.Response{StatusCode: http.StatusUnauthorized}, nil
+	}
+
+	err = req.process(ctx)
+	if err != nil && !errors.Is(err, ErrUnknownTelegramEvent) {
+		req.err = err
+
+		req.logger.Error(ctx, "process_router_request_failed", logger.OneMonth, []logger.Object{logger.ErrObject(req.err)})
+	}
+
+	return &apigateway.Response{StatusCode: http.StatusOK}, nil
+}
+
+// Init initializes the cache used by the router, it panics when the cache, the topics or the approvals config are invalid
+func Init(ctx context.Context) {
+	defaultLogger.Must(ctx, errCommandTopics, logger.OneDay)
+	defaultLogger.Must(ctx, errCommandApprovals, logger.OneDay)
+	defaultLogger.Must(ctx, cache.InitFromEnv(), logger.OneDay)
+}
+
+// Handler is the handler of the router Lambda behind API Gateway
+func Handler(ctx context.Context, proxyRequest *events.APIGatewayProxyRequest) (*apigateway.Response, error) {
+	return apiGatewayHandler(ctx, &request{APIGatewayProxyRequest: proxyRequest})
+}
+
+// ProcessUpdate routes a Telegram update received without API Gateway, e.g. by long polling, through
+// the same path of the webhook requests. The webhook secret token is not verified
+func ProcessUpdate(ctx context.Context, update []byte) error {
+	req := &request{APIGatewayProxyRequest: &events.APIGatewayProxyRequest{Body: string(update)}}
+	req.init(ctx)
+
+	defer req.finish(ctx)
+
+	req.err = req.process(ctx)
+
+	return req.err
+}
+
+// Dispatch routes a command sent on behalf of the user, e.g. a scheduled command, like the commands typed
+// by the user. The user is authorized again and the command must have all its arguments
+func Dispatch(ctx context.Context, message *models.CallbackMessage) error {
+	ctx, entry := startAudit(ctx)
+	ctx = withUserLanguage(ctx, &message.From)
+
+	err := dispatch(ctx, message)
+	finishAudit(ctx, entry, err)
+
+	return err
+}
+
+func dispatch(ctx context.Context, message *models.CallbackMessage) error {
+	telegramClient, err := newTelegramClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	name, err := getCommand(message)
+	if err != nil {
+		return err
+	}
+
+	trackCommand(ctx, message, name)
+
+	botCommand, err := resolveCommand(ctx, telegramClient, message, name)
+	if err != nil {
+		setOutcome(ctx, models.OutcomeInvalid)
+
+		return err
+	}
+
+	trackCommand(ctx, message, botCommand.Name)
+
+	if _, ok := routerCommands[botCommand.Name]; ok {
+		setOutcome(ctx, models.OutcomeInvalid)
+
+		return fmt.Errorf("%w: /%s can not be dispatched", ErrInvalidCommand, botCommand.Name)
+	}
+
+	err = authorize(ctx, telegramClient, message, botCommand)
+	if errors.Is(err, ErrUserNotRegistered) || errors.Is(err, ErrRoleNotAllowed) {
+		setOutcome(ctx, models.OutcomeDenied)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return submitCommand(ctx, telegramClient, botCommand, message)
+}
+
+// SetPublisher replaces how the routed messages are published, e.g. to print them instead of sending
+// them to SNS. A nil publisher sends them to SNS again
+func SetPublisher(publisher func(ctx context.Context, topic, attribute string, message *models.CallbackMessage) error) {
+	if publisher == nil {
+		publisher = sendSNS
+	}
+
+	publishMessage = publisher
+}
@@ -1,4 +1,4 @@
-package main
+package router
 
 import (
 	"bytes"
@@ -7,16 +7,19 @@ import (
 	"net/http"
 	"testing"
 
-	"bitbucket.org/truora/scrap-services/devops/bot/storage"
-	"bitbucket.org/truora/scrap-services/devops/bot/storage/conversation"
-	"bitbucket.org/truora/scrap-services/devops/models"
+	"shared/app/bot/botclient"
+	"shared/app/bot/models"
+	"shared/app/bot/storage"
+	"shared/app/bot/storage/conversation"
+
 	"bitbucket.org/truora/scrap-services/logger"
 	"github.com/stretchr/testify/require"
 	"shared/aws/apigateway"
 	"shared/aws/cache"
-	"shared/aws/client"
-	"shared/aws/secrets"
 	"shared/aws/sns"
+	"shared/shared/aws/secrets"
+	"shared/shared/client"
+	"shared/shared/telegram"
 )
 
 func BenchmarkHandler(b *testing.B) {
@@ -27,9 +30,7 @@ func BenchmarkHandler(b *testing.B) {
 	defer deactivateMockedClient()
 
 	for i := 0; i < b.N; i++ {
-		response, err := apiGatewayHandler(context.Background(), &request{
-			APIGatewayProxyRequest: &apigateway.Request{},
-		})
+		response, err := apiGatewayHandler(context.Background(), newTestRequest(""))
 		c.NoError(err)
 		c.Equal(http.StatusOK, response.StatusCode)
 	}
@@ -54,34 +55,47 @@ func TestGetMessage(t *testing.T) {
 	c := require.New(t)
 
 	cache.InitMock()
+	setMockedCallbackCodec(c)
 
-	err := cache.Add(context.Background(), "BOTdata_id", "data", 0)
-	c.NoError(err)
+	defer resetCallbackCodec()
+
+	data := encodeTestCallback(c, "command", "data", 1, 2)
 
-	message, err := getMessage(context.Background(), &event{CallbackMessage: &models.CallbackMessage{Data: "command, BOTdata_id"}})
+	message, err := getMessage(context.Background(), &event{CallbackMessage: newTestCallback(data, 1, 2)})
 	c.NoError(err)
+	c.Equal("command", message.Command)
 	c.Equal("data", message.Data)
+	c.Equal(models.EventCallbackQuery, message.EventType)
 }
 
 func TestGetMessageError(t *testing.T) {
 	c := require.New(t)
 
+	setMockedCallbackCodec(c)
+
+	defer resetCallbackCodec()
+
 	_, err := getMessage(context.Background(), &event{})
 	c.ErrorIs(err, ErrUnknownTelegramEvent)
 
 	_, err = getMessage(context.Background(), &event{CallbackMessage: &models.CallbackMessage{}})
 	c.ErrorIs(err, ErrInvalidCallback)
+
+	_, err = getMessage(context.Background(), &event{CallbackMessage: &models.CallbackMessage{Data: "command BOTdata_id"}})
+	c.ErrorIs(err, ErrInvalidCallback)
 }
 
 func TestCreateTelegramClientFailed(t *testing.T) {
 	c := require.New(t)
 
 	secrets.InitSecretsMock()
+	secrets.SetMockedSecret(webhookSecretName, "secret-token")
+	resetWebhookSecrets()
 	client.ActivateMock()
 
-	response, err := apiGatewayHandler(context.Background(), &request{
-		APIGatewayProxyRequest: &apigateway.Request{Body: `{"message":{"text":"/hi dummy_email@dummy.com"}}`},
-	})
+	defer deactivateMockedClient()
+
+	response, err := apiGatewayHandler(context.Background(), newTestRequest(`{"message":{"text":"/hi dummy_email@dummy.com"}}`))
 	c.NoError(err)
 	c.Equal(http.StatusOK, response.StatusCode)
 }
@@ -95,17 +109,13 @@ func TestApiGatewayHandlerUnauthorizedCommand(t *testing.T) {
 
 	sns.InitSNSMock()
 
-	response, err := apiGatewayHandler(context.Background(), &request{
-		APIGatewayProxyRequest: &apigateway.Request{Body: `{"message":{"text":"/hi dummy_email@dummy.com"}}`},
-	})
+	response, err := apiGatewayHandler(context.Background(), newTestRequest(`{"message":{"text":"/hi dummy_email@dummy.com"}}`))
 	c.NoError(err)
 	c.Equal(http.StatusOK, response.StatusCode)
 
 	assignNewWebhook = true
 
-	response, err = apiGatewayHandler(context.Background(), &request{
-		APIGatewayProxyRequest: &apigateway.Request{Body: `{"message":{"text":"/hi dummy_email@dummy.com"}}`},
-	})
+	response, err = apiGatewayHandler(context.Background(), newTestRequest(`{"message":{"text":"/hi dummy_email@dummy.com"}}`))
 	c.NoError(err)
 	c.Equal(http.StatusOK, response.StatusCode)
 }
@@ -124,18 +134,14 @@ func TestApiGatewayHandlerAuthorizedCommand(t *testing.T) {
 	c.NoError(err)
 
 	// when user is not verified
-	response, err := apiGatewayHandler(context.Background(), &request{
-		APIGatewayProxyRequest: &apigateway.Request{Body: `{"message":{"text":"/deployTerraformStaging -b master checks/core","id":"000"}}`},
-	})
+	response, err := apiGatewayHandler(context.Background(), newTestRequest(`{"message":{"text":"/deployTerraformStaging -b master checks/core","id":"000"}}`))
 	c.NoError(err)
 	c.Equal(http.StatusOK, response.StatusCode)
 
 	err = storage.VerifyUserEmail(context.Background(), "dummy_email@dummy.com")
 	c.NoError(err)
 
-	response, err = apiGatewayHandler(context.Background(), &request{
-		APIGatewayProxyRequest: &apigateway.Request{Body: `{"message":{"text":"/deployTerraformStaging -b master checks/core","id":"000"}}`},
-	})
+	response, err = apiGatewayHandler(context.Background(), newTestRequest(`{"message":{"text":"/deployTerraformStaging -b master checks/core","id":"000"}}`))
 	c.NoError(err)
 	c.Equal(http.StatusOK, response.StatusCode)
 
@@ -143,9 +149,7 @@ func TestApiGatewayHandlerAuthorizedCommand(t *testing.T) {
 
 	defer storage.DeactiveForceFailure()
 
-	response, err = apiGatewayHandler(context.Background(), &request{
-		APIGatewayProxyRequest: &apigateway.Request{Body: `{"message":{"text":"/deployTerraformStaging -b master checks/core","id":"000"}}`},
-	})
+	response, err = apiGatewayHandler(context.Background(), newTestRequest(`{"message":{"text":"/deployTerraformStaging -b master checks/core","id":"000"}}`))
 	c.NoError(err)
 	c.Equal(http.StatusOK, response.StatusCode)
 }
@@ -157,9 +161,7 @@ func TestApiGatewayHandlerErrorInit(t *testing.T) {
 
 	defer deactivateMockedClient()
 
-	response, err := apiGatewayHandler(context.Background(), &request{
-		APIGatewayProxyRequest: &apigateway.Request{},
-	})
+	response, err := apiGatewayHandler(context.Background(), newTestRequest(""))
 	c.NoError(err)
 	c.Equal(http.StatusOK, response.StatusCode)
 }
@@ -171,9 +173,7 @@ func TestApiGatewayHandlerErrorUnmarshall(t *testing.T) {
 
 	defer deactivateMockedClient()
 
-	response, err := apiGatewayHandler(context.Background(), &request{
-		APIGatewayProxyRequest: &apigateway.Request{Body: "{-}"},
-	})
+	response, err := apiGatewayHandler(context.Background(), newTestRequest("{-}"))
 	c.NoError(err)
 	c.Equal(http.StatusOK, response.StatusCode)
 }
@@ -194,9 +194,7 @@ func TestApiGatewayHandlerErrorGettingCommands(t *testing.T) {
 
 	ctx = logger.Set(ctx, log)
 
-	response, err := apiGatewayHandler(ctx, &request{
-		APIGatewayProxyRequest: &apigateway.Request{Body: `{"message":null}`},
-	})
+	response, err := apiGatewayHandler(ctx, newTestRequest(`{"message":null}`))
 	c.NoError(err)
 	c.Equal(http.StatusOK, response.StatusCode)
 
@@ -205,9 +203,7 @@ func TestApiGatewayHandlerErrorGettingCommands(t *testing.T) {
 
 	buf.Reset()
 
-	response, err = apiGatewayHandler(ctx, &request{
-		APIGatewayProxyRequest: &apigateway.Request{Body: `{"message":{"text":""}}`},
-	})
+	response, err = apiGatewayHandler(ctx, newTestRequest(`{"message":{"text":""}}`))
 	c.NoError(err)
 	c.Equal(http.StatusOK, response.StatusCode)
 
@@ -216,9 +212,7 @@ func TestApiGatewayHandlerErrorGettingCommands(t *testing.T) {
 
 	buf.Reset()
 
-	response, err = apiGatewayHandler(ctx, &request{
-		APIGatewayProxyRequest: &apigateway.Request{Body: `{"message":{"text":"  "}}`},
-	})
+	response, err = apiGatewayHandler(ctx, newTestRequest(`{"message":{"text":"  "}}`))
 	c.NoError(err)
 	c.Equal(http.StatusOK, response.StatusCode)
 
@@ -255,9 +249,7 @@ func TestApiGatewayHandlerUseCachedConversationData(t *testing.T) {
 
 	ctx = logger.Set(ctx, log)
 
-	response, err := apiGatewayHandler(ctx, &request{
-		APIGatewayProxyRequest: &apigateway.Request{Body: `{"message":{"text":""}}`},
-	})
+	response, err := apiGatewayHandler(ctx, newTestRequest(`{"message":{"text":""}}`))
 	c.NoError(err)
 	c.Equal(http.StatusOK, response.StatusCode)
 
@@ -276,9 +268,7 @@ func TestApiGatewayHandlerUseCachedConversationData(t *testing.T) {
 
 	buf.Reset()
 
-	response, err = apiGatewayHandler(ctx, &request{
-		APIGatewayProxyRequest: &apigateway.Request{Body: `{"message":{"text":""}}`},
-	})
+	response, err = apiGatewayHandler(ctx, newTestRequest(`{"message":{"text":""}}`))
 	c.NoError(err)
 	c.Equal(http.StatusOK, response.StatusCode)
 
@@ -289,16 +279,119 @@ func TestApiGatewayHandlerUseCachedConversationData(t *testing.T) {
 
 func setMockedClient() {
 	secrets.InitSecretsMock()
-	secrets.SetMockedSecret("betty-bot-token", "token")
+	secrets.SetMockedSecret(botclient.TokenSecretName, "token")
+	secrets.SetMockedSecret(webhookSecretName, "secret-token")
+	resetWebhookSecrets()
 
 	client.ActivateMock()
 
-	client.AddMockedResponse(http.MethodPost, "https://api.telegram.org/bottoken/getMe", http.StatusOK, `{"ok": true}`)
 	client.AddMockedResponse(http.MethodPost, "https://api.telegram.org/bottoken/setWebhook", http.StatusOK, `{"ok": true}`)
 	client.AddMockedResponse(http.MethodPost, "https://api.telegram.org/bottoken/sendMessage", http.StatusOK, `{"ok": true}`)
 }
 
+// setFakeTelegram makes the router use a fake Bot API, the returned function restores the real one
+func setFakeTelegram() (*telegram.Fake, func()) {
+	secrets.InitSecretsMock()
+	secrets.SetMockedSecret(webhookSecretName, "secret-token")
+	resetWebhookSecrets()
+
+	fake := telegram.NewFake("")
+
+	newTelegramClient = func(ctx context.Context) (*telegram.Client, error) {
+		return fake.Client(), nil
+	}
+
+	return fake, func() {
+		newTelegramClient = botclient.New
+		fake.Close()
+		secrets.DeactivateMock()
+		resetWebhookSecrets()
+	}
+}
+
 func deactivateMockedClient() {
 	client.DeactivateMock()
 	secrets.DeactivateMock()
+	resetWebhookSecrets()
+}
+
+func newTestRequest(body string) *request {
+	return &request{
+		APIGatewayProxyRequest: &apigateway.Request{
+			Body:    body,
+			Headers: map[string]string{telegramSecretTokenHeader: "secret-token"},
+		},
+	}
+}
+
+func TestProcessUpdate(t *testing.T) {
+	c := require.New(t)
+
+	setMockedClient()
+
+	defer deactivateMockedClient()
+
+	cache.InitMock()
+
+	published := []string{}
+
+	SetPublisher(func(ctx context.Context, topic, attribute string, message *models.CallbackMessage) error {
+		published = append(published, attribute)
+
+		return nil
+	})
+
+	defer SetPublisher(nil)
+
+	err := ProcessUpdate(context.Background(), []byte(`{"update_id":1,"message":{"text":"/hi dummy_email@dummy.com","chat":{"id":1,"type":"private"}}}`))
+	c.NoError(err)
+	c.Equal([]string{registerCommand}, published)
+
+	err = ProcessUpdate(context.Background(), []byte(`{`))
+	c.Error(err)
+}
+
+func TestDispatch(t *testing.T) {
+	c := require.New(t)
+
+	saveVerifiedUser(c, 10, "dummy_email@dummy.com")
+
+	fake, restore := setFakeTelegram()
+
+	defer restore()
+
+	published := []*models.CallbackMessage{}
+
+	SetPublisher(func(ctx context.Context, topic, attribute string, message *models.CallbackMessage) error {
+		published = append(published, message)
+
+		return nil
+	})
+
+	defer SetPublisher(nil)
+
+	ctx := context.Background()
+
+	newMessage := func(userID int64, text string) *models.CallbackMessage {
+		return &models.CallbackMessage{
+			EventType: models.EventMessage,
+			From:      models.From{ID: userID},
+			Message:   models.Message{Text: text, Chat: models.Chat{ID: userID, Type: "private"}},
+		}
+	}
+
+	err := Dispatch(ctx, newMessage(10, "/deployterraformstaging checks/core"))
+	c.NoError(err)
+	c.Len(published, 1)
+	c.Equal("dummy_email@dummy.com", published[0].From.Email)
+	c.Equal("checks/core", published[0].Args["project"])
+	c.NotEmpty(published[0].CorrelationID)
+	c.Contains(fake.SentTexts()[0], "Accepted /deployterraformstaging")
+
+	err = Dispatch(ctx, newMessage(10, "/"+helpCommand))
+	c.ErrorIs(err, ErrInvalidCommand)
+
+	err = Dispatch(ctx, newMessage(20, "/deployterraformstaging checks/core"))
+	c.ErrorIs(err, ErrUserNotRegistered)
+	c.Len(published, 1)
 }
@@ -0,0 +1,210 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"shared/app/bot/i18n"
+	"shared/app/bot/models"
+	"shared/app/bot/storage/schedule"
+	"shared/shared/telegram"
+)
+
+const (
+	scheduleCommand   = "schedule"
+	schedulesCommand  = "schedules"
+	unscheduleCommand = "unschedule"
+
+	everyFlag = "every"
+	// minScheduleDelay is the precision of the scheduler ticks
+	minScheduleDelay = time.Minute
+	maxScheduleDelay = 30 * 24 * time.Hour
+)
+
+// errInvalidDelay when the delay or interval of a scheduled command is not a valid duration
+var errInvalidDelay = i18n.NewError("schedule.invalid_duration", i18n.Vars{"min": formatDuration(minScheduleDelay), "max": formatDuration(maxScheduleDelay)})
+
+// scheduleMessage validates the nested command like it was sent now and stores it to be routed by the scheduler
+func scheduleMessage(ctx context.Context, telegramClient *telegram.Client, message *models.CallbackMessage) error {
+	delay, err := parseScheduleDuration(message.Args["delay"])
+	if err != nil {
+		telegramClient.SendText(ctx, replyChatID(message), translate(ctx, "schedule.invalid_delay", i18n.Vars{"delay": strconv.Quote(message.Args["delay"]), "reason": i18n.ErrorText(ctx, err)}))
+
+		return nil
+	}
+
+	every := time.Duration(0)
+
+	if message.Input.HasFlag(everyFlag) {
+		every, err = parseScheduleDuration(message.Input.Flag(everyFlag))
+		if err != nil {
+			telegramClient.SendText(ctx, replyChatID(message), translate(ctx, "schedule.invalid_interval", i18n.Vars{"interval": strconv.Quote(message.Input.Flag(everyFlag)), "reason": i18n.ErrorText(ctx, err)}))
+
+			return nil
+		}
+	}
+
+	scheduled := &models.CallbackMessage{
+		EventType: models.EventMessage,
+		From:      message.From,
+		Message: models.Message{
+			Text: message.Args["command"],
+			From: message.Message.From,
+			Chat: message.Message.Chat,
+		},
+	}
+
+	if !isSchedulable(ctx, telegramClient, scheduled) {
+		return nil
+	}
+
+	id, err := schedule.NewID()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+
+	// the command is parsed again when it fires
+	scheduled.Input = nil
+	scheduled.Args = nil
+
+	err = schedule.Add(ctx, &models.ScheduledCommand{
+		ID:        id,
+		Text:      scheduled.Message.Text,
+		Every:     every,
+		FireAt:    now.Add(delay),
+		CreatedAt: now,
+		Message:   *scheduled,
+	})
+	if errors.Is(err, schedule.ErrTooManySchedules) {
+		telegramClient.SendText(ctx, replyChatID(message), translate(ctx, "schedule.too_many", i18n.Vars{"command": unscheduleCommand}))
+
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	vars := i18n.Vars{"text": scheduled.Message.Text, "delay": formatDuration(delay), "id": id, "command": unscheduleCommand}
+
+	key := "schedule.scheduled"
+	if every > 0 {
+		key = "schedule.scheduled_every"
+		vars["every"] = formatDuration(every)
+	}
+
+	telegramClient.SendText(ctx, replyChatID(message), translate(ctx, key, vars))
+
+	return nil
+}
+
+// isSchedulable returns true if the command exists, has all its arguments and the user can run it,
+// the user is told what is wrong otherwise
+func isSchedulable(ctx context.Context, telegramClient *telegram.Client, scheduled *models.CallbackMessage) bool {
+	name, err := getCommand(scheduled)
+	if err != nil {
+		telegramClient.SendText(ctx, replyChatID(scheduled), translate(ctx, "schedule.missing_slash", nil))
+
+		return false
+	}
+
+	botCommand, err := resolveCommand(ctx, telegramClient, scheduled, name)
+	if errors.Is(err, errWizardRequired) {
+		telegramClient.SendText(ctx, replyChatID(scheduled), translate(ctx, "schedule.missing_args", i18n.Vars{"usage": botCommand.Usage()}))
+
+		return false
+	}
+
+	if err != nil {
+		return false
+	}
+
+	if _, ok := routerCommands[botCommand.Name]; ok {
+		telegramClient.SendText(ctx, replyChatID(scheduled), translate(ctx, "schedule.not_schedulable", i18n.Vars{"command": botCommand.Name}))
+
+		return false
+	}
+
+	return authorize(ctx, telegramClient, scheduled, botCommand) == nil
+}
+
+func listSchedules(ctx context.Context, telegramClient *telegram.Client, message *models.CallbackMessage) error {
+	scheduled, err := schedule.ListByUser(ctx, message.From.ID)
+	if err != nil {
+		return err
+	}
+
+	if len(scheduled) == 0 {
+		telegramClient.SendText(ctx, replyChatID(message), translate(ctx, "schedule.empty", i18n.Vars{"command": scheduleCommand}))
+
+		return nil
+	}
+
+	now := time.Now()
+	lines := []string{translate(ctx, "schedule.list_header", nil)}
+
+	for _, command := range scheduled {
+		vars := i18n.Vars{"id": command.ID, "delay": formatDuration(command.FireAt.Sub(now)), "text": command.Text}
+
+		key := "schedule.list_item"
+		if command.IsRecurring() {
+			key = "schedule.list_item_every"
+			vars["every"] = formatDuration(command.Every)
+		}
+
+		lines = append(lines, translate(ctx, key, vars))
+	}
+
+	telegramClient.SendText(ctx, replyChatID(message), strings.Join(lines, "\n"))
+
+	return nil
+}
+
+func unschedule(ctx context.Context, telegramClient *telegram.Client, message *models.CallbackMessage) error {
+	id := message.Args["id"]
+
+	err := schedule.Delete(ctx, message.From.ID, id)
+	if errors.Is(err, schedule.ErrScheduleNotFound) {
+		telegramClient.SendText(ctx, replyChatID(message), translate(ctx, "schedule.not_found", i18n.Vars{"id": id, "command": schedulesCommand}))
+
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	telegramClient.SendText(ctx, replyChatID(message), translate(ctx, "schedule.canceled", i18n.Vars{"id": id}))
+
+	return nil
+}
+
+func parseScheduleDuration(value string) (time.Duration, error) {
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration < minScheduleDelay || duration > maxScheduleDelay {
+		return 0, errInvalidDelay
+	}
+
+	return duration, nil
+}
+
+// formatDuration formats the duration rounded to minutes without the zero units, e.g. 2h instead of 2h0m0s
+func formatDuration(duration time.Duration) string {
+	formatted := duration.Round(time.Minute).String()
+	formatted = strings.TrimSuffix(formatted, "0s")
+
+	if strings.HasSuffix(formatted, "h0m") {
+		formatted = strings.TrimSuffix(formatted, "0m")
+	}
+
+	if formatted == "" || formatted == "0" {
+		return "0m"
+	}
+
+	return formatted
+}
@@ -0,0 +1,122 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"shared/app/bot/models"
+	"shared/app/bot/storage"
+	"shared/app/bot/storage/schedule"
+
+	"bitbucket.org/truora/scrap-services/shared/cache"
+	"github.com/stretchr/testify/require"
+)
+
+func newScheduleUpdate(updateID int64, text string) []byte {
+	return []byte(fmt.Sprintf(`{"update_id":%d,"message":{"message_id":1,"text":%q,"from":{"id":10},"chat":{"id":10,"type":"private"}}}`, updateID, text))
+}
+
+func saveVerifiedUser(c *require.Assertions, id int64, email string) {
+	storage.InitDynamoMock()
+
+	err := storage.SaveUser(context.Background(), models.From{Email: email, ID: id})
+	c.NoError(err)
+
+	err = storage.VerifyUserEmail(context.Background(), email)
+	c.NoError(err)
+}
+
+func TestSchedule(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+	saveVerifiedUser(c, 10, "dummy_email@dummy.com")
+
+	fake, restore := setFakeTelegram()
+
+	defer restore()
+
+	ctx := context.Background()
+
+	err := ProcessUpdate(ctx, newScheduleUpdate(1, "/schedule --every 24h 30m /deployterraformstaging checks/core -b master"))
+	c.NoError(err)
+
+	texts := fake.SentTexts()
+	c.Len(texts, 1)
+	c.Contains(texts[0], "Scheduled /deployterraformstaging checks/core -b master in 30m and every 24h after that")
+
+	scheduled, err := schedule.ListByUser(ctx, 10)
+	c.NoError(err)
+	c.Len(scheduled, 1)
+	c.Equal(24*time.Hour, scheduled[0].Every)
+	c.Equal(int64(10), scheduled[0].Message.Message.Chat.ID)
+	c.Nil(scheduled[0].Message.Input)
+
+	err = ProcessUpdate(ctx, newScheduleUpdate(2, "/schedules"))
+	c.NoError(err)
+	c.Contains(fake.SentTexts()[1], scheduled[0].ID+" in 30m: /deployterraformstaging checks/core -b master (every 24h)")
+
+	err = ProcessUpdate(ctx, newScheduleUpdate(3, "/unschedule "+scheduled[0].ID))
+	c.NoError(err)
+	c.Contains(fake.SentTexts()[2], "Canceled the scheduled command "+scheduled[0].ID)
+
+	err = ProcessUpdate(ctx, newScheduleUpdate(4, "/unschedule "+scheduled[0].ID))
+	c.NoError(err)
+	c.Contains(fake.SentTexts()[3], "You have no scheduled command with id")
+
+	err = ProcessUpdate(ctx, newScheduleUpdate(5, "/schedules"))
+	c.NoError(err)
+	c.Contains(fake.SentTexts()[4], "You have no scheduled commands")
+}
+
+func TestScheduleRejected(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+	saveVerifiedUser(c, 10, "dummy_email@dummy.com")
+
+	fake, restore := setFakeTelegram()
+
+	defer restore()
+
+	ctx := context.Background()
+
+	updates := map[string]string{
+		"/schedule soon /deployterraformstaging checks/core":   "Invalid delay \"soon\"",
+		"/schedule 10s /deployterraformstaging checks/core":    "Invalid delay \"10s\"",
+		"/schedule 30m -e 1s /deployterraformstaging core":     "Invalid interval \"1s\"",
+		"/schedule 30m /deployterraformstaging":                "Add the arguments to schedule it",
+		"/schedule 30m /schedules":                             "/schedules can not be scheduled",
+		"/schedule 30m /unknown":                               "Unknown command /unknown",
+		"/schedule 30m deployterraformstaging checks/core now": "too many arguments",
+	}
+
+	updateID := int64(1)
+
+	for text, expected := range updates {
+		err := ProcessUpdate(ctx, newScheduleUpdate(updateID, text))
+		c.NoError(err, text)
+
+		texts := fake.SentTexts()
+		c.Contains(texts[len(texts)-1], expected, text)
+
+		updateID++
+	}
+
+	scheduled, err := schedule.ListByUser(ctx, 10)
+	c.NoError(err)
+	c.Empty(scheduled)
+}
+
+func TestFormatDuration(t *testing.T) {
+	c := require.New(t)
+
+	c.Equal("30m", formatDuration(30*time.Minute))
+	c.Equal("2h", formatDuration(2*time.Hour))
+	c.Equal("1h30m", formatDuration(90*time.Minute+10*time.Second))
+	c.Equal("0m", formatDuration(0))
+	c.True(strings.HasPrefix(errInvalidDelay.Error(), "use a duration between 1m and 720h"))
+}
@@ -0,0 +1,117 @@
+package router
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"shared/app/bot/commands"
+	"shared/app/bot/models"
+	"shared/shared/aws/sns"
+
+	"bitbucket.org/truora/scrap-services/shared/env"
+)
+
+// SNS message attributes used by the subscription filter policies
+const (
+	commandAttribute   = "command"
+	chatTypeAttribute  = "chat_type"
+	userRoleAttribute  = "user_role"
+	eventTypeAttribute = "event_type"
+)
+
+var (
+	// commandTopics maps commands and event types to the topic where they are published, it overrides
+	// the topic declared in the registry, e.g. COMMAND_TOPICS={"deployterraformstaging":"arn:aws:sns:..."}
+	commandTopics, errCommandTopics = parseCommandTopics(env.GetString("COMMAND_TOPICS", ""))
+
+	// legacyAttributes also sends the command name as attribute key, the subscriptions that still filter on
+	// it need SNS_LEGACY_ATTRIBUTES=true until their filter policy is replaced by the one of FilterPolicies
+	legacyAttributes = env.GetBool("SNS_LEGACY_ATTRIBUTES", false)
+)
+
+func parseCommandTopics(config string) (map[string]string, error) {
+	topics := map[string]string{}
+
+	if config == "" {
+		return topics, nil
+	}
+
+	err := json.Unmarshal([]byte(config), &topics)
+	if err != nil {
+		return map[string]string{}, fmt.Errorf("invalid COMMAND_TOPICS: %w", err)
+	}
+
+	return topics, nil
+}
+
+func commandTopic(command *commands.Command) string {
+	if topic, ok := commandTopics[command.Name]; ok {
+		return topic
+	}
+
+	if command.Topic != "" {
+		return command.Topic
+	}
+
+	return botCommandsTopic
+}
+
+func eventTopic(eventType models.EventType) string {
+	if topic, ok := commandTopics[string(eventType)]; ok {
+		return topic
+	}
+
+	return botCommandsTopic
+}
+
+// messageAttributes returns the attributes of the routed message, name is the command or the event type
+func messageAttributes(name string, message *models.CallbackMessage) map[string]string {
+	attributes := map[string]string{
+		eventTypeAttribute: string(message.EventType),
+		chatTypeAttribute:  message.Message.Chat.Type,
+		userRoleAttribute:  message.From.UserRole,
+	}
+
+	if message.EventType == "" || isCommandEvent(message.EventType) {
+		attributes[commandAttribute] = name
+	}
+
+	if legacyAttributes {
+		attributes[name] = name
+	}
+
+	for key, value := range attributes {
+		if value == "" {
+			delete(attributes, key)
+		}
+	}
+
+	return attributes
+}
+
+// CommandTopics returns the topic of every registered command published to SNS
+func CommandTopics() map[string]string {
+	topics := map[string]string{}
+
+	for _, command := range botCommands.Commands() {
+		if isRouterCommand(command.Name) {
+			continue
+		}
+
+		topics[command.Name] = commandTopic(command)
+	}
+
+	return topics
+}
+
+// isRouterCommand returns true if the command is answered by the router so it is never published
+func isRouterCommand(name string) bool {
+	_, ok := routerCommands[name]
+
+	return ok
+}
+
+// FilterPolicies returns the subscription filter policy of each topic matching the commands published to it
+func FilterPolicies() map[string]sns.FilterPolicy {
+	return sns.FilterPolicies(commandAttribute, CommandTopics())
+}
@@ -0,0 +1,93 @@
+package router
+
+import (
+	"testing"
+
+	"shared/app/bot/commands"
+	"shared/app/bot/models"
+	"shared/shared/aws/sns"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCommandTopics(t *testing.T) {
+	c := require.New(t)
+
+	topics, err := parseCommandTopics("")
+	c.NoError(err)
+	c.Empty(topics)
+
+	topics, err = parseCommandTopics(`{"deployterraformstaging":"arn:aws:sns:us-east-1:123:deploys"}`)
+	c.NoError(err)
+	c.Equal("arn:aws:sns:us-east-1:123:deploys", topics["deployterraformstaging"])
+
+	_, err = parseCommandTopics(`{"deployterraformstaging":`)
+	c.Error(err)
+}
+
+func TestCommandTopic(t *testing.T) {
+	c := require.New(t)
+
+	oldCommandTopics := commandTopics
+	commandTopics = map[string]string{
+		deployTerraformStagingCommand:   "arn:aws:sns:us-east-1:123:deploys",
+		string(models.EventInlineQuery): "arn:aws:sns:us-east-1:123:inline",
+	}
+
+	defer func() {
+		commandTopics = oldCommandTopics
+	}()
+
+	c.Equal("arn:aws:sns:us-east-1:123:deploys", commandTopic(&commands.Command{Name: deployTerraformStagingCommand, Topic: "arn:aws:sns:us-east-1:123:other"}))
+	c.Equal("arn:aws:sns:us-east-1:123:other", commandTopic(&commands.Command{Name: "status", Topic: "arn:aws:sns:us-east-1:123:other"}))
+	c.Equal(botCommandsTopic, commandTopic(&commands.Command{Name: "status"}))
+	c.Equal("arn:aws:sns:us-east-1:123:inline", eventTopic(models.EventInlineQuery))
+	c.Equal(botCommandsTopic, eventTopic(models.EventChannelPost))
+
+	policies := FilterPolicies()
+	c.Equal(sns.FilterPolicy{commandAttribute: {deployTerraformStagingCommand}}, policies["arn:aws:sns:us-east-1:123:deploys"])
+	// the commands answered by the router are not published so they are not in the policies
+	c.Equal(sns.FilterPolicy{commandAttribute: {deployTerraformProductionCommand, registerCommand}}, policies[botCommandsTopic])
+	c.NotContains(CommandTopics(), helpCommand)
+}
+
+func TestMessageAttributes(t *testing.T) {
+	c := require.New(t)
+
+	message := &models.CallbackMessage{
+		EventType: models.EventMessage,
+		From:      models.From{UserRole: models.RoleAdmin},
+		Message:   models.Message{Chat: models.Chat{Type: "private"}},
+	}
+
+	c.Equal(map[string]string{
+		commandAttribute:   deployTerraformStagingCommand,
+		eventTypeAttribute: "message",
+		chatTypeAttribute:  "private",
+		userRoleAttribute:  models.RoleAdmin,
+	}, messageAttributes(deployTerraformStagingCommand, message))
+
+	message = &models.CallbackMessage{EventType: models.EventInlineQuery}
+
+	c.Equal(map[string]string{eventTypeAttribute: "inline_query"}, messageAttributes("inline_query", message))
+
+	legacyAttributes = true
+
+	defer func() {
+		legacyAttributes = false
+	}()
+
+	message = &models.CallbackMessage{
+		EventType: models.EventMessage,
+		From:      models.From{UserRole: models.RoleAdmin},
+		Message:   models.Message{Chat: models.Chat{Type: "private"}},
+	}
+
+	c.Equal(map[string]string{
+		commandAttribute:              deployTerraformStagingCommand,
+		eventTypeAttribute:            "message",
+		chatTypeAttribute:             "private",
+		userRoleAttribute:             models.RoleAdmin,
+		deployTerraformStagingCommand: deployTerraformStagingCommand,
+	}, messageAttributes(deployTerraformStagingCommand, message))
+}
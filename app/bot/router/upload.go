@@ -0,0 +1,196 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+
+	"shared/app/bot/commands"
+	"shared/app/bot/i18n"
+	"shared/app/bot/models"
+	"shared/shared/telegram"
+
+	"bitbucket.org/truora/scrap-services/logger"
+	"bitbucket.org/truora/scrap-services/shared/env"
+)
+
+const anyMimeType = "*/*"
+
+var (
+	// maxUploadSize is the limit of the commands that do not set one, getFile can not download larger files
+	maxUploadSize = env.GetInt64("BOT_MAX_UPLOAD_SIZE_BYTES", 20<<20)
+	// uploadMimeTypes are the types accepted by the commands that do not set them
+	uploadMimeTypes = strings.Split(env.GetString("BOT_UPLOAD_MIME_TYPES", "image/*,application/pdf,text/plain,text/csv"), ",")
+
+	// errUploadNotAccepted when a file is sent with a command that does not accept files
+	errUploadNotAccepted = i18n.NewError("upload.not_accepted", nil)
+	// errUploadRequired when a command that needs a file is sent without one
+	errUploadRequired = i18n.NewError("upload.required", nil)
+	// errUploadTooLarge when the file is larger than the limit of the command
+	errUploadTooLarge = i18n.NewError("upload.too_large", nil)
+	// errUploadType when the type of the file is not accepted by the command
+	errUploadType = i18n.NewError("upload.type_not_allowed", nil)
+	// errUploadFailed when getFile can not resolve the file
+	errUploadFailed = i18n.NewError("upload.failed", nil)
+)
+
+// isUploadCommand returns true if the document is sent with a command as caption or answers a conversation,
+// the other documents are published as document events
+func isUploadCommand(ctx context.Context, message *models.Message) bool {
+	if strings.HasPrefix(stripMention(message.Caption), commandPrefix) {
+		return true
+	}
+
+	_, err := getConversationState(ctx, *message)
+
+	return err == nil
+}
+
+// isUploadRejected returns true if the error is why a file was rejected, the user was already told
+func isUploadRejected(err error) bool {
+	return errors.Is(err, errUploadNotAccepted) || errors.Is(err, errUploadRequired) ||
+		errors.Is(err, errUploadTooLarge) || errors.Is(err, errUploadType) || errors.Is(err, errUploadFailed)
+}
+
+// attachUpload checks the file sent with the command and adds its reference to the message so workers can
+// download it, the user is told when the file is rejected
+func attachUpload(ctx context.Context, telegramClient *telegram.Client, message *models.CallbackMessage, command *commands.Command) error {
+	// the files answered in a wizard were already checked
+	if len(message.Uploads) > 0 {
+		return nil
+	}
+
+	upload := message.Message.Upload()
+	if upload == nil {
+		if command.Uploads != nil && command.Uploads.Required {
+			telegramClient.SendText(ctx, replyChatID(message), translate(ctx, "upload.required", i18n.Vars{"command": command.Name}))
+
+			return errUploadRequired
+		}
+
+		return nil
+	}
+
+	resolved, err := resolveUpload(ctx, telegramClient, message, command, upload)
+	if err != nil {
+		return err
+	}
+
+	message.Uploads = append(message.Uploads, resolved)
+
+	return nil
+}
+
+// resolveUpload checks the file against the limits of the command and gets its path with getFile, the size
+// is checked again with the one returned by getFile because it is optional in the updates
+func resolveUpload(ctx context.Context, telegramClient *telegram.Client, message *models.CallbackMessage, command *commands.Command, upload *models.Upload) (*models.Upload, error) {
+	err := checkUpload(command, upload)
+	if err != nil {
+		return nil, rejectUpload(ctx, telegramClient, message, command, upload, err)
+	}
+
+	file, err := telegramClient.GetFile(ctx, upload.FileID)
+	if err != nil {
+		logger.Get(ctx).Error(ctx, "get_file_failed", logger.OneMonth, []logger.Object{
+			logger.ErrObject(err),
+			logger.MapObject("upload", map[string]interface{}{"s_command": command.Name, "s_file_id": upload.FileID}),
+		})
+		telegramClient.SendText(ctx, replyChatID(message), i18n.ErrorText(ctx, errUploadFailed))
+
+		return nil, errUploadFailed
+	}
+
+	upload.FilePath = file.FilePath
+
+	if file.FileUniqueID != "" {
+		upload.FileUniqueID = file.FileUniqueID
+	}
+
+	if file.FileSize > 0 {
+		upload.FileSize = file.FileSize
+	}
+
+	err = checkUpload(command, upload)
+	if err != nil {
+		return nil, rejectUpload(ctx, telegramClient, message, command, upload, err)
+	}
+
+	return upload, nil
+}
+
+// rejectUpload logs why the file was rejected and tells the user
+func rejectUpload(ctx context.Context, telegramClient *telegram.Client, message *models.CallbackMessage, command *commands.Command, upload *models.Upload, err error) error {
+	logger.Get(ctx).Warning(ctx, "upload_rejected", logger.OneMonth, []logger.Object{
+		logger.ErrObject(err),
+		logger.MapObject("upload", map[string]interface{}{
+			"s_command":     command.Name,
+			"s_mime_type":   upload.MimeType,
+			"i_file_size":   upload.FileSize,
+			"i_telegram_id": message.From.ID,
+		}),
+	})
+
+	telegramClient.SendText(ctx, replyChatID(message), i18n.ErrorText(ctx, err))
+
+	return err
+}
+
+// checkUpload returns why the file is not accepted by the command, nil when it is accepted
+func checkUpload(command *commands.Command, upload *models.Upload) error {
+	limits := command.Uploads
+	if limits == nil {
+		return i18n.NewError(errUploadNotAccepted.Key, i18n.Vars{"command": command.Name})
+	}
+
+	maxSize := limits.MaxSize
+	if maxSize <= 0 {
+		maxSize = maxUploadSize
+	}
+
+	if upload.FileSize > maxSize {
+		return i18n.NewError(errUploadTooLarge.Key, i18n.Vars{"limit": formatSize(maxSize)})
+	}
+
+	mimeTypes := limits.MimeTypes
+	if len(mimeTypes) == 0 {
+		mimeTypes = uploadMimeTypes
+	}
+
+	if !mimeTypeAllowed(upload.MimeType, mimeTypes) {
+		return i18n.NewError(errUploadType.Key, i18n.Vars{"type": strconv.Quote(upload.MimeType), "types": strings.Join(mimeTypes, ", ")})
+	}
+
+	return nil
+}
+
+// mimeTypeAllowed returns true if the type matches one of the allowed ones, e.g. image/png matches image/*
+func mimeTypeAllowed(mimeType string, allowed []string) bool {
+	mimeType = strings.ToLower(mimeType)
+
+	for _, pattern := range allowed {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+
+		if pattern == anyMimeType || pattern == mimeType {
+			return true
+		}
+
+		if strings.HasSuffix(pattern, "/*") && mimeType != "" && strings.HasPrefix(mimeType, strings.TrimSuffix(pattern, "*")) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// formatSize formats the bytes in the largest unit without decimals, e.g. 20 MB
+func formatSize(size int64) string {
+	switch {
+	case size >= 1<<20:
+		return strconv.FormatInt(size>>20, 10) + " MB"
+	case size >= 1<<10:
+		return strconv.FormatInt(size>>10, 10) + " KB"
+	}
+
+	return strconv.FormatInt(size, 10) + " B"
+}
@@ -0,0 +1,262 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"shared/app/bot/commands"
+	"shared/app/bot/models"
+	"shared/app/bot/storage/conversation"
+
+	"bitbucket.org/truora/scrap-services/shared/cache"
+	"github.com/stretchr/testify/require"
+)
+
+func newUploadUpdate(updateID int64, upload string) []byte {
+	return []byte(fmt.Sprintf(`{"update_id":%d,"message":{"message_id":1,%s,"from":{"id":10},"chat":{"id":10,"type":"private"}}}`, updateID, upload))
+}
+
+// setDeployUploads makes the deploy command accept files with the given limits until the returned function is called
+func setDeployUploads(c *require.Assertions, limits *commands.UploadLimits) func() {
+	command, err := botCommands.Get(deployTerraformStagingCommand)
+	c.NoError(err)
+
+	declared := command.Uploads
+	command.Uploads = limits
+
+	return func() { command.Uploads = declared }
+}
+
+func capturePublished() (*[]*models.CallbackMessage, func()) {
+	published := []*models.CallbackMessage{}
+
+	SetPublisher(func(ctx context.Context, topic, attribute string, message *models.CallbackMessage) error {
+		published = append(published, message)
+
+		return nil
+	})
+
+	return &published, func() { SetPublisher(nil) }
+}
+
+func TestCheckUpload(t *testing.T) {
+	c := require.New(t)
+
+	command := &commands.Command{Name: "report"}
+	pdf := &models.Upload{MimeType: "application/pdf", FileSize: 1 << 20}
+
+	c.ErrorIs(checkUpload(command, pdf), errUploadNotAccepted)
+
+	command.Uploads = &commands.UploadLimits{}
+	c.NoError(checkUpload(command, pdf))
+	c.NoError(checkUpload(command, &models.Upload{MimeType: "image/png"}))
+	c.ErrorIs(checkUpload(command, &models.Upload{MimeType: "application/zip"}), errUploadType)
+	c.ErrorIs(checkUpload(command, &models.Upload{MimeType: "application/pdf", FileSize: maxUploadSize + 1}), errUploadTooLarge)
+
+	command.Uploads = &commands.UploadLimits{MaxSize: 1 << 10, MimeTypes: []string{"application/zip"}}
+	c.ErrorIs(checkUpload(command, pdf), errUploadTooLarge)
+	c.EqualError(checkUpload(command, pdf), "The file is too large, the limit is 1 KB")
+	c.EqualError(checkUpload(command, &models.Upload{MimeType: "application/pdf"}), `Files of type "application/pdf" are not accepted, send one of application/zip`)
+	c.NoError(checkUpload(command, &models.Upload{MimeType: "application/zip", FileSize: 1 << 10}))
+}
+
+func TestMimeTypeAllowed(t *testing.T) {
+	c := require.New(t)
+
+	c.True(mimeTypeAllowed("image/png", []string{"image/*"}))
+	c.True(mimeTypeAllowed("Application/PDF", []string{" application/pdf"}))
+	c.True(mimeTypeAllowed("", []string{anyMimeType}))
+	c.False(mimeTypeAllowed("", []string{"image/*"}))
+	c.False(mimeTypeAllowed("imagery/png", []string{"image/*"}))
+	c.False(mimeTypeAllowed("text/csv", []string{"text/plain"}))
+}
+
+func TestFormatSize(t *testing.T) {
+	c := require.New(t)
+
+	c.Equal("20 MB", formatSize(20<<20))
+	c.Equal("1 KB", formatSize(1500))
+	c.Equal("512 B", formatSize(512))
+}
+
+func TestProcessPhotoWithCommand(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+	saveVerifiedUser(c, 10, "dummy_email@dummy.com")
+
+	fake, restore := setFakeTelegram()
+
+	defer restore()
+	defer setDeployUploads(c, &commands.UploadLimits{})()
+
+	published, restorePublisher := capturePublished()
+
+	defer restorePublisher()
+
+	err := ProcessUpdate(context.Background(), newUploadUpdate(1,
+		`"caption":"/deployterraformstaging checks/core","photo":[{"file_id":"small","file_size":10},{"file_id":"large","file_unique_id":"unique","file_size":100}]`))
+	c.NoError(err)
+
+	c.Len(fake.Requests("getFile"), 1)
+	c.Equal("large", fake.Requests("getFile")[0].Params["file_id"])
+
+	c.Len(*published, 1)
+	c.Equal("checks/core", (*published)[0].Args["project"])
+	c.Equal([]*models.Upload{{
+		Type:         models.UploadPhoto,
+		FileID:       "large",
+		FileUniqueID: "large",
+		MimeType:     models.PhotoMimeType,
+		FileSize:     100,
+		FilePath:     "documents/large",
+	}}, (*published)[0].Uploads)
+}
+
+func TestProcessDocumentRejected(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+	saveVerifiedUser(c, 10, "dummy_email@dummy.com")
+
+	fake, restore := setFakeTelegram()
+
+	defer restore()
+
+	published, restorePublisher := capturePublished()
+
+	defer restorePublisher()
+
+	err := ProcessUpdate(context.Background(), newUploadUpdate(1, `"caption":"/schedules","document":{"file_id":"file","mime_type":"application/pdf"}`))
+	c.NoError(err)
+	c.Equal("/schedules does not accept files", fake.SentTexts()[0])
+
+	document := `"caption":"/deployterraformstaging checks/core","document":{"file_id":"file","file_name":"plan.zip","mime_type":"application/zip","file_size":2048}`
+
+	restoreUploads := setDeployUploads(c, &commands.UploadLimits{MaxSize: 1 << 10})
+
+	defer restoreUploads()
+
+	err = ProcessUpdate(context.Background(), newUploadUpdate(2, document))
+	c.NoError(err)
+	c.Equal("The file is too large, the limit is 1 KB", fake.SentTexts()[1])
+
+	setDeployUploads(c, &commands.UploadLimits{Required: true})
+
+	err = ProcessUpdate(context.Background(), newUploadUpdate(3, document))
+	c.NoError(err)
+	c.Equal(`Files of type "application/zip" are not accepted, send one of image/*, application/pdf, text/plain, text/csv`, fake.SentTexts()[2])
+
+	err = ProcessUpdate(context.Background(), newScheduleUpdate(4, "/deployterraformstaging checks/core"))
+	c.NoError(err)
+	c.Equal("/deployterraformstaging needs a file, send it with the command as caption", fake.SentTexts()[3])
+
+	c.Empty(fake.Requests("getFile"))
+	c.Empty(*published)
+}
+
+func TestProcessDeployWithDocument(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+	saveVerifiedUser(c, 10, "dummy_email@dummy.com")
+
+	fake, restore := setFakeTelegram()
+
+	defer restore()
+
+	published, restorePublisher := capturePublished()
+
+	defer restorePublisher()
+
+	for _, name := range []string{deployTerraformStagingCommand, deployTerraformProductionCommand} {
+		command, err := botCommands.Get(name)
+		c.NoError(err)
+		c.NotNil(command.Uploads, name)
+	}
+
+	ctx := context.Background()
+
+	err := ProcessUpdate(ctx, newUploadUpdate(1, `"caption":"/deployterraformstaging checks/core","document":{"file_id":"plan","file_name":"plan.txt","mime_type":"text/plain","file_size":10}`))
+	c.NoError(err)
+	c.Contains(fake.SentTexts()[0], "Accepted /deployterraformstaging")
+
+	c.Len(*published, 1)
+	c.Equal("checks/core", (*published)[0].Args["project"])
+	c.Len((*published)[0].Uploads, 1)
+	c.Equal("plan.txt", (*published)[0].Uploads[0].FileName)
+	c.Equal("documents/plan", (*published)[0].Uploads[0].FilePath)
+
+	err = ProcessUpdate(ctx, newUploadUpdate(2, `"caption":"/deployterraformstaging checks/core","document":{"file_id":"plan","file_name":"plan.zip","mime_type":"application/zip","file_size":10}`))
+	c.NoError(err)
+	c.Equal(`Files of type "application/zip" are not accepted, send one of image/*, application/pdf, text/plain, text/csv`, fake.SentTexts()[1])
+	c.Len(*published, 1)
+}
+
+func TestProcessDocumentEvent(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+
+	_, restore := setFakeTelegram()
+
+	defer restore()
+
+	published, restorePublisher := capturePublished()
+
+	defer restorePublisher()
+
+	err := ProcessUpdate(context.Background(), newUploadUpdate(1, `"caption":"monthly report","document":{"file_id":"file","mime_type":"application/pdf"}`))
+	c.NoError(err)
+	c.Len(*published, 1)
+	c.Equal(models.EventDocument, (*published)[0].EventType)
+	c.Empty((*published)[0].Uploads)
+}
+
+func TestProcessUploadWizardAnswer(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+	saveVerifiedUser(c, 10, "dummy_email@dummy.com")
+
+	fake, restore := setFakeTelegram()
+
+	defer restore()
+	defer setDeployUploads(c, &commands.UploadLimits{MimeTypes: []string{"application/pdf"}})()
+
+	published, restorePublisher := capturePublished()
+
+	defer restorePublisher()
+
+	wizard := botWizards[deployTerraformStagingCommand]
+	botWizards[deployTerraformStagingCommand] = conversation.MustNewWizard(deployTerraformStagingCommand,
+		&conversation.Step{Name: "plan", Prompt: "Send the plan", Upload: true, NextStep: "project"},
+		&conversation.Step{Name: "project", Prompt: "Which project?"},
+	)
+
+	defer func() { botWizards[deployTerraformStagingCommand] = wizard }()
+
+	ctx := context.Background()
+
+	err := ProcessUpdate(ctx, newScheduleUpdate(1, "/deployterraformstaging"))
+	c.NoError(err)
+	c.Equal("Send the plan", fake.SentTexts()[0])
+
+	err = ProcessUpdate(ctx, newUploadUpdate(2, `"photo":[{"file_id":"photo","file_size":10}]`))
+	c.NoError(err)
+	c.Equal(`Files of type "image/jpeg" are not accepted, send one of application/pdf`, fake.SentTexts()[1])
+
+	err = ProcessUpdate(ctx, newUploadUpdate(3, `"document":{"file_id":"plan","file_name":"plan.pdf","mime_type":"application/pdf","file_size":10}`))
+	c.NoError(err)
+	c.Equal("Which project?", fake.SentTexts()[2])
+
+	err = ProcessUpdate(ctx, newScheduleUpdate(4, "checks/core"))
+	c.NoError(err)
+
+	c.Len(*published, 1)
+	c.Equal("checks/core", (*published)[0].Args["project"])
+	c.Len((*published)[0].Uploads, 1)
+	c.Equal("plan.pdf", (*published)[0].Uploads[0].FileName)
+	c.Equal("documents/plan", (*published)[0].Uploads[0].FilePath)
+}
@@ -0,0 +1,163 @@
+package router
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"shared/shared/aws/secrets"
+	"shared/shared/telegram"
+
+	"bitbucket.org/truora/scrap-services/logger"
+	"bitbucket.org/truora/scrap-services/shared/apigateway"
+	"bitbucket.org/truora/scrap-services/shared/env"
+	"github.com/aws/smithy-go"
+)
+
+const (
+	telegramSecretTokenHeader = "X-Telegram-Bot-Api-Secret-Token" // #nosec This is not a hardcoded credential
+)
+
+var (
+	// ErrInvalidSecretToken when the webhook secret token header does not match the configured secret
+	ErrInvalidSecretToken = errors.New("invalid webhook secret token")
+	// ErrEmptyWebhookSecret when the webhook secret is not configured
+	ErrEmptyWebhookSecret = errors.New("webhook secret is empty")
+	// ErrSetWebhookFailed when Telegram rejects the webhook registration
+	ErrSetWebhookFailed = errors.New("set webhook failed")
+
+	webhookSecretName    = env.GetString("WEBHOOK_SECRET_NAME", "betty-bot-webhook-secret")
+	oldWebhookSecretName = env.GetString("OLD_WEBHOOK_SECRET_NAME", "betty-bot-webhook-secret-old")
+	webhookURL           = env.GetString("WEBHOOK_URL", "")
+	// webhookSecretsRefresh is how long the secrets are kept before they are read again
+	webhookSecretsRefresh = time.Duration(env.GetInt64("WEBHOOK_SECRETS_REFRESH_MINUTES", 5)) * time.Minute
+
+	getSecret = secrets.Get
+
+	// notFoundCodes are the error codes of a secret that does not exist
+	notFoundCodes = map[string]bool{"ResourceNotFoundException": true, "NotFound": true}
+
+	cachedSecrets      *webhookSecrets
+	cachedSecretsMutex sync.Mutex
+)
+
+// webhookSecrets are kept for the lifetime of the Lambda so the requests do not read them every time
+type webhookSecrets struct {
+	secret    string
+	oldSecret string
+	readAt    time.Time
+}
+
+// getWebhookSecrets returns the current secret token and the old one, the old one is
+// optional and only exists while the secret is being rotated. The secrets are read again
+// after webhookSecretsRefresh, the last ones are used while they can not be read
+func getWebhookSecrets(ctx context.Context) (string, string, error) {
+	cachedSecretsMutex.Lock()
+	defer cachedSecretsMutex.Unlock()
+
+	if cachedSecrets != nil && time.Since(cachedSecrets.readAt) < webhookSecretsRefresh {
+		return cachedSecrets.secret, cachedSecrets.oldSecret, nil
+	}
+
+	read, err := readWebhookSecrets(ctx)
+	if err != nil && cachedSecrets != nil {
+		logger.Get(ctx).Warning(ctx, "refresh_webhook_secrets_failed", logger.OneMonth, []logger.Object{logger.ErrObject(err)})
+
+		return cachedSecrets.secret, cachedSecrets.oldSecret, nil
+	}
+
+	if err != nil {
+		return "", "", err
+	}
+
+	cachedSecrets = read
+
+	return read.secret, read.oldSecret, nil
+}
+
+func readWebhookSecrets(ctx context.Context) (*webhookSecrets, error) {
+	secret, err := getSecret(ctx, webhookSecretName)
+	if err != nil {
+		return nil, err
+	}
+
+	if secret == "" {
+		return nil, ErrEmptyWebhookSecret
+	}
+
+	oldSecret, err := getSecret(ctx, oldWebhookSecretName)
+	if isSecretNotFound(err) {
+		oldSecret = ""
+	} else if err != nil {
+		return nil, fmt.Errorf("get old webhook secret failed: %w", err)
+	}
+
+	return &webhookSecrets{secret: secret, oldSecret: oldSecret, readAt: time.Now()}, nil
+}
+
+// resetWebhookSecrets drops the kept secrets so the next request reads them again
+func resetWebhookSecrets() {
+	cachedSecretsMutex.Lock()
+	defer cachedSecretsMutex.Unlock()
+
+	cachedSecrets = nil
+}
+
+// isSecretNotFound returns true if the error is the one of a secret that does not exist
+func isSecretNotFound(err error) bool {
+	var apiErr smithy.APIError
+
+	return errors.As(err, &apiErr) && notFoundCodes[apiErr.ErrorCode()]
+}
+
+func (req *request) verifySecretToken(ctx context.Context) error {
+	secret, oldSecret, err := getWebhookSecrets(ctx)
+	if err != nil {
+		return fmt.Errorf("get webhook secrets failed: %w", err)
+	}
+
+	token := apigateway.GetHeader(req.APIGatewayProxyRequest, telegramSecretTokenHeader)
+	if token == "" {
+		return ErrInvalidSecretToken
+	}
+
+	if subtle.ConstantTimeCompare([]byte(token), []byte(secret)) == 1 {
+		return nil
+	}
+
+	if oldSecret != "" && subtle.ConstantTimeCompare([]byte(token), []byte(oldSecret)) == 1 {
+		return nil
+	}
+
+	return ErrInvalidSecretToken
+}
+
+// getWebhookURL returns the configured webhook URL or the URL used to invoke the lambda
+func (req *request) getWebhookURL() string {
+	if webhookURL != "" {
+		return webhookURL
+	}
+
+	return fmt.Sprintf("https://%s%s", req.RequestContext.DomainName, req.RequestContext.Path)
+}
+
+// registerWebhook points the Telegram webhook to the given endpoint with the current secret token
+func registerWebhook(ctx context.Context, telegramClient *telegram.Client, endpoint string) error {
+	// the webhook is registered with the secret that is stored right now
+	resetWebhookSecrets()
+
+	secret, _, err := getWebhookSecrets(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = telegramClient.SetWebhook(ctx, &telegram.SetWebhookRequest{URL: endpoint, SecretToken: secret})
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrSetWebhookFailed, err)
+	}
+
+	return nil
+}
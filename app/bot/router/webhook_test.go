@@ -0,0 +1,214 @@
+package router
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"shared/app/bot/botclient"
+	"shared/shared/aws/secrets"
+	"shared/shared/client"
+
+	"bitbucket.org/truora/scrap-services/logger"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifySecretToken(t *testing.T) {
+	c := require.New(t)
+
+	secrets.InitSecretsMock()
+	resetWebhookSecrets()
+
+	defer secrets.DeactivateMock()
+
+	req := newTestRequest("")
+
+	err := req.verifySecretToken(context.Background())
+	c.Error(err)
+
+	secrets.SetMockedSecret(webhookSecretName, "secret-token")
+
+	err = req.verifySecretToken(context.Background())
+	c.NoError(err)
+
+	req.Headers = map[string]string{"x-telegram-bot-api-secret-token": "wrong-token"}
+
+	err = req.verifySecretToken(context.Background())
+	c.ErrorIs(err, ErrInvalidSecretToken)
+
+	req.Headers = map[string]string{}
+
+	err = req.verifySecretToken(context.Background())
+	c.ErrorIs(err, ErrInvalidSecretToken)
+}
+
+func TestVerifySecretTokenRotation(t *testing.T) {
+	c := require.New(t)
+
+	secrets.InitSecretsMock()
+	resetWebhookSecrets()
+
+	defer secrets.DeactivateMock()
+
+	secrets.SetMockedSecret(webhookSecretName, "new-secret-token")
+	secrets.SetMockedSecret(oldWebhookSecretName, "secret-token")
+
+	req := newTestRequest("")
+
+	err := req.verifySecretToken(context.Background())
+	c.NoError(err)
+
+	req.Headers = map[string]string{telegramSecretTokenHeader: "new-secret-token"}
+
+	err = req.verifySecretToken(context.Background())
+	c.NoError(err)
+
+	secrets.DeleteMocketSecret(oldWebhookSecretName)
+	resetWebhookSecrets()
+
+	req.Headers = map[string]string{telegramSecretTokenHeader: "secret-token"}
+
+	err = req.verifySecretToken(context.Background())
+	c.ErrorIs(err, ErrInvalidSecretToken)
+}
+
+func TestWebhookSecretsCached(t *testing.T) {
+	c := require.New(t)
+
+	secrets.InitSecretsMock()
+	resetWebhookSecrets()
+
+	defer secrets.DeactivateMock()
+	defer resetWebhookSecrets()
+
+	secrets.SetMockedSecret(webhookSecretName, "secret-token")
+
+	reads := 0
+	getSecret = func(ctx context.Context, name string) (string, error) {
+		reads++
+
+		return secrets.Get(ctx, name)
+	}
+
+	defer func() { getSecret = secrets.Get }()
+
+	ctx := context.Background()
+
+	secret, oldSecret, err := getWebhookSecrets(ctx)
+	c.NoError(err)
+	c.Equal("secret-token", secret)
+	c.Empty(oldSecret)
+
+	_, _, err = getWebhookSecrets(ctx)
+	c.NoError(err)
+	c.Equal(2, reads)
+
+	// the secrets are read again after the refresh and the last ones are kept when they can not be read
+	cachedSecrets.readAt = time.Now().Add(-webhookSecretsRefresh)
+	getSecret = func(ctx context.Context, name string) (string, error) {
+		return "", errors.New("throttled")
+	}
+
+	secret, _, err = getWebhookSecrets(ctx)
+	c.NoError(err)
+	c.Equal("secret-token", secret)
+}
+
+func TestOldWebhookSecretFailed(t *testing.T) {
+	c := require.New(t)
+
+	secrets.InitSecretsMock()
+	resetWebhookSecrets()
+
+	defer secrets.DeactivateMock()
+	defer resetWebhookSecrets()
+
+	secrets.SetMockedSecret(webhookSecretName, "secret-token")
+
+	getSecret = func(ctx context.Context, name string) (string, error) {
+		if name == oldWebhookSecretName {
+			return "", errors.New("throttled")
+		}
+
+		return secrets.Get(ctx, name)
+	}
+
+	defer func() { getSecret = secrets.Get }()
+
+	// a failed read of the old secret is not taken as the end of the rotation
+	_, _, err := getWebhookSecrets(context.Background())
+	c.ErrorContains(err, "throttled")
+}
+
+func TestApiGatewayHandlerRejectsInvalidSecretToken(t *testing.T) {
+	c := require.New(t)
+
+	setMockedClient()
+
+	defer deactivateMockedClient()
+
+	ctx := context.Background()
+	log := logger.New("test")
+	buf := bytes.NewBufferString("")
+	log.Output = buf
+
+	ctx = logger.Set(ctx, log)
+
+	req := newTestRequest(`{"message":{"text":"/hi dummy_email@dummy.com"}}`)
+	req.Headers = map[string]string{telegramSecretTokenHeader: "wrong-token"}
+
+	response, err := apiGatewayHandler(ctx, req)
+	c.NoError(err)
+	c.Equal(http.StatusUnauthorized, response.StatusCode)
+
+	output := buf.String()
+	c.Contains(output, "webhook_request_rejected")
+	c.Contains(output, ErrInvalidSecretToken.Error())
+}
+
+func TestGetWebhookURL(t *testing.T) {
+	c := require.New(t)
+
+	req := newTestRequest("")
+	req.RequestContext.DomainName = "bot.truora.com"
+	req.RequestContext.Path = "/v1/router"
+
+	c.Equal("https://bot.truora.com/v1/router", req.getWebhookURL())
+
+	oldWebhookURL := webhookURL
+	webhookURL = "https://custom.truora.com/router"
+
+	defer func() {
+		webhookURL = oldWebhookURL
+	}()
+
+	c.Equal("https://custom.truora.com/router", req.getWebhookURL())
+}
+
+func TestRegisterWebhook(t *testing.T) {
+	c := require.New(t)
+
+	setMockedClient()
+
+	defer deactivateMockedClient()
+
+	telegramClient, err := botclient.New(context.Background())
+	c.NoError(err)
+
+	err = registerWebhook(context.Background(), telegramClient, "https://bot.truora.com/v1/router")
+	c.NoError(err)
+
+	client.AddMockedResponse(http.MethodPost, "https://api.telegram.org/bottoken/setWebhook", http.StatusBadRequest, `{"ok": false, "description": "bad webhook"}`)
+
+	err = registerWebhook(context.Background(), telegramClient, "https://bot.truora.com/v1/router")
+	c.ErrorIs(err, ErrSetWebhookFailed)
+	c.Contains(err.Error(), "bad webhook")
+
+	secrets.DeleteMocketSecret(webhookSecretName)
+
+	err = registerWebhook(context.Background(), telegramClient, "https://bot.truora.com/v1/router")
+	c.Error(err)
+}
@@ -0,0 +1,210 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"shared/app/bot/commands"
+	"shared/app/bot/i18n"
+	"shared/app/bot/models"
+	"shared/app/bot/storage/conversation"
+	"shared/shared/telegram"
+
+	"bitbucket.org/truora/scrap-services/logger"
+)
+
+const (
+	deployTerraformStagingCommand = "deployterraformstaging"
+	// skipAnswer leaves an optional wizard answer empty
+	skipAnswer = "-"
+)
+
+var (
+	// errWizardRequired when a command without arguments is going to be asked step by step
+	errWizardRequired = errors.New("command arguments will be asked by a wizard")
+	// errEmptyAnswer when the user answers a wizard step with an empty text
+	errEmptyAnswer = i18n.NewError("wizard.empty_answer", nil)
+	// errYesOrNo when the user does not answer a confirmation step with yes or no
+	errYesOrNo = i18n.NewError("wizard.yes_or_no", nil)
+
+	// botWizards are the wizards started when their command is sent without arguments
+	botWizards = map[string]*conversation.Wizard{
+		deployTerraformStagingCommand: conversation.MustNewWizard(deployTerraformStagingCommand,
+			&conversation.Step{
+				Name:     "project",
+				Prompt:   "wizard.deployterraformstaging.project",
+				Validate: notEmpty,
+				NextStep: "branch",
+			},
+			&conversation.Step{
+				Name:     "branch",
+				Prompt:   "wizard.deployterraformstaging.branch",
+				Vars:     i18n.Vars{"skip": skipAnswer},
+				Validate: notEmpty,
+				NextStep: "confirm",
+			},
+			&conversation.Step{
+				Name:   "confirm",
+				Prompt: "wizard.deployterraformstaging.confirm",
+				Validate: func(answer string, answers map[string]string) error {
+					if !isYes(answer) && !isNo(answer) {
+						return errYesOrNo
+					}
+
+					return nil
+				},
+				Next: func(answer string, answers map[string]string) string {
+					if isYes(answer) {
+						return conversation.EndStep
+					}
+
+					return conversation.CancelAnswer
+				},
+			},
+		),
+	}
+)
+
+func notEmpty(answer string, answers map[string]string) error {
+	if answer == "" {
+		return errEmptyAnswer
+	}
+
+	return nil
+}
+
+// isYes returns true if the answer is yes in any supported language
+func isYes(answer string) bool {
+	switch strings.ToLower(answer) {
+	case "yes", "si", "sí":
+		return true
+	}
+
+	return false
+}
+
+// isNo returns true if the answer is no in any supported language
+func isNo(answer string) bool {
+	return strings.ToLower(answer) == "no"
+}
+
+// needsWizard returns true if the command has a wizard and it was sent without any argument
+func needsWizard(command *commands.Command, input *models.CommandInput) bool {
+	_, ok := botWizards[command.Name]
+
+	return ok && len(input.Positional) == 0 && len(input.Flags) == 0 && len(input.KeyValues) == 0
+}
+
+// isWizardControl returns true if the command is one of the answers that control a wizard, e.g. /cancel
+func isWizardControl(command string) bool {
+	return commandPrefix+command == conversation.BackAnswer || commandPrefix+command == conversation.CancelAnswer
+}
+
+// startWizard stores the wizard state of the command and asks the first step
+func startWizard(ctx context.Context, telegramClient *telegram.Client, message *models.CallbackMessage, command *commands.Command) error {
+	result, err := botWizards[command.Name].Start(ctx, message.Message)
+	if err != nil {
+		return fmt.Errorf("start wizard failed: %w", err)
+	}
+
+	telegramClient.SendText(ctx, replyChatID(message), result.Reply)
+
+	return nil
+}
+
+// handleWizard processes the message as the answer of the current wizard step, the command is returned
+// when the wizard is done and the message has the answers as the command arguments
+func handleWizard(ctx context.Context, telegramClient *telegram.Client, message *models.CallbackMessage, state *models.ConversationState) (string, error) {
+	wizard, ok := botWizards[state.Command]
+	if !ok {
+		return "", conversation.DeleteConversationState(ctx, message.Message)
+	}
+
+	command, err := botCommands.Get(state.Command)
+	if err != nil {
+		return "", err
+	}
+
+	result, err := answerWizard(ctx, telegramClient, message, state, wizard, command)
+	if isUploadRejected(err) {
+		return "", nil
+	}
+
+	if err != nil {
+		return "", fmt.Errorf("handle wizard failed: %w", err)
+	}
+
+	if !result.Done {
+		telegramClient.SendText(ctx, replyChatID(message), result.Reply)
+
+		return "", nil
+	}
+
+	message.ID = defaultConversationID
+	message.Command = command.Name
+	message.Input = inputFromAnswers(command, result.Answers)
+	message.Uploads = result.Uploads
+
+	message.Args, err = command.ParseArgs(message.Input.Positional)
+	if err != nil {
+		telegramClient.SendText(ctx, replyChatID(message), argsErrorText(ctx, command, err))
+
+		return "", err
+	}
+
+	logger.Get(ctx).Info(ctx, "wizard_completed", logger.OneMonth, []logger.Object{
+		logger.MapObject("wizard", map[string]interface{}{
+			"s_command":     command.Name,
+			"i_telegram_id": message.From.ID,
+		}),
+	})
+
+	return command.Name, nil
+}
+
+// answerWizard handles the message as the answer of the current step, a file is checked against the limits
+// of the command before it is handled as the answer
+func answerWizard(ctx context.Context, telegramClient *telegram.Client, message *models.CallbackMessage, state *models.ConversationState, wizard *conversation.Wizard, command *commands.Command) (*conversation.Result, error) {
+	upload := message.Message.Upload()
+	if upload == nil {
+		return wizard.Handle(ctx, message.Message, state)
+	}
+
+	resolved, err := resolveUpload(ctx, telegramClient, message, command, upload)
+	if err != nil {
+		return nil, err
+	}
+
+	return wizard.HandleUpload(ctx, message.Message, state, resolved)
+}
+
+// inputFromAnswers builds the command input from the answers named as the command arguments and flags
+func inputFromAnswers(command *commands.Command, answers map[string]string) *models.CommandInput {
+	input := &models.CommandInput{
+		Name:      command.Name,
+		Flags:     map[string][]string{},
+		KeyValues: map[string]string{},
+	}
+
+	for _, arg := range append(append([]commands.Argument{}, command.RequiredArgs...), command.OptionalArgs...) {
+		answer := strings.TrimSpace(answers[arg.Name])
+		if answer == "" || answer == skipAnswer {
+			break
+		}
+
+		input.Positional = append(input.Positional, answer)
+	}
+
+	for _, flag := range command.Flags {
+		answer := strings.TrimSpace(answers[flag.Name])
+		if answer == "" || answer == skipAnswer {
+			continue
+		}
+
+		input.Flags[flag.Name] = []string{answer}
+	}
+
+	return input
+}
@@ -0,0 +1,107 @@
+package router
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"shared/app/bot/commands"
+	"shared/app/bot/models"
+	"shared/app/bot/storage"
+	"shared/app/bot/storage/conversation"
+
+	"bitbucket.org/truora/scrap-services/logger"
+	"bitbucket.org/truora/scrap-services/shared/cache"
+	"bitbucket.org/truora/scrap-services/shared/sns"
+	"github.com/stretchr/testify/require"
+)
+
+func sendWizardText(c *require.Assertions, ctx context.Context, updateID int, text string) {
+	body := fmt.Sprintf(`{"update_id":%d,"message":{"text":%q,"from":{"id":123},"chat":{"id":123,"type":"private"}}}`, updateID, text)
+
+	response, err := apiGatewayHandler(ctx, newTestRequest(body))
+	c.NoError(err)
+	c.Equal(http.StatusOK, response.StatusCode)
+}
+
+func TestApiGatewayHandlerWizard(t *testing.T) {
+	c := require.New(t)
+
+	setMockedClient()
+
+	defer deactivateMockedClient()
+
+	cache.InitMock()
+	sns.InitSNSMock()
+	storage.InitDynamoMock()
+
+	ctx := context.Background()
+	log := logger.New("test")
+	buf := bytes.NewBufferString("")
+	log.Output = buf
+
+	ctx = logger.Set(ctx, log)
+
+	err := storage.SaveUser(ctx, models.From{Email: "dummy_email@dummy.com", ID: 123})
+	c.NoError(err)
+
+	err = storage.VerifyUserEmail(ctx, "dummy_email@dummy.com")
+	c.NoError(err)
+
+	message := models.Message{From: models.From{ID: 123}, Chat: models.Chat{ID: 123}}
+
+	sendWizardText(c, ctx, 1, "/deployTerraformStaging")
+
+	state, err := conversation.GetConversationState(ctx, message)
+	c.NoError(err)
+	c.Equal("project", state.Wizard.Step)
+
+	sendWizardText(c, ctx, 2, "checks/core")
+	sendWizardText(c, ctx, 3, "-")
+
+	state, err = conversation.GetConversationState(ctx, message)
+	c.NoError(err)
+	c.Equal("confirm", state.Wizard.Step)
+
+	sendWizardText(c, ctx, 4, "yes")
+
+	_, err = conversation.GetConversationState(ctx, message)
+	c.Equal(conversation.ErrConversationNotFound, err)
+	c.Contains(buf.String(), "wizard_completed")
+	c.NotContains(buf.String(), "process_router_request_failed")
+
+	sendWizardText(c, ctx, 5, "/deployTerraformStaging")
+	sendWizardText(c, ctx, 6, "/cancel")
+
+	_, err = conversation.GetConversationState(ctx, message)
+	c.Equal(conversation.ErrConversationNotFound, err)
+}
+
+func TestInputFromAnswers(t *testing.T) {
+	c := require.New(t)
+
+	command, err := botCommands.Get(deployTerraformStagingCommand)
+	c.NoError(err)
+
+	input := inputFromAnswers(command, map[string]string{"project": "checks/core", "branch": "master", "confirm": "yes"})
+	c.Equal([]string{"checks/core"}, input.Positional)
+	c.Equal("master", input.Flag("branch"))
+
+	input = inputFromAnswers(command, map[string]string{"project": "checks/core", "branch": skipAnswer})
+	c.False(input.HasFlag("branch"))
+}
+
+func TestNeedsWizard(t *testing.T) {
+	c := require.New(t)
+
+	command, err := botCommands.Get(deployTerraformStagingCommand)
+	c.NoError(err)
+
+	c.True(needsWizard(command, &models.CommandInput{}))
+	c.False(needsWizard(command, &models.CommandInput{Flags: map[string][]string{"branch": {"master"}}}))
+	c.False(needsWizard(&commands.Command{Name: registerCommand}, &models.CommandInput{}))
+	c.True(isWizardControl("cancel"))
+	c.False(isWizardControl("deployterraformstaging"))
+}
@@ -0,0 +1,101 @@
+// Package scheduler fires the scheduled bot commands, it runs on every tick of a scheduled Lambda and
+// routes the due commands through the router like they were sent by the user
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"shared/app/bot/models"
+	"shared/app/bot/router"
+	"shared/app/bot/storage/queue"
+	"shared/app/bot/storage/schedule"
+
+	"bitbucket.org/truora/scrap-services/logger"
+	"bitbucket.org/truora/scrap-services/shared/env"
+	"github.com/aws/aws-lambda-go/events"
+)
+
+var (
+	// maxPerTick limits the commands fired by a tick so it finishes before the next one starts
+	maxPerTick = int(env.GetInt64("SCHEDULER_MAX_PER_TICK", 100))
+
+	dispatch        = router.Dispatch
+	expireApprovals = router.ExpireApprovals
+	now             = time.Now
+)
+
+// Init initializes the cache and the topics used by the router
+func Init(ctx context.Context) {
+	router.Init(ctx)
+}
+
+// Handler is the handler of the scheduler Lambda triggered every minute by an EventBridge rule
+func Handler(ctx context.Context, event events.CloudWatchEvent) error {
+	return Tick(ctx)
+}
+
+// Tick fires the commands due now and expires the approval requests that were not approved in time, a
+// failure firing the commands does not stop the expiration
+func Tick(ctx context.Context) error {
+	return errors.Join(fireDue(ctx), expireApprovals(ctx, now()))
+}
+
+// fireDue fires the commands due now, it stops when there are no more due commands or another tick is
+// firing them
+func fireDue(ctx context.Context) error {
+	for fired := 0; fired < maxPerTick; fired++ {
+		tickTime := now()
+
+		command, err := schedule.ClaimDue(ctx, tickTime)
+		if errors.Is(err, schedule.ErrNoDueSchedule) || errors.Is(err, schedule.ErrScheduleClaimed) {
+			return nil
+		}
+
+		if errors.Is(err, schedule.ErrScheduleNotFound) {
+			continue
+		}
+
+		if errors.Is(err, queue.ErrDropped) {
+			logger.Get(ctx).Error(ctx, "scheduled_command_dropped", logger.OneMonth, []logger.Object{
+				logger.ErrObject(err),
+			})
+
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+
+		// the next run of a recurring command is stored before firing so a fire that never returns
+		// does not lose it
+		err = schedule.Complete(ctx, command, tickTime)
+		if err != nil {
+			return err
+		}
+
+		fire(ctx, command)
+	}
+
+	return nil
+}
+
+// fire routes a copy of the stored message, a failed command is not retried, the router already told the user why
+func fire(ctx context.Context, command *models.ScheduledCommand) {
+	message := command.Message
+
+	err := dispatch(ctx, &message)
+	if err != nil {
+		logger.Get(ctx).Warning(ctx, "scheduled_command_failed", logger.OneMonth, []logger.Object{
+			logger.ErrObject(err),
+			logger.MapObject("schedule", map[string]interface{}{
+				"s_id":          command.ID,
+				"s_text":        command.Text,
+				"i_telegram_id": command.Message.From.ID,
+				"i_chat_id":     command.Message.Message.Chat.ID,
+			}),
+		})
+	}
+}
@@ -0,0 +1,208 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"shared/app/bot/models"
+	"shared/app/bot/router"
+	"shared/app/bot/storage/queue"
+	"shared/app/bot/storage/schedule"
+
+	"bitbucket.org/truora/scrap-services/shared/cache"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/require"
+)
+
+func addTestCommand(c *require.Assertions, fireAt time.Time, every time.Duration) *models.ScheduledCommand {
+	id, err := schedule.NewID()
+	c.NoError(err)
+
+	command := &models.ScheduledCommand{
+		ID:     id,
+		Text:   "/deployterraformstaging checks/core",
+		Every:  every,
+		FireAt: fireAt,
+		Message: models.CallbackMessage{
+			From:    models.From{ID: 10},
+			Message: models.Message{Text: "/deployterraformstaging checks/core", Chat: models.Chat{ID: 10}},
+		},
+	}
+
+	c.NoError(schedule.Add(context.Background(), command))
+
+	return command
+}
+
+func mockDispatch(err error) (*[]string, func()) {
+	dispatched := []string{}
+
+	dispatch = func(ctx context.Context, message *models.CallbackMessage) error {
+		dispatched = append(dispatched, message.Message.Text)
+
+		return err
+	}
+
+	return &dispatched, func() { dispatch = router.Dispatch }
+}
+
+func TestTick(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+
+	dispatched, restore := mockDispatch(nil)
+
+	defer restore()
+
+	ctx := context.Background()
+	tickTime := time.Now()
+
+	once := addTestCommand(c, tickTime.Add(-time.Minute), 0)
+	recurring := addTestCommand(c, tickTime.Add(-2*time.Minute), time.Hour)
+	addTestCommand(c, tickTime.Add(time.Hour), 0)
+
+	err := Handler(ctx, events.CloudWatchEvent{})
+	c.NoError(err)
+	c.Len(*dispatched, 2)
+
+	_, err = schedule.Get(ctx, once.ID)
+	c.ErrorIs(err, schedule.ErrScheduleNotFound)
+
+	stored, err := schedule.Get(ctx, recurring.ID)
+	c.NoError(err)
+	c.True(stored.FireAt.After(tickTime))
+
+	// nothing is due until the next run of the recurring command
+	err = Tick(ctx)
+	c.NoError(err)
+	c.Len(*dispatched, 2)
+
+	now = func() time.Time { return stored.FireAt }
+
+	defer func() {
+		now = time.Now
+	}()
+
+	err = Tick(ctx)
+	c.NoError(err)
+	c.Len(*dispatched, 3)
+}
+
+func TestTickLimit(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+
+	dispatched, restore := mockDispatch(errors.New("user not registered"))
+
+	defer restore()
+
+	oldMaxPerTick := maxPerTick
+	maxPerTick = 1
+
+	defer func() {
+		maxPerTick = oldMaxPerTick
+	}()
+
+	first := addTestCommand(c, time.Now().Add(-2*time.Minute), 0)
+	addTestCommand(c, time.Now().Add(-time.Minute), 0)
+
+	// a failed command is completed anyway
+	err := Tick(context.Background())
+	c.NoError(err)
+	c.Len(*dispatched, 1)
+
+	_, err = schedule.Get(context.Background(), first.ID)
+	c.ErrorIs(err, schedule.ErrScheduleNotFound)
+
+	err = Tick(context.Background())
+	c.NoError(err)
+	c.Len(*dispatched, 2)
+}
+
+func TestTickReschedulesBeforeFiring(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+
+	ctx := context.Background()
+	tickTime := time.Now()
+
+	recurring := addTestCommand(c, tickTime.Add(-time.Minute), time.Hour)
+
+	dispatch = func(ctx context.Context, message *models.CallbackMessage) error {
+		stored, err := schedule.Get(ctx, recurring.ID)
+		c.NoError(err)
+		c.True(stored.FireAt.After(tickTime))
+
+		return nil
+	}
+
+	defer func() {
+		dispatch = router.Dispatch
+	}()
+
+	c.NoError(Tick(ctx))
+}
+
+func TestTickDropsUnreadable(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+
+	dispatched, restore := mockDispatch(nil)
+
+	defer restore()
+
+	oldMaxReads := queue.MaxReads
+	queue.MaxReads = 1
+
+	defer func() {
+		queue.MaxReads = oldMaxReads
+	}()
+
+	ctx := context.Background()
+
+	unreadable := addTestCommand(c, time.Now().Add(-2*time.Minute), 0)
+	addTestCommand(c, time.Now().Add(-time.Minute), 0)
+
+	c.NoError(cache.Add(ctx, "BOT-SCHEDULE:"+unreadable.ID, "{", time.Hour))
+
+	// the unreadable command does not block the next one
+	c.NoError(Tick(ctx))
+	c.Len(*dispatched, 1)
+}
+
+func TestTickExpiresApprovals(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+
+	_, restore := mockDispatch(nil)
+
+	defer restore()
+
+	expiredAt := []time.Time{}
+
+	expireApprovals = func(ctx context.Context, tickTime time.Time) error {
+		expiredAt = append(expiredAt, tickTime)
+
+		return nil
+	}
+
+	defer func() {
+		expireApprovals = router.ExpireApprovals
+	}()
+
+	c.NoError(Tick(context.Background()))
+	c.Len(expiredAt, 1)
+
+	// the approvals expire even when the commands can not be read
+	c.NoError(cache.Add(context.Background(), "BOT-SCHEDULES", "not an ordered set", time.Hour))
+
+	c.Error(Tick(context.Background()))
+	c.Len(expiredAt, 2)
+}
@@ -0,0 +1,72 @@
+package slack
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"bitbucket.org/truora/scrap-services/logger"
+	"bitbucket.org/truora/scrap-services/shared/cache"
+	"bitbucket.org/truora/scrap-services/shared/env"
+)
+
+const (
+	routedRequestKey = "BOT-SLACK-ROUTED-REQUEST:%s"
+	// retryHeader is sent by Slack when it resends a request that was not answered in time
+	retryHeader = "X-Slack-Retry-Num"
+)
+
+var (
+	dedupeMinutes = env.GetInt64("SLACK_DEDUPE_MINUTES", 60)
+	dedupeWindow  = time.Duration(dedupeMinutes) * time.Minute
+)
+
+// claimRequest marks the request as routed and returns false when it was already routed. Slack resends
+// the request with the same trigger_id when the command is not answered in time, a resent request without
+// trigger_id is never routed. A cache failure does not block the request, it is logged and routed anyway
+func claimRequest(ctx context.Context, request *Request, retry string) bool {
+	if request.TriggerID == "" {
+		if retry == "" {
+			return true
+		}
+
+		logDuplicatedRequest(ctx, request, retry)
+
+		return false
+	}
+
+	claimed, err := cache.AddOnce(ctx, fmt.Sprintf(routedRequestKey, request.TriggerID), now().Unix(), dedupeWindow)
+	if err != nil {
+		logger.Get(ctx).Error(ctx, "claim_slack_request_failed", logger.OneMonth, []logger.Object{
+			logger.ErrObject(err),
+			requestObject(request, retry),
+		})
+
+		return true
+	}
+
+	if !claimed {
+		logDuplicatedRequest(ctx, request, retry)
+	}
+
+	return claimed
+}
+
+func logDuplicatedRequest(ctx context.Context, request *Request, retry string) {
+	logger.Get(ctx).Warning(ctx, "duplicated_slack_request_skipped", logger.OneMonth, []logger.Object{
+		requestObject(request, retry),
+		logger.MapObject("metric", map[string]interface{}{
+			"s_name":  "duplicated_slack_requests",
+			"i_value": 1,
+		}),
+	})
+}
+
+func requestObject(request *Request, retry string) logger.Object {
+	return logger.MapObject("slack", map[string]interface{}{
+		"s_user_id":    request.UserID,
+		"s_command":    request.Command,
+		"s_trigger_id": request.TriggerID,
+		"s_retry":      retry,
+	})
+}
@@ -0,0 +1,171 @@
+package slack
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"shared/app/bot/models"
+
+	"bitbucket.org/truora/scrap-services/shared/env"
+)
+
+const (
+	// blockActionsType is the type of the interaction payloads sent when a user presses a block button
+	blockActionsType = "block_actions"
+	// directMessageChannel is the channel name Slack sends for the direct messages with the app
+	directMessageChannel = "directmessage"
+
+	privateChat = "private"
+	groupChat   = "group"
+)
+
+// rootCommand is the slash command that receives the bot command as its first word, e.g. /betty help.
+// Any other slash command configured in the Slack app runs the bot command with its name
+var rootCommand = env.GetString("SLACK_ROOT_COMMAND", "/betty")
+
+// Request is a slash command or a block action normalized to the bot command it runs
+type Request struct {
+	// Command is the name of the bot command without the slash
+	Command     string
+	Text        string
+	TeamID      string
+	UserID      string
+	UserName    string
+	ChannelID   string
+	ChannelName string
+	ResponseURL string
+	TriggerID   string
+}
+
+// interaction is the payload of the Slack interactivity requests
+type interaction struct {
+	Type string `json:"type"`
+	User struct {
+		ID       string `json:"id"`
+		Username string `json:"username"`
+	} `json:"user"`
+	Team struct {
+		ID string `json:"id"`
+	} `json:"team"`
+	Channel struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"channel"`
+	ResponseURL string `json:"response_url"`
+	TriggerID   string `json:"trigger_id"`
+	// Actions are the pressed elements, the value of the buttons is the command line they run
+	Actions []struct {
+		ActionID string `json:"action_id"`
+		Value    string `json:"value"`
+	} `json:"actions"`
+}
+
+// ParseRequest parses the form-encoded body of a slash command or of a block actions interaction,
+// a nil request is returned for the interactions that do not run a command
+func ParseRequest(body []byte) (*Request, error) {
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidPayload, err)
+	}
+
+	if form.Has("payload") {
+		return parseInteraction(form.Get("payload"))
+	}
+
+	if form.Get("command") == "" || form.Get("user_id") == "" {
+		return nil, fmt.Errorf("%w: missing command or user", ErrInvalidPayload)
+	}
+
+	request := &Request{
+		TeamID:      form.Get("team_id"),
+		UserID:      form.Get("user_id"),
+		UserName:    form.Get("user_name"),
+		ChannelID:   form.Get("channel_id"),
+		ChannelName: form.Get("channel_name"),
+		ResponseURL: form.Get("response_url"),
+		TriggerID:   form.Get("trigger_id"),
+	}
+
+	request.Command, request.Text = splitCommand(form.Get("command"), form.Get("text"))
+
+	return request, nil
+}
+
+func parseInteraction(payload string) (*Request, error) {
+	data := &interaction{}
+
+	err := json.Unmarshal([]byte(payload), data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidPayload, err)
+	}
+
+	if data.Type != blockActionsType || len(data.Actions) == 0 || data.Actions[0].Value == "" {
+		return nil, nil
+	}
+
+	if data.User.ID == "" {
+		return nil, fmt.Errorf("%w: missing user", ErrInvalidPayload)
+	}
+
+	request := &Request{
+		TeamID:      data.Team.ID,
+		UserID:      data.User.ID,
+		UserName:    data.User.Username,
+		ChannelID:   data.Channel.ID,
+		ChannelName: data.Channel.Name,
+		ResponseURL: data.ResponseURL,
+		TriggerID:   data.TriggerID,
+	}
+
+	request.Command, request.Text = splitCommand(rootCommand, data.Actions[0].Value)
+
+	return request, nil
+}
+
+// splitCommand returns the bot command and its arguments, the root command takes them from the text
+func splitCommand(command, text string) (string, string) {
+	text = strings.TrimSpace(text)
+
+	if command != rootCommand {
+		return strings.ToLower(strings.TrimPrefix(command, "/")), text
+	}
+
+	name, args, _ := strings.Cut(text, " ")
+
+	return strings.ToLower(strings.TrimPrefix(name, "/")), strings.TrimSpace(args)
+}
+
+// IsDirectMessage returns true if the request was sent in a direct message with the app
+func (request *Request) IsDirectMessage() bool {
+	return request.ChannelName == directMessageChannel || strings.HasPrefix(request.ChannelID, "D")
+}
+
+// Message returns the routed message of the request sent by the stored user. The chat is the Telegram
+// chat of the user so the workers that only answer in Telegram keep working
+func (request *Request) Message(user *models.From) *models.CallbackMessage {
+	chatType := groupChat
+	if request.IsDirectMessage() {
+		chatType = privateChat
+	}
+
+	text := strings.TrimSpace("/" + request.Command + " " + request.Text)
+
+	return &models.CallbackMessage{
+		From: *user,
+		Message: models.Message{
+			Text: text,
+			From: *user,
+			Chat: models.Chat{ID: user.ID, Type: chatType, Title: request.ChannelName},
+		},
+		EventType: models.EventMessage,
+		Slack: &models.SlackContext{
+			TeamID:      request.TeamID,
+			UserID:      request.UserID,
+			ChannelID:   request.ChannelID,
+			ResponseURL: request.ResponseURL,
+			TriggerID:   request.TriggerID,
+		},
+	}
+}
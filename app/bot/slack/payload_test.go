@@ -0,0 +1,97 @@
+package slack
+
+import (
+	"net/url"
+	"testing"
+
+	"shared/app/bot/models"
+
+	"github.com/stretchr/testify/require"
+)
+
+const blockActionsPayload = `{
+	"type": "block_actions",
+	"user": {"id": "U024BE7LH", "username": "dummy"},
+	"team": {"id": "T0001"},
+	"channel": {"id": "C0001", "name": "deploys"},
+	"response_url": "https://hooks.slack.com/actions/1",
+	"trigger_id": "trigger-1",
+	"actions": [{"action_id": "deploy", "value": "/deployterraformstaging checks/core"}]
+}`
+
+func slashCommandBody(command, text, channelID, channelName string) string {
+	return url.Values{
+		"command":      {command},
+		"text":         {text},
+		"team_id":      {"T0001"},
+		"user_id":      {"U024BE7LH"},
+		"user_name":    {"dummy"},
+		"channel_id":   {channelID},
+		"channel_name": {channelName},
+		"response_url": {"https://hooks.slack.com/commands/1"},
+		"trigger_id":   {"trigger-1"},
+	}.Encode()
+}
+
+func TestParseSlashCommand(t *testing.T) {
+	c := require.New(t)
+
+	request, err := ParseRequest([]byte(slashCommandBody("/betty", " DeployTerraformStaging  checks/core -b main", "D0001", "directmessage")))
+	c.NoError(err)
+	c.Equal("deployterraformstaging", request.Command)
+	c.Equal("checks/core -b main", request.Text)
+	c.Equal("U024BE7LH", request.UserID)
+	c.True(request.IsDirectMessage())
+
+	request, err = ParseRequest([]byte(slashCommandBody("/deployterraformstaging", "checks/core", "C0001", "deploys")))
+	c.NoError(err)
+	c.Equal("deployterraformstaging", request.Command)
+	c.Equal("checks/core", request.Text)
+	c.False(request.IsDirectMessage())
+
+	_, err = ParseRequest([]byte("text=help"))
+	c.ErrorIs(err, ErrInvalidPayload)
+
+	_, err = ParseRequest([]byte("%zz"))
+	c.ErrorIs(err, ErrInvalidPayload)
+}
+
+func TestParseBlockActions(t *testing.T) {
+	c := require.New(t)
+
+	request, err := ParseRequest([]byte(url.Values{"payload": {blockActionsPayload}}.Encode()))
+	c.NoError(err)
+	c.Equal(&Request{
+		Command:     "deployterraformstaging",
+		Text:        "checks/core",
+		TeamID:      "T0001",
+		UserID:      "U024BE7LH",
+		UserName:    "dummy",
+		ChannelID:   "C0001",
+		ChannelName: "deploys",
+		ResponseURL: "https://hooks.slack.com/actions/1",
+		TriggerID:   "trigger-1",
+	}, request)
+
+	// the interactions without a command, e.g. a closed modal, are ignored
+	request, err = ParseRequest([]byte(url.Values{"payload": {`{"type":"view_closed","user":{"id":"U024BE7LH"}}`}}.Encode()))
+	c.NoError(err)
+	c.Nil(request)
+
+	_, err = ParseRequest([]byte(url.Values{"payload": {"{"}}.Encode()))
+	c.ErrorIs(err, ErrInvalidPayload)
+}
+
+func TestRequestMessage(t *testing.T) {
+	c := require.New(t)
+
+	request := &Request{Command: "deployterraformstaging", Text: "checks/core", UserID: "U024BE7LH", ChannelID: "C0001", ChannelName: "deploys", ResponseURL: "https://hooks.slack.com/commands/1"}
+	user := &models.From{ID: 10, Email: "dummy@dummy.com", SlackID: "U024BE7LH"}
+
+	message := request.Message(user)
+	c.Equal("/deployterraformstaging checks/core", message.Message.Text)
+	c.Equal(models.Chat{ID: 10, Type: "group", Title: "deploys"}, message.Message.Chat)
+	c.Equal("dummy@dummy.com", message.From.Email)
+	c.Equal(models.EventMessage, message.EventType)
+	c.Equal(&models.SlackContext{UserID: "U024BE7LH", ChannelID: "C0001", ResponseURL: "https://hooks.slack.com/commands/1"}, message.Slack)
+}
@@ -0,0 +1,77 @@
+package slack
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"shared/shared/aws/secrets"
+
+	"bitbucket.org/truora/scrap-services/shared/env"
+)
+
+const (
+	signatureHeader = "X-Slack-Signature"
+	timestampHeader = "X-Slack-Request-Timestamp"
+	// signatureVersion prefixes the signature and the signed base string
+	signatureVersion = "v0"
+
+	// maxRequestAge rejects the replays of old signed requests
+	maxRequestAge = 5 * time.Minute
+)
+
+var (
+	// signingSecretName is the signing secret of the Slack app used to sign the requests
+	signingSecretName = env.GetString("SLACK_SIGNING_SECRET_NAME", "betty-slack-signing-secret")
+
+	getSecret = secrets.Get
+)
+
+// VerifySignature checks the X-Slack-Signature of the request is the HMAC SHA256 of v0:timestamp:body with
+// the signing secret, the requests signed more than five minutes away from now are rejected
+func VerifySignature(ctx context.Context, body []byte, timestamp, signature string, now time.Time) error {
+	secret, err := getSecret(ctx, signingSecretName)
+	if err != nil {
+		return err
+	}
+
+	if secret == "" {
+		return ErrEmptySigningSecret
+	}
+
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+
+	age := now.Sub(time.Unix(seconds, 0))
+	if age > maxRequestAge || age < -maxRequestAge {
+		return ErrExpiredRequest
+	}
+
+	prefix := signatureVersion + "="
+
+	if !strings.HasPrefix(signature, prefix) {
+		return ErrInvalidSignature
+	}
+
+	received, err := hex.DecodeString(strings.TrimPrefix(signature, prefix))
+	if err != nil {
+		return ErrInvalidSignature
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%s:%s:", signatureVersion, timestamp)
+	mac.Write(body)
+
+	if !hmac.Equal(received, mac.Sum(nil)) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
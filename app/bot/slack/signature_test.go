@@ -0,0 +1,64 @@
+package slack
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"testing"
+	"time"
+
+	"shared/shared/aws/secrets"
+
+	"github.com/stretchr/testify/require"
+)
+
+func sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "v0:%s:", timestamp)
+	mac.Write(body)
+
+	return "v0=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifySignature(t *testing.T) {
+	c := require.New(t)
+
+	secrets.InitSecretsMock()
+
+	defer secrets.DeactivateMock()
+
+	ctx := context.Background()
+	body := []byte("command=%2Fbetty&text=help")
+	now := time.Unix(1700000000, 0)
+	timestamp := "1700000000"
+
+	secrets.SetMockedSecret(signingSecretName, "")
+
+	err := VerifySignature(ctx, body, timestamp, sign("", timestamp, body), now)
+	c.ErrorIs(err, ErrEmptySigningSecret)
+
+	secrets.SetMockedSecret(signingSecretName, "signing-secret")
+
+	c.NoError(VerifySignature(ctx, body, timestamp, sign("signing-secret", timestamp, body), now))
+	c.NoError(VerifySignature(ctx, body, timestamp, sign("signing-secret", timestamp, body), now.Add(4*time.Minute)))
+
+	err = VerifySignature(ctx, body, timestamp, sign("signing-secret", timestamp, body), now.Add(6*time.Minute))
+	c.ErrorIs(err, ErrExpiredRequest)
+
+	err = VerifySignature(ctx, body, timestamp, sign("other-secret", timestamp, body), now)
+	c.ErrorIs(err, ErrInvalidSignature)
+
+	err = VerifySignature(ctx, []byte("command=%2Fbetty&text=other"), timestamp, sign("signing-secret", timestamp, body), now)
+	c.ErrorIs(err, ErrInvalidSignature)
+
+	err = VerifySignature(ctx, body, "not-a-number", sign("signing-secret", timestamp, body), now)
+	c.ErrorIs(err, ErrInvalidSignature)
+
+	err = VerifySignature(ctx, body, timestamp, "v0=not-hex", now)
+	c.ErrorIs(err, ErrInvalidSignature)
+
+	err = VerifySignature(ctx, body, timestamp, "", now)
+	c.ErrorIs(err, ErrInvalidSignature)
+}
@@ -0,0 +1,136 @@
+// Package slack receives the slash commands and the block actions of the Slack app and routes them as
+// commands of the linked bot users, they are published to the same topics of the Telegram commands
+package slack
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"shared/app/bot/i18n"
+	"shared/app/bot/router"
+	"shared/app/bot/storage"
+
+	"bitbucket.org/truora/scrap-services/logger"
+	"bitbucket.org/truora/scrap-services/shared/apigateway"
+	"bitbucket.org/truora/scrap-services/shared/cache"
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// ephemeralResponse is only shown to the user that sent the command
+const ephemeralResponse = "ephemeral"
+
+var (
+	// ErrInvalidSignature when the request signature does not match the body
+	ErrInvalidSignature = errors.New("invalid slack request signature")
+	// ErrExpiredRequest when the request was signed too long ago, e.g. a replayed request
+	ErrExpiredRequest = errors.New("expired slack request")
+	// ErrEmptySigningSecret when the signing secret is not configured
+	ErrEmptySigningSecret = errors.New("slack signing secret is empty")
+	// ErrInvalidPayload when the body is not a valid slash command or interaction
+	ErrInvalidPayload = errors.New("invalid slack payload")
+
+	defaultLogger = logger.New("bot-slack")
+
+	getUser       = storage.GetVerifiedUserBySlackID
+	routeExternal = router.RouteExternal
+	now           = time.Now
+)
+
+// response is the message Slack shows as the answer of the request
+type response struct {
+	ResponseType string `json:"response_type"`
+	Text         string `json:"text"`
+}
+
+// Init initializes the cache used by the router, it panics when the cache is invalid
+func Init(ctx context.Context) {
+	defaultLogger.Must(ctx, cache.InitFromEnv(), logger.OneDay)
+}
+
+// Handler is the handler of the Slack Lambda behind API Gateway, it answers the slash commands and the
+// block actions with an ephemeral message. The requests resent by Slack are answered without routing them
+func Handler(ctx context.Context, request *events.APIGatewayProxyRequest) (*apigateway.Response, error) {
+	body := []byte(request.Body)
+
+	if request.IsBase64Encoded {
+		decoded, err := base64.StdEncoding.DecodeString(request.Body)
+		if err != nil {
+			return &apigateway.Response{StatusCode: http.StatusBadRequest}, nil
+		}
+
+		body = decoded
+	}
+
+	err := VerifySignature(ctx, body, apigateway.GetHeader(request, timestampHeader), apigateway.GetHeader(request, signatureHeader), now())
+	if err != nil {
+		logger.Get(ctx).Error(ctx, "slack_request_rejected", logger.OneMonth, []logger.Object{
+			logger.ErrObject(err),
+			logger.APIGatewayObject(request),
+		})
+
+		return &apigateway.Response{StatusCode: http.StatusUnauthorized}, nil
+	}
+
+	slackRequest, err := ParseRequest(body)
+	if err != nil {
+		logger.Get(ctx).Error(ctx, "slack_request_invalid", logger.OneMonth, []logger.Object{logger.ErrObject(err)})
+
+		return &apigateway.Response{StatusCode: http.StatusBadRequest}, nil
+	}
+
+	if slackRequest == nil || !claimRequest(ctx, slackRequest, apigateway.GetHeader(request, retryHeader)) {
+		return &apigateway.Response{StatusCode: http.StatusOK}, nil
+	}
+
+	text, err := Route(ctx, slackRequest)
+	if err != nil {
+		logger.Get(ctx).Error(ctx, "route_slack_command_failed", logger.OneMonth, []logger.Object{
+			logger.ErrObject(err),
+			logger.MapObject("slack", map[string]interface{}{"s_user_id": slackRequest.UserID, "s_command": slackRequest.Command}),
+		})
+	}
+
+	return newResponse(text), nil
+}
+
+// Route routes the command of the request on behalf of the bot user linked to the Slack user and returns the
+// answer for the user, the users that are not linked are told how to link their Slack user
+func Route(ctx context.Context, request *Request) (string, error) {
+	if request.Command == "" {
+		return i18n.Text(ctx, "slack.empty", i18n.Vars{"root": rootCommand}), nil
+	}
+
+	user, err := getUser(ctx, request.UserID)
+	if errors.Is(err, storage.ErrUserNotFound) {
+		return i18n.Text(ctx, "slack.not_linked", i18n.Vars{"slack_id": request.UserID}), nil
+	}
+
+	if err != nil {
+		return i18n.Text(ctx, "slack.user_failed", nil), fmt.Errorf("get slack user failed: %w", err)
+	}
+
+	text, err := routeExternal(ctx, request.Message(user))
+	if text == "" {
+		text = i18n.Text(ctx, "slack.command_failed", i18n.Vars{"command": request.Command})
+	}
+
+	return text, err
+}
+
+func newResponse(text string) *apigateway.Response {
+	body, err := json.Marshal(response{ResponseType: ephemeralResponse, Text: text})
+	if err != nil {
+		return &apigateway.Response{StatusCode: http.StatusInternalServerError}
+	}
+
+	return &apigateway.Response{
+		StatusCode: http.StatusOK,
+		Headers:    map[string]string{"Content-Type": "application/json"},
+		Body:       string(body),
+	}
+}
@@ -0,0 +1,186 @@
+package slack
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"shared/app/bot/models"
+	"shared/app/bot/router"
+	"shared/app/bot/storage"
+	"shared/shared/aws/secrets"
+
+	"bitbucket.org/truora/scrap-services/shared/cache"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/require"
+)
+
+func setUp(c *require.Assertions) (*[]*models.CallbackMessage, func()) {
+	cache.InitMock()
+	storage.InitDynamoMock()
+	secrets.InitSecretsMock()
+	secrets.SetMockedSecret(signingSecretName, "signing-secret")
+
+	ctx := context.Background()
+
+	c.NoError(storage.PutUser(ctx, &models.From{ID: 10, Email: "dummy@dummy.com", SlackID: "U024BE7LH", EmailVerified: true, UserRole: models.RoleDevelopers}))
+
+	published := []*models.CallbackMessage{}
+
+	router.SetPublisher(func(ctx context.Context, topic, attribute string, message *models.CallbackMessage) error {
+		published = append(published, message)
+
+		return nil
+	})
+
+	now = func() time.Time {
+		return time.Unix(1700000000, 0)
+	}
+
+	return &published, func() {
+		router.SetPublisher(nil)
+		secrets.DeactivateMock()
+
+		now = time.Now
+	}
+}
+
+func newSlackRequest(body, signature string) *events.APIGatewayProxyRequest {
+	return &events.APIGatewayProxyRequest{
+		Headers: map[string]string{
+			"X-Slack-Request-Timestamp": "1700000000",
+			"X-Slack-Signature":         signature,
+		},
+		Body: body,
+	}
+}
+
+func responseText(c *require.Assertions, response string) string {
+	body := &struct {
+		ResponseType string `json:"response_type"`
+		Text         string `json:"text"`
+	}{}
+
+	c.NoError(json.Unmarshal([]byte(response), body))
+	c.Equal("ephemeral", body.ResponseType)
+
+	return body.Text
+}
+
+func TestHandler(t *testing.T) {
+	c := require.New(t)
+
+	published, tearDown := setUp(c)
+
+	defer tearDown()
+
+	ctx := context.Background()
+	body := slashCommandBody("/betty", "deployterraformstaging checks/core", "C0001", "deploys")
+
+	response, err := Handler(ctx, newSlackRequest(body, sign("signing-secret", "1700000000", []byte(body))))
+	c.NoError(err)
+	c.Equal(http.StatusOK, response.StatusCode)
+	c.Contains(responseText(c, response.Body), "Accepted /deployterraformstaging (id: ")
+
+	c.Len(*published, 1)
+
+	message := (*published)[0]
+	c.Equal("dummy@dummy.com", message.From.Email)
+	c.Equal("checks/core", message.Args["project"])
+	c.Equal("https://hooks.slack.com/commands/1", message.Slack.ResponseURL)
+
+	body = strings.Replace(body, "trigger-1", "trigger-2", 1)
+
+	request := newSlackRequest(base64.StdEncoding.EncodeToString([]byte(body)), sign("signing-secret", "1700000000", []byte(body)))
+	request.IsBase64Encoded = true
+
+	response, err = Handler(ctx, request)
+	c.NoError(err)
+	c.Equal(http.StatusOK, response.StatusCode)
+	c.Len(*published, 2)
+
+	response, err = Handler(ctx, newSlackRequest(body, sign("other-secret", "1700000000", []byte(body))))
+	c.NoError(err)
+	c.Equal(http.StatusUnauthorized, response.StatusCode)
+	c.Len(*published, 2)
+
+	invalid := "text=help"
+
+	response, err = Handler(ctx, newSlackRequest(invalid, sign("signing-secret", "1700000000", []byte(invalid))))
+	c.NoError(err)
+	c.Equal(http.StatusBadRequest, response.StatusCode)
+}
+
+func TestHandlerRetry(t *testing.T) {
+	c := require.New(t)
+
+	published, tearDown := setUp(c)
+
+	defer tearDown()
+
+	ctx := context.Background()
+	body := slashCommandBody("/betty", "deployterraformstaging checks/core", "C0001", "deploys")
+
+	response, err := Handler(ctx, newSlackRequest(body, sign("signing-secret", "1700000000", []byte(body))))
+	c.NoError(err)
+	c.Equal(http.StatusOK, response.StatusCode)
+	c.Len(*published, 1)
+
+	// Slack resends the request with the same trigger_id when it was not answered in time
+	retried := newSlackRequest(body, sign("signing-secret", "1700000000", []byte(body)))
+	retried.Headers["X-Slack-Retry-Num"] = "1"
+
+	response, err = Handler(ctx, retried)
+	c.NoError(err)
+	c.Equal(http.StatusOK, response.StatusCode)
+	c.Empty(response.Body)
+	c.Len(*published, 1)
+
+	// a resent request without trigger_id can not be matched, it is skipped
+	body = strings.Replace(body, "trigger_id=trigger-1", "", 1)
+
+	retried = newSlackRequest(body, sign("signing-secret", "1700000000", []byte(body)))
+	retried.Headers["X-Slack-Retry-Num"] = "1"
+
+	response, err = Handler(ctx, retried)
+	c.NoError(err)
+	c.Equal(http.StatusOK, response.StatusCode)
+	c.Empty(response.Body)
+	c.Len(*published, 1)
+}
+
+func TestHandlerNotLinked(t *testing.T) {
+	c := require.New(t)
+
+	published, tearDown := setUp(c)
+
+	defer tearDown()
+
+	text, err := Route(context.Background(), &Request{Command: "deployterraformstaging", Text: "checks/core", UserID: "U0000"})
+	c.NoError(err)
+	c.Contains(text, "Your Slack user is not linked to a bot user")
+	c.Empty(*published)
+}
+
+func TestRoute(t *testing.T) {
+	c := require.New(t)
+
+	published, tearDown := setUp(c)
+
+	defer tearDown()
+
+	ctx := context.Background()
+
+	text, err := Route(ctx, &Request{UserID: "U024BE7LH"})
+	c.NoError(err)
+	c.Contains(text, "Please send a command")
+
+	text, err = Route(ctx, &Request{Command: "users", UserID: "U024BE7LH"})
+	c.NoError(err)
+	c.Equal("/users is only available in Telegram", text)
+	c.Empty(*published)
+}
@@ -0,0 +1,208 @@
+// Package audit stores the records of the commands routed by the bot. The records expire after the
+// retention and they are indexed by time in ordered sets of the team, each user, each command and each
+// command of each user
+package audit
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"shared/app/bot/models"
+
+	"bitbucket.org/truora/scrap-services/shared/cache"
+	"bitbucket.org/truora/scrap-services/shared/env"
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	// recordsKey is the ordered set of the IDs of every record scored by creation time in unix nanoseconds
+	recordsKey        = "BOT-AUDIT"
+	userRecordsKey    = "BOT-AUDIT-USER:%d"
+	commandRecordsKey = "BOT-AUDIT-COMMAND:%s"
+	// userCommandRecordsKey indexes the records of a command of a user so /history <command> reads only them
+	userCommandRecordsKey = "BOT-AUDIT-USER-COMMAND:%d:%s"
+	recordKey             = "BOT-AUDIT-RECORD:%s"
+
+	idLength = 8
+)
+
+var (
+	// ErrRecordNotFound when the record does not exist or it expired
+	ErrRecordNotFound = errors.New("audit record not found")
+
+	retentionDays = env.GetInt64("AUDIT_RETENTION_DAYS", 30)
+	// Retention is the time the records are kept
+	Retention = time.Duration(retentionDays) * 24 * time.Hour
+	// MaxPerIndex is the maximum of records kept in each index, the oldest ones are dropped first
+	MaxPerIndex = int(env.GetInt64("AUDIT_MAX_RECORDS", 500))
+)
+
+func newID() (string, error) {
+	id := make([]byte, idLength)
+
+	_, err := rand.Read(id)
+	if err != nil {
+		return "", fmt.Errorf("generate audit ID failed: %w", err)
+	}
+
+	return hex.EncodeToString(id), nil
+}
+
+// Save stores the record and adds it to the team, user, command and user command indexes
+func Save(ctx context.Context, record *models.AuditRecord) error {
+	id, err := newID()
+	if err != nil {
+		return err
+	}
+
+	record.ID = id
+
+	rawData, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	err = cache.Add(ctx, fmt.Sprintf(recordKey, record.ID), string(rawData), Retention)
+	if err != nil {
+		return fmt.Errorf("store audit record failed: %w", err)
+	}
+
+	keys := []string{
+		recordsKey,
+		fmt.Sprintf(userRecordsKey, record.UserID),
+		fmt.Sprintf(commandRecordsKey, record.Command),
+		fmt.Sprintf(userCommandRecordsKey, record.UserID, record.Command),
+	}
+
+	for _, key := range keys {
+		err = cache.AddToOrderedSetWithOption(ctx, key, record.ID, float64(record.CreatedAt.UnixNano()), cache.OnlyAdd)
+		if err != nil {
+			return fmt.Errorf("index audit record failed: %w", err)
+		}
+	}
+
+	return trim(ctx, keys)
+}
+
+// trim renews the expiration of the indexes and drops their records over MaxPerIndex in one round trip,
+// the records are ranked by creation time so the oldest ones are dropped
+func trim(ctx context.Context, keys []string) error {
+	pipe := cache.GetPipeliner()
+
+	for _, key := range keys {
+		pipe.Expire(ctx, key, Retention)
+		pipe.ZRemRangeByRank(ctx, key, 0, int64(-MaxPerIndex-1))
+	}
+
+	_, err := pipe.Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("trim audit indexes failed: %w", err)
+	}
+
+	return nil
+}
+
+// Get returns the record
+func Get(ctx context.Context, id string) (*models.AuditRecord, error) {
+	rawData, err := cache.Get(ctx, fmt.Sprintf(recordKey, id))
+	if errors.Is(err, cache.ErrKeyNotExists) {
+		return nil, ErrRecordNotFound
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("read audit record failed: %w", err)
+	}
+
+	return decode(rawData)
+}
+
+func decode(rawData string) (*models.AuditRecord, error) {
+	record := &models.AuditRecord{}
+
+	err := json.Unmarshal([]byte(rawData), record)
+	if err != nil {
+		return nil, fmt.Errorf("audit record json unmarshal error: %w", err)
+	}
+
+	return record, nil
+}
+
+// List returns the latest records of the team, newest first
+func List(ctx context.Context, limit int) ([]*models.AuditRecord, error) {
+	return list(ctx, recordsKey, limit)
+}
+
+// ListByUser returns the latest records of the user, newest first
+func ListByUser(ctx context.Context, userID int64, limit int) ([]*models.AuditRecord, error) {
+	return list(ctx, fmt.Sprintf(userRecordsKey, userID), limit)
+}
+
+// ListByCommand returns the latest records of the command, newest first
+func ListByCommand(ctx context.Context, command string, limit int) ([]*models.AuditRecord, error) {
+	return list(ctx, fmt.Sprintf(commandRecordsKey, command), limit)
+}
+
+// ListByUserCommand returns the latest records of the command of the user, newest first
+func ListByUserCommand(ctx context.Context, userID int64, command string, limit int) ([]*models.AuditRecord, error) {
+	return list(ctx, fmt.Sprintf(userCommandRecordsKey, userID, command), limit)
+}
+
+// list reads the newest limit IDs of the index and their records in one round trip, the IDs of the
+// expired records are dropped from the index so the list can return less than limit records
+func list(ctx context.Context, key string, limit int) ([]*models.AuditRecord, error) {
+	if limit <= 0 {
+		return []*models.AuditRecord{}, nil
+	}
+
+	ids, err := cache.GetClient().ZRevRange(ctx, key, 0, int64(limit-1)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("read audit index failed: %w", err)
+	}
+
+	records := []*models.AuditRecord{}
+
+	if len(ids) == 0 {
+		return records, nil
+	}
+
+	// the records are read with a pipeline instead of MGET, their keys are in different cluster slots
+	pipe := cache.GetPipeliner()
+	reads := make([]*redis.StringCmd, 0, len(ids))
+
+	for _, id := range ids {
+		reads = append(reads, pipe.Get(ctx, fmt.Sprintf(recordKey, id)))
+	}
+
+	_, err = pipe.Exec(ctx)
+	if err != nil && !errors.Is(err, cache.ErrKeyNotExists) {
+		return nil, fmt.Errorf("read audit records failed: %w", err)
+	}
+
+	for i, read := range reads {
+		rawData, err := read.Result()
+		if errors.Is(err, cache.ErrKeyNotExists) {
+			// the record expired after the retention
+			_ = cache.RemoveFromToOrderedSet(ctx, key, ids[i])
+
+			continue
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("read audit record failed: %w", err)
+		}
+
+		record, err := decode(rawData)
+		if err != nil {
+			return nil, err
+		}
+
+		records = append(records, record)
+	}
+
+	return records, nil
+}
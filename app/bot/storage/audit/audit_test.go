@@ -0,0 +1,124 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"shared/app/bot/models"
+
+	"bitbucket.org/truora/scrap-services/shared/cache"
+	"github.com/stretchr/testify/require"
+)
+
+func saveTestRecord(c *require.Assertions, userID int64, command string, createdAt time.Time) *models.AuditRecord {
+	record := &models.AuditRecord{
+		UserID:    userID,
+		ChatID:    userID,
+		Command:   command,
+		Args:      map[string]string{"project": "checks/core"},
+		Outcome:   models.OutcomePublished,
+		Latency:   120 * time.Millisecond,
+		CreatedAt: createdAt,
+	}
+
+	c.NoError(Save(context.Background(), record))
+	c.NotEmpty(record.ID)
+
+	return record
+}
+
+func TestSaveList(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+
+	ctx := context.Background()
+	now := time.Now()
+
+	first := saveTestRecord(c, 1, "deployterraformstaging", now.Add(-2*time.Minute))
+	second := saveTestRecord(c, 2, "deployterraformstaging", now.Add(-time.Minute))
+	third := saveTestRecord(c, 1, "help", now)
+
+	records, err := List(ctx, 10)
+	c.NoError(err)
+	c.Len(records, 3)
+	c.Equal(third.ID, records[0].ID)
+	c.Equal(first.ID, records[2].ID)
+
+	records, err = ListByUser(ctx, 1, 10)
+	c.NoError(err)
+	c.Len(records, 2)
+	c.Equal(third.ID, records[0].ID)
+	c.Equal("checks/core", records[1].Args["project"])
+
+	records, err = ListByCommand(ctx, "deployterraformstaging", 1)
+	c.NoError(err)
+	c.Len(records, 1)
+	c.Equal(second.ID, records[0].ID)
+	c.Equal(models.OutcomePublished, records[0].Outcome)
+	c.Equal(120*time.Millisecond, records[0].Latency)
+
+	records, err = ListByUserCommand(ctx, 1, "deployterraformstaging", 10)
+	c.NoError(err)
+	c.Len(records, 1)
+	c.Equal(first.ID, records[0].ID)
+}
+
+func TestSaveTrimsIndexes(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+
+	oldMaxPerIndex := MaxPerIndex
+	MaxPerIndex = 2
+
+	defer func() {
+		MaxPerIndex = oldMaxPerIndex
+	}()
+
+	ctx := context.Background()
+	now := time.Now()
+
+	saveTestRecord(c, 1, "help", now.Add(-2*time.Minute))
+	second := saveTestRecord(c, 1, "help", now.Add(-time.Minute))
+	third := saveTestRecord(c, 1, "help", now)
+
+	records, err := ListByUser(ctx, 1, 10)
+	c.NoError(err)
+	c.Len(records, 2)
+	c.Equal(third.ID, records[0].ID)
+	c.Equal(second.ID, records[1].ID)
+
+	for _, key := range []string{recordsKey, fmt.Sprintf(commandRecordsKey, "help"), fmt.Sprintf(userCommandRecordsKey, 1, "help")} {
+		ids, err := cache.GetAllOrderedSetMembers(ctx, key)
+		c.NoError(err)
+		c.Equal([]string{second.ID, third.ID}, ids)
+	}
+}
+
+func TestListSkipsExpiredRecords(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+
+	ctx := context.Background()
+
+	expired := saveTestRecord(c, 1, "help", time.Now().Add(-time.Minute))
+	saveTestRecord(c, 1, "help", time.Now())
+
+	c.NoError(cache.Del(ctx, "BOT-AUDIT-RECORD:"+expired.ID))
+
+	records, err := List(ctx, 10)
+	c.NoError(err)
+	c.Len(records, 1)
+
+	_, err = Get(ctx, expired.ID)
+	c.ErrorIs(err, ErrRecordNotFound)
+
+	// the expired record is dropped from the index
+	ids, err := cache.GetAllOrderedSetMembers(ctx, recordsKey)
+	c.NoError(err)
+	c.NotContains(ids, expired.ID)
+}
@@ -12,7 +12,8 @@ import (
 	"bitbucket.org/truora/scrap-services/shared/env"
 )
 
-const botConversationDataKey = "BOT-CONVERSATION-DATA:%d"
+// botConversationDataKey is keyed by chat and user so a user can talk to the bot in a group and in private at the same time
+const botConversationDataKey = "BOT-CONVERSATION-DATA:%d:%d"
 
 var (
 	conversationMinutes        = env.GetInt64("CONVERSATION_MINUTES", 15)
@@ -26,7 +27,7 @@ var (
 
 // GetConversationState returns the conversation data stored in cache
 func GetConversationState(ctx context.Context, message models.Message) (*models.ConversationState, error) {
-	rawData, err := cache.Get(ctx, fmt.Sprintf(botConversationDataKey, message.From.ID))
+	rawData, err := cache.Get(ctx, conversationKey(message))
 	if errors.Is(err, cache.ErrKeyNotExists) {
 		return nil, ErrConversationNotFound
 	}
@@ -42,20 +43,32 @@ func GetConversationState(ctx context.Context, message models.Message) (*models.
 		return nil, fmt.Errorf("cache data json unmarshal error: %w", err)
 	}
 
+	if conversationState.Wizard != nil && conversationState.Version != WizardSchemaVersion {
+		return nil, ErrConversationNotFound
+	}
+
 	return conversationState, nil
 }
 
 // StoreConversationState stores the conversation data in cache
 func StoreConversationState(ctx context.Context, message models.Message, conversationState *models.ConversationState) error {
+	return storeConversationState(ctx, message, conversationState, conversationExpirationTime)
+}
+
+func storeConversationState(ctx context.Context, message models.Message, conversationState *models.ConversationState, expiration time.Duration) error {
 	rawData, err := marshal(conversationState)
 	if err != nil {
 		return err
 	}
 
-	return cache.Add(ctx, fmt.Sprintf(botConversationDataKey, message.From.ID), string(rawData), conversationExpirationTime)
+	return cache.Add(ctx, conversationKey(message), string(rawData), expiration)
 }
 
 // DeleteConversationState deletes the conversation data in cache
 func DeleteConversationState(ctx context.Context, message models.Message) error {
-	return cache.Del(ctx, fmt.Sprintf(botConversationDataKey, message.From.ID))
+	return cache.Del(ctx, conversationKey(message))
+}
+
+func conversationKey(message models.Message) string {
+	return fmt.Sprintf(botConversationDataKey, message.Chat.ID, message.From.ID)
 }
@@ -44,6 +44,39 @@ func TestConversationState(t *testing.T) {
 	c.Equal(ErrConversationNotFound, err)
 }
 
+func TestConversationStatePerChat(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+
+	ctx := context.Background()
+	privateMessage := models.Message{From: models.From{ID: 1}, Chat: models.Chat{ID: 1, Type: "private"}}
+	groupMessage := models.Message{From: models.From{ID: 1}, Chat: models.Chat{ID: -100, Type: "group"}}
+
+	err := StoreConversationState(ctx, privateMessage, &models.ConversationState{Command: "private"})
+	c.NoError(err)
+
+	err = StoreConversationState(ctx, groupMessage, &models.ConversationState{Command: "group"})
+	c.NoError(err)
+
+	conversationState, err := GetConversationState(ctx, privateMessage)
+	c.NoError(err)
+	c.Equal("private", conversationState.Command)
+
+	conversationState, err = GetConversationState(ctx, groupMessage)
+	c.NoError(err)
+	c.Equal("group", conversationState.Command)
+
+	err = DeleteConversationState(ctx, groupMessage)
+	c.NoError(err)
+
+	_, err = GetConversationState(ctx, groupMessage)
+	c.Equal(ErrConversationNotFound, err)
+
+	_, err = GetConversationState(ctx, privateMessage)
+	c.NoError(err)
+}
+
 func TestStoreConversationStateError(t *testing.T) {
 	c := require.New(t)
 
@@ -0,0 +1,291 @@
+package conversation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"shared/app/bot/i18n"
+	"shared/app/bot/models"
+)
+
+const (
+	// WizardSchemaVersion is the version of the wizard state stored in cache, increase it when
+	// models.WizardState changes in an incompatible way so old states are discarded
+	WizardSchemaVersion = 1
+
+	// BackAnswer goes back to the previous step
+	BackAnswer = "/back"
+	// CancelAnswer cancels the wizard, Next can also return it to cancel the wizard
+	CancelAnswer = "/cancel"
+	// EndStep returned by Next finishes the wizard
+	EndStep = ""
+
+	// expirationLeeway keeps the state in cache after the step timeout so the user is told it timed out
+	expirationLeeway = time.Minute
+)
+
+var (
+	// ErrInvalidWizard when the wizard declaration is invalid
+	ErrInvalidWizard = errors.New("invalid wizard declaration")
+	// ErrUnknownStep when the stored step is not declared in the wizard
+	ErrUnknownStep = errors.New("unknown wizard step")
+
+	// errUploadRequired when a step that asks for a file is answered with text
+	errUploadRequired = i18n.NewError("conversation.upload_required", nil)
+	// errUploadNotExpected when a step that asks for text is answered with a file
+	errUploadNotExpected = i18n.NewError("conversation.upload_not_expected", nil)
+
+	now = time.Now
+)
+
+// Step is a step of a wizard
+type Step struct {
+	Name string
+	// Prompt is the catalog key of the question of the step, it is sent as it is when no catalog has it
+	Prompt string
+	// Vars are the values of the placeholders of the prompt
+	Vars i18n.Vars
+	// Validate checks the answer, the error is translated when it is an i18n.Error and sent to the user and
+	// the step is asked again
+	Validate func(answer string, answers map[string]string) error
+	// Next returns the step that follows the answer, it allows branching. When nil NextStep is used
+	Next func(answer string, answers map[string]string) string
+	// NextStep is the step that follows, EndStep finishes the wizard
+	NextStep string
+	// Timeout to answer the step, zero uses the wizard timeout
+	Timeout time.Duration
+	// Upload steps are answered with a file, the answer is the file ID and the file is kept in the state
+	Upload bool
+}
+
+// Wizard is a multi-step conversation declared as named steps, the first step starts the wizard
+type Wizard struct {
+	Command string
+	Steps   []*Step
+	// Timeout to answer each step, zero uses the conversation expiration time
+	Timeout time.Duration
+
+	steps map[string]*Step
+}
+
+// Result is the outcome of starting a wizard or handling an answer
+type Result struct {
+	// Reply is the message for the user, e.g. the next prompt or the validation error
+	Reply    string
+	Done     bool
+	Canceled bool
+	TimedOut bool
+	// Answers by step name, only present when the wizard is done
+	Answers map[string]string
+	// Uploads are the files sent as answers, only present when the wizard is done
+	Uploads []*models.Upload
+}
+
+// NewWizard creates a wizard for the command with the given steps
+func NewWizard(command string, steps ...*Step) (*Wizard, error) {
+	if command == "" || len(steps) == 0 {
+		return nil, fmt.Errorf("%w: missing command or steps", ErrInvalidWizard)
+	}
+
+	wizard := &Wizard{Command: command, Steps: steps, steps: map[string]*Step{}}
+
+	for _, step := range steps {
+		if step.Name == "" || step.Name == CancelAnswer {
+			return nil, fmt.Errorf("%w: %s has a step with an invalid name", ErrInvalidWizard, command)
+		}
+
+		if _, ok := wizard.steps[step.Name]; ok {
+			return nil, fmt.Errorf("%w: %s step %s is declared twice", ErrInvalidWizard, command, step.Name)
+		}
+
+		wizard.steps[step.Name] = step
+	}
+
+	for _, step := range steps {
+		if _, ok := wizard.steps[step.NextStep]; step.Next == nil && step.NextStep != EndStep && !ok {
+			return nil, fmt.Errorf("%w: %s step %s goes to unknown step %s", ErrInvalidWizard, command, step.Name, step.NextStep)
+		}
+	}
+
+	return wizard, nil
+}
+
+// MustNewWizard creates a wizard and panics if the declaration is invalid
+func MustNewWizard(command string, steps ...*Step) *Wizard {
+	wizard, err := NewWizard(command, steps...)
+	if err != nil {
+		panic(err)
+	}
+
+	return wizard
+}
+
+// Start stores a new wizard state for the message chat and user and returns the first prompt
+func (w *Wizard) Start(ctx context.Context, message models.Message) (*Result, error) {
+	first := w.Steps[0]
+	state := &models.ConversationState{
+		Version: WizardSchemaVersion,
+		Command: w.Command,
+		Wizard: &models.WizardState{
+			Step:    first.Name,
+			History: []string{},
+			Answers: map[string]string{},
+		},
+	}
+
+	err := w.store(ctx, message, state, first)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Result{Reply: first.prompt(ctx)}, nil
+}
+
+// Handle processes the message text as the answer of the current step of the stored state
+func (w *Wizard) Handle(ctx context.Context, message models.Message, state *models.ConversationState) (*Result, error) {
+	return w.handle(ctx, message, state, nil)
+}
+
+// HandleUpload processes the file as the answer of the current step of the stored state, the file must
+// be already checked and resolved by the caller
+func (w *Wizard) HandleUpload(ctx context.Context, message models.Message, state *models.ConversationState, upload *models.Upload) (*Result, error) {
+	return w.handle(ctx, message, state, upload)
+}
+
+func (w *Wizard) handle(ctx context.Context, message models.Message, state *models.ConversationState, upload *models.Upload) (*Result, error) {
+	wizardState := state.Wizard
+
+	step, ok := w.steps[wizardState.Step]
+	if !ok {
+		return nil, w.finish(ctx, message, fmt.Errorf("%w: %s", ErrUnknownStep, wizardState.Step))
+	}
+
+	if now().Unix() > wizardState.ExpiresAt {
+		return &Result{TimedOut: true, Reply: i18n.Text(ctx, "conversation.timed_out", nil)}, w.finish(ctx, message, nil)
+	}
+
+	answer := strings.TrimSpace(message.Text)
+
+	switch answer {
+	case CancelAnswer:
+		return &Result{Canceled: true, Reply: i18n.Text(ctx, "conversation.canceled", nil)}, w.finish(ctx, message, nil)
+	case BackAnswer:
+		return w.back(ctx, message, state, step)
+	}
+
+	if upload != nil {
+		answer = upload.FileID
+	}
+
+	if step.Upload != (upload != nil) {
+		return w.retry(ctx, message, state, step, uploadMismatch(step))
+	}
+
+	if step.Validate != nil {
+		err := step.Validate(answer, wizardState.Answers)
+		if err != nil {
+			return w.retry(ctx, message, state, step, err)
+		}
+	}
+
+	wizardState.Answers[step.Name] = answer
+
+	if upload != nil {
+		wizardState.Uploads = append(wizardState.Uploads, upload)
+	}
+
+	next := step.NextStep
+	if step.Next != nil {
+		next = step.Next(answer, wizardState.Answers)
+	}
+
+	if next == CancelAnswer {
+		return &Result{Canceled: true, Reply: i18n.Text(ctx, "conversation.canceled", nil)}, w.finish(ctx, message, nil)
+	}
+
+	if next == EndStep {
+		return &Result{Done: true, Answers: wizardState.Answers, Uploads: wizardState.Uploads}, w.finish(ctx, message, nil)
+	}
+
+	nextStep, ok := w.steps[next]
+	if !ok {
+		return nil, w.finish(ctx, message, fmt.Errorf("%w: %s", ErrUnknownStep, next))
+	}
+
+	wizardState.History = append(wizardState.History, step.Name)
+	wizardState.Step = nextStep.Name
+
+	return &Result{Reply: nextStep.prompt(ctx)}, w.store(ctx, message, state, nextStep)
+}
+
+func (w *Wizard) back(ctx context.Context, message models.Message, state *models.ConversationState, step *Step) (*Result, error) {
+	wizardState := state.Wizard
+
+	if len(wizardState.History) == 0 {
+		return &Result{Reply: step.prompt(ctx)}, w.store(ctx, message, state, step)
+	}
+
+	previous := w.steps[wizardState.History[len(wizardState.History)-1]]
+	wizardState.History = wizardState.History[:len(wizardState.History)-1]
+	wizardState.Step = previous.Name
+
+	delete(wizardState.Answers, previous.Name)
+
+	if previous.Upload && len(wizardState.Uploads) > 0 {
+		wizardState.Uploads = wizardState.Uploads[:len(wizardState.Uploads)-1]
+	}
+
+	return &Result{Reply: previous.prompt(ctx)}, w.store(ctx, message, state, previous)
+}
+
+// retry tells the user why the answer was rejected and asks the step again
+func (w *Wizard) retry(ctx context.Context, message models.Message, state *models.ConversationState, step *Step, err error) (*Result, error) {
+	return &Result{Reply: fmt.Sprintf("%s\n%s", i18n.ErrorText(ctx, err), step.prompt(ctx))}, w.store(ctx, message, state, step)
+}
+
+// uploadMismatch returns why the answer does not match the kind of answer the step asks for
+func uploadMismatch(step *Step) error {
+	if step.Upload {
+		return errUploadRequired
+	}
+
+	return errUploadNotExpected
+}
+
+// prompt returns the question of the step in the language of the context
+func (step *Step) prompt(ctx context.Context) string {
+	return i18n.Text(ctx, step.Prompt, step.Vars)
+}
+
+// store saves the state with the timeout of the step that is being asked
+func (w *Wizard) store(ctx context.Context, message models.Message, state *models.ConversationState, step *Step) error {
+	timeout := w.stepTimeout(step)
+	state.Wizard.ExpiresAt = now().Add(timeout).Unix()
+
+	return storeConversationState(ctx, message, state, timeout+expirationLeeway)
+}
+
+// finish deletes the wizard state, the given error is returned unless deleting the state fails
+func (w *Wizard) finish(ctx context.Context, message models.Message, err error) error {
+	deleteErr := DeleteConversationState(ctx, message)
+	if deleteErr != nil {
+		return deleteErr
+	}
+
+	return err
+}
+
+func (w *Wizard) stepTimeout(step *Step) time.Duration {
+	if step.Timeout > 0 {
+		return step.Timeout
+	}
+
+	if w.Timeout > 0 {
+		return w.Timeout
+	}
+
+	return conversationExpirationTime
+}
@@ -0,0 +1,291 @@
+package conversation
+
+import (
+	"context"
+	"errors"
+	"shared/app/bot/i18n"
+	"shared/app/bot/models"
+	"testing"
+	"time"
+
+	"bitbucket.org/truora/scrap-services/shared/cache"
+	"github.com/stretchr/testify/require"
+)
+
+var errEmptyAnswer = errors.New("the answer can not be empty")
+
+func newTestWizard() *Wizard {
+	return MustNewWizard("deploy",
+		&Step{
+			Name:   "project",
+			Prompt: "Which project?",
+			Validate: func(answer string, answers map[string]string) error {
+				if answer == "" {
+					return errEmptyAnswer
+				}
+
+				return nil
+			},
+			NextStep: "confirm",
+		},
+		&Step{
+			Name:   "confirm",
+			Prompt: "Are you sure? yes/no",
+			Next: func(answer string, answers map[string]string) string {
+				if answer == "yes" {
+					return EndStep
+				}
+
+				return CancelAnswer
+			},
+			Timeout: time.Minute,
+		},
+	)
+}
+
+func newWizardMessage(text string) models.Message {
+	return models.Message{Text: text, From: models.From{ID: 1}, Chat: models.Chat{ID: 1, Type: "private"}}
+}
+
+func answerWizard(c *require.Assertions, wizard *Wizard, text string) *Result {
+	ctx := context.Background()
+	message := newWizardMessage(text)
+
+	state, err := GetConversationState(ctx, message)
+	c.NoError(err)
+
+	result, err := wizard.Handle(ctx, message, state)
+	c.NoError(err)
+
+	return result
+}
+
+func TestNewWizardInvalid(t *testing.T) {
+	c := require.New(t)
+
+	_, err := NewWizard("deploy")
+	c.ErrorIs(err, ErrInvalidWizard)
+
+	_, err = NewWizard("deploy", &Step{Name: "project"}, &Step{Name: "project"})
+	c.ErrorIs(err, ErrInvalidWizard)
+
+	_, err = NewWizard("deploy", &Step{Name: "project", NextStep: "branch"})
+	c.ErrorIs(err, ErrInvalidWizard)
+
+	c.Panics(func() { MustNewWizard("", &Step{Name: "project"}) })
+}
+
+func TestWizardDone(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+
+	wizard := newTestWizard()
+
+	result, err := wizard.Start(context.Background(), newWizardMessage("/deploy"))
+	c.NoError(err)
+	c.Equal("Which project?", result.Reply)
+
+	state, err := GetConversationState(context.Background(), newWizardMessage(""))
+	c.NoError(err)
+	c.Equal(WizardSchemaVersion, state.Version)
+	c.Equal("deploy", state.Command)
+	c.Equal("project", state.Wizard.Step)
+
+	result = answerWizard(c, wizard, "")
+	c.Equal("the answer can not be empty\nWhich project?", result.Reply)
+	c.False(result.Done)
+
+	result = answerWizard(c, wizard, "checks/core")
+	c.Equal("Are you sure? yes/no", result.Reply)
+
+	result = answerWizard(c, wizard, "yes")
+	c.True(result.Done)
+	c.Equal(map[string]string{"project": "checks/core", "confirm": "yes"}, result.Answers)
+
+	_, err = GetConversationState(context.Background(), newWizardMessage(""))
+	c.Equal(ErrConversationNotFound, err)
+}
+
+func TestWizardBackAndCancel(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+
+	wizard := newTestWizard()
+
+	_, err := wizard.Start(context.Background(), newWizardMessage("/deploy"))
+	c.NoError(err)
+
+	result := answerWizard(c, wizard, BackAnswer)
+	c.Equal("Which project?", result.Reply)
+
+	answerWizard(c, wizard, "checks/core")
+
+	result = answerWizard(c, wizard, BackAnswer)
+	c.Equal("Which project?", result.Reply)
+
+	state, err := GetConversationState(context.Background(), newWizardMessage(""))
+	c.NoError(err)
+	c.Empty(state.Wizard.Answers)
+	c.Empty(state.Wizard.History)
+
+	result = answerWizard(c, wizard, CancelAnswer)
+	c.True(result.Canceled)
+
+	_, err = GetConversationState(context.Background(), newWizardMessage(""))
+	c.Equal(ErrConversationNotFound, err)
+}
+
+func TestWizardBranchCancel(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+
+	wizard := newTestWizard()
+
+	_, err := wizard.Start(context.Background(), newWizardMessage("/deploy"))
+	c.NoError(err)
+
+	answerWizard(c, wizard, "checks/core")
+
+	result := answerWizard(c, wizard, "no")
+	c.True(result.Canceled)
+	c.False(result.Done)
+}
+
+func TestWizardTimeout(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+
+	defer func() { now = time.Now }()
+
+	wizard := newTestWizard()
+
+	_, err := wizard.Start(context.Background(), newWizardMessage("/deploy"))
+	c.NoError(err)
+
+	answerWizard(c, wizard, "checks/core")
+
+	now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+
+	result := answerWizard(c, wizard, "yes")
+	c.True(result.TimedOut)
+
+	_, err = GetConversationState(context.Background(), newWizardMessage(""))
+	c.Equal(ErrConversationNotFound, err)
+}
+
+func TestWizardTranslated(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+
+	ctx := i18n.WithLanguage(context.Background(), i18n.Spanish)
+	wizard := MustNewWizard("deploy", &Step{
+		Name:     "project",
+		Prompt:   "wizard.deployterraformstaging.project",
+		Validate: func(answer string, answers map[string]string) error { return i18n.NewError("wizard.empty_answer", nil) },
+	})
+
+	result, err := wizard.Start(ctx, newWizardMessage("/deploy"))
+	c.NoError(err)
+	c.Equal("¿Qué proyecto de terraform quieres desplegar? p. ej. checks/core", result.Reply)
+
+	state, err := GetConversationState(ctx, newWizardMessage(""))
+	c.NoError(err)
+
+	result, err = wizard.Handle(ctx, newWizardMessage(""), state)
+	c.NoError(err)
+	c.Equal("la respuesta no puede estar vacía\n¿Qué proyecto de terraform quieres desplegar? p. ej. checks/core", result.Reply)
+
+	result, err = wizard.Handle(ctx, newWizardMessage(CancelAnswer), state)
+	c.NoError(err)
+	c.Equal("Cancelado", result.Reply)
+}
+
+func TestWizardUpload(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+
+	ctx := context.Background()
+	wizard := MustNewWizard("report",
+		&Step{Name: "file", Prompt: "Which file?", Upload: true, NextStep: "title"},
+		&Step{Name: "title", Prompt: "Which title?"},
+	)
+
+	_, err := wizard.Start(ctx, newWizardMessage("/report"))
+	c.NoError(err)
+
+	result := answerWizard(c, wizard, "report.pdf")
+	c.Equal("please send a file\nWhich file?", result.Reply)
+
+	state, err := GetConversationState(ctx, newWizardMessage(""))
+	c.NoError(err)
+
+	upload := &models.Upload{Type: models.UploadDocument, FileID: "file-id", FilePath: "documents/file-id"}
+
+	result, err = wizard.HandleUpload(ctx, newWizardMessage(""), state, upload)
+	c.NoError(err)
+	c.Equal("Which title?", result.Reply)
+
+	state, err = GetConversationState(ctx, newWizardMessage(""))
+	c.NoError(err)
+
+	result, err = wizard.HandleUpload(ctx, newWizardMessage(""), state, upload)
+	c.NoError(err)
+	c.Equal("please answer with text, not with a file\nWhich title?", result.Reply)
+
+	result = answerWizard(c, wizard, "Monthly report")
+	c.True(result.Done)
+	c.Equal(map[string]string{"file": "file-id", "title": "Monthly report"}, result.Answers)
+	c.Equal([]*models.Upload{upload}, result.Uploads)
+}
+
+func TestWizardBackRemovesUpload(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+
+	ctx := context.Background()
+	wizard := MustNewWizard("report",
+		&Step{Name: "file", Prompt: "Which file?", Upload: true, NextStep: "title"},
+		&Step{Name: "title", Prompt: "Which title?"},
+	)
+
+	_, err := wizard.Start(ctx, newWizardMessage("/report"))
+	c.NoError(err)
+
+	state, err := GetConversationState(ctx, newWizardMessage(""))
+	c.NoError(err)
+
+	_, err = wizard.HandleUpload(ctx, newWizardMessage(""), state, &models.Upload{FileID: "file-id"})
+	c.NoError(err)
+
+	result := answerWizard(c, wizard, BackAnswer)
+	c.Equal("Which file?", result.Reply)
+
+	state, err = GetConversationState(ctx, newWizardMessage(""))
+	c.NoError(err)
+	c.Empty(state.Wizard.Uploads)
+	c.Empty(state.Wizard.Answers)
+}
+
+func TestWizardOldSchemaVersion(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+
+	message := newWizardMessage("")
+
+	err := StoreConversationState(context.Background(), message, &models.ConversationState{
+		Command: "deploy",
+		Wizard:  &models.WizardState{Step: "project"},
+	})
+	c.NoError(err)
+
+	_, err = GetConversationState(context.Background(), message)
+	c.Equal(ErrConversationNotFound, err)
+}
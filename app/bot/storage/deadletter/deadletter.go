@@ -0,0 +1,260 @@
+// Package deadletter parks the routed messages that could not be published to SNS so they are published
+// again with backoff. The messages waiting for an attempt are indexed by attempt time in an ordered set
+package deadletter
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"shared/app/bot/models"
+	"shared/app/bot/storage/queue"
+
+	"bitbucket.org/truora/scrap-services/shared/cache"
+	"bitbucket.org/truora/scrap-services/shared/env"
+)
+
+const (
+	// lettersKey is the set of the IDs of every parked message, including the exhausted ones
+	lettersKey = "BOT-DEAD-LETTERS"
+	// retriesKey is the ordered set of the IDs waiting for an attempt scored by attempt time in unix seconds
+	retriesKey = "BOT-DEAD-LETTER-RETRIES"
+	letterKey  = "BOT-DEAD-LETTER:%s"
+	// claimKey is keyed by ID and attempts so every attempt of the exhausted messages is made once
+	claimKey = "BOT-DEAD-LETTER-CLAIM:%s:%d"
+
+	idLength    = 4
+	claimWindow = 10 * time.Minute
+	maxBackoff  = time.Hour
+)
+
+var (
+	// ErrDeadLetterNotFound when the message does not exist or it was already published or discarded
+	ErrDeadLetterNotFound = errors.New("dead letter not found")
+	// ErrNoDueDeadLetter when no message has to be attempted yet
+	ErrNoDueDeadLetter = errors.New("no dead letter due")
+	// ErrDeadLetterClaimed when the attempt was claimed by another worker or admin
+	ErrDeadLetterClaimed = errors.New("dead letter already claimed")
+
+	retries = queue.Queue{Key: retriesKey, ErrNoDue: ErrNoDueDeadLetter, ErrClaimed: ErrDeadLetterClaimed, ErrNotFound: ErrDeadLetterNotFound}
+
+	// MaxAttempts is the number of failed publishes before the message is only retried by an admin
+	MaxAttempts = int(env.GetInt64("DEAD_LETTER_MAX_ATTEMPTS", 8))
+
+	backoffSeconds = env.GetInt64("DEAD_LETTER_BACKOFF_SECONDS", 30)
+	retentionHours = env.GetInt64("DEAD_LETTER_RETENTION_HOURS", 7*24)
+	retention      = time.Duration(retentionHours) * time.Hour
+)
+
+// NewID returns a short ID the admins can type to retry or discard the message
+func NewID() (string, error) {
+	id := make([]byte, idLength)
+
+	_, err := rand.Read(id)
+	if err != nil {
+		return "", fmt.Errorf("generate dead letter ID failed: %w", err)
+	}
+
+	return hex.EncodeToString(id), nil
+}
+
+// Backoff returns the wait after the failed attempt, it doubles on every attempt up to an hour
+func Backoff(attempts int) time.Duration {
+	backoff := time.Duration(backoffSeconds) * time.Second
+
+	for attempt := 1; attempt < attempts && backoff < maxBackoff; attempt++ {
+		backoff *= 2
+	}
+
+	if backoff > maxBackoff {
+		return maxBackoff
+	}
+
+	return backoff
+}
+
+// Park stores the message that failed to publish, publishErr is its first failed attempt
+func Park(ctx context.Context, topic, attribute string, message *models.CallbackMessage, publishErr error, now time.Time) (*models.DeadLetter, error) {
+	id, err := NewID()
+	if err != nil {
+		return nil, err
+	}
+
+	letter := &models.DeadLetter{
+		ID:        id,
+		Topic:     topic,
+		Attribute: attribute,
+		Message:   *message,
+		CreatedAt: now,
+	}
+
+	err = Fail(ctx, letter, publishErr, now)
+	if err != nil {
+		return nil, err
+	}
+
+	return letter, nil
+}
+
+// Fail records a failed attempt, the message is attempted again after Backoff until MaxAttempts
+func Fail(ctx context.Context, letter *models.DeadLetter, publishErr error, now time.Time) error {
+	letter.Attempts++
+	letter.LastError = publishErr.Error()
+	letter.NextAttemptAt = time.Time{}
+
+	if letter.Attempts < MaxAttempts {
+		letter.NextAttemptAt = now.Add(Backoff(letter.Attempts))
+	}
+
+	return save(ctx, letter)
+}
+
+// Postpone attempts the message again after Backoff without counting an attempt, e.g. while its command
+// is disabled. The exhausted messages are kept for the admins
+func Postpone(ctx context.Context, letter *models.DeadLetter, now time.Time) error {
+	if letter.IsExhausted() {
+		return nil
+	}
+
+	letter.NextAttemptAt = now.Add(Backoff(letter.Attempts))
+
+	return save(ctx, letter)
+}
+
+func save(ctx context.Context, letter *models.DeadLetter) error {
+	rawData, err := json.Marshal(letter)
+	if err != nil {
+		return err
+	}
+
+	err = cache.Add(ctx, fmt.Sprintf(letterKey, letter.ID), string(rawData), retention)
+	if err != nil {
+		return fmt.Errorf("store dead letter failed: %w", err)
+	}
+
+	err = cache.AddToUnorderedSet(ctx, lettersKey, letter.ID)
+	if err != nil {
+		return fmt.Errorf("index dead letter failed: %w", err)
+	}
+
+	if letter.IsExhausted() {
+		return nil
+	}
+
+	return retries.Add(ctx, letter.ID, letter.NextAttemptAt)
+}
+
+// Get returns the parked message
+func Get(ctx context.Context, id string) (*models.DeadLetter, error) {
+	rawData, err := cache.Get(ctx, fmt.Sprintf(letterKey, id))
+	if errors.Is(err, cache.ErrKeyNotExists) {
+		return nil, ErrDeadLetterNotFound
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("read dead letter failed: %w", err)
+	}
+
+	letter := &models.DeadLetter{}
+
+	err = json.Unmarshal([]byte(rawData), letter)
+	if err != nil {
+		return nil, fmt.Errorf("dead letter json unmarshal error: %w", err)
+	}
+
+	return letter, nil
+}
+
+// List returns the parked messages sorted by creation time
+func List(ctx context.Context) ([]*models.DeadLetter, error) {
+	ids, err := cache.GetAllUnorderedSetMembers(ctx, lettersKey)
+	if err != nil {
+		return nil, fmt.Errorf("read dead letters failed: %w", err)
+	}
+
+	letters := make([]*models.DeadLetter, 0, len(ids))
+
+	for _, id := range ids {
+		letter, err := Get(ctx, id)
+		if errors.Is(err, ErrDeadLetterNotFound) {
+			// the message expired after the retention without being retried or discarded
+			_ = Delete(ctx, id)
+
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		letters = append(letters, letter)
+	}
+
+	sort.Slice(letters, func(i, j int) bool {
+		return letters[i].CreatedAt.Before(letters[j].CreatedAt)
+	})
+
+	return letters, nil
+}
+
+// Claim takes the next attempt of the message so the worker and the admins never publish it twice,
+// ErrDeadLetterClaimed is returned to the one that lost the claim. The messages waiting for an attempt
+// are claimed by removing them from the retries, the exhausted ones are not there and they are claimed
+// once per attempt
+func Claim(ctx context.Context, letter *models.DeadLetter, now time.Time) error {
+	if !letter.IsExhausted() {
+		return retries.Claim(ctx, letter.ID)
+	}
+
+	claimed, err := cache.AddOnce(ctx, fmt.Sprintf(claimKey, letter.ID, letter.Attempts), now.Unix(), claimWindow)
+	if err != nil {
+		return fmt.Errorf("claim dead letter failed: %w", err)
+	}
+
+	if !claimed {
+		return ErrDeadLetterClaimed
+	}
+
+	return nil
+}
+
+// ClaimDue claims the next message to attempt at now
+func ClaimDue(ctx context.Context, now time.Time) (*models.DeadLetter, error) {
+	var letter *models.DeadLetter
+
+	err := retries.ClaimDue(ctx, now, func(ctx context.Context, id string) (err error) {
+		letter, err = Get(ctx, id)
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return letter, nil
+}
+
+// Delete removes the message once it is published or discarded
+func Delete(ctx context.Context, id string) error {
+	err := retries.Remove(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	err = cache.RemoveFromUnorderedSet(ctx, lettersKey, id)
+	if err != nil {
+		return fmt.Errorf("remove dead letter failed: %w", err)
+	}
+
+	err = cache.Del(ctx, fmt.Sprintf(letterKey, id))
+	if err != nil {
+		return fmt.Errorf("delete dead letter failed: %w", err)
+	}
+
+	return nil
+}
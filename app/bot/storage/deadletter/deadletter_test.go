@@ -0,0 +1,159 @@
+package deadletter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"shared/app/bot/models"
+
+	"bitbucket.org/truora/scrap-services/shared/cache"
+	"github.com/stretchr/testify/require"
+)
+
+var errPublish = errors.New("sns unavailable")
+
+func parkTestLetter(c *require.Assertions, now time.Time) *models.DeadLetter {
+	message := &models.CallbackMessage{From: models.From{ID: 10}, Message: models.Message{Text: "/deployterraformstaging checks/core"}}
+
+	letter, err := Park(context.Background(), "commands-topic", "deployterraformstaging", message, errPublish, now)
+	c.NoError(err)
+
+	return letter
+}
+
+func TestBackoff(t *testing.T) {
+	c := require.New(t)
+
+	c.Equal(30*time.Second, Backoff(1))
+	c.Equal(time.Minute, Backoff(2))
+	c.Equal(4*time.Minute, Backoff(4))
+	c.Equal(time.Hour, Backoff(20))
+}
+
+func TestParkListDelete(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+
+	ctx := context.Background()
+	now := time.Now()
+
+	first := parkTestLetter(c, now.Add(-time.Minute))
+	second := parkTestLetter(c, now)
+
+	c.Equal(1, first.Attempts)
+	c.Equal(errPublish.Error(), first.LastError)
+	c.Equal(now.Add(-time.Minute).Add(Backoff(1)), first.NextAttemptAt)
+
+	letters, err := List(ctx)
+	c.NoError(err)
+	c.Len(letters, 2)
+	c.Equal(first.ID, letters[0].ID)
+	c.Equal(second.ID, letters[1].ID)
+	c.Equal("/deployterraformstaging checks/core", letters[0].Message.Message.Text)
+
+	c.NoError(Delete(ctx, first.ID))
+
+	_, err = Get(ctx, first.ID)
+	c.ErrorIs(err, ErrDeadLetterNotFound)
+
+	letters, err = List(ctx)
+	c.NoError(err)
+	c.Len(letters, 1)
+}
+
+func TestClaimDue(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+
+	ctx := context.Background()
+	now := time.Now()
+
+	letter := parkTestLetter(c, now)
+
+	_, err := ClaimDue(ctx, now)
+	c.ErrorIs(err, ErrNoDueDeadLetter)
+
+	claimed, err := ClaimDue(ctx, letter.NextAttemptAt)
+	c.NoError(err)
+	c.Equal(letter.ID, claimed.ID)
+
+	// the attempt is made once
+	c.ErrorIs(Claim(ctx, claimed, now), ErrDeadLetterClaimed)
+
+	_, err = ClaimDue(ctx, letter.NextAttemptAt)
+	c.ErrorIs(err, ErrNoDueDeadLetter)
+
+	c.NoError(Fail(ctx, claimed, errPublish, letter.NextAttemptAt))
+	c.Equal(2, claimed.Attempts)
+
+	claimed, err = ClaimDue(ctx, claimed.NextAttemptAt)
+	c.NoError(err)
+	c.Equal(letter.ID, claimed.ID)
+}
+
+func TestFailExhausted(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+
+	oldMaxAttempts := MaxAttempts
+	MaxAttempts = 2
+
+	defer func() {
+		MaxAttempts = oldMaxAttempts
+	}()
+
+	ctx := context.Background()
+	now := time.Now()
+
+	letter := parkTestLetter(c, now)
+	c.False(letter.IsExhausted())
+
+	claimed, err := ClaimDue(ctx, letter.NextAttemptAt)
+	c.NoError(err)
+
+	c.NoError(Fail(ctx, claimed, errPublish, now))
+	c.True(claimed.IsExhausted())
+
+	// the exhausted messages are kept for the admins but never attempted again
+	_, err = ClaimDue(ctx, now.Add(24*time.Hour))
+	c.ErrorIs(err, ErrNoDueDeadLetter)
+
+	stored, err := Get(ctx, letter.ID)
+	c.NoError(err)
+	c.Equal(2, stored.Attempts)
+	c.True(stored.IsExhausted())
+
+	// the admins retry the exhausted messages once per attempt
+	c.NoError(Claim(ctx, stored, now))
+	c.ErrorIs(Claim(ctx, stored, now), ErrDeadLetterClaimed)
+}
+
+func TestPostpone(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+
+	ctx := context.Background()
+	now := time.Now()
+
+	letter := parkTestLetter(c, now)
+
+	claimed, err := ClaimDue(ctx, letter.NextAttemptAt)
+	c.NoError(err)
+
+	c.NoError(Postpone(ctx, claimed, letter.NextAttemptAt))
+	c.Equal(1, claimed.Attempts)
+
+	_, err = ClaimDue(ctx, letter.NextAttemptAt)
+	c.ErrorIs(err, ErrNoDueDeadLetter)
+
+	claimed, err = ClaimDue(ctx, letter.NextAttemptAt.Add(Backoff(1)))
+	c.NoError(err)
+	c.Equal(letter.ID, claimed.ID)
+	c.Equal(1, claimed.Attempts)
+}
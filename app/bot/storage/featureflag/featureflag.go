@@ -0,0 +1,202 @@
+// Package featureflag stores the flags that disable commands, limit the commands in beta to some users and
+// put the bot in maintenance. Every flag is a field of one hash so all of them are read at once, and they are
+// kept in memory for a few seconds because they are read for every published command
+package featureflag
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"shared/app/bot/models"
+
+	"bitbucket.org/truora/scrap-services/shared/cache"
+	"bitbucket.org/truora/scrap-services/shared/env"
+)
+
+const (
+	// flagsKey is the hash of every flag
+	flagsKey = "BOT-FLAGS"
+
+	maintenanceField = "maintenance"
+	// disabledField has the reason the command is disabled
+	disabledField = "disabled:"
+	// betaField marks the command as beta, the allowed users have their own fields with their email
+	betaField = "beta:"
+	// betaUserField is followed by the command and the Telegram ID of the allowed user
+	betaUserField = "beta-user:"
+)
+
+var (
+	// cacheTTL is how long the flags read from Redis are used, a change made in another Lambda takes this
+	// long to be seen
+	cacheTTL = time.Duration(env.GetInt64("BOT_FLAGS_CACHE_SECONDS", 10)) * time.Second
+
+	mutex    sync.Mutex
+	cached   *models.Flags
+	cachedAt time.Time
+)
+
+// Get returns the flags, they are read from Redis again when the ones in memory are older than cacheTTL
+func Get(ctx context.Context) (*models.Flags, error) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if cached != nil && time.Since(cachedAt) < cacheTTL {
+		return cached, nil
+	}
+
+	fields, err := getFields(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cached = parseFlags(fields)
+	cachedAt = time.Now()
+
+	return cached, nil
+}
+
+// SetMaintenance puts the bot in maintenance with the message sent to the users, an empty message ends it
+func SetMaintenance(ctx context.Context, message string) error {
+	if message == "" {
+		return del(ctx, maintenanceField)
+	}
+
+	return set(ctx, maintenanceField, message)
+}
+
+// DisableCommand stops publishing the command, the reason is sent to the users and it can be empty
+func DisableCommand(ctx context.Context, command, reason string) error {
+	return set(ctx, disabledField+command, reason)
+}
+
+// EnableCommand publishes the disabled command again
+func EnableCommand(ctx context.Context, command string) error {
+	return del(ctx, disabledField+command)
+}
+
+// StartBeta limits the command to the users allowed with AllowBetaUser
+func StartBeta(ctx context.Context, command string) error {
+	return set(ctx, betaField+command, strconv.FormatInt(time.Now().Unix(), 10))
+}
+
+// EndBeta makes the command available to every user again, the allowed users are removed
+func EndBeta(ctx context.Context, command string) error {
+	fields, err := getFields(ctx)
+	if err != nil {
+		return err
+	}
+
+	for field := range fields {
+		if strings.HasPrefix(field, betaUserField+command+":") {
+			err = del(ctx, field)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return del(ctx, betaField+command)
+}
+
+// AllowBetaUser allows the user to run the command while it is in beta
+func AllowBetaUser(ctx context.Context, command string, user *models.From) error {
+	return set(ctx, betaUser(command, user.ID), user.Email)
+}
+
+// DenyBetaUser removes the user from the users allowed to run the command in beta
+func DenyBetaUser(ctx context.Context, command string, userID int64) error {
+	return del(ctx, betaUser(command, userID))
+}
+
+func betaUser(command string, userID int64) string {
+	return betaUserField + command + ":" + strconv.FormatInt(userID, 10)
+}
+
+func getFields(ctx context.Context) (map[string]string, error) {
+	fields, err := cache.HGetAll(ctx, flagsKey)
+	if errors.Is(err, cache.ErrKeyNotExists) {
+		return map[string]string{}, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("get flags failed: %w", err)
+	}
+
+	return fields, nil
+}
+
+// set changes a flag, the flags in memory are dropped so the change is seen right away by this Lambda
+func set(ctx context.Context, field, value string) error {
+	err := cache.HSet(ctx, flagsKey, field, value)
+	if err != nil {
+		return fmt.Errorf("set flag failed: %w", err)
+	}
+
+	clearCached()
+
+	return nil
+}
+
+func del(ctx context.Context, field string) error {
+	err := cache.HDel(ctx, flagsKey, field)
+	if err != nil {
+		return fmt.Errorf("delete flag failed: %w", err)
+	}
+
+	clearCached()
+
+	return nil
+}
+
+func clearCached() {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	cached = nil
+}
+
+// parseFlags builds the flags from the fields of the hash, the unknown fields and the users of the commands
+// that are not in beta are ignored
+func parseFlags(fields map[string]string) *models.Flags {
+	flags := &models.Flags{
+		Maintenance: fields[maintenanceField],
+		Disabled:    map[string]string{},
+		Betas:       map[string]map[int64]string{},
+	}
+
+	for field, value := range fields {
+		switch {
+		case strings.HasPrefix(field, disabledField):
+			flags.Disabled[strings.TrimPrefix(field, disabledField)] = value
+		case strings.HasPrefix(field, betaField):
+			command := strings.TrimPrefix(field, betaField)
+			if flags.Betas[command] == nil {
+				flags.Betas[command] = map[int64]string{}
+			}
+		}
+	}
+
+	for field, email := range fields {
+		if !strings.HasPrefix(field, betaUserField) {
+			continue
+		}
+
+		// the command names do not have colons so the command ends at the first one
+		command, id, _ := strings.Cut(strings.TrimPrefix(field, betaUserField), ":")
+
+		userID, err := strconv.ParseInt(id, 10, 64)
+		if err != nil || flags.Betas[command] == nil {
+			continue
+		}
+
+		flags.Betas[command][userID] = email
+	}
+
+	return flags
+}
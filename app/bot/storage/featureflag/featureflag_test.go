@@ -0,0 +1,91 @@
+package featureflag
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"shared/app/bot/models"
+
+	"bitbucket.org/truora/scrap-services/shared/cache"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFlags(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+	clearCached()
+
+	ctx := context.Background()
+
+	flags, err := Get(ctx)
+	c.NoError(err)
+	c.Equal(&models.Flags{Disabled: map[string]string{}, Betas: map[string]map[int64]string{}}, flags)
+
+	c.NoError(SetMaintenance(ctx, "Deploying the new cluster"))
+	c.NoError(DisableCommand(ctx, "deployterraformstaging", "broken pipeline"))
+	c.NoError(DisableCommand(ctx, "report", ""))
+	c.NoError(StartBeta(ctx, "report"))
+	c.NoError(AllowBetaUser(ctx, "report", &models.From{ID: 10, Email: "dummy_email@dummy.com"}))
+	c.NoError(AllowBetaUser(ctx, "report", &models.From{ID: 11, Email: "other_email@dummy.com"}))
+	// the users of a command that is not in beta are ignored
+	c.NoError(AllowBetaUser(ctx, "schedule", &models.From{ID: 10, Email: "dummy_email@dummy.com"}))
+
+	flags, err = Get(ctx)
+	c.NoError(err)
+	c.Equal("Deploying the new cluster", flags.Maintenance)
+	c.Equal(map[string]string{"deployterraformstaging": "broken pipeline", "report": ""}, flags.Disabled)
+	c.Equal(map[string]map[int64]string{"report": {10: "dummy_email@dummy.com", 11: "other_email@dummy.com"}}, flags.Betas)
+
+	c.NoError(SetMaintenance(ctx, ""))
+	c.NoError(EnableCommand(ctx, "report"))
+	c.NoError(DenyBetaUser(ctx, "report", 11))
+
+	flags, err = Get(ctx)
+	c.NoError(err)
+	c.Empty(flags.Maintenance)
+	c.Equal(map[string]string{"deployterraformstaging": "broken pipeline"}, flags.Disabled)
+	c.Equal(map[string]map[int64]string{"report": {10: "dummy_email@dummy.com"}}, flags.Betas)
+
+	c.NoError(EndBeta(ctx, "report"))
+
+	flags, err = Get(ctx)
+	c.NoError(err)
+	c.Empty(flags.Betas)
+
+	// the users allowed before are not allowed when the beta starts again
+	c.NoError(StartBeta(ctx, "report"))
+
+	flags, err = Get(ctx)
+	c.NoError(err)
+	c.Equal(map[string]map[int64]string{"report": {}}, flags.Betas)
+}
+
+func TestGetUsesCachedFlags(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+	clearCached()
+
+	ctx := context.Background()
+
+	c.NoError(SetMaintenance(ctx, "Deploying the new cluster"))
+
+	flags, err := Get(ctx)
+	c.NoError(err)
+	c.Equal("Deploying the new cluster", flags.Maintenance)
+
+	// a change made by another Lambda is not seen until the flags in memory expire
+	c.NoError(cache.HDel(ctx, flagsKey, maintenanceField))
+
+	flags, err = Get(ctx)
+	c.NoError(err)
+	c.Equal("Deploying the new cluster", flags.Maintenance)
+
+	cachedAt = time.Now().Add(-cacheTTL)
+
+	flags, err = Get(ctx)
+	c.NoError(err)
+	c.Empty(flags.Maintenance)
+}
@@ -0,0 +1,194 @@
+// Package gate parks the commands that need approvals until enough users approve them, they expire
+// after TTL. The requests are stored in the cache and indexed by expiration in an ordered set
+package gate
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"shared/app/bot/models"
+	"shared/app/bot/storage/queue"
+
+	"bitbucket.org/truora/scrap-services/shared/cache"
+	"bitbucket.org/truora/scrap-services/shared/env"
+)
+
+const (
+	// requestsKey is the ordered set of the pending request IDs scored by expiration in unix seconds
+	requestsKey = "BOT-APPROVALS"
+	requestKey  = "BOT-APPROVAL:%s"
+	// votesKey is the set of the Telegram IDs of the users that approved the request
+	votesKey = "BOT-APPROVAL-VOTES:%s"
+	// resolvedKey is claimed once by the approval, rejection or expiration of the request
+	resolvedKey = "BOT-APPROVAL-RESOLVED:%s"
+
+	idLength = 4
+	// retention keeps the request after its expiration so a late tick can still notify it
+	retention = 24 * time.Hour
+)
+
+var (
+	// ErrRequestNotFound when the request does not exist or it was already resolved
+	ErrRequestNotFound = errors.New("approval request not found")
+	// ErrRequestExpired when the request is approved after its expiration
+	ErrRequestExpired = errors.New("approval request expired")
+	// ErrRequestResolved when the request was approved, rejected or expired by someone else
+	ErrRequestResolved = errors.New("approval request already resolved")
+	// ErrSelfApproval when the user that sent the command tries to approve it
+	ErrSelfApproval = errors.New("the requester can not approve the command")
+	// ErrNoExpiredRequest when no pending request is expired
+	ErrNoExpiredRequest = errors.New("no expired approval request")
+
+	requests = queue.Queue{Key: requestsKey, ErrNoDue: ErrNoExpiredRequest, ErrClaimed: ErrRequestResolved, ErrNotFound: ErrRequestNotFound}
+
+	ttlMinutes = env.GetInt64("APPROVAL_TTL_MINUTES", 60)
+	// TTL is the time the approvers have to approve a request
+	TTL = time.Duration(ttlMinutes) * time.Minute
+)
+
+// NewID returns a short ID to identify the request in the messages
+func NewID() (string, error) {
+	id := make([]byte, idLength)
+
+	_, err := rand.Read(id)
+	if err != nil {
+		return "", fmt.Errorf("generate approval ID failed: %w", err)
+	}
+
+	return hex.EncodeToString(id), nil
+}
+
+// Park stores a new request until it expires
+func Park(ctx context.Context, request *models.ApprovalRequest) error {
+	err := Save(ctx, request)
+	if err != nil {
+		return err
+	}
+
+	return requests.Add(ctx, request.ID, request.ExpiresAt)
+}
+
+// Save updates a parked request, e.g. to add the prompts sent to the approvers
+func Save(ctx context.Context, request *models.ApprovalRequest) error {
+	rawData, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	err = cache.Add(ctx, fmt.Sprintf(requestKey, request.ID), string(rawData), time.Until(request.ExpiresAt)+retention)
+	if err != nil {
+		return fmt.Errorf("store approval request failed: %w", err)
+	}
+
+	return nil
+}
+
+// Get returns the pending request
+func Get(ctx context.Context, id string) (*models.ApprovalRequest, error) {
+	rawData, err := cache.Get(ctx, fmt.Sprintf(requestKey, id))
+	if errors.Is(err, cache.ErrKeyNotExists) {
+		return nil, ErrRequestNotFound
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("read approval request failed: %w", err)
+	}
+
+	request := &models.ApprovalRequest{}
+
+	err = json.Unmarshal([]byte(rawData), request)
+	if err != nil {
+		return nil, fmt.Errorf("approval request json unmarshal error: %w", err)
+	}
+
+	return request, nil
+}
+
+// Approve adds the approval of the user and returns the number of distinct users that approved the
+// request, the approvals of the same user are only counted once
+func Approve(ctx context.Context, request *models.ApprovalRequest, approverID int64, now time.Time) (int, error) {
+	if approverID == request.RequesterID() {
+		return 0, ErrSelfApproval
+	}
+
+	if now.After(request.ExpiresAt) {
+		return 0, ErrRequestExpired
+	}
+
+	key := fmt.Sprintf(votesKey, request.ID)
+
+	err := cache.AddToUnorderedSet(ctx, key, strconv.FormatInt(approverID, 10))
+	if err != nil {
+		return 0, fmt.Errorf("store approval failed: %w", err)
+	}
+
+	err = cache.Expire(ctx, key, time.Until(request.ExpiresAt)+retention)
+	if err != nil {
+		return 0, fmt.Errorf("expire approvals failed: %w", err)
+	}
+
+	approvers, err := cache.GetAllUnorderedSetMembers(ctx, key)
+	if err != nil {
+		return 0, fmt.Errorf("read approvals failed: %w", err)
+	}
+
+	return len(approvers), nil
+}
+
+// Resolve claims the request and deletes it, only the first of the approval, rejection or expiration
+// of the request resolves it and the others get ErrRequestResolved
+func Resolve(ctx context.Context, request *models.ApprovalRequest) error {
+	claimed, err := cache.AddOnce(ctx, fmt.Sprintf(resolvedKey, request.ID), time.Now().Unix(), time.Until(request.ExpiresAt)+retention)
+	if err != nil {
+		return fmt.Errorf("claim approval request failed: %w", err)
+	}
+
+	if !claimed {
+		return ErrRequestResolved
+	}
+
+	return remove(ctx, request.ID)
+}
+
+func remove(ctx context.Context, id string) error {
+	err := requests.Remove(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	err = cache.Del(ctx, fmt.Sprintf(requestKey, id), fmt.Sprintf(votesKey, id))
+	if err != nil {
+		return fmt.Errorf("delete approval request failed: %w", err)
+	}
+
+	return nil
+}
+
+// ClaimExpired resolves the next request expired at now. ErrRequestResolved is returned when the
+// request was claimed by another tick or resolved at the same time by an approver and ErrRequestNotFound
+// when the request was lost, the request is out of the queue in both cases
+func ClaimExpired(ctx context.Context, now time.Time) (*models.ApprovalRequest, error) {
+	var request *models.ApprovalRequest
+
+	err := requests.ClaimDue(ctx, now, func(ctx context.Context, id string) (err error) {
+		request, err = Get(ctx, id)
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = Resolve(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	return request, nil
+}
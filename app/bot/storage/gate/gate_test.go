@@ -0,0 +1,121 @@
+package gate
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"shared/app/bot/models"
+
+	"bitbucket.org/truora/scrap-services/shared/cache"
+	"github.com/stretchr/testify/require"
+)
+
+func newRequest(c *require.Assertions, expiresAt time.Time) *models.ApprovalRequest {
+	id, err := NewID()
+	c.NoError(err)
+
+	return &models.ApprovalRequest{
+		ID:        id,
+		Command:   "deploy",
+		Required:  2,
+		Message:   models.CallbackMessage{From: models.From{ID: 1}},
+		CreatedAt: time.Now(),
+		ExpiresAt: expiresAt,
+	}
+}
+
+func TestApprove(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+
+	ctx := context.Background()
+	request := newRequest(c, time.Now().Add(TTL))
+
+	c.NoError(Park(ctx, request))
+
+	stored, err := Get(ctx, request.ID)
+	c.NoError(err)
+	c.Equal(request.Command, stored.Command)
+
+	_, err = Approve(ctx, stored, 1, time.Now())
+	c.ErrorIs(err, ErrSelfApproval)
+
+	approvals, err := Approve(ctx, stored, 2, time.Now())
+	c.NoError(err)
+	c.Equal(1, approvals)
+
+	// the same approver only counts once
+	approvals, err = Approve(ctx, stored, 2, time.Now())
+	c.NoError(err)
+	c.Equal(1, approvals)
+
+	approvals, err = Approve(ctx, stored, 3, time.Now())
+	c.NoError(err)
+	c.Equal(2, approvals)
+
+	_, err = Approve(ctx, stored, 4, request.ExpiresAt.Add(time.Second))
+	c.ErrorIs(err, ErrRequestExpired)
+
+	c.NoError(Resolve(ctx, stored))
+	c.ErrorIs(Resolve(ctx, stored), ErrRequestResolved)
+
+	_, err = Get(ctx, request.ID)
+	c.ErrorIs(err, ErrRequestNotFound)
+}
+
+func TestClaimExpired(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+
+	ctx := context.Background()
+	now := time.Now()
+
+	_, err := ClaimExpired(ctx, now)
+	c.ErrorIs(err, ErrNoExpiredRequest)
+
+	expired := newRequest(c, now.Add(-time.Minute))
+	pending := newRequest(c, now.Add(time.Hour))
+
+	c.NoError(Park(ctx, expired))
+	c.NoError(Park(ctx, pending))
+
+	claimed, err := ClaimExpired(ctx, now)
+	c.NoError(err)
+	c.Equal(expired.ID, claimed.ID)
+
+	_, err = ClaimExpired(ctx, now)
+	c.ErrorIs(err, ErrNoExpiredRequest)
+
+	// an approved request is not expired later
+	c.NoError(Resolve(ctx, pending))
+
+	_, err = ClaimExpired(ctx, now.Add(2*time.Hour))
+	c.ErrorIs(err, ErrNoExpiredRequest)
+}
+
+func TestClaimExpiredLost(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+
+	ctx := context.Background()
+	now := time.Now()
+
+	lost := newRequest(c, now.Add(-2*time.Minute))
+	expired := newRequest(c, now.Add(-time.Minute))
+
+	c.NoError(Park(ctx, lost))
+	c.NoError(Park(ctx, expired))
+	c.NoError(cache.Del(ctx, fmt.Sprintf(requestKey, lost.ID)))
+
+	_, err := ClaimExpired(ctx, now)
+	c.ErrorIs(err, ErrRequestNotFound)
+
+	claimed, err := ClaimExpired(ctx, now)
+	c.NoError(err)
+	c.Equal(expired.ID, claimed.ID)
+}
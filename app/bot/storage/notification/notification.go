@@ -0,0 +1,60 @@
+// Package notification stores the notification settings of the bot users
+package notification
+
+import (
+	"context"
+	"fmt"
+
+	"shared/app/bot/models"
+
+	"bitbucket.org/truora/scrap-services/shared/cache"
+)
+
+// mutesKey is the set of the notification events muted by the Telegram user
+const mutesKey = "BOT-NOTIFICATION-MUTES:%d"
+
+// Mute stops notifying the event type to the user
+func Mute(ctx context.Context, userID int64, event models.NotificationEvent) error {
+	err := cache.AddToUnorderedSet(ctx, fmt.Sprintf(mutesKey, userID), string(event))
+	if err != nil {
+		return fmt.Errorf("mute notification failed: %w", err)
+	}
+
+	return nil
+}
+
+// Unmute notifies the event type to the user again
+func Unmute(ctx context.Context, userID int64, event models.NotificationEvent) error {
+	err := cache.RemoveFromUnorderedSet(ctx, fmt.Sprintf(mutesKey, userID), string(event))
+	if err != nil {
+		return fmt.Errorf("unmute notification failed: %w", err)
+	}
+
+	return nil
+}
+
+// Muted returns the event types muted by the user
+func Muted(ctx context.Context, userID int64) (map[models.NotificationEvent]bool, error) {
+	members, err := cache.GetAllUnorderedSetMembers(ctx, fmt.Sprintf(mutesKey, userID))
+	if err != nil {
+		return nil, fmt.Errorf("get muted notifications failed: %w", err)
+	}
+
+	muted := make(map[models.NotificationEvent]bool, len(members))
+
+	for _, member := range members {
+		muted[models.NotificationEvent(member)] = true
+	}
+
+	return muted, nil
+}
+
+// IsMuted returns true if the user muted the event type
+func IsMuted(ctx context.Context, userID int64, event models.NotificationEvent) (bool, error) {
+	muted, err := Muted(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+
+	return muted[event], nil
+}
@@ -0,0 +1,41 @@
+package notification
+
+import (
+	"context"
+	"testing"
+
+	"shared/app/bot/models"
+
+	"bitbucket.org/truora/scrap-services/shared/cache"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMute(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+
+	ctx := context.Background()
+
+	muted, err := Muted(ctx, 1)
+	c.NoError(err)
+	c.Empty(muted)
+
+	c.NoError(Mute(ctx, 1, models.NotificationPRMerged))
+	c.NoError(Mute(ctx, 1, models.NotificationMention))
+
+	isMuted, err := IsMuted(ctx, 1, models.NotificationPRMerged)
+	c.NoError(err)
+	c.True(isMuted)
+
+	// the settings are per user
+	isMuted, err = IsMuted(ctx, 2, models.NotificationPRMerged)
+	c.NoError(err)
+	c.False(isMuted)
+
+	c.NoError(Unmute(ctx, 1, models.NotificationPRMerged))
+
+	muted, err = Muted(ctx, 1)
+	c.NoError(err)
+	c.Equal(map[models.NotificationEvent]bool{models.NotificationMention: true}, muted)
+}
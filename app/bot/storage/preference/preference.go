@@ -0,0 +1,46 @@
+// Package preference stores the preferences of the bot users that are not part of their DynamoDB user,
+// so they can be read before the user is authorized
+package preference
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"bitbucket.org/truora/scrap-services/shared/cache"
+)
+
+// languagesKey is the hash of the language chosen by each Telegram user
+const languagesKey = "BOT-USER-LANGUAGES"
+
+// SetLanguage stores the language chosen by the user, an empty language removes the preference
+func SetLanguage(ctx context.Context, userID int64, language string) error {
+	var err error
+
+	if language == "" {
+		err = cache.HDel(ctx, languagesKey, strconv.FormatInt(userID, 10))
+	} else {
+		err = cache.HSet(ctx, languagesKey, strconv.FormatInt(userID, 10), language)
+	}
+
+	if err != nil {
+		return fmt.Errorf("set language failed: %w", err)
+	}
+
+	return nil
+}
+
+// Language returns the language chosen by the user, it is empty when the user did not choose one
+func Language(ctx context.Context, userID int64) (string, error) {
+	language, err := cache.HGet(ctx, languagesKey, strconv.FormatInt(userID, 10))
+	if errors.Is(err, cache.ErrKeyNotExists) {
+		return "", nil
+	}
+
+	if err != nil {
+		return "", fmt.Errorf("get language failed: %w", err)
+	}
+
+	return language, nil
+}
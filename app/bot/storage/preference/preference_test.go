@@ -0,0 +1,39 @@
+package preference
+
+import (
+	"context"
+	"testing"
+
+	"bitbucket.org/truora/scrap-services/shared/cache"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLanguage(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+
+	ctx := context.Background()
+
+	language, err := Language(ctx, 1)
+	c.NoError(err)
+	c.Empty(language)
+
+	c.NoError(SetLanguage(ctx, 1, "es"))
+	c.NoError(SetLanguage(ctx, 2, "en"))
+
+	language, err = Language(ctx, 1)
+	c.NoError(err)
+	c.Equal("es", language)
+
+	c.NoError(SetLanguage(ctx, 1, ""))
+
+	language, err = Language(ctx, 1)
+	c.NoError(err)
+	c.Empty(language)
+
+	// the preferences are per user
+	language, err = Language(ctx, 2)
+	c.NoError(err)
+	c.Equal("en", language)
+}
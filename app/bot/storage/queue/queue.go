@@ -0,0 +1,141 @@
+// Package queue claims the items that the stores index by due time in a cache ordered set, e.g. the
+// scheduled commands, the approval requests and the dead letters. An item is claimed by removing it from
+// the ordered set so only one of the concurrent claimers gets it and a claimer that dies does not block
+// the next items
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"bitbucket.org/truora/scrap-services/shared/cache"
+)
+
+const (
+	// readsKey counts the failed reads of the item at the head of the queue
+	readsKey    = "%s-READS:%s"
+	readsWindow = 24 * time.Hour
+)
+
+var (
+	// ErrDropped when the item could not be read MaxReads times and was removed so it does not block the
+	// next ones
+	ErrDropped = errors.New("unreadable queued item dropped")
+
+	// MaxReads is the number of failed reads of an item before it is dropped
+	MaxReads = int64(5)
+)
+
+// Queue is an ordered set of item IDs scored by due time in unix seconds. The errors are the ones of the
+// store so its callers keep matching them
+type Queue struct {
+	// Key of the ordered set
+	Key string
+	// ErrNoDue is returned when no item is due yet
+	ErrNoDue error
+	// ErrClaimed is returned to the claimer that lost the item
+	ErrClaimed error
+	// ErrNotFound is returned by the read of the item when it does not exist
+	ErrNotFound error
+}
+
+// Add indexes the item at its due time, an item already indexed keeps its due time
+func (queue Queue) Add(ctx context.Context, id string, dueAt time.Time) error {
+	err := cache.AddToOrderedSetWithOption(ctx, queue.Key, id, float64(dueAt.Unix()), cache.OnlyAdd)
+	if err != nil {
+		return fmt.Errorf("queue item failed: %w", err)
+	}
+
+	return nil
+}
+
+// Remove removes the item from the queue without claiming it, e.g. when it is canceled
+func (queue Queue) Remove(ctx context.Context, id string) error {
+	err := cache.RemoveFromToOrderedSet(ctx, queue.Key, id)
+	if err != nil {
+		return fmt.Errorf("remove queued item failed: %w", err)
+	}
+
+	return nil
+}
+
+// Claim takes the item by removing it from the queue, ErrClaimed is returned when it was already removed
+func (queue Queue) Claim(ctx context.Context, id string) error {
+	removed, err := cache.GetClient().ZRem(ctx, queue.Key, id).Result()
+	if err != nil {
+		return fmt.Errorf("claim queued item failed: %w", err)
+	}
+
+	if removed == 0 {
+		return queue.ErrClaimed
+	}
+
+	return nil
+}
+
+// ClaimDue claims the next item due at now. The item is read before it is claimed so a failed read
+// leaves it queued until it fails MaxReads times, an item that no longer exists is removed so it does
+// not block the next ones and ErrNotFound is only returned once it is out of the queue
+func (queue Queue) ClaimDue(ctx context.Context, now time.Time, read func(ctx context.Context, id string) error) error {
+	id, score, err := cache.GetOrderedSetMin(ctx, queue.Key)
+	if err != nil {
+		return fmt.Errorf("read next queued item failed: %w", err)
+	}
+
+	if id == "" || score > float64(now.Unix()) {
+		return queue.ErrNoDue
+	}
+
+	err = read(ctx, id)
+	if errors.Is(err, queue.ErrNotFound) {
+		err = queue.Remove(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		return queue.ErrNotFound
+	}
+
+	if err != nil {
+		return queue.readFailed(ctx, id, err)
+	}
+
+	err = queue.Claim(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	_ = cache.Del(ctx, fmt.Sprintf(readsKey, queue.Key, id))
+
+	return nil
+}
+
+// readFailed counts the failed read of the item, the item is dropped once it failed MaxReads times
+func (queue Queue) readFailed(ctx context.Context, id string, readErr error) error {
+	key := fmt.Sprintf(readsKey, queue.Key, id)
+
+	reads, err := cache.Incr(ctx, key)
+	if err != nil {
+		return fmt.Errorf("count queued item reads failed: %w", err)
+	}
+
+	err = cache.Expire(ctx, key, readsWindow)
+	if err != nil {
+		return fmt.Errorf("count queued item reads failed: %w", err)
+	}
+
+	if reads < MaxReads {
+		return readErr
+	}
+
+	err = queue.Remove(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	_ = cache.Del(ctx, key)
+
+	return fmt.Errorf("%w: %s: %w", ErrDropped, id, readErr)
+}
@@ -0,0 +1,147 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"bitbucket.org/truora/scrap-services/shared/cache"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	errNoDue    = errors.New("no due")
+	errClaimed  = errors.New("claimed")
+	errNotFound = errors.New("not found")
+	errRead     = errors.New("read failed")
+
+	testQueue = Queue{Key: "TEST-QUEUE", ErrNoDue: errNoDue, ErrClaimed: errClaimed, ErrNotFound: errNotFound}
+)
+
+func readAll(ctx context.Context, id string) error {
+	return nil
+}
+
+func TestClaimDue(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+
+	ctx := context.Background()
+	now := time.Now()
+
+	c.ErrorIs(testQueue.ClaimDue(ctx, now, readAll), errNoDue)
+
+	c.NoError(testQueue.Add(ctx, "later", now.Add(time.Hour)))
+	c.NoError(testQueue.Add(ctx, "first", now.Add(-time.Minute)))
+
+	// the due time of a queued item is not changed
+	c.NoError(testQueue.Add(ctx, "first", now.Add(2*time.Hour)))
+
+	claimed := ""
+
+	c.NoError(testQueue.ClaimDue(ctx, now, func(ctx context.Context, id string) error {
+		claimed = id
+
+		return nil
+	}))
+	c.Equal("first", claimed)
+
+	c.ErrorIs(testQueue.Claim(ctx, "first"), errClaimed)
+	c.ErrorIs(testQueue.ClaimDue(ctx, now, readAll), errNoDue)
+
+	c.NoError(testQueue.Remove(ctx, "later"))
+	c.ErrorIs(testQueue.ClaimDue(ctx, now.Add(2*time.Hour), readAll), errNoDue)
+}
+
+func TestClaimDueReadFailed(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+
+	ctx := context.Background()
+	now := time.Now()
+
+	c.NoError(testQueue.Add(ctx, "lost", now.Add(-2*time.Minute)))
+	c.NoError(testQueue.Add(ctx, "next", now.Add(-time.Minute)))
+
+	// a failed read keeps the item queued
+	c.ErrorIs(testQueue.ClaimDue(ctx, now, func(ctx context.Context, id string) error {
+		return errRead
+	}), errRead)
+
+	// a missing item is removed so it does not block the next ones
+	c.ErrorIs(testQueue.ClaimDue(ctx, now, func(ctx context.Context, id string) error {
+		return errNotFound
+	}), errNotFound)
+
+	claimed := ""
+
+	c.NoError(testQueue.ClaimDue(ctx, now, func(ctx context.Context, id string) error {
+		claimed = id
+
+		return nil
+	}))
+	c.Equal("next", claimed)
+}
+
+func TestClaimDueLost(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+
+	ctx := context.Background()
+	now := time.Now()
+
+	c.NoError(testQueue.Add(ctx, "first", now.Add(-time.Minute)))
+
+	// another claimer removes the item while it is read
+	c.ErrorIs(testQueue.ClaimDue(ctx, now, func(ctx context.Context, id string) error {
+		return testQueue.Claim(ctx, id)
+	}), errClaimed)
+}
+
+func TestClaimDueDropsUnreadable(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+
+	oldMaxReads := MaxReads
+	MaxReads = 2
+
+	defer func() {
+		MaxReads = oldMaxReads
+	}()
+
+	ctx := context.Background()
+	now := time.Now()
+
+	c.NoError(testQueue.Add(ctx, "unreadable", now.Add(-2*time.Minute)))
+	c.NoError(testQueue.Add(ctx, "next", now.Add(-time.Minute)))
+
+	readUnreadable := func(ctx context.Context, id string) error {
+		if id == "unreadable" {
+			return errRead
+		}
+
+		return nil
+	}
+
+	err := testQueue.ClaimDue(ctx, now, readUnreadable)
+	c.ErrorIs(err, errRead)
+	c.NotErrorIs(err, ErrDropped)
+
+	err = testQueue.ClaimDue(ctx, now, readUnreadable)
+	c.ErrorIs(err, errRead)
+	c.ErrorIs(err, ErrDropped)
+
+	claimed := ""
+
+	c.NoError(testQueue.ClaimDue(ctx, now, func(ctx context.Context, id string) error {
+		claimed = id
+
+		return nil
+	}))
+	c.Equal("next", claimed)
+}
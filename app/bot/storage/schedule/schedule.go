@@ -0,0 +1,216 @@
+// Package schedule stores the commands scheduled by the bot users in a cache ordered set scored by fire time
+package schedule
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"shared/app/bot/models"
+	"shared/app/bot/storage/queue"
+
+	"bitbucket.org/truora/scrap-services/shared/cache"
+	"bitbucket.org/truora/scrap-services/shared/env"
+)
+
+const (
+	// schedulesKey is the ordered set of the scheduled command IDs scored by fire time in unix seconds
+	schedulesKey     = "BOT-SCHEDULES"
+	scheduleKey      = "BOT-SCHEDULE:%s"
+	userSchedulesKey = "BOT-USER-SCHEDULES:%d"
+
+	idLength = 4
+	// retention keeps the command after its fire time so a late tick can still run it
+	retention = 24 * time.Hour
+)
+
+var (
+	// ErrScheduleNotFound when the scheduled command does not exist or belongs to another user
+	ErrScheduleNotFound = errors.New("scheduled command not found")
+	// ErrNoDueSchedule when no scheduled command has to fire yet
+	ErrNoDueSchedule = errors.New("no scheduled command due")
+	// ErrScheduleClaimed when the due command was claimed by another tick
+	ErrScheduleClaimed = errors.New("scheduled command already claimed")
+	// ErrTooManySchedules when the user reached the maximum of pending commands
+	ErrTooManySchedules = errors.New("too many scheduled commands")
+
+	// MaxPerUser is the maximum of pending commands of each user
+	MaxPerUser = int(env.GetInt64("SCHEDULE_MAX_PER_USER", 20))
+
+	schedules = queue.Queue{Key: schedulesKey, ErrNoDue: ErrNoDueSchedule, ErrClaimed: ErrScheduleClaimed, ErrNotFound: ErrScheduleNotFound}
+
+	getCommand = Get
+)
+
+// NewID returns a short ID the users can type to cancel the command
+func NewID() (string, error) {
+	id := make([]byte, idLength)
+
+	_, err := rand.Read(id)
+	if err != nil {
+		return "", fmt.Errorf("generate schedule ID failed: %w", err)
+	}
+
+	return hex.EncodeToString(id), nil
+}
+
+// Add stores a new scheduled command, it fails when the user already has MaxPerUser pending commands
+func Add(ctx context.Context, command *models.ScheduledCommand) error {
+	pending, err := ListByUser(ctx, command.Message.From.ID)
+	if err != nil {
+		return err
+	}
+
+	if len(pending) >= MaxPerUser {
+		return fmt.Errorf("%w, the maximum is %d", ErrTooManySchedules, MaxPerUser)
+	}
+
+	err = save(ctx, command)
+	if err != nil {
+		return err
+	}
+
+	return cache.AddToUnorderedSet(ctx, fmt.Sprintf(userSchedulesKey, command.Message.From.ID), command.ID)
+}
+
+func save(ctx context.Context, command *models.ScheduledCommand) error {
+	rawData, err := json.Marshal(command)
+	if err != nil {
+		return err
+	}
+
+	err = cache.Add(ctx, fmt.Sprintf(scheduleKey, command.ID), string(rawData), time.Until(command.FireAt)+retention)
+	if err != nil {
+		return fmt.Errorf("store scheduled command failed: %w", err)
+	}
+
+	return schedules.Add(ctx, command.ID, command.FireAt)
+}
+
+// Get returns the scheduled command
+func Get(ctx context.Context, id string) (*models.ScheduledCommand, error) {
+	rawData, err := cache.Get(ctx, fmt.Sprintf(scheduleKey, id))
+	if errors.Is(err, cache.ErrKeyNotExists) {
+		return nil, ErrScheduleNotFound
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("read scheduled command failed: %w", err)
+	}
+
+	command := &models.ScheduledCommand{}
+
+	err = json.Unmarshal([]byte(rawData), command)
+	if err != nil {
+		return nil, fmt.Errorf("scheduled command json unmarshal error: %w", err)
+	}
+
+	return command, nil
+}
+
+// ListByUser returns the pending commands of the user sorted by fire time
+func ListByUser(ctx context.Context, userID int64) ([]*models.ScheduledCommand, error) {
+	key := fmt.Sprintf(userSchedulesKey, userID)
+
+	ids, err := cache.GetAllUnorderedSetMembers(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("read user scheduled commands failed: %w", err)
+	}
+
+	commands := make([]*models.ScheduledCommand, 0, len(ids))
+
+	for _, id := range ids {
+		command, err := Get(ctx, id)
+		if errors.Is(err, ErrScheduleNotFound) {
+			// the command expired without firing, e.g. the ticks were stopped
+			_ = cache.RemoveFromUnorderedSet(ctx, key, id)
+
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		commands = append(commands, command)
+	}
+
+	sort.Slice(commands, func(i, j int) bool {
+		return commands[i].FireAt.Before(commands[j].FireAt)
+	})
+
+	return commands, nil
+}
+
+// Delete cancels a pending command of the user
+func Delete(ctx context.Context, userID int64, id string) error {
+	command, err := Get(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if command.Message.From.ID != userID {
+		return ErrScheduleNotFound
+	}
+
+	return remove(ctx, command)
+}
+
+func remove(ctx context.Context, command *models.ScheduledCommand) error {
+	err := schedules.Remove(ctx, command.ID)
+	if err != nil {
+		return err
+	}
+
+	err = cache.Del(ctx, fmt.Sprintf(scheduleKey, command.ID))
+	if err != nil {
+		return fmt.Errorf("delete scheduled command failed: %w", err)
+	}
+
+	return cache.RemoveFromUnorderedSet(ctx, fmt.Sprintf(userSchedulesKey, command.Message.From.ID), command.ID)
+}
+
+// ClaimDue claims the next command due at now, ErrScheduleClaimed is returned to the tick that lost the claim
+func ClaimDue(ctx context.Context, now time.Time) (*models.ScheduledCommand, error) {
+	var command *models.ScheduledCommand
+
+	err := schedules.ClaimDue(ctx, now, func(ctx context.Context, id string) (err error) {
+		command, err = getCommand(ctx, id)
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return command, nil
+}
+
+// Complete reschedules a claimed recurring command at its next fire time after now, the commands
+// that run once are deleted
+func Complete(ctx context.Context, command *models.ScheduledCommand, now time.Time) error {
+	if !command.IsRecurring() {
+		return remove(ctx, command)
+	}
+
+	exists, err := cache.Exists(ctx, fmt.Sprintf(scheduleKey, command.ID))
+	if err != nil {
+		return fmt.Errorf("read scheduled command failed: %w", err)
+	}
+
+	// the user canceled the command after it was claimed
+	if !exists {
+		return nil
+	}
+
+	for !command.FireAt.After(now) {
+		command.FireAt = command.FireAt.Add(command.Every)
+	}
+
+	return save(ctx, command)
+}
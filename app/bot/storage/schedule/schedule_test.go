@@ -0,0 +1,195 @@
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"shared/app/bot/models"
+
+	"bitbucket.org/truora/scrap-services/shared/cache"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestCommand(c *require.Assertions, userID int64, fireAt time.Time, every time.Duration) *models.ScheduledCommand {
+	id, err := NewID()
+	c.NoError(err)
+
+	return &models.ScheduledCommand{
+		ID:      id,
+		Text:    "/deployterraformstaging checks/core",
+		Every:   every,
+		FireAt:  fireAt,
+		Message: models.CallbackMessage{From: models.From{ID: userID}},
+	}
+}
+
+func TestAddListDelete(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+
+	ctx := context.Background()
+	now := time.Now()
+
+	later := newTestCommand(c, 1, now.Add(time.Hour), 0)
+	sooner := newTestCommand(c, 1, now.Add(time.Minute), 0)
+	other := newTestCommand(c, 2, now.Add(time.Minute), 0)
+
+	c.NoError(Add(ctx, later))
+	c.NoError(Add(ctx, sooner))
+	c.NoError(Add(ctx, other))
+
+	commands, err := ListByUser(ctx, 1)
+	c.NoError(err)
+	c.Len(commands, 2)
+	c.Equal(sooner.ID, commands[0].ID)
+	c.Equal(later.ID, commands[1].ID)
+
+	err = Delete(ctx, 2, sooner.ID)
+	c.ErrorIs(err, ErrScheduleNotFound)
+
+	err = Delete(ctx, 1, sooner.ID)
+	c.NoError(err)
+
+	_, err = Get(ctx, sooner.ID)
+	c.ErrorIs(err, ErrScheduleNotFound)
+
+	commands, err = ListByUser(ctx, 1)
+	c.NoError(err)
+	c.Len(commands, 1)
+}
+
+func TestAddTooMany(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+
+	oldMaxPerUser := MaxPerUser
+	MaxPerUser = 1
+
+	defer func() {
+		MaxPerUser = oldMaxPerUser
+	}()
+
+	ctx := context.Background()
+
+	c.NoError(Add(ctx, newTestCommand(c, 1, time.Now().Add(time.Hour), 0)))
+	c.ErrorIs(Add(ctx, newTestCommand(c, 1, time.Now().Add(time.Hour), 0)), ErrTooManySchedules)
+}
+
+func TestClaimDue(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+
+	ctx := context.Background()
+	now := time.Now()
+
+	_, err := ClaimDue(ctx, now)
+	c.ErrorIs(err, ErrNoDueSchedule)
+
+	once := newTestCommand(c, 1, now.Add(-time.Minute), 0)
+	c.NoError(Add(ctx, once))
+	c.NoError(Add(ctx, newTestCommand(c, 1, now.Add(time.Hour), 0)))
+
+	claimed, err := ClaimDue(ctx, now)
+	c.NoError(err)
+	c.Equal(once.ID, claimed.ID)
+
+	// the next command is not due yet
+	_, err = ClaimDue(ctx, now)
+	c.ErrorIs(err, ErrNoDueSchedule)
+
+	c.NoError(Complete(ctx, claimed, now))
+
+	_, err = Get(ctx, once.ID)
+	c.ErrorIs(err, ErrScheduleNotFound)
+
+	commands, err := ListByUser(ctx, 1)
+	c.NoError(err)
+	c.Len(commands, 1)
+}
+
+func TestClaimDueOnce(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+
+	ctx := context.Background()
+	now := time.Now()
+
+	command := newTestCommand(c, 1, now.Add(-time.Minute), 0)
+	c.NoError(Add(ctx, command))
+
+	// another tick claimed the command while this one was reading it
+	getCommand = func(ctx context.Context, id string) (*models.ScheduledCommand, error) {
+		c.NoError(cache.RemoveFromToOrderedSet(ctx, schedulesKey, id))
+
+		return Get(ctx, id)
+	}
+
+	defer func() { getCommand = Get }()
+
+	_, err := ClaimDue(ctx, now)
+	c.ErrorIs(err, ErrScheduleClaimed)
+}
+
+func TestClaimDueMissing(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+
+	ctx := context.Background()
+	now := time.Now()
+
+	missing := newTestCommand(c, 1, now.Add(-2*time.Minute), 0)
+	c.NoError(Add(ctx, missing))
+	c.NoError(cache.Del(ctx, fmt.Sprintf(scheduleKey, missing.ID)))
+
+	command := newTestCommand(c, 1, now.Add(-time.Minute), 0)
+	c.NoError(Add(ctx, command))
+
+	_, err := ClaimDue(ctx, now)
+	c.ErrorIs(err, ErrScheduleNotFound)
+
+	// the missing command does not block the next one
+	claimed, err := ClaimDue(ctx, now)
+	c.NoError(err)
+	c.Equal(command.ID, claimed.ID)
+}
+
+func TestCompleteRecurring(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+
+	ctx := context.Background()
+	now := time.Now()
+
+	command := newTestCommand(c, 1, now.Add(-90*time.Minute), time.Hour)
+	c.NoError(Add(ctx, command))
+
+	claimed, err := ClaimDue(ctx, now)
+	c.NoError(err)
+	c.NoError(Complete(ctx, claimed, now))
+
+	// the missed runs are skipped
+	stored, err := Get(ctx, command.ID)
+	c.NoError(err)
+	c.Equal(command.FireAt.Add(2*time.Hour).Unix(), stored.FireAt.Unix())
+
+	_, err = ClaimDue(ctx, now)
+	c.ErrorIs(err, ErrNoDueSchedule)
+
+	claimed, err = ClaimDue(ctx, stored.FireAt)
+	c.NoError(err)
+
+	// the user canceled the command after it was claimed
+	c.NoError(Delete(ctx, 1, command.ID))
+	c.NoError(Complete(ctx, claimed, stored.FireAt))
+
+	_, err = Get(ctx, command.ID)
+	c.ErrorIs(err, ErrScheduleNotFound)
+}
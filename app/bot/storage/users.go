@@ -4,14 +4,15 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"shared/app/bot/models"
 
-	"bitbucket.org/truora/scrap-services/deployments/approval"
 	"bitbucket.org/truora/scrap-services/shared/awscore"
 	"bitbucket.org/truora/scrap-services/shared/env"
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
@@ -24,17 +25,25 @@ const (
 
 	idIndex                 = "id_index"
 	bitbucketAccountIDIndex = "bitbucket_account_id_index"
+	slackIDIndex            = "slack_id_index"
+
+	usersPageLimit = 100
 )
 
 var (
 	useAssumeRole = env.GetBool("USE_ASSUME_ROLE", false)
 	roleToAssume  = env.GetString("ROLE_TO_ASSUME", "arn:aws:iam::031975712270:role/access_betty_users")
 	dynamoClient  dynamodbiface.DynamoDBAPI
+	// selfSignupDomains are the email domains whose users are created when they register, the users of
+	// other domains must be added by an admin first
+	selfSignupDomains = parseDomains(env.GetString("BOT_SELF_SIGNUP_DOMAINS", ""))
 
 	// ErrUserNotFound when user is not in the table
 	ErrUserNotFound = errors.New("user not found")
 	// ErrMissingEmail when user email is missing
 	ErrMissingEmail = errors.New("missing email")
+	// ErrUserExists when the email added by an admin is already a user
+	ErrUserExists = errors.New("user already exists")
 )
 
 func init() {
@@ -188,11 +197,43 @@ func GetVerifiedUser(ctx context.Context, email string, id int64) (*models.From,
 	return user, nil
 }
 
+// GetVerifiedUserBySlackID find verified user by the ID of the linked Slack user
+func GetVerifiedUserBySlackID(ctx context.Context, slackID string) (*models.From, error) {
+	input := &dynamodb.QueryInput{
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":slack_id":       {S: aws.String(slackID)},
+			":email_verified": {BOOL: aws.Bool(true)},
+		},
+		FilterExpression:       aws.String("email_verified = :email_verified"),
+		KeyConditionExpression: aws.String("slack_id = :slack_id"),
+		IndexName:              aws.String(slackIDIndex),
+		TableName:              aws.String(bettyTableUsers),
+	}
+
+	result, err := dynamoClient.QueryWithContext(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	if *result.Count == 0 {
+		return nil, ErrUserNotFound
+	}
+
+	var user *models.From
+
+	err = dynamodbattribute.UnmarshalMap(result.Items[0], &user)
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
 // SaveUser saves user to dynamodb
 func SaveUser(ctx context.Context, user models.From) error {
 	user.CreationDate = time.Now()
 	user.ExpirationTime = time.Now().Add(5 * time.Minute).Unix()
-	user.UserRole = approval.RoleDevelopers
+	user.UserRole = models.RoleDevelopers
 
 	item, err := dynamodbattribute.MarshalMap(user)
 	if err != nil {
@@ -252,3 +293,242 @@ func PutUser(ctx context.Context, user *models.From) error {
 
 	return err
 }
+
+// AddUser adds the email with the role so its owner can link it with /register, the email is not verified
+// until the owner sends the code emailed to it
+func AddUser(ctx context.Context, email, role string) (*models.From, error) {
+	if email == "" {
+		return nil, ErrMissingEmail
+	}
+
+	user := &models.From{
+		Email:        email,
+		CreationDate: time.Now(),
+		UserRole:     role,
+	}
+
+	item, err := dynamodbattribute.MarshalMap(user)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = dynamoClient.PutItemWithContext(ctx, &dynamodb.PutItemInput{
+		Item:                item,
+		TableName:           aws.String(bettyTableUsers),
+		ConditionExpression: aws.String("attribute_not_exists(email)"),
+	})
+
+	var awsErr awserr.Error
+	if errors.As(err, &awsErr) && awsErr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		return nil, ErrUserExists
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// LinkTelegramUser marks the email as verified and links it to the Telegram user. ErrUserNotFound is
+// returned when the email was not added by an admin, unless its domain allows self signup and the user is
+// created with the default role
+func LinkTelegramUser(ctx context.Context, email string, telegramUser models.From) (*models.From, error) {
+	user, err := GetUser(ctx, email)
+	if errors.Is(err, ErrUserNotFound) {
+		if !allowsSelfSignup(email) {
+			return nil, err
+		}
+
+		user = &models.From{
+			Email:        email,
+			CreationDate: time.Now(),
+			UserRole:     models.RoleDevelopers,
+		}
+	} else if err != nil {
+		return nil, err
+	}
+
+	user.ID = telegramUser.ID
+	user.FirstName = telegramUser.FirstName
+	user.LastName = telegramUser.LastName
+	user.Username = telegramUser.Username
+	user.EmailVerified = true
+	user.ExpirationTime = 0
+
+	return user, PutUser(ctx, user)
+}
+
+// allowsSelfSignup returns true if the domain of the email is one of the self signup domains
+func allowsSelfSignup(email string) bool {
+	_, domain, ok := strings.Cut(email, "@")
+	if !ok {
+		return false
+	}
+
+	for _, allowed := range selfSignupDomains {
+		if strings.EqualFold(domain, allowed) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseDomains splits the comma separated domains ignoring the empty ones
+func parseDomains(value string) []string {
+	domains := []string{}
+
+	for _, domain := range strings.Split(value, ",") {
+		domain = strings.TrimSpace(domain)
+		if domain != "" {
+			domains = append(domains, domain)
+		}
+	}
+
+	return domains
+}
+
+// ListUsers returns a page of users, the page starts after the email of the cursor and the returned
+// cursor is empty when there are no more users
+func ListUsers(ctx context.Context, limit int64, cursor string) ([]*models.From, string, error) {
+	input := &dynamodb.ScanInput{
+		TableName: aws.String(bettyTableUsers),
+		Limit:     aws.Int64(limit),
+	}
+
+	if cursor != "" {
+		input.ExclusiveStartKey = map[string]*dynamodb.AttributeValue{
+			"email": {S: aws.String(cursor)},
+		}
+	}
+
+	result, err := dynamoClient.ScanWithContext(ctx, input)
+	if err != nil {
+		return nil, "", err
+	}
+
+	users := []*models.From{}
+
+	err = dynamodbattribute.UnmarshalListOfMaps(result.Items, &users)
+	if err != nil {
+		return nil, "", err
+	}
+
+	next := ""
+	if email, ok := result.LastEvaluatedKey["email"]; ok && email.S != nil {
+		next = *email.S
+	}
+
+	return users, next, nil
+}
+
+// ListVerifiedUsers returns every user with a verified email linked to a Telegram user
+func ListVerifiedUsers(ctx context.Context) ([]*models.From, error) {
+	verified := []*models.From{}
+	cursor := ""
+
+	for {
+		users, next, err := ListUsers(ctx, usersPageLimit, cursor)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, user := range users {
+			if user.EmailVerified && user.ID != 0 {
+				verified = append(verified, user)
+			}
+		}
+
+		if next == "" {
+			return verified, nil
+		}
+
+		cursor = next
+	}
+}
+
+// UpdateUserRole changes the role of the user
+func UpdateUserRole(ctx context.Context, email, role string) error {
+	return updateUser(ctx, email, "SET user_role = :user_role", map[string]*dynamodb.AttributeValue{
+		":user_role": {S: aws.String(role)},
+	})
+}
+
+// RevokeUserVerification marks the email of the user as not verified, the user can not run commands
+// until the email is verified again
+func RevokeUserVerification(ctx context.Context, email string) error {
+	return updateUser(ctx, email, "SET email_verified = :email_verified", map[string]*dynamodb.AttributeValue{
+		":email_verified": {BOOL: aws.Bool(false)},
+	})
+}
+
+// SetBitbucketID links the Bitbucket account ID to the user, an empty ID unlinks the account
+func SetBitbucketID(ctx context.Context, email, bitbucketID string) error {
+	if bitbucketID == "" {
+		// empty strings are not valid keys of the bitbucket index so the attribute is removed
+		return updateUser(ctx, email, "REMOVE bitbucket_account_id", nil)
+	}
+
+	return updateUser(ctx, email, "SET bitbucket_account_id = :bitbucket_account_id", map[string]*dynamodb.AttributeValue{
+		":bitbucket_account_id": {S: aws.String(bitbucketID)},
+	})
+}
+
+// SetSlackID links the Slack user ID to the user, an empty ID unlinks the Slack user
+func SetSlackID(ctx context.Context, email, slackID string) error {
+	if slackID == "" {
+		// empty strings are not valid keys of the slack index so the attribute is removed
+		return updateUser(ctx, email, "REMOVE slack_id", nil)
+	}
+
+	return updateUser(ctx, email, "SET slack_id = :slack_id", map[string]*dynamodb.AttributeValue{
+		":slack_id": {S: aws.String(slackID)},
+	})
+}
+
+// DeleteUser deletes the user
+func DeleteUser(ctx context.Context, email string) error {
+	_, err := dynamoClient.DeleteItemWithContext(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(bettyTableUsers),
+		Key: map[string]*dynamodb.AttributeValue{
+			"email": {S: aws.String(email)},
+		},
+		ConditionExpression: aws.String("attribute_exists(email)"),
+	})
+
+	return userUpdateError(err)
+}
+
+// updateUser applies the update expression to an existing user
+func updateUser(ctx context.Context, email, expression string, values map[string]*dynamodb.AttributeValue) error {
+	if email == "" {
+		return ErrMissingEmail
+	}
+
+	params := &dynamodb.UpdateItemInput{
+		TableName: aws.String(bettyTableUsers),
+		Key: map[string]*dynamodb.AttributeValue{
+			"email": {S: aws.String(email)},
+		},
+		UpdateExpression:    aws.String(expression),
+		ConditionExpression: aws.String("attribute_exists(email)"),
+	}
+
+	if len(values) > 0 {
+		params.ExpressionAttributeValues = values
+	}
+
+	_, err := dynamoClient.UpdateItemWithContext(ctx, params)
+
+	return userUpdateError(err)
+}
+
+func userUpdateError(err error) error {
+	var awsErr awserr.Error
+	if errors.As(err, &awsErr) && awsErr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		return ErrUserNotFound
+	}
+
+	return err
+}
@@ -1,7 +1,8 @@
 package storage
 
 import (
-	"bitbucket.org/truora/scrap-services/devops/models"
+	"shared/app/bot/models"
+
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/truora/minidyn"
@@ -26,6 +27,10 @@ func InitDynamoMock() {
 				AttributeName: aws.String("id"),
 				AttributeType: aws.String("N"),
 			},
+			{
+				AttributeName: aws.String("slack_id"),
+				AttributeType: aws.String("S"),
+			},
 		},
 		BillingMode: aws.String("PAY_PER_REQUEST"),
 		KeySchema: []*dynamodb.KeySchemaElement{
@@ -59,6 +64,18 @@ func InitDynamoMock() {
 					},
 				},
 			},
+			{
+				IndexName: aws.String(slackIDIndex),
+				Projection: &dynamodb.Projection{
+					ProjectionType: aws.String(dynamodb.ProjectionTypeAll),
+				},
+				KeySchema: []*dynamodb.KeySchemaElement{
+					{
+						AttributeName: aws.String("slack_id"),
+						KeyType:       aws.String("HASH"),
+					},
+				},
+			},
 		},
 	})
 	checkErr(err)
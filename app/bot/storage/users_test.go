@@ -4,7 +4,8 @@ import (
 	"context"
 	"testing"
 
-	"bitbucket.org/truora/scrap-services/devops/models"
+	"shared/app/bot/models"
+
 	"github.com/stretchr/testify/require"
 	"github.com/truora/minidyn"
 )
@@ -215,6 +216,35 @@ func TestErrorGetTelegramUserByBitbucketID(t *testing.T) {
 	c.EqualError(ErrUserNotFound, err.Error())
 }
 
+func TestGetVerifiedUserBySlackID(t *testing.T) {
+	c := require.New(t)
+
+	ctx := context.Background()
+
+	InitDynamoMock()
+
+	user := GetDummyUser(int64(123456), "dummy-email@example.com", "dummy-name", "dummy-last-name", "dummy-phone-number", "dummy-bitbucket-id")
+	c.NoError(PutUser(ctx, user))
+	c.NoError(SetSlackID(ctx, user.Email, "U024BE7LH"))
+
+	_, err := GetVerifiedUserBySlackID(ctx, "U024BE7LH")
+	c.ErrorIs(err, ErrUserNotFound)
+
+	c.NoError(VerifyUserEmail(ctx, user.Email))
+
+	user, err = GetVerifiedUserBySlackID(ctx, "U024BE7LH")
+	c.NoError(err)
+	c.Equal(int64(123456), user.ID)
+	c.Equal("U024BE7LH", user.SlackID)
+
+	c.NoError(SetSlackID(ctx, user.Email, ""))
+
+	_, err = GetVerifiedUserBySlackID(ctx, "U024BE7LH")
+	c.ErrorIs(err, ErrUserNotFound)
+
+	c.ErrorIs(SetSlackID(ctx, "missing@example.com", "U024BE7LH"), ErrUserNotFound)
+}
+
 func TestGetTelegramUserFail(t *testing.T) {
 	c := require.New(t)
 
@@ -276,3 +306,184 @@ func TestPutUserError(t *testing.T) {
 	c.Equal(minidyn.ErrForcedFailure, err)
 	c.Panics(func() { checkErr(err) })
 }
+
+func TestLinkTelegramUser(t *testing.T) {
+	c := require.New(t)
+
+	ctx := context.Background()
+
+	InitDynamoMock()
+
+	telegramUser := models.From{ID: int64(123456), FirstName: "Beto", Username: "bgomez"}
+
+	// an email that was not added by an admin is not linked
+	_, err := LinkTelegramUser(ctx, "bgomez@truora.com", telegramUser)
+	c.ErrorIs(err, ErrUserNotFound)
+
+	_, err = GetTelegramUser(ctx, int64(123456))
+	c.ErrorIs(err, ErrUserNotFound)
+
+	_, err = GetUser(ctx, "bgomez@truora.com")
+	c.ErrorIs(err, ErrUserNotFound)
+
+	// the users of the self signup domains are created
+	selfSignupDomains = parseDomains(" Truora.com, ,")
+
+	defer func() { selfSignupDomains = []string{} }()
+
+	_, err = LinkTelegramUser(ctx, "bgomez@truora.com.evil.com", telegramUser)
+	c.ErrorIs(err, ErrUserNotFound)
+
+	user, err := LinkTelegramUser(ctx, "bgomez@truora.com", telegramUser)
+	c.NoError(err)
+	c.True(user.EmailVerified)
+	c.Equal(models.RoleDevelopers, user.UserRole)
+
+	savedUser, err := GetTelegramUser(ctx, int64(123456))
+	c.NoError(err)
+	c.Equal("bgomez@truora.com", savedUser.Email)
+	c.Equal("bgomez", savedUser.Username)
+
+	existingUser := GetDummyUser(int64(654321), "dummy-email@example.com", "dummy-name", "dummy-last-name", "dummy-phone-number", "dummy-bitbucket-id")
+	existingUser.EmailVerified = false
+
+	c.NoError(PutUser(ctx, existingUser))
+
+	user, err = LinkTelegramUser(ctx, "dummy-email@example.com", models.From{ID: int64(111111), FirstName: "Other"})
+	c.NoError(err)
+	c.Equal(int64(111111), user.ID)
+	c.True(user.EmailVerified)
+	c.Equal("dummy-bitbucket-id", user.BitbucketID)
+}
+
+func TestAddUser(t *testing.T) {
+	c := require.New(t)
+
+	ctx := context.Background()
+
+	InitDynamoMock()
+
+	_, err := AddUser(ctx, "", models.RoleDevelopers)
+	c.ErrorIs(err, ErrMissingEmail)
+
+	user, err := AddUser(ctx, "dev@dummy.com", models.RoleDevelopers)
+	c.NoError(err)
+	c.False(user.EmailVerified)
+
+	_, err = AddUser(ctx, "dev@dummy.com", models.RoleAdmin)
+	c.ErrorIs(err, ErrUserExists)
+
+	user, err = LinkTelegramUser(ctx, "dev@dummy.com", models.From{ID: int64(222222), Username: "dev"})
+	c.NoError(err)
+	c.True(user.EmailVerified)
+	c.Equal(models.RoleDevelopers, user.UserRole)
+}
+
+func TestListUsers(t *testing.T) {
+	c := require.New(t)
+
+	ctx := context.Background()
+
+	InitDynamoMock()
+
+	users, cursor, err := ListUsers(ctx, 2, "")
+	c.NoError(err)
+	c.Empty(users)
+	c.Empty(cursor)
+
+	for _, email := range []string{"a@example.com", "b@example.com", "c@example.com"} {
+		c.NoError(PutUser(ctx, &models.From{Email: email}))
+	}
+
+	seen := map[string]bool{}
+
+	users, cursor, err = ListUsers(ctx, 2, "")
+	c.NoError(err)
+	c.Len(users, 2)
+	c.NotEmpty(cursor)
+
+	for _, user := range users {
+		seen[user.Email] = true
+	}
+
+	for cursor != "" {
+		users, cursor, err = ListUsers(ctx, 2, cursor)
+		c.NoError(err)
+
+		for _, user := range users {
+			seen[user.Email] = true
+		}
+	}
+
+	c.Len(seen, 3)
+}
+
+func TestUpdateUser(t *testing.T) {
+	c := require.New(t)
+
+	ctx := context.Background()
+
+	InitDynamoMock()
+
+	user := GetDummyUser(int64(123456), "dummy-email@example.com", "dummy-name", "dummy-last-name", "dummy-phone-number", "dummy-bitbucket-id")
+	user.EmailVerified = true
+	c.NoError(PutUser(ctx, user))
+
+	c.NoError(UpdateUserRole(ctx, user.Email, "leads"))
+	c.NoError(SetBitbucketID(ctx, user.Email, "other-bitbucket-id"))
+
+	savedUser, err := GetUser(ctx, user.Email)
+	c.NoError(err)
+	c.Equal("leads", savedUser.UserRole)
+	c.Equal("other-bitbucket-id", savedUser.BitbucketID)
+
+	c.NoError(SetBitbucketID(ctx, user.Email, ""))
+	c.NoError(RevokeUserVerification(ctx, user.Email))
+
+	savedUser, err = GetUser(ctx, user.Email)
+	c.NoError(err)
+	c.Empty(savedUser.BitbucketID)
+	c.False(savedUser.EmailVerified)
+
+	err = UpdateUserRole(ctx, "missing@example.com", "leads")
+	c.ErrorIs(err, ErrUserNotFound)
+
+	err = UpdateUserRole(ctx, "", "leads")
+	c.ErrorIs(err, ErrMissingEmail)
+}
+
+func TestDeleteUser(t *testing.T) {
+	c := require.New(t)
+
+	ctx := context.Background()
+
+	InitDynamoMock()
+
+	user := GetDummyUser(int64(123456), "dummy-email@example.com", "dummy-name", "dummy-last-name", "dummy-phone-number", "dummy-bitbucket-id")
+	c.NoError(PutUser(ctx, user))
+
+	c.NoError(DeleteUser(ctx, user.Email))
+
+	_, err := GetUser(ctx, user.Email)
+	c.ErrorIs(err, ErrUserNotFound)
+
+	err = DeleteUser(ctx, user.Email)
+	c.ErrorIs(err, ErrUserNotFound)
+}
+
+func TestListVerifiedUsers(t *testing.T) {
+	c := require.New(t)
+
+	ctx := context.Background()
+
+	InitDynamoMock()
+
+	c.NoError(PutUser(ctx, &models.From{ID: 1, Email: "verified@example.com", EmailVerified: true}))
+	c.NoError(PutUser(ctx, &models.From{ID: 2, Email: "unverified@example.com"}))
+	c.NoError(PutUser(ctx, &models.From{Email: "unlinked@example.com", EmailVerified: true}))
+
+	users, err := ListVerifiedUsers(ctx)
+	c.NoError(err)
+	c.Len(users, 1)
+	c.Equal("verified@example.com", users[0].Email)
+}
@@ -0,0 +1,216 @@
+// Package verification issues and checks the one-time codes sent to verify the email of the bot users,
+// only a salted hash of the code is stored in the cache
+package verification
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"bitbucket.org/truora/scrap-services/shared/cache"
+	"bitbucket.org/truora/scrap-services/shared/env"
+)
+
+const (
+	// codeKey, attemptsKey, cooldownKey and sendsKey are keyed by Telegram user ID
+	codeKey     = "BOT-VERIFICATION-CODE:%d"
+	attemptsKey = "BOT-VERIFICATION-ATTEMPTS:%d"
+	cooldownKey = "BOT-VERIFICATION-COOLDOWN:%d"
+	sendsKey    = "BOT-VERIFICATION-SENDS:%d"
+
+	codeDigits  = 6
+	saltLength  = 16
+	sendsWindow = time.Hour
+)
+
+var (
+	// ErrCodeNotFound when the user has no pending code or it expired
+	ErrCodeNotFound = errors.New("verification code not found")
+	// ErrInvalidCode when the code does not match
+	ErrInvalidCode = errors.New("invalid verification code")
+	// ErrTooManyAttempts when the code was discarded after too many invalid attempts
+	ErrTooManyAttempts = errors.New("too many verification attempts")
+	// ErrResendTooSoon when a new code is requested before the cooldown ends
+	ErrResendTooSoon = errors.New("verification code requested too soon")
+	// ErrTooManyCodes when the user requested too many codes in the last hour
+	ErrTooManyCodes = errors.New("too many verification codes requested")
+
+	codeMinutes     = env.GetInt64("VERIFICATION_CODE_MINUTES", 10)
+	codeTTL         = time.Duration(codeMinutes) * time.Minute
+	cooldownSeconds = env.GetInt64("VERIFICATION_RESEND_SECONDS", 60)
+	// Cooldown is the time to wait before requesting a new code
+	Cooldown = time.Duration(cooldownSeconds) * time.Second
+	// MaxAttempts is the number of codes the user can try before the code is discarded
+	MaxAttempts = env.GetInt64("VERIFICATION_MAX_ATTEMPTS", 5)
+	// MaxCodesPerHour is the number of codes the user can request in an hour
+	MaxCodesPerHour = env.GetInt64("VERIFICATION_MAX_CODES_PER_HOUR", 5)
+)
+
+type pendingCode struct {
+	Email string `json:"email"`
+	Salt  string `json:"salt"`
+	Hash  string `json:"hash"`
+}
+
+// Issue creates a new code to verify the email of the Telegram user, the previous code of the user is
+// replaced. New codes are rate limited by a cooldown and a maximum per hour
+func Issue(ctx context.Context, userID int64, email string) (string, error) {
+	allowed, err := cache.AddOnce(ctx, fmt.Sprintf(cooldownKey, userID), time.Now().Unix(), Cooldown)
+	if err != nil {
+		return "", fmt.Errorf("check verification cooldown failed: %w", err)
+	}
+
+	if !allowed {
+		return "", ErrResendTooSoon
+	}
+
+	sends, err := cache.Incr(ctx, fmt.Sprintf(sendsKey, userID))
+	if err != nil {
+		return "", fmt.Errorf("count verification codes failed: %w", err)
+	}
+
+	if sends == 1 {
+		err = cache.Expire(ctx, fmt.Sprintf(sendsKey, userID), sendsWindow)
+		if err != nil {
+			return "", fmt.Errorf("expire verification codes count failed: %w", err)
+		}
+	}
+
+	if sends > MaxCodesPerHour {
+		return "", ErrTooManyCodes
+	}
+
+	code, err := newCode()
+	if err != nil {
+		return "", err
+	}
+
+	salt := make([]byte, saltLength)
+
+	_, err = rand.Read(salt)
+	if err != nil {
+		return "", fmt.Errorf("generate verification salt failed: %w", err)
+	}
+
+	pending := &pendingCode{Email: email, Salt: hex.EncodeToString(salt)}
+	pending.Hash = hashCode(pending.Salt, userID, email, code)
+
+	rawData, err := json.Marshal(pending)
+	if err != nil {
+		return "", err
+	}
+
+	err = cache.Del(ctx, fmt.Sprintf(attemptsKey, userID))
+	if err != nil {
+		return "", fmt.Errorf("reset verification attempts failed: %w", err)
+	}
+
+	err = cache.Add(ctx, fmt.Sprintf(codeKey, userID), string(rawData), codeTTL)
+	if err != nil {
+		return "", fmt.Errorf("store verification code failed: %w", err)
+	}
+
+	return code, nil
+}
+
+// Verify checks the code of the Telegram user and returns the verified email, the code can only be
+// used once and it is discarded after MaxAttempts invalid attempts
+func Verify(ctx context.Context, userID int64, code string) (string, error) {
+	pending, err := getPendingCode(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+
+	// the attempts are counted before comparing so concurrent guesses can not exceed the maximum
+	attempts, err := cache.Incr(ctx, fmt.Sprintf(attemptsKey, userID))
+	if err != nil {
+		return "", fmt.Errorf("count verification attempts failed: %w", err)
+	}
+
+	if attempts == 1 {
+		err = cache.Expire(ctx, fmt.Sprintf(attemptsKey, userID), codeTTL)
+		if err != nil {
+			return "", fmt.Errorf("expire verification attempts failed: %w", err)
+		}
+	}
+
+	if attempts > MaxAttempts {
+		return "", discard(ctx, userID, ErrTooManyAttempts)
+	}
+
+	hash := hashCode(pending.Salt, userID, pending.Email, strings.TrimSpace(code))
+	if subtle.ConstantTimeCompare([]byte(hash), []byte(pending.Hash)) != 1 {
+		if attempts == MaxAttempts {
+			return "", discard(ctx, userID, ErrTooManyAttempts)
+		}
+
+		return "", ErrInvalidCode
+	}
+
+	return pending.Email, discard(ctx, userID, nil)
+}
+
+// PendingEmail returns the email of the pending code of the user
+func PendingEmail(ctx context.Context, userID int64) (string, error) {
+	pending, err := getPendingCode(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+
+	return pending.Email, nil
+}
+
+func getPendingCode(ctx context.Context, userID int64) (*pendingCode, error) {
+	rawData, err := cache.Get(ctx, fmt.Sprintf(codeKey, userID))
+	if errors.Is(err, cache.ErrKeyNotExists) {
+		return nil, ErrCodeNotFound
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("read verification code failed: %w", err)
+	}
+
+	pending := &pendingCode{}
+
+	err = json.Unmarshal([]byte(rawData), pending)
+	if err != nil {
+		return nil, fmt.Errorf("verification code json unmarshal error: %w", err)
+	}
+
+	return pending, nil
+}
+
+// discard deletes the code and its attempts and returns the reason
+func discard(ctx context.Context, userID int64, reason error) error {
+	err := cache.Del(ctx, fmt.Sprintf(codeKey, userID), fmt.Sprintf(attemptsKey, userID))
+	if err != nil {
+		return fmt.Errorf("delete verification code failed: %w", err)
+	}
+
+	return reason
+}
+
+func newCode() (string, error) {
+	limit := new(big.Int).Exp(big.NewInt(10), big.NewInt(codeDigits), nil)
+
+	number, err := rand.Int(rand.Reader, limit)
+	if err != nil {
+		return "", fmt.Errorf("generate verification code failed: %w", err)
+	}
+
+	return fmt.Sprintf("%0*d", codeDigits, number), nil
+}
+
+func hashCode(salt string, userID int64, email, code string) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s\n%d\n%s\n%s", salt, userID, strings.ToLower(email), code)))
+
+	return hex.EncodeToString(sum[:])
+}
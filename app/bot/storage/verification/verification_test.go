@@ -0,0 +1,104 @@
+package verification
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"bitbucket.org/truora/scrap-services/shared/cache"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIssueAndVerify(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+
+	ctx := context.Background()
+
+	_, err := Verify(ctx, 1, "123456")
+	c.ErrorIs(err, ErrCodeNotFound)
+
+	code, err := Issue(ctx, 1, "dummy_email@dummy.com")
+	c.NoError(err)
+	c.Len(code, codeDigits)
+
+	// only the hash is stored
+	rawData, err := cache.Get(ctx, fmt.Sprintf(codeKey, 1))
+	c.NoError(err)
+	c.NotContains(rawData, code)
+
+	email, err := PendingEmail(ctx, 1)
+	c.NoError(err)
+	c.Equal("dummy_email@dummy.com", email)
+
+	// the code is bound to the user
+	_, err = Verify(ctx, 2, code)
+	c.ErrorIs(err, ErrCodeNotFound)
+
+	email, err = Verify(ctx, 1, " "+code+" ")
+	c.NoError(err)
+	c.Equal("dummy_email@dummy.com", email)
+
+	// the code can only be used once
+	_, err = Verify(ctx, 1, code)
+	c.ErrorIs(err, ErrCodeNotFound)
+}
+
+func TestVerifyTooManyAttempts(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+
+	ctx := context.Background()
+
+	code, err := Issue(ctx, 1, "dummy_email@dummy.com")
+	c.NoError(err)
+
+	wrong := "000000"
+	if code == wrong {
+		wrong = "111111"
+	}
+
+	for i := int64(1); i < MaxAttempts; i++ {
+		_, err = Verify(ctx, 1, wrong)
+		c.ErrorIs(err, ErrInvalidCode)
+	}
+
+	_, err = Verify(ctx, 1, wrong)
+	c.ErrorIs(err, ErrTooManyAttempts)
+
+	// the right code does not work after the code was discarded
+	_, err = Verify(ctx, 1, code)
+	c.ErrorIs(err, ErrCodeNotFound)
+}
+
+func TestIssueRateLimited(t *testing.T) {
+	c := require.New(t)
+
+	cache.InitMock()
+
+	ctx := context.Background()
+
+	_, err := Issue(ctx, 1, "dummy_email@dummy.com")
+	c.NoError(err)
+
+	_, err = Issue(ctx, 1, "dummy_email@dummy.com")
+	c.ErrorIs(err, ErrResendTooSoon)
+
+	// other users are not limited
+	_, err = Issue(ctx, 2, "other@dummy.com")
+	c.NoError(err)
+
+	for i := int64(1); i < MaxCodesPerHour; i++ {
+		c.NoError(cache.Del(ctx, fmt.Sprintf(cooldownKey, 1)))
+
+		_, err = Issue(ctx, 1, "dummy_email@dummy.com")
+		c.NoError(err)
+	}
+
+	c.NoError(cache.Del(ctx, fmt.Sprintf(cooldownKey, 1)))
+
+	_, err = Issue(ctx, 1, "dummy_email@dummy.com")
+	c.ErrorIs(err, ErrTooManyCodes)
+}
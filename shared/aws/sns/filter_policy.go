@@ -0,0 +1,94 @@
+package sns
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+)
+
+const filterPolicyAttributeKey = "FilterPolicy"
+
+// FilterPolicy is a subscription filter policy, each attribute matches any of its values
+type FilterPolicy map[string][]string
+
+// NewFilterPolicy creates a filter policy matching any of the values of the attribute
+func NewFilterPolicy(attribute string, values ...string) FilterPolicy {
+	return FilterPolicy{}.With(attribute, values...)
+}
+
+// With adds the values matched by the attribute, the message must match every attribute of the policy
+func (policy FilterPolicy) With(attribute string, values ...string) FilterPolicy {
+	policy[attribute] = uniqueSorted(append(policy[attribute], values...))
+
+	return policy
+}
+
+// JSON returns the policy as expected by the FilterPolicy attribute of a subscription
+func (policy FilterPolicy) JSON() (string, error) {
+	data, err := json.Marshal(map[string][]string(policy))
+	if err != nil {
+		return "", err
+	}
+
+	return string(data), nil
+}
+
+// FilterPolicies returns the filter policy of each topic given the topic where each attribute value
+// is published, e.g. the topic of each command. The subscription of each topic matches its values
+func FilterPolicies(attribute string, topics map[string]string) map[string]FilterPolicy {
+	policies := map[string]FilterPolicy{}
+
+	for value, topic := range topics {
+		policy, ok := policies[topic]
+		if !ok {
+			policy = FilterPolicy{}
+			policies[topic] = policy
+		}
+
+		policy.With(attribute, value)
+	}
+
+	return policies
+}
+
+// SubscribeLambdaWithFilterPolicy subscribes a lambda to a sns topic only receiving the messages matching the policy
+func SubscribeLambdaWithFilterPolicy(ctx context.Context, arn string, lambdaARN string, policy FilterPolicy) (string, error) {
+	filterPolicy, err := policy.JSON()
+	if err != nil {
+		return "", err
+	}
+
+	input := sns.SubscribeInput{
+		Endpoint:              aws.String(lambdaARN),
+		Protocol:              aws.String("lambda"),
+		ReturnSubscriptionArn: true,
+		TopicArn:              aws.String(arn),
+		Attributes:            map[string]string{filterPolicyAttributeKey: filterPolicy},
+	}
+
+	result, err := SNSClient.Subscribe(ctx, &input)
+	if err != nil {
+		return "", errorManager(err)
+	}
+
+	return *result.SubscriptionArn, nil
+}
+
+func uniqueSorted(values []string) []string {
+	unique := map[string]bool{}
+	result := []string{}
+
+	for _, value := range values {
+		if !unique[value] {
+			unique[value] = true
+			result = append(result, value)
+		}
+	}
+
+	sort.Strings(result)
+
+	return result
+}
@@ -0,0 +1,49 @@
+package sns
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilterPolicy(t *testing.T) {
+	c := require.New(t)
+
+	policy := NewFilterPolicy("command", "deploy", "status", "deploy").With("chat_type", "private")
+
+	policyJSON, err := policy.JSON()
+	c.NoError(err)
+	c.JSONEq(`{"command":["deploy","status"],"chat_type":["private"]}`, policyJSON)
+}
+
+func TestFilterPolicies(t *testing.T) {
+	c := require.New(t)
+
+	policies := FilterPolicies("command", map[string]string{
+		"deploy": "arn:aws:sns:us-east-1:123:deploys",
+		"status": "arn:aws:sns:us-east-1:123:deploys",
+		"help":   "arn:aws:sns:us-east-1:123:commands",
+	})
+
+	c.Len(policies, 2)
+	c.Equal(FilterPolicy{"command": {"deploy", "status"}}, policies["arn:aws:sns:us-east-1:123:deploys"])
+	c.Equal(FilterPolicy{"command": {"help"}}, policies["arn:aws:sns:us-east-1:123:commands"])
+}
+
+func TestSubscribeLambdaWithFilterPolicy(t *testing.T) {
+	c := require.New(t)
+
+	InitSNSMock()
+
+	arn, err := SubscribeLambdaWithFilterPolicy(context.Background(), "arn:aws:sns:us-east-1:123:deploys", "arn:aws:lambda:us-east-1:123:function:deploy", NewFilterPolicy("command", "deploy"))
+	c.NoError(err)
+	c.Contains(arn, "arn:aws:sns:us-east-1:123:deploys:")
+
+	ForceTopicNotFound = true
+
+	defer InitSNSMock()
+
+	_, err = SubscribeLambdaWithFilterPolicy(context.Background(), "arn:aws:sns:us-east-1:123:deploys", "arn:aws:lambda:us-east-1:123:function:deploy", NewFilterPolicy("command", "deploy"))
+	c.ErrorIs(err, ErrTopicNotFound)
+}
@@ -0,0 +1,330 @@
+// Package telegram is a client of the Telegram Bot API built on the shared http client
+package telegram
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"time"
+
+	"shared/shared/aws/secrets"
+	"shared/shared/client"
+
+	"bitbucket.org/truora/scrap-services/logger"
+	"bitbucket.org/truora/scrap-services/shared/env"
+)
+
+const (
+	// DefaultBaseURL of the Bot API
+	DefaultBaseURL = "https://api.telegram.org"
+
+	methodURL = "%s/bot%s/%s"
+	fileURL   = "%s/file/bot%s/%s"
+
+	jsonContentType = "application/json"
+)
+
+var (
+	// ErrAPI when Telegram answers a request with an error
+	ErrAPI = errors.New("telegram api error")
+	// ErrTooManyRequests when Telegram rate limits a request and the retries are exhausted
+	ErrTooManyRequests = errors.New("telegram too many requests")
+	// ErrEmptyToken when the bot token is empty
+	ErrEmptyToken = errors.New("telegram bot token is empty")
+
+	maxRetries    = int(env.GetInt64("TELEGRAM_MAX_RETRIES", 2))
+	maxRetryAfter = time.Duration(env.GetInt64("TELEGRAM_MAX_RETRY_AFTER_SECONDS", 30)) * time.Second
+
+	sleep = sleepContext
+)
+
+// Error is an error answered by Telegram
+type Error struct {
+	Method      string
+	Code        int
+	Description string
+	// RetryAfter is the number of seconds to wait before repeating a rate limited request
+	RetryAfter int
+}
+
+// Error returns the method, code and description of the error
+func (err *Error) Error() string {
+	return fmt.Sprintf("telegram %s failed with code %d: %s", err.Method, err.Code, err.Description)
+}
+
+// Unwrap allows to use errors.Is(err, ErrAPI)
+func (err *Error) Unwrap() error {
+	return ErrAPI
+}
+
+// Is allows to use errors.Is(err, ErrTooManyRequests) with rate limited requests
+func (err *Error) Is(target error) bool {
+	return target == ErrTooManyRequests && err.Code == http.StatusTooManyRequests
+}
+
+// Client of the Telegram Bot API
+type Client struct {
+	token string
+
+	// BaseURL of the Bot API, it can point to a local Bot API server
+	BaseURL string
+	// HTTPClient used to send the requests
+	HTTPClient *client.Client
+	// MaxRetries of a rate limited request, requests asking to wait more than MaxRetryAfter are not retried
+	MaxRetries    int
+	MaxRetryAfter time.Duration
+}
+
+// New creates a client for the bot token using the default http client
+func New(token string) (*Client, error) {
+	if token == "" {
+		return nil, ErrEmptyToken
+	}
+
+	return &Client{
+		token:         token,
+		BaseURL:       DefaultBaseURL,
+		HTTPClient:    client.Default,
+		MaxRetries:    maxRetries,
+		MaxRetryAfter: maxRetryAfter,
+	}, nil
+}
+
+// NewFromSecret creates a client with the bot token stored in the secrets manager
+func NewFromSecret(ctx context.Context, secretName string) (*Client, error) {
+	token, err := secrets.Get(ctx, secretName)
+	if err != nil {
+		return nil, fmt.Errorf("get telegram bot token failed: %w", err)
+	}
+
+	return New(token)
+}
+
+// SendMessage sends a text message
+func (c *Client) SendMessage(ctx context.Context, request *SendMessageRequest) (*Message, error) {
+	message := &Message{}
+
+	return message, c.call(ctx, "sendMessage", request, message)
+}
+
+// SendText sends a plain text message to the chat
+func (c *Client) SendText(ctx context.Context, chatID int64, text string) (*Message, error) {
+	return c.SendMessage(ctx, &SendMessageRequest{ChatID: chatID, Text: text})
+}
+
+// EditMessageText edits the text and keyboard of a message sent by the bot
+func (c *Client) EditMessageText(ctx context.Context, request *EditMessageTextRequest) (*Message, error) {
+	message := &Message{}
+
+	return message, c.call(ctx, "editMessageText", request, message)
+}
+
+// AnswerCallbackQuery stops the loading animation of the pressed button and optionally shows a notification
+func (c *Client) AnswerCallbackQuery(ctx context.Context, request *AnswerCallbackQueryRequest) error {
+	return c.call(ctx, "answerCallbackQuery", request, nil)
+}
+
+// SendPhoto sends a photo by file_id or URL, or uploads it when request.File is set
+func (c *Client) SendPhoto(ctx context.Context, request *SendPhotoRequest) (*Message, error) {
+	message := &Message{}
+
+	if request.File == nil {
+		return message, c.call(ctx, "sendPhoto", request, message)
+	}
+
+	body, contentType, err := photoMultipart(request)
+	if err != nil {
+		return nil, err
+	}
+
+	return message, c.send(ctx, "sendPhoto", body, contentType, message)
+}
+
+// SendDocument sends a general file by file_id or URL
+func (c *Client) SendDocument(ctx context.Context, request *SendDocumentRequest) (*Message, error) {
+	message := &Message{}
+
+	return message, c.call(ctx, "sendDocument", request, message)
+}
+
+// GetFile returns the metadata of a file, use FileURL to download it
+func (c *Client) GetFile(ctx context.Context, fileID string) (*File, error) {
+	file := &File{}
+
+	return file, c.call(ctx, "getFile", &GetFileRequest{FileID: fileID}, file)
+}
+
+// FileURL returns the URL to download the file path returned by GetFile
+func (c *Client) FileURL(filePath string) string {
+	return fmt.Sprintf(fileURL, c.BaseURL, c.token, filePath)
+}
+
+// SetWebhook points the bot updates to the webhook URL
+func (c *Client) SetWebhook(ctx context.Context, request *SetWebhookRequest) error {
+	return c.call(ctx, "setWebhook", request, nil)
+}
+
+// DeleteWebhook removes the webhook so updates can be received with GetUpdates
+func (c *Client) DeleteWebhook(ctx context.Context, request *DeleteWebhookRequest) error {
+	return c.call(ctx, "deleteWebhook", request, nil)
+}
+
+// SetMyCommands sets the commands shown in the Telegram commands menu
+func (c *Client) SetMyCommands(ctx context.Context, request *SetMyCommandsRequest) error {
+	return c.call(ctx, "setMyCommands", request, nil)
+}
+
+// GetUpdates returns the pending updates, it only works when the bot does not have a webhook
+func (c *Client) GetUpdates(ctx context.Context, request *GetUpdatesRequest) ([]Update, error) {
+	updates := []Update{}
+
+	return updates, c.call(ctx, "getUpdates", request, &updates)
+}
+
+// call sends the params as JSON and retries the request when it is rate limited
+func (c *Client) call(ctx context.Context, method string, params interface{}, result interface{}) error {
+	body, err := json.Marshal(params)
+	if err != nil {
+		return fmt.Errorf("telegram %s marshal failed: %w", method, err)
+	}
+
+	return c.send(ctx, method, string(body), jsonContentType, result)
+}
+
+func (c *Client) send(ctx context.Context, method, body, contentType string, result interface{}) error {
+	for attempt := 0; ; attempt++ {
+		err := c.do(ctx, method, body, contentType, result)
+
+		var apiErr *Error
+		if !errors.As(err, &apiErr) || apiErr.RetryAfter == 0 || attempt >= c.MaxRetries {
+			return err
+		}
+
+		retryAfter := time.Duration(apiErr.RetryAfter) * time.Second
+		if retryAfter > c.MaxRetryAfter {
+			return err
+		}
+
+		logger.Get(ctx).Warning(ctx, "telegram_request_rate_limited", logger.OneMonth, []logger.Object{
+			logger.ErrObject(err),
+			logger.MapObject("telegram", map[string]interface{}{
+				"s_method":      method,
+				"i_retry_after": apiErr.RetryAfter,
+				"i_attempt":     attempt + 1,
+			}),
+		})
+
+		err = sleep(ctx, retryAfter)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (c *Client) do(ctx context.Context, method, body, contentType string, result interface{}) error {
+	headers := http.Header{"Content-Type": {contentType}}
+
+	httpResponse, err := c.HTTPClient.SendRequest(ctx, http.MethodPost, fmt.Sprintf(methodURL, c.BaseURL, c.token, method), headers, body)
+	if httpResponse == nil {
+		return fmt.Errorf("telegram %s request failed: %w", method, err)
+	}
+
+	defer httpResponse.Body.Close()
+
+	rawBody, readErr := io.ReadAll(httpResponse.Body)
+	if readErr != nil {
+		return fmt.Errorf("telegram %s read response failed: %w", method, readErr)
+	}
+
+	apiResponse := &response{}
+
+	unmarshalErr := json.Unmarshal(rawBody, apiResponse)
+	if unmarshalErr != nil {
+		if err != nil {
+			return fmt.Errorf("telegram %s request failed: %w", method, err)
+		}
+
+		return fmt.Errorf("telegram %s unmarshal response failed: %w", method, unmarshalErr)
+	}
+
+	if !apiResponse.OK {
+		apiErr := &Error{Method: method, Code: apiResponse.ErrorCode, Description: apiResponse.Description}
+		if apiResponse.Parameters != nil {
+			apiErr.RetryAfter = apiResponse.Parameters.RetryAfter
+		}
+
+		return apiErr
+	}
+
+	if result == nil || len(apiResponse.Result) == 0 {
+		return nil
+	}
+
+	return json.Unmarshal(apiResponse.Result, result)
+}
+
+func photoMultipart(request *SendPhotoRequest) (string, string, error) {
+	buffer := &bytes.Buffer{}
+	writer := multipart.NewWriter(buffer)
+
+	fields := map[string]string{
+		"chat_id":    strconv.FormatInt(request.ChatID, 10),
+		"caption":    request.Caption,
+		"parse_mode": string(request.ParseMode),
+	}
+
+	if request.ReplyMarkup != nil {
+		replyMarkup, err := json.Marshal(request.ReplyMarkup)
+		if err != nil {
+			return "", "", err
+		}
+
+		fields["reply_markup"] = string(replyMarkup)
+	}
+
+	for name, value := range fields {
+		if value == "" {
+			continue
+		}
+
+		err := writer.WriteField(name, value)
+		if err != nil {
+			return "", "", err
+		}
+	}
+
+	part, err := writer.CreateFormFile("photo", request.File.Name)
+	if err != nil {
+		return "", "", err
+	}
+
+	_, err = part.Write(request.File.Content)
+	if err != nil {
+		return "", "", err
+	}
+
+	err = writer.Close()
+	if err != nil {
+		return "", "", err
+	}
+
+	return buffer.String(), writer.FormDataContentType(), nil
+}
+
+func sleepContext(ctx context.Context, duration time.Duration) error {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
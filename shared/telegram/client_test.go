@@ -0,0 +1,236 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"shared/shared/aws/secrets"
+
+	"github.com/stretchr/testify/require"
+)
+
+func mockSleep() (*[]time.Duration, func()) {
+	waits := []time.Duration{}
+
+	sleep = func(ctx context.Context, duration time.Duration) error {
+		waits = append(waits, duration)
+
+		return nil
+	}
+
+	return &waits, func() { sleep = sleepContext }
+}
+
+func TestNew(t *testing.T) {
+	c := require.New(t)
+
+	_, err := New("")
+	c.ErrorIs(err, ErrEmptyToken)
+
+	secrets.InitSecretsMock()
+
+	defer secrets.DeactivateMock()
+
+	_, err = NewFromSecret(context.Background(), "bot-token")
+	c.Error(err)
+
+	secrets.SetMockedSecret("bot-token", "token")
+
+	telegramClient, err := NewFromSecret(context.Background(), "bot-token")
+	c.NoError(err)
+	c.Equal("https://api.telegram.org/file/bottoken/documents/file.pdf", telegramClient.FileURL("documents/file.pdf"))
+}
+
+func TestSendMessage(t *testing.T) {
+	c := require.New(t)
+
+	fake := NewFake("")
+
+	defer fake.Close()
+
+	ctx := context.Background()
+	telegramClient := fake.Client()
+
+	message, err := telegramClient.SendMessage(ctx, &SendMessageRequest{
+		ChatID:      123,
+		Text:        "hello",
+		ParseMode:   ParseModeHTML,
+		ReplyMarkup: &InlineKeyboardMarkup{InlineKeyboard: [][]InlineKeyboardButton{{{Text: "Yes", CallbackData: "yes"}}}},
+	})
+	c.NoError(err)
+	c.Equal(1, message.MessageID)
+	c.Equal(int64(123), message.Chat.ID)
+
+	message, err = telegramClient.SendText(ctx, 123, "bye")
+	c.NoError(err)
+	c.Equal(2, message.MessageID)
+	c.Equal([]string{"hello", "bye"}, fake.SentTexts())
+
+	request := fake.Requests("sendMessage")[0]
+	c.Equal("HTML", request.Params["parse_mode"])
+	c.NotNil(request.Params["reply_markup"])
+
+	message, err = telegramClient.EditMessageText(ctx, &EditMessageTextRequest{ChatID: 123, MessageID: 1, Text: "done"})
+	c.NoError(err)
+	c.Equal(1, message.MessageID)
+	c.Equal("done", message.Text)
+
+	err = telegramClient.AnswerCallbackQuery(ctx, &AnswerCallbackQueryRequest{CallbackQueryID: "1", Text: "ok"})
+	c.NoError(err)
+	c.Len(fake.Requests("answerCallbackQuery"), 1)
+}
+
+func TestFakeBaseURL(t *testing.T) {
+	c := require.New(t)
+
+	fake := NewFake("http://localhost:8081")
+
+	defer fake.Close()
+
+	telegramClient := fake.Client()
+	c.Equal("http://localhost:8081", telegramClient.BaseURL)
+
+	_, err := telegramClient.SendText(context.Background(), 123, "hello")
+	c.NoError(err)
+	c.Equal([]string{"hello"}, fake.SentTexts())
+}
+
+func TestSendPhoto(t *testing.T) {
+	c := require.New(t)
+
+	fake := NewFake("")
+
+	defer fake.Close()
+
+	ctx := context.Background()
+	telegramClient := fake.Client()
+
+	_, err := telegramClient.SendPhoto(ctx, &SendPhotoRequest{ChatID: 123, Photo: "file-id"})
+	c.NoError(err)
+
+	message, err := telegramClient.SendPhoto(ctx, &SendPhotoRequest{ChatID: 123, Caption: "graph", File: &InputFile{Name: "graph.png", Content: []byte("png")}})
+	c.NoError(err)
+	c.Equal(int64(123), message.Chat.ID)
+
+	_, err = telegramClient.SendDocument(ctx, &SendDocumentRequest{ChatID: 123, Document: "https://example.com/report.pdf"})
+	c.NoError(err)
+	c.Equal("https://example.com/report.pdf", fake.Requests("sendDocument")[0].Params["document"])
+
+	requests := fake.Requests("sendPhoto")
+	c.Len(requests, 2)
+	c.Equal("file-id", requests[0].Params["photo"])
+	c.Equal("graph.png", requests[1].Params["photo"])
+	c.Equal("graph", requests[1].Params["caption"])
+}
+
+func TestWebhookAndUpdates(t *testing.T) {
+	c := require.New(t)
+
+	fake := NewFake("")
+
+	defer fake.Close()
+
+	ctx := context.Background()
+	telegramClient := fake.Client()
+
+	err := telegramClient.SetWebhook(ctx, &SetWebhookRequest{URL: "https://bot.example.com", SecretToken: "secret"})
+	c.NoError(err)
+	c.Equal("secret", fake.Requests("setWebhook")[0].Params["secret_token"])
+
+	err = telegramClient.DeleteWebhook(ctx, &DeleteWebhookRequest{DropPendingUpdates: true})
+	c.NoError(err)
+
+	err = telegramClient.SetMyCommands(ctx, &SetMyCommandsRequest{Commands: []BotCommand{{Command: "help", Description: "Shows the available commands"}}})
+	c.NoError(err)
+
+	fake.AddUpdates(`{"update_id":1,"message":{"message_id":1,"text":"/help","chat":{"id":1,"type":"private"}}}`)
+
+	updates, err := telegramClient.GetUpdates(ctx, &GetUpdatesRequest{Timeout: 1})
+	c.NoError(err)
+	c.Len(updates, 1)
+	c.Equal(int64(1), updates[0].UpdateID)
+	c.Equal("/help", updates[0].Message.Text)
+	c.Contains(string(updates[0].Raw), `"update_id":1`)
+
+	updates, err = telegramClient.GetUpdates(ctx, &GetUpdatesRequest{Offset: 2})
+	c.NoError(err)
+	c.Empty(updates)
+
+	file, err := telegramClient.GetFile(ctx, "file-id")
+	c.NoError(err)
+	c.Equal("documents/file-id", file.FilePath)
+}
+
+func TestRetryAfter(t *testing.T) {
+	c := require.New(t)
+
+	fake := NewFake("")
+
+	defer fake.Close()
+
+	waits, restore := mockSleep()
+
+	defer restore()
+
+	ctx := context.Background()
+	telegramClient := fake.Client()
+	telegramClient.MaxRetries = 2
+
+	fake.AddRateLimit("sendMessage", 3)
+
+	_, err := telegramClient.SendText(ctx, 123, "hello")
+	c.NoError(err)
+	c.Equal([]time.Duration{3 * time.Second}, *waits)
+	c.Len(fake.Requests("sendMessage"), 2)
+
+	fake.AddRateLimit("sendMessage", 1)
+	fake.AddRateLimit("sendMessage", 1)
+	fake.AddRateLimit("sendMessage", 1)
+
+	_, err = telegramClient.SendText(ctx, 123, "hello")
+	c.ErrorIs(err, ErrTooManyRequests)
+	c.ErrorIs(err, ErrAPI)
+
+	var apiErr *Error
+	c.True(errors.As(err, &apiErr))
+	c.Equal(1, apiErr.RetryAfter)
+
+	fake.AddRateLimit("sendMessage", 3600)
+
+	_, err = telegramClient.SendText(ctx, 123, "hello")
+	c.ErrorIs(err, ErrTooManyRequests)
+}
+
+func TestAPIError(t *testing.T) {
+	c := require.New(t)
+
+	fake := NewFake("")
+
+	defer fake.Close()
+
+	telegramClient := fake.Client()
+
+	fake.AddError("sendMessage", http.StatusBadRequest, "Bad Request: chat not found")
+
+	_, err := telegramClient.SendText(context.Background(), 123, "hello")
+	c.ErrorIs(err, ErrAPI)
+	c.NotErrorIs(err, ErrTooManyRequests)
+	c.Contains(err.Error(), "chat not found")
+
+	fake.AddResponse("sendMessage", http.StatusBadRequest, "<html>bad request</html>")
+
+	_, err = telegramClient.SendText(context.Background(), 123, "hello")
+	c.Error(err)
+
+	err = sleepContext(context.Background(), time.Millisecond)
+	c.NoError(err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err = sleepContext(ctx, time.Hour)
+	c.ErrorIs(err, context.Canceled)
+}
@@ -0,0 +1,248 @@
+package telegram
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"shared/shared/client"
+
+	"github.com/jarcoal/httpmock"
+)
+
+// FakeToken is the bot token of the clients created by the fake
+const FakeToken = "fake-token"
+
+// fakeMethods are the methods answered by the fake
+var fakeMethods = []string{
+	"sendMessage", "editMessageText", "answerCallbackQuery", "sendPhoto", "sendDocument", "getFile",
+	"setWebhook", "deleteWebhook", "setMyCommands", "getUpdates",
+}
+
+// FakeRequest is a request received by the fake
+type FakeRequest struct {
+	Method string
+	// Params are the JSON or multipart form params of the request
+	Params map[string]interface{}
+}
+
+// Fake is a Bot API registered in httpmock for tests, it records the requests and answers
+// every method with a successful response unless another one is set
+type Fake struct {
+	Token string
+	// BaseURL is the Bot API URL the fake is registered in
+	BaseURL string
+
+	mutex         sync.Mutex
+	requests      []FakeRequest
+	responses     map[string][]fakeResponse
+	updates       []json.RawMessage
+	nextMessageID int
+}
+
+type fakeResponse struct {
+	statusCode int
+	body       string
+}
+
+// NewFake activates the http client mock and registers the fake Bot API
+func NewFake(baseURL string) *Fake {
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+
+	fake := &Fake{Token: FakeToken, BaseURL: baseURL, responses: map[string][]fakeResponse{}, nextMessageID: 1}
+
+	client.ActivateMock()
+
+	for _, method := range fakeMethods {
+		method := method
+
+		httpmock.RegisterResponder(http.MethodPost, fmt.Sprintf(methodURL, baseURL, FakeToken, method), func(request *http.Request) (*http.Response, error) {
+			response, err := fake.respond(method, request)
+			if response != nil {
+				// the HTTP client reads the request of the blocked (403) responses like real responses have it
+				response.Request = request
+			}
+
+			return response, err
+		})
+	}
+
+	return fake
+}
+
+// Client returns a client of the fake Bot API
+func (fake *Fake) Client() *Client {
+	telegramClient, _ := New(fake.Token)
+	telegramClient.BaseURL = fake.BaseURL
+
+	return telegramClient
+}
+
+// Close deactivates the http client mock
+func (fake *Fake) Close() {
+	client.DeactivateMock()
+}
+
+// Requests returns the requests received for the method
+func (fake *Fake) Requests(method string) []FakeRequest {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+
+	requests := []FakeRequest{}
+
+	for _, request := range fake.requests {
+		if request.Method == method {
+			requests = append(requests, request)
+		}
+	}
+
+	return requests
+}
+
+// SentTexts returns the texts sent with sendMessage
+func (fake *Fake) SentTexts() []string {
+	texts := []string{}
+
+	for _, request := range fake.Requests("sendMessage") {
+		text, _ := request.Params["text"].(string)
+		texts = append(texts, text)
+	}
+
+	return texts
+}
+
+// AddResponse queues a response for the next request of the method
+func (fake *Fake) AddResponse(method string, statusCode int, body string) {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+
+	fake.responses[method] = append(fake.responses[method], fakeResponse{statusCode: statusCode, body: body})
+}
+
+// AddError queues an error response for the next request of the method
+func (fake *Fake) AddError(method string, code int, description string) {
+	fake.AddResponse(method, code, fmt.Sprintf(`{"ok":false,"error_code":%d,"description":%q}`, code, description))
+}
+
+// AddRateLimit queues a 429 response asking to retry after the given seconds
+func (fake *Fake) AddRateLimit(method string, retryAfter int) {
+	fake.AddResponse(method, http.StatusTooManyRequests, fmt.Sprintf(
+		`{"ok":false,"error_code":429,"description":"Too Many Requests: retry after %d","parameters":{"retry_after":%d}}`, retryAfter, retryAfter))
+}
+
+// AddUpdates queues updates returned by the next getUpdates request
+func (fake *Fake) AddUpdates(updates ...string) {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+
+	for _, update := range updates {
+		fake.updates = append(fake.updates, json.RawMessage(update))
+	}
+}
+
+func (fake *Fake) respond(method string, request *http.Request) (*http.Response, error) {
+	params, err := fakeParams(request)
+	if err != nil {
+		return nil, err
+	}
+
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+
+	fake.requests = append(fake.requests, FakeRequest{Method: method, Params: params})
+
+	if queued := fake.responses[method]; len(queued) > 0 {
+		fake.responses[method] = queued[1:]
+
+		return httpmock.NewStringResponse(queued[0].statusCode, queued[0].body), nil
+	}
+
+	result, err := fake.result(method, params)
+	if err != nil {
+		return nil, err
+	}
+
+	return httpmock.NewStringResponse(http.StatusOK, fmt.Sprintf(`{"ok":true,"result":%s}`, result)), nil
+}
+
+// result returns a successful result of the method built from the params
+func (fake *Fake) result(method string, params map[string]interface{}) ([]byte, error) {
+	switch method {
+	case "sendMessage", "sendPhoto", "sendDocument", "editMessageText":
+		text, _ := params["text"].(string)
+
+		messageID := fakeInt(params["message_id"])
+		if messageID == 0 {
+			messageID = int64(fake.nextMessageID)
+			fake.nextMessageID++
+		}
+
+		return json.Marshal(&Message{MessageID: int(messageID), Chat: Chat{ID: fakeInt(params["chat_id"])}, Date: time.Now().Unix(), Text: text})
+	case "getFile":
+		fileID, _ := params["file_id"].(string)
+
+		return json.Marshal(&File{FileID: fileID, FileUniqueID: fileID, FilePath: "documents/" + fileID})
+	case "getUpdates":
+		updates := fake.updates
+		fake.updates = nil
+
+		if updates == nil {
+			updates = []json.RawMessage{}
+		}
+
+		return json.Marshal(updates)
+	}
+
+	return []byte("true"), nil
+}
+
+func fakeParams(request *http.Request) (map[string]interface{}, error) {
+	params := map[string]interface{}{}
+
+	mediaType, _, _ := mime.ParseMediaType(request.Header.Get("Content-Type"))
+	if strings.HasPrefix(mediaType, "multipart/") {
+		err := request.ParseMultipartForm(1 << 20)
+		if err != nil {
+			return nil, err
+		}
+
+		for name, values := range request.MultipartForm.Value {
+			params[name] = values[0]
+		}
+
+		for name, files := range request.MultipartForm.File {
+			params[name] = files[0].Filename
+		}
+
+		return params, nil
+	}
+
+	body, err := io.ReadAll(request.Body)
+	if err != nil || len(body) == 0 {
+		return params, err
+	}
+
+	return params, json.Unmarshal(body, &params)
+}
+
+// fakeInt returns the integer of a JSON number or of a multipart form value
+func fakeInt(value interface{}) int64 {
+	switch typed := value.(type) {
+	case float64:
+		return int64(typed)
+	case string:
+		number, _ := strconv.ParseInt(typed, 10, 64)
+
+		return number
+	}
+
+	return 0
+}
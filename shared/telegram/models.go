@@ -0,0 +1,257 @@
+package telegram
+
+import (
+	"encoding/json"
+)
+
+// ParseMode of the message text
+type ParseMode string
+
+const (
+	// ParseModeMarkdownV2 formats the text with Telegram MarkdownV2
+	ParseModeMarkdownV2 ParseMode = "MarkdownV2"
+	// ParseModeHTML formats the text with HTML tags
+	ParseModeHTML ParseMode = "HTML"
+)
+
+// response is the envelope of every Bot API response
+type response struct {
+	OK          bool                `json:"ok"`
+	Result      json.RawMessage     `json:"result"`
+	ErrorCode   int                 `json:"error_code"`
+	Description string              `json:"description"`
+	Parameters  *ResponseParameters `json:"parameters"`
+}
+
+// ResponseParameters are the details of a failed request
+type ResponseParameters struct {
+	// RetryAfter is the number of seconds to wait when the request was rate limited
+	RetryAfter      int   `json:"retry_after"`
+	MigrateToChatID int64 `json:"migrate_to_chat_id"`
+}
+
+// User is a Telegram user or bot
+type User struct {
+	ID           int64  `json:"id"`
+	IsBot        bool   `json:"is_bot"`
+	FirstName    string `json:"first_name"`
+	LastName     string `json:"last_name,omitempty"`
+	Username     string `json:"username,omitempty"`
+	LanguageCode string `json:"language_code,omitempty"`
+}
+
+// Chat is a Telegram chat
+type Chat struct {
+	ID       int64  `json:"id"`
+	Type     string `json:"type"`
+	Title    string `json:"title,omitempty"`
+	Username string `json:"username,omitempty"`
+}
+
+// PhotoSize is one of the sizes of a photo
+type PhotoSize struct {
+	FileID       string `json:"file_id"`
+	FileUniqueID string `json:"file_unique_id"`
+	Width        int    `json:"width"`
+	Height       int    `json:"height"`
+	FileSize     int64  `json:"file_size,omitempty"`
+}
+
+// Document is a general file sent in a message
+type Document struct {
+	FileID       string `json:"file_id"`
+	FileUniqueID string `json:"file_unique_id"`
+	FileName     string `json:"file_name,omitempty"`
+	MimeType     string `json:"mime_type,omitempty"`
+	FileSize     int64  `json:"file_size,omitempty"`
+}
+
+// Message is a Telegram message
+type Message struct {
+	MessageID      int         `json:"message_id"`
+	From           *User       `json:"from,omitempty"`
+	Chat           Chat        `json:"chat"`
+	Date           int64       `json:"date"`
+	Text           string      `json:"text,omitempty"`
+	Caption        string      `json:"caption,omitempty"`
+	Photo          []PhotoSize `json:"photo,omitempty"`
+	Document       *Document   `json:"document,omitempty"`
+	ReplyToMessage *Message    `json:"reply_to_message,omitempty"`
+}
+
+// CallbackQuery is sent when an inline keyboard button is pressed
+type CallbackQuery struct {
+	ID      string   `json:"id"`
+	From    User     `json:"from"`
+	Message *Message `json:"message,omitempty"`
+	Data    string   `json:"data,omitempty"`
+}
+
+// InlineQuery is sent when the bot is used in inline mode
+type InlineQuery struct {
+	ID     string `json:"id"`
+	From   User   `json:"from"`
+	Query  string `json:"query"`
+	Offset string `json:"offset"`
+}
+
+// ChatMember is the status of a user in a chat
+type ChatMember struct {
+	Status string `json:"status"`
+	User   User   `json:"user"`
+}
+
+// ChatMemberUpdated is sent when the status of a chat member changes
+type ChatMemberUpdated struct {
+	Chat          Chat       `json:"chat"`
+	From          User       `json:"from"`
+	Date          int64      `json:"date"`
+	OldChatMember ChatMember `json:"old_chat_member"`
+	NewChatMember ChatMember `json:"new_chat_member"`
+}
+
+// Update is an incoming update, Raw keeps the original JSON so it can be forwarded as received
+type Update struct {
+	UpdateID      int64              `json:"update_id"`
+	Message       *Message           `json:"message,omitempty"`
+	EditedMessage *Message           `json:"edited_message,omitempty"`
+	ChannelPost   *Message           `json:"channel_post,omitempty"`
+	CallbackQuery *CallbackQuery     `json:"callback_query,omitempty"`
+	InlineQuery   *InlineQuery       `json:"inline_query,omitempty"`
+	MyChatMember  *ChatMemberUpdated `json:"my_chat_member,omitempty"`
+
+	Raw json.RawMessage `json:"-"`
+}
+
+// UnmarshalJSON decodes the update and keeps the raw JSON
+func (update *Update) UnmarshalJSON(data []byte) error {
+	type plainUpdate Update
+
+	err := json.Unmarshal(data, (*plainUpdate)(update))
+	if err != nil {
+		return err
+	}
+
+	update.Raw = append(json.RawMessage{}, data...)
+
+	return nil
+}
+
+// InlineKeyboardButton is a button of an inline keyboard
+type InlineKeyboardButton struct {
+	Text         string `json:"text"`
+	CallbackData string `json:"callback_data,omitempty"`
+	URL          string `json:"url,omitempty"`
+}
+
+// InlineKeyboardMarkup is an inline keyboard attached to a message
+type InlineKeyboardMarkup struct {
+	InlineKeyboard [][]InlineKeyboardButton `json:"inline_keyboard"`
+}
+
+// SendMessageRequest are the parameters of sendMessage
+type SendMessageRequest struct {
+	ChatID                int64                 `json:"chat_id"`
+	Text                  string                `json:"text"`
+	ParseMode             ParseMode             `json:"parse_mode,omitempty"`
+	ReplyMarkup           *InlineKeyboardMarkup `json:"reply_markup,omitempty"`
+	ReplyToMessageID      int                   `json:"reply_to_message_id,omitempty"`
+	DisableWebPagePreview bool                  `json:"disable_web_page_preview,omitempty"`
+}
+
+// EditMessageTextRequest are the parameters of editMessageText
+type EditMessageTextRequest struct {
+	ChatID      int64                 `json:"chat_id"`
+	MessageID   int                   `json:"message_id"`
+	Text        string                `json:"text"`
+	ParseMode   ParseMode             `json:"parse_mode,omitempty"`
+	ReplyMarkup *InlineKeyboardMarkup `json:"reply_markup,omitempty"`
+}
+
+// AnswerCallbackQueryRequest are the parameters of answerCallbackQuery
+type AnswerCallbackQueryRequest struct {
+	CallbackQueryID string `json:"callback_query_id"`
+	Text            string `json:"text,omitempty"`
+	ShowAlert       bool   `json:"show_alert,omitempty"`
+}
+
+// InputFile is a file uploaded with the request
+type InputFile struct {
+	Name    string
+	Content []byte
+}
+
+// SendPhotoRequest are the parameters of sendPhoto, Photo is a file_id or URL. When File
+// is set the photo is uploaded instead
+type SendPhotoRequest struct {
+	ChatID      int64                 `json:"chat_id"`
+	Photo       string                `json:"photo,omitempty"`
+	File        *InputFile            `json:"-"`
+	Caption     string                `json:"caption,omitempty"`
+	ParseMode   ParseMode             `json:"parse_mode,omitempty"`
+	ReplyMarkup *InlineKeyboardMarkup `json:"reply_markup,omitempty"`
+}
+
+// SendDocumentRequest are the parameters of sendDocument, Document is a file_id or URL
+type SendDocumentRequest struct {
+	ChatID      int64                 `json:"chat_id"`
+	Document    string                `json:"document"`
+	Caption     string                `json:"caption,omitempty"`
+	ParseMode   ParseMode             `json:"parse_mode,omitempty"`
+	ReplyMarkup *InlineKeyboardMarkup `json:"reply_markup,omitempty"`
+}
+
+// File is the metadata of a file ready to be downloaded
+type File struct {
+	FileID       string `json:"file_id"`
+	FileUniqueID string `json:"file_unique_id"`
+	FileSize     int64  `json:"file_size,omitempty"`
+	FilePath     string `json:"file_path,omitempty"`
+}
+
+// GetFileRequest are the parameters of getFile
+type GetFileRequest struct {
+	FileID string `json:"file_id"`
+}
+
+// SetWebhookRequest are the parameters of setWebhook
+type SetWebhookRequest struct {
+	URL                string   `json:"url"`
+	SecretToken        string   `json:"secret_token,omitempty"`
+	MaxConnections     int      `json:"max_connections,omitempty"`
+	AllowedUpdates     []string `json:"allowed_updates,omitempty"`
+	DropPendingUpdates bool     `json:"drop_pending_updates,omitempty"`
+}
+
+// DeleteWebhookRequest are the parameters of deleteWebhook
+type DeleteWebhookRequest struct {
+	DropPendingUpdates bool `json:"drop_pending_updates,omitempty"`
+}
+
+// BotCommand is a command shown in the Telegram commands menu
+type BotCommand struct {
+	Command     string `json:"command"`
+	Description string `json:"description"`
+}
+
+// BotCommandScope is the scope where the commands are shown, e.g. all_private_chats
+type BotCommandScope struct {
+	Type   string `json:"type"`
+	ChatID int64  `json:"chat_id,omitempty"`
+}
+
+// SetMyCommandsRequest are the parameters of setMyCommands
+type SetMyCommandsRequest struct {
+	Commands     []BotCommand     `json:"commands"`
+	Scope        *BotCommandScope `json:"scope,omitempty"`
+	LanguageCode string           `json:"language_code,omitempty"`
+}
+
+// GetUpdatesRequest are the parameters of getUpdates
+type GetUpdatesRequest struct {
+	Offset int64 `json:"offset,omitempty"`
+	Limit  int   `json:"limit,omitempty"`
+	// Timeout in seconds for long polling
+	Timeout        int      `json:"timeout,omitempty"`
+	AllowedUpdates []string `json:"allowed_updates,omitempty"`
+}